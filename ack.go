@@ -0,0 +1,140 @@
+package delayqueue
+
+import "time"
+
+// inflightTask 通过 PopDue 或 Channel 交出、等待确认的任务
+type inflightTask struct {
+	t      *task
+	cancel chan struct{} // 确认或重新安排时关闭，结束或取消可见性超时的计时
+}
+
+// Ack 确认任务已经处理完成，需要配合 WithVisibilityTimeout 使用
+// 任务从存储中移除，一次性任务的句柄关闭 Done；任务不在等待确认时返回 ErrTaskNotFound。
+// 周期任务的多次执行可能同时等待确认，Ack 与 Nack 按交出的先后顺序处理其中最早的一次
+func (q *DelayQueue) Ack(id string) error {
+	it := q.takeInflight(id)
+	if it == nil {
+		return ErrTaskNotFound
+	}
+	close(it.cancel)
+
+	t := it.t
+	if t.last && t.serializable() {
+		// 周期任务的下一次执行以同一个id保存，只有最后一次执行才从存储中移除
+		q.forget(t.id)
+	}
+	q.saveDeferred(t)
+	q.logEvent(LevelDebug, "task acked", "id", t.id)
+	q.completed(t, 0, nil)
+	if t.last {
+		q.executions.add(t.id)
+		t.complete()
+	}
+	return nil
+}
+
+// Nack 放弃处理任务，任务在 redeliverAfter 之后重新交给消费者，需要配合 WithVisibilityTimeout 使用
+// redeliverAfter 不大于 0 时立即重新交出；等待重新交出期间依然可以 Ack，任务不在等待确认时返回 ErrTaskNotFound
+func (q *DelayQueue) Nack(id string, redeliverAfter time.Duration) error {
+	it := q.takeInflight(id)
+	if it == nil {
+		return ErrTaskNotFound
+	}
+	close(it.cancel)
+
+	q.logEvent(LevelDebug, "task nacked", "id", id, "redeliver_after", redeliverAfter)
+	if redeliverAfter <= 0 {
+		q.redeliver(it.t)
+		return nil
+	}
+	q.track(it.t, redeliverAfter)
+	return nil
+}
+
+// track 将交出的任务记为等待确认，after 之后仍未确认时重新交给消费者
+func (q *DelayQueue) track(t *task, after time.Duration) {
+	q.arm(q.register(t), after)
+}
+
+// register 将即将交出的任务记为等待确认，交出之前调用，消费者收到任务后立即 Ack 也能找到它
+// 同一个任务的每次交出各自记录，周期任务重叠的多次执行不会互相覆盖
+func (q *DelayQueue) register(t *task) *inflightTask {
+	it := &inflightTask{t: t, cancel: make(chan struct{})}
+	q.inflightMu.Lock()
+	defer q.inflightMu.Unlock()
+	if q.inflight == nil {
+		q.inflight = make(map[string][]*inflightTask)
+	}
+	q.inflight[t.id] = append(q.inflight[t.id], it)
+	return it
+}
+
+// arm 任务交出之后开始可见性超时的计时，after 之后仍未确认时重新交给消费者
+// 计时开始之前已经确认或重新安排的任务不再计时
+func (q *DelayQueue) arm(it *inflightTask, after time.Duration) {
+	timer := q.clock.NewTimer(after)
+	go func() {
+		select {
+		case <-timer.C():
+			if q.untrack(it) {
+				q.logger.Printf("task %s not acked in time, redeliver", it.t.id)
+				q.redeliver(it.t)
+			}
+		case <-it.cancel:
+			timer.Stop()
+		case <-q.quit:
+			// 队列停止时还没有确认的任务留在存储中，下次启动时重新加载
+			timer.Stop()
+		}
+	}()
+}
+
+// untrack 将一次交出移出等待确认的列表，已经被确认或重新安排时返回 false
+func (q *DelayQueue) untrack(it *inflightTask) bool {
+	q.inflightMu.Lock()
+	defer q.inflightMu.Unlock()
+	list := q.inflight[it.t.id]
+	for i, x := range list {
+		if x != it {
+			continue
+		}
+		if len(list) == 1 {
+			delete(q.inflight, it.t.id)
+		} else {
+			q.inflight[it.t.id] = append(list[:i:i], list[i+1:]...)
+		}
+		return true
+	}
+	return false
+}
+
+// isInflight 判断任务是否已经交出、正在等待确认
+func (q *DelayQueue) isInflight(id string) bool {
+	q.inflightMu.Lock()
+	defer q.inflightMu.Unlock()
+	return len(q.inflight[id]) > 0
+}
+
+// takeInflight 取出任务最早的一次等待确认的交出，任务不存在时返回 nil
+func (q *DelayQueue) takeInflight(id string) *inflightTask {
+	q.inflightMu.Lock()
+	defer q.inflightMu.Unlock()
+	list := q.inflight[id]
+	if len(list) == 0 {
+		return nil
+	}
+	if len(list) == 1 {
+		delete(q.inflight, id)
+	} else {
+		q.inflight[id] = list[1:]
+	}
+	return list[0]
+}
+
+// redeliver 将任务重新放入等待领取的列表，重新交出的任务 Retries 加一
+func (q *DelayQueue) redeliver(t *task) {
+	q.do(func() {
+		t.ensureExtra().attempt++
+		q.readyTasks = append(q.readyTasks, t)
+	})
+}
@@ -0,0 +1,105 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestChannelAckRightAfterReceive(t *testing.T) {
+	q, clock := newTestQueue(t, WithConsumerMode(), WithVisibilityTimeout(time.Hour))
+
+	const n = 500
+	for i := 0; i < n; i++ {
+		q.Push(time.Second, func() {})
+	}
+	c := q.Channel()
+	fireNext(clock, time.Second)
+
+	// 收到任务后立即确认，任务一定已经记为等待确认
+	for i := 0; i < n; i++ {
+		info := receive(t, c)
+		if err := q.Ack(info.ID); err != nil {
+			t.Fatalf("Ack %s right after receive: %v", info.ID, err)
+		}
+	}
+}
+
+func TestAckOverlappingPeriodicDeliveries(t *testing.T) {
+	q, clock := newTestQueue(t, WithConsumerMode(), WithVisibilityTimeout(time.Hour))
+	id := q.PushRepeating(time.Second, func() {}, WithMaxRepeats(2))
+
+	// 第一次交出还没有确认时第二次执行到期，两次交出各自等待确认
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		fireNext(clock, time.Second)
+		info, err := q.PopDue(ctx)
+		if err != nil {
+			t.Fatalf("PopDue: %v", err)
+		}
+		if info.ID != id {
+			t.Fatalf("PopDue returned %s, want %s", info.ID, id)
+		}
+	}
+	for i := 0; i < 2; i++ {
+		if err := q.Ack(id); err != nil {
+			t.Fatalf("Ack delivery %d: %v", i, err)
+		}
+	}
+	if err := q.Ack(id); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("third Ack error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestNackRedelivers(t *testing.T) {
+	q, clock := newTestQueue(t, WithConsumerMode(), WithVisibilityTimeout(time.Hour))
+	h := q.Push(time.Second, func() {})
+	fireNext(clock, time.Second)
+
+	ctx := context.Background()
+	if _, err := q.PopDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := q.Nack(h.ID(), 0); err != nil {
+		t.Fatalf("Nack: %v", err)
+	}
+	info, err := q.PopDue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ID != h.ID() || info.Retries != 1 {
+		t.Errorf("redelivered %s with %d retries, want %s with 1", info.ID, info.Retries, h.ID())
+	}
+	if err := q.Ack(h.ID()); err != nil {
+		t.Fatalf("Ack redelivered task: %v", err)
+	}
+	receive(t, h.Done())
+}
+
+func TestVisibilityTimeoutRedelivers(t *testing.T) {
+	q, clock := newTestQueue(t, WithConsumerMode(), WithVisibilityTimeout(time.Minute))
+	h := q.Push(time.Second, func() {})
+	fireNext(clock, time.Second)
+
+	ctx := context.Background()
+	if _, err := q.PopDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// 超过可见性超时仍未确认，任务重新交出，之前的交出不能再确认
+	fireNext(clock, time.Minute)
+	info, err := q.PopDue(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ID != h.ID() {
+		t.Fatalf("redelivered %s, want %s", info.ID, h.ID())
+	}
+	if err := q.Ack(h.ID()); err != nil {
+		t.Fatalf("Ack redelivered task: %v", err)
+	}
+	if err := q.Ack(h.ID()); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("second Ack error = %v, want ErrTaskNotFound", err)
+	}
+}
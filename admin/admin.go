@@ -0,0 +1,300 @@
+// Package admin 为延时任务队列提供可嵌入的 HTTP 管理接口
+//
+// 接口默认只读，取消任务需要通过 WithCancel 显式开启，推送、调整与暂停等修改操作需要通过 WithWrite 显式开启：
+//
+//	GET  /snapshot                    导出等待执行的具名处理函数任务
+//	GET  /tasks                       列出所有等待执行的任务
+//	GET  /stats                       队列统计信息
+//	GET  /metrics                     队列运行指标
+//	GET  /healthz                     健康检查，不健康时返回 503 与错误信息，可以作为 Kubernetes 的探针
+//	POST /cancel?id=                  取消指定任务，任务不存在时返回 404
+//	POST /push                        推送具名处理函数任务，请求体为 PushRequest，返回 {"id": 任务id}
+//	POST /reschedule?id=&delay=       将任务调整为 delay（如 30s）之后执行，任务不存在时返回 404
+//	POST /pause                       暂停队列
+//	POST /resume                      恢复队列
+//
+// 修改操作不做身份认证，嵌入时需要由外层的中间件负责鉴权。
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+)
+
+// Queue 管理接口依赖的队列能力，*delayqueue.DelayQueue 满足该接口
+type Queue interface {
+	Snapshot() []delayqueue.PendingTask
+	Delete(id string) (bool, error)
+	Stats() delayqueue.Stats
+	Tasks() []delayqueue.TaskInfo
+	Metrics() delayqueue.Metrics
+	PushHandler(timeInterval time.Duration, name string, payload []byte, opts ...delayqueue.PushOption) string
+	Reschedule(id string, newDelay time.Duration) error
+	Pause()
+	Resume()
+	IsPaused() bool
+	Healthy() error
+}
+
+// Option 管理接口的可选配置
+type Option func(h *handler)
+
+// WithCancel 开启取消任务的接口
+func WithCancel() Option {
+	return func(h *handler) {
+		h.allowCancel = true
+	}
+}
+
+// WithWrite 开启推送、调整执行时间、暂停与恢复队列的接口，同时开启取消任务的接口
+func WithWrite() Option {
+	return func(h *handler) {
+		h.allowCancel = true
+		h.allowWrite = true
+	}
+}
+
+// PushRequest 推送任务的请求体
+type PushRequest struct {
+	Handler string `json:"handler"` // 具名处理函数的名称
+	Payload []byte `json:"payload"` // 传给处理函数的数据，JSON 中为 base64 编码
+	Delay   string `json:"delay"`   // 延时，格式与 time.ParseDuration 相同
+}
+
+// statsResponse /stats 接口的响应，在队列的统计快照之外附带队列是否暂停
+type statsResponse struct {
+	delayqueue.Stats
+	Paused bool `json:"paused"` // 队列是否暂停
+}
+
+// handler 管理接口
+type handler struct {
+	q           Queue
+	allowCancel bool
+	allowWrite  bool
+	mux         *http.ServeMux
+}
+
+// NewHandler 创建队列 q 的管理接口
+func NewHandler(q Queue, opts ...Option) http.Handler {
+	h := &handler{q: q, mux: http.NewServeMux()}
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	h.mux.HandleFunc("/snapshot", h.snapshot)
+	h.mux.HandleFunc("/stats", h.stats)
+	h.mux.HandleFunc("/cancel", h.cancel)
+	h.mux.HandleFunc("/tasks", h.tasks)
+	h.mux.HandleFunc("/metrics", h.metrics)
+	h.mux.HandleFunc("/healthz", h.healthz)
+	h.mux.HandleFunc("/push", h.push)
+	h.mux.HandleFunc("/reschedule", h.reschedule)
+	h.mux.HandleFunc("/pause", h.pause)
+	h.mux.HandleFunc("/resume", h.resume)
+	return h
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// snapshot 导出等待执行的任务
+func (h *handler) snapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tasks := h.q.Snapshot()
+	if tasks == nil {
+		tasks = []delayqueue.PendingTask{}
+	}
+	writeJSON(w, tasks)
+}
+
+// stats 队列统计信息
+func (h *handler) stats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, statsResponse{
+		Stats:  h.q.Stats(),
+		Paused: h.q.IsPaused(),
+	})
+}
+
+// healthz 健康检查
+func (h *handler) healthz(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := h.q.Healthy(); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	_, _ = w.Write([]byte("ok\n"))
+}
+
+// tasks 列出所有等待执行的任务
+func (h *handler) tasks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tasks := h.q.Tasks()
+	if tasks == nil {
+		tasks = []delayqueue.TaskInfo{}
+	}
+	writeJSON(w, tasks)
+}
+
+// metrics 队列运行指标
+func (h *handler) metrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJSON(w, h.q.Metrics())
+}
+
+// cancel 取消指定任务
+func (h *handler) cancel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.allowCancel {
+		http.Error(w, "cancel is disabled", http.StatusForbidden)
+		return
+	}
+
+	id := r.FormValue("id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+	if _, err := h.q.Delete(id); err != nil {
+		if errors.Is(err, delayqueue.ErrTaskNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, delayqueue.ErrTaskExecuted) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// push 推送具名处理函数任务
+func (h *handler) push(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w, r) {
+		return
+	}
+
+	var req PushRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Handler == "" {
+		http.Error(w, "missing handler", http.StatusBadRequest)
+		return
+	}
+	var delay time.Duration
+	if req.Delay != "" {
+		d, err := time.ParseDuration(req.Delay)
+		if err != nil {
+			http.Error(w, "invalid delay: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		delay = d
+	}
+
+	id := h.q.PushHandler(delay, req.Handler, req.Payload)
+	if id == "" {
+		// 队列已经停止，或者被准入控制、限流、数量上限拒绝
+		http.Error(w, "task rejected", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	writeJSON(w, map[string]string{"id": id})
+}
+
+// reschedule 调整任务的执行时间
+func (h *handler) reschedule(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w, r) {
+		return
+	}
+
+	id := r.FormValue("id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+	delay, err := time.ParseDuration(r.FormValue("delay"))
+	if err != nil {
+		http.Error(w, "invalid delay: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.q.Reschedule(id, delay); err != nil {
+		if errors.Is(err, delayqueue.ErrTaskNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// pause 暂停队列
+func (h *handler) pause(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w, r) {
+		return
+	}
+	h.q.Pause()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// resume 恢复队列
+func (h *handler) resume(w http.ResponseWriter, r *http.Request) {
+	if !h.writable(w, r) {
+		return
+	}
+	h.q.Resume()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writable 检查修改操作的请求方法与开关，不满足时输出错误并返回 false
+func (h *handler) writable(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return false
+	}
+	if !h.allowWrite {
+		http.Error(w, "write is disabled", http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// writeJSON 以 JSON 格式输出响应
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
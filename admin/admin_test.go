@@ -0,0 +1,224 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+)
+
+// newTestServer 创建使用模拟时钟的队列与它的管理接口，测试结束时停止队列
+func newTestServer(t *testing.T, opts ...Option) (*delayqueue.DelayQueue, *httptest.Server) {
+	t.Helper()
+	q := delayqueue.NewDelayQueue(
+		delayqueue.WithClock(delayqueue.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))),
+		delayqueue.WithHandler("h", func(payload []byte) {}),
+	)
+	srv := httptest.NewServer(NewHandler(q, opts...))
+	t.Cleanup(func() {
+		srv.Close()
+		_ = q.Stop(context.Background())
+	})
+	return q, srv
+}
+
+// do 发送请求，返回状态码与响应体
+func do(t *testing.T, srv *httptest.Server, method, path, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(data)
+}
+
+func TestReadEndpoints(t *testing.T) {
+	q, srv := newTestServer(t)
+	id := q.PushHandler(time.Hour, "h", []byte("x"))
+
+	code, body := do(t, srv, http.MethodGet, "/snapshot", "")
+	var snapshot []delayqueue.PendingTask
+	if code != http.StatusOK || json.Unmarshal([]byte(body), &snapshot) != nil || len(snapshot) != 1 || snapshot[0].ID != id {
+		t.Errorf("GET /snapshot = %d %s, want the pushed task", code, body)
+	}
+
+	code, body = do(t, srv, http.MethodGet, "/tasks", "")
+	var tasks []delayqueue.TaskInfo
+	if code != http.StatusOK || json.Unmarshal([]byte(body), &tasks) != nil || len(tasks) != 1 || tasks[0].ID != id {
+		t.Errorf("GET /tasks = %d %s, want the pushed task", code, body)
+	}
+
+	code, body = do(t, srv, http.MethodGet, "/stats", "")
+	var stats statsResponse
+	if code != http.StatusOK || json.Unmarshal([]byte(body), &stats) != nil || stats.PeakPending != 1 || stats.Paused {
+		t.Errorf("GET /stats = %d %s, want peak_pending 1 and not paused", code, body)
+	}
+
+	code, body = do(t, srv, http.MethodGet, "/metrics", "")
+	var metrics delayqueue.Metrics
+	if code != http.StatusOK || json.Unmarshal([]byte(body), &metrics) != nil {
+		t.Errorf("GET /metrics = %d %s", code, body)
+	}
+
+	if code, body = do(t, srv, http.MethodGet, "/healthz", ""); code != http.StatusOK {
+		t.Errorf("GET /healthz = %d %s, want 200", code, body)
+	}
+
+	// 只读接口拒绝其他请求方法
+	for _, path := range []string{"/snapshot", "/tasks", "/stats", "/metrics", "/healthz"} {
+		if code, _ := do(t, srv, http.MethodPost, path, ""); code != http.StatusMethodNotAllowed {
+			t.Errorf("POST %s = %d, want 405", path, code)
+		}
+	}
+}
+
+func TestHealthzUnhealthy(t *testing.T) {
+	q, srv := newTestServer(t)
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, body := do(t, srv, http.MethodGet, "/healthz", ""); code != http.StatusServiceUnavailable {
+		t.Errorf("GET /healthz on stopped queue = %d %s, want 503", code, body)
+	}
+}
+
+func TestWriteEndpointsDisabled(t *testing.T) {
+	q, srv := newTestServer(t)
+	id := q.PushHandler(time.Hour, "h", nil)
+
+	// 未开启时修改操作全部被拒绝，任务保持不变
+	for _, path := range []string{"/cancel?id=" + id, "/push", "/reschedule?id=" + id + "&delay=1s", "/pause", "/resume"} {
+		if code, _ := do(t, srv, http.MethodPost, path, `{"handler":"h"}`); code != http.StatusForbidden {
+			t.Errorf("POST %s = %d, want 403", path, code)
+		}
+	}
+	if n := q.Len(); n != 1 {
+		t.Errorf("Len after rejected writes = %d, want 1", n)
+	}
+}
+
+func TestCancel(t *testing.T) {
+	q, srv := newTestServer(t, WithCancel())
+	id := q.PushHandler(time.Hour, "h", nil)
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/cancel?id=" + id, http.StatusMethodNotAllowed},
+		{http.MethodPost, "/cancel", http.StatusBadRequest},
+		{http.MethodPost, "/cancel?id=" + id, http.StatusNoContent},
+		{http.MethodPost, "/cancel?id=" + id, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		if code, body := do(t, srv, tt.method, tt.path, ""); code != tt.want {
+			t.Errorf("%s %s = %d %s, want %d", tt.method, tt.path, code, body, tt.want)
+		}
+	}
+	if n := q.Len(); n != 0 {
+		t.Errorf("Len after cancel = %d, want 0", n)
+	}
+
+	// WithCancel 不开启其他修改操作
+	if code, _ := do(t, srv, http.MethodPost, "/pause", ""); code != http.StatusForbidden {
+		t.Errorf("POST /pause with only WithCancel = %d, want 403", code)
+	}
+}
+
+func TestPush(t *testing.T) {
+	q, srv := newTestServer(t, WithWrite())
+
+	tests := []struct {
+		body string
+		want int
+	}{
+		{`not json`, http.StatusBadRequest},
+		{`{"delay":"1s"}`, http.StatusBadRequest},
+		{`{"handler":"h","delay":"soon"}`, http.StatusBadRequest},
+		{`{"handler":"h","payload":"eA==","delay":"30s"}`, http.StatusCreated},
+	}
+	for _, tt := range tests {
+		code, body := do(t, srv, http.MethodPost, "/push", tt.body)
+		if code != tt.want {
+			t.Errorf("POST /push %s = %d %s, want %d", tt.body, code, body, tt.want)
+			continue
+		}
+		if code != http.StatusCreated {
+			continue
+		}
+
+		var resp map[string]string
+		if err := json.Unmarshal([]byte(body), &resp); err != nil || resp["id"] == "" {
+			t.Fatalf("POST /push response = %s, want an id", body)
+		}
+		tasks := q.Snapshot()
+		if len(tasks) != 1 || tasks[0].ID != resp["id"] || tasks[0].Handler != "h" || string(tasks[0].Payload) != "x" {
+			t.Errorf("Snapshot after push = %+v", tasks)
+		}
+	}
+}
+
+func TestReschedule(t *testing.T) {
+	q, srv := newTestServer(t, WithWrite())
+	id := q.PushHandler(time.Hour, "h", nil)
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/reschedule?delay=1s", http.StatusBadRequest},
+		{"/reschedule?id=" + id + "&delay=later", http.StatusBadRequest},
+		{"/reschedule?id=missing&delay=1s", http.StatusNotFound},
+		{"/reschedule?id=" + id + "&delay=30s", http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		if code, body := do(t, srv, http.MethodPost, tt.path, ""); code != tt.want {
+			t.Errorf("POST %s = %d %s, want %d", tt.path, code, body, tt.want)
+		}
+	}
+	if tasks := q.Tasks(); len(tasks) != 1 || tasks[0].Remaining != 30*time.Second {
+		t.Errorf("Tasks after reschedule = %+v, want 30s remaining", tasks)
+	}
+}
+
+func TestPauseResume(t *testing.T) {
+	q, srv := newTestServer(t, WithWrite())
+
+	if code, _ := do(t, srv, http.MethodGet, "/pause", ""); code != http.StatusMethodNotAllowed {
+		t.Errorf("GET /pause = %d, want 405", code)
+	}
+	if code, _ := do(t, srv, http.MethodPost, "/pause", ""); code != http.StatusNoContent {
+		t.Errorf("POST /pause = %d, want 204", code)
+	}
+	if !q.IsPaused() {
+		t.Error("queue not paused after POST /pause")
+	}
+	_, body := do(t, srv, http.MethodGet, "/stats", "")
+	var stats statsResponse
+	if err := json.Unmarshal([]byte(body), &stats); err != nil || !stats.Paused {
+		t.Errorf("GET /stats while paused = %s, want paused", body)
+	}
+
+	if code, _ := do(t, srv, http.MethodPost, "/resume", ""); code != http.StatusNoContent {
+		t.Errorf("POST /resume = %d, want 204", code)
+	}
+	if q.IsPaused() {
+		t.Error("queue still paused after POST /resume")
+	}
+}
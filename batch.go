@@ -0,0 +1,129 @@
+package delayqueue
+
+import (
+	"container/heap"
+	"context"
+	"time"
+)
+
+// PushItem 批量操作中的单个任务
+type PushItem struct {
+	Delay time.Duration // 延时
+	Func  func()        // 执行函数
+}
+
+// newItemTask 根据 PushItem 创建任务
+func (q *DelayQueue) newItemTask(item PushItem) *task {
+	t := q.newPushTask(item.Delay)
+	t.fn = item.Func
+	// 批量推送的任务直接在调度协程中加入任务列表，不经过 acceptTask 的平移
+	t.fromEnqueue = false
+	return t
+}
+
+// ReplaceAll 用一组新任务原子地替换所有等待执行的任务，返回新任务的id
+// 清空旧任务与加入新任务在调度协程的同一次操作中完成，不存在队列为空或新旧任务混杂的中间状态；
+// 调用之前推送的任务同样会被替换，已经开始执行的任务不受影响。替换不经过准入控制与推送限流；
+// 被替换的旧任务与 Delete 删除的任务相同，计入删除数量、回调 OnDelete 并从持久化存储中移除
+func (q *DelayQueue) ReplaceAll(items []PushItem) []string {
+	tasks := make([]*task, len(items))
+	ids := make([]string, len(items))
+	for i, item := range items {
+		tasks[i] = q.newItemTask(item)
+		ids[i] = tasks[i].id
+	}
+
+	// 旧任务与 Purge 一样经过正常的删除流程：计入删除数量、回调 OnDelete 并从存储中移除
+	var removed []string
+	q.do(func() {
+		removed = q.removeWhere(func(*task) bool {
+			return true
+		})
+		q.addTasks(tasks)
+	})
+	q.forgetDeleted(removed)
+	q.logEvent(LevelDebug, "tasks replaced", "deleted", len(removed), "added", len(tasks))
+	return ids
+}
+
+// PushBatch 批量推送任务，返回各个任务的id，顺序与 items 一致，被拒绝的任务对应的id为空字符串
+// 所有任务在调度协程的一次操作中加入任务列表，并一次性重建堆，比逐个 Push 少了大量的管道往返与堆调整；
+// 每个任务依然经过准入控制与任务数量上限的检查，但不受推送限流的约束；opts 应用到批量中的每一个任务
+func (q *DelayQueue) PushBatch(items []PushItem, opts ...PushOption) []string {
+	tasks := make([]*task, 0, len(items))
+	pushed := make([]func(context.Context), 0, len(items))
+	recorded := make([]func(), 0, len(items))
+	ids := make([]string, len(items))
+	pending := q.pending()
+	for i, item := range items {
+		t := q.newItemTask(item).apply(opts)
+		q.applyJitter(t)
+		if q.admission != nil {
+			if err := q.admission(t.execTime); err != nil {
+				q.logger.Printf("push task %s rejected: %v", t.id, err)
+				continue
+			}
+		}
+		if q.maxPending > 0 && pending+len(tasks) >= q.maxPending {
+			q.logger.Printf("push task %s rejected: %v", t.id, ErrQueueFull)
+			continue
+		}
+
+		pushed = append(pushed, q.hookPushed(t))
+		recorded = append(recorded, q.recordEnqueue(t))
+		tasks = append(tasks, t)
+		ids[i] = t.id
+	}
+
+	added := false
+	q.do(func() {
+		q.addTasks(tasks)
+		added = true
+	})
+	if !added {
+		// 队列已经停止，任务没有被加入
+		return make([]string, len(items))
+	}
+	// 任务已经由调度协程接管，只使用加入之前准备好的回调
+	for i := range tasks {
+		recorded[i]()
+		pushed[i](context.Background())
+	}
+	return ids
+}
+
+// addTasks 将一批任务添加到任务列表中
+// 数量较多时先全部追加再整体建堆，复杂度为 O(n)，而逐个插入为 O(k log n)
+func (q *DelayQueue) addTasks(tasks []*task) {
+	if q.wheel != nil || len(tasks) < len(q.tasks) {
+		// 使用时间轮时逐个放置，大部分任务进入时间轮，插入本身就是 O(1)
+		for _, t := range tasks {
+			if q.acceptBatched(t) {
+				q.addTask(t)
+			}
+		}
+		return
+	}
+
+	for _, t := range tasks {
+		if !q.acceptBatched(t) {
+			continue
+		}
+		// 替换同一id的任务时可能从尚未建堆的部分中移除元素，下标始终保持一致，最后统一建堆即可
+		q.indexTask(t)
+		q.seq++
+		t.seq = q.seq
+		t.index = len(q.tasks)
+		q.tasks = append(q.tasks, t)
+	}
+	heap.Init(&q.tasks)
+
+	if n := int64(q.taskCount()); n > q.peakPending.Load() {
+		q.peakPending.Store(n)
+	}
+}
+
+// acceptBatched 处理批量加入的任务通过 WithUniqueKey 设置的去重冲突，返回任务是否需要加入任务列表
+func (q *DelayQueue) acceptBatched(t *task) bool {
+	return t.extra().unique == "" || q.acceptUnique(t)
+}
@@ -0,0 +1,115 @@
+package delayqueue
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReplaceAll(t *testing.T) {
+	q, clock := newTestQueue(t, WithMaxConcurrency(1))
+
+	ran := make(chan string, 8)
+	old := []*Task{
+		q.Push(time.Second, func() { ran <- "old-1" }),
+		q.Push(2*time.Second, func() { ran <- "old-2" }),
+	}
+
+	// 替换之后旧任务全部结束且不会执行，新任务全部按时执行
+	ids := q.ReplaceAll([]PushItem{
+		{Delay: 1500 * time.Millisecond, Func: func() { ran <- "new-1" }},
+		{Delay: 3 * time.Second, Func: func() { ran <- "new-2" }},
+	})
+	if len(ids) != 2 || ids[0] == "" || ids[1] == "" {
+		t.Fatalf("ReplaceAll ids = %v", ids)
+	}
+	for _, h := range old {
+		receive(t, h.Done())
+	}
+	if n := q.Len(); n != 2 {
+		t.Errorf("Len after ReplaceAll = %d, want 2", n)
+	}
+
+	fireNext(clock, 1500*time.Millisecond)
+	if got := receive(t, ran); got != "new-1" {
+		t.Errorf("first task ran = %s, want new-1", got)
+	}
+	fireNext(clock, 1500*time.Millisecond)
+	if got := receive(t, ran); got != "new-2" {
+		t.Errorf("second task ran = %s, want new-2", got)
+	}
+	stopQueue(t, q)
+	close(ran)
+	for name := range ran {
+		t.Errorf("unexpected task %s ran", name)
+	}
+}
+
+func TestReplaceAllDeletesOldTasks(t *testing.T) {
+	storage := newTestStorage(t)
+	var deleted []string
+	q, _ := newTestQueue(t, WithStorage(storage), OnDelete(func(info TaskInfo) {
+		deleted = append(deleted, info.ID)
+	}))
+	old := q.PushHandler(time.Hour, "h", nil)
+
+	// 被替换的任务与 Delete 删除的任务相同：回调 OnDelete、计入删除数量并从存储中移除
+	q.ReplaceAll([]PushItem{{Delay: time.Hour, Func: func() {}}})
+	if len(deleted) != 1 || deleted[0] != old {
+		t.Errorf("OnDelete called for %v, want [%s]", deleted, old)
+	}
+	if n := q.Stats().Deleted; n != 1 {
+		t.Errorf("Stats().Deleted = %d, want 1", n)
+	}
+	if _, err := storage.Load(old); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("load replaced task error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestPushBatchFiringOrder(t *testing.T) {
+	for _, existing := range []int{0, 10} {
+		t.Run(fmt.Sprintf("%d existing", existing), func(t *testing.T) {
+			q, clock := newTestQueue(t, WithMaxConcurrency(1))
+			// 已有任务比批量多时逐个插入，否则追加后统一建堆，两种方式的执行顺序相同
+			for i := 0; i < existing; i++ {
+				q.Push(time.Hour, func() {})
+			}
+
+			ran := make(chan string, 5)
+			item := func(d time.Duration, name string) PushItem {
+				return PushItem{Delay: d, Func: func() { ran <- name }}
+			}
+			ids := q.PushBatch([]PushItem{
+				item(3*time.Second, "3s"),
+				item(time.Second, "1s-a"),
+				item(2*time.Second, "2s-a"),
+				item(time.Second, "1s-b"),
+				item(2*time.Second, "2s-b"),
+			})
+			for i, id := range ids {
+				if id == "" {
+					t.Fatalf("item %d rejected", i)
+				}
+			}
+
+			// 整批任务在调度协程的一次操作中直接加入任务列表，PushBatch 返回时已经全部可见，不经过 add 管道
+			if n, backlog := q.Len(), len(q.add); n != existing+5 || backlog != 0 {
+				t.Fatalf("Len, add backlog after PushBatch = %d, %d, want %d, 0", n, backlog, existing+5)
+			}
+
+			// 按执行时间触发，执行时间相同的任务保持在批量中的先后顺序
+			var got []string
+			for _, due := range []int{2, 2, 1} {
+				fireNext(clock, time.Second)
+				for i := 0; i < due; i++ {
+					got = append(got, receive(t, ran))
+				}
+			}
+			if want := []string{"1s-a", "1s-b", "2s-a", "2s-b", "3s"}; !reflect.DeepEqual(got, want) {
+				t.Errorf("firing order = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package delayqueue
+
+import (
+	"sync"
+	"time"
+)
+
+// CircuitBreakerConfig 熔断器配置
+type CircuitBreakerConfig struct {
+	FailureThreshold int           // 连续失败多少次后熔断，小于 1 时按 1 处理
+	Cooldown         time.Duration // 熔断持续的时间，之后进入半开状态，放行一个任务试探
+	PerKey           bool          // 是否按任务的 key 分别熔断，默认所有任务共用一个熔断器
+	DeferWhenOpen    bool          // 熔断期间到期的任务是否推迟到熔断结束后执行，默认直接跳过
+}
+
+// breakerState 熔断器的状态
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // 关闭，任务正常执行
+	breakerOpen                         // 打开，任务被短路
+	breakerHalfOpen                     // 半开，放行一个任务试探下游是否恢复
+)
+
+// breakerEntry 单个熔断器的状态
+type breakerEntry struct {
+	state    breakerState
+	failures int       // 连续失败的次数
+	openedAt time.Time // 进入打开状态的时间
+	trialing bool      // 半开状态下是否已经放行了试探任务
+}
+
+// circuitBreaker 任务执行的熔断器，只统计返回错误的任务
+type circuitBreaker struct {
+	mu      sync.Mutex
+	config  CircuitBreakerConfig
+	clock   Clock
+	entries map[string]*breakerEntry
+}
+
+// newCircuitBreaker 创建熔断器
+func newCircuitBreaker(config CircuitBreakerConfig, clock Clock) *circuitBreaker {
+	if config.FailureThreshold < 1 {
+		config.FailureThreshold = 1
+	}
+	return &circuitBreaker{
+		config:  config,
+		clock:   clock,
+		entries: make(map[string]*breakerEntry),
+	}
+}
+
+// entry 返回 key 对应的熔断器状态
+func (b *circuitBreaker) entry(key string) *breakerEntry {
+	if !b.config.PerKey {
+		key = ""
+	}
+	e, ok := b.entries[key]
+	if !ok {
+		e = &breakerEntry{}
+		b.entries[key] = e
+	}
+	return e
+}
+
+// allow 判断任务是否可以执行
+func (b *circuitBreaker) allow(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e := b.entry(key)
+	switch e.state {
+	case breakerOpen:
+		if b.clock.Now().Sub(e.openedAt) < b.config.Cooldown {
+			return false
+		}
+		// 熔断时间已过，进入半开状态，放行当前任务试探
+		e.state = breakerHalfOpen
+		e.trialing = true
+		return true
+	case breakerHalfOpen:
+		if e.trialing {
+			// 试探任务还没有结果，其余任务继续短路
+			return false
+		}
+		e.trialing = true
+		return true
+	default:
+		return true
+	}
+}
+
+// record 记录任务的执行结果
+func (b *circuitBreaker) record(key string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e := b.entry(key)
+	if err == nil {
+		// 成功一次即恢复
+		e.state = breakerClosed
+		e.failures = 0
+		e.trialing = false
+		return
+	}
+
+	e.failures++
+	if e.state == breakerHalfOpen || e.failures >= b.config.FailureThreshold {
+		// 试探失败或连续失败达到阈值，打开熔断器
+		e.state = breakerOpen
+		e.openedAt = b.clock.Now()
+		e.trialing = false
+	}
+}
+
+// reopenAt 返回熔断器预计进入半开状态的时间
+func (b *circuitBreaker) reopenAt(key string) time.Time {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	e := b.entry(key)
+	if e.state != breakerOpen {
+		return b.clock.Now()
+	}
+	return e.openedAt.Add(b.config.Cooldown)
+}
+
+// returnsError 判断任务的执行函数是否会返回错误，只有这类任务受熔断器保护
+func (t *task) returnsError() bool {
+	x := t.extra()
+	return t.handler != "" || x.fe != nil || x.fr != nil || x.fx != nil || x.publish != ""
+}
+
+// shortCircuit 熔断期间到期的任务，按配置跳过或推迟到熔断结束后执行，返回任务是否被推迟
+func (q *DelayQueue) shortCircuit(t *task, currentTime time.Time) bool {
+	q.logExecution(t, currentTime, OutcomeShortCircuited, nil)
+	if !q.breaker.config.DeferWhenOpen || q.runCanceled(t.id) {
+		q.logger.Printf("circuit breaker is open, skip task %s", t.id)
+		return false
+	}
+
+	// 推迟执行的任务是一次新的执行，周期任务的下一次执行已经另行安排，这里只推迟本次
+	next := t.copy()
+	next.execTime = q.breaker.reopenAt(t.extra().key)
+	next.ensureExtra().period = 0
+	next.ensureExtra().rerun = true
+	return q.enqueue(next) == nil
+}
@@ -0,0 +1,61 @@
+package delayqueue
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerShortCircuits(t *testing.T) {
+	var buf bytes.Buffer
+	q, clock := newTestQueue(t,
+		WithExecutionLog(&buf),
+		WithMaxConcurrency(1),
+		WithCircuitBreaker(CircuitBreakerConfig{FailureThreshold: 2, Cooldown: 10 * time.Second}),
+	)
+	q.RegisterHandlerContext("fail", func(ctx context.Context, payload []byte) error {
+		return errors.New("down")
+	})
+
+	// 处理函数连续失败两次打开熔断器，之后到期的返回错误的任务被短路，不返回错误的任务照常执行
+	plain := q.Push(5*time.Second, func() {})
+	want := map[string]string{
+		plain.ID():                                                                  OutcomeOK,
+		q.PushHandler(time.Second, "fail", nil):                                     OutcomeError,
+		q.PushHandler(2*time.Second, "fail", nil):                                   OutcomeError,
+		q.PushErrFunc(3*time.Second, func() error { return nil }):                   OutcomeShortCircuited,
+		q.PushResultFunc(4*time.Second, func() ([]byte, error) { return nil, nil }): OutcomeShortCircuited,
+	}
+	for i := 0; i < len(want); i++ {
+		fireNext(clock, time.Second)
+	}
+
+	// 任务按顺序执行，最后一个任务结束时熔断器已经按模拟时钟的当前时间打开
+	receive(t, plain.Done())
+
+	// 冷却时间过后放行试探任务，试探成功后熔断器恢复
+	want[q.PushErrFunc(10*time.Second, func() error { return nil })] = OutcomeOK
+	fireNext(clock, 10*time.Second)
+	want[q.PushHandler(time.Second, "fail", nil)] = OutcomeError
+	fireNext(clock, time.Second)
+
+	stopQueue(t, q)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var e ExecutionEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("execution log line is not JSON: %v", err)
+		}
+		if outcome := want[e.ID]; e.Outcome != outcome {
+			t.Errorf("task %s outcome = %q, want %q", e.ID, e.Outcome, outcome)
+		}
+		delete(want, e.ID)
+	}
+	if len(want) != 0 {
+		t.Errorf("tasks missing from execution log: %v", want)
+	}
+}
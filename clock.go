@@ -0,0 +1,152 @@
+package delayqueue
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock 时钟接口，队列中所有的取时与计时都通过它完成，便于在测试中替换为模拟时钟
+type Clock interface {
+	Now() time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer 计时器接口，语义与 *time.Timer 保持一致
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+// SystemClock 返回基于系统时间的时钟，即队列默认使用的时钟
+func SystemClock() Clock {
+	return realClock{}
+}
+
+// realClock 基于系统时间的时钟
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return realTimer{time.NewTimer(d)}
+}
+
+// realTimer 对 *time.Timer 的包装
+type realTimer struct {
+	t *time.Timer
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.t.Stop()
+}
+
+// ManualClock 手动推进的模拟时钟，时间只会在调用 Advance 或 Set 时前进
+type ManualClock struct {
+	mu     sync.Mutex
+	cond   *sync.Cond // 等待中的计时器数量变化时通知 BlockUntil
+	now    time.Time
+	timers []*manualTimer
+}
+
+// NewManualClock 创建从 start 开始的模拟时钟
+func NewManualClock(start time.Time) *ManualClock {
+	c := &ManualClock{now: start}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// BlockUntil 阻塞直到至少有 n 个计时器在等待触发
+// 测试中推进时钟之前先调用，可以确保队列已经为最近的任务设置好计时器，而不需要 sleep 等待
+func (c *ManualClock) BlockUntil(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+// Now 返回模拟时钟的当前时间
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer 创建在模拟时间经过 d 之后触发的计时器
+func (c *ManualClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &manualTimer{
+		clock:    c,
+		c:        make(chan time.Time, 1),
+		deadline: c.now.Add(d),
+	}
+	if d <= 0 {
+		// 时间已经到了，立即触发
+		t.c <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance 将模拟时钟向前推进 d，并触发所有到期的计时器
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	now := c.now.Add(d)
+	c.mu.Unlock()
+	c.Set(now)
+}
+
+// Set 将模拟时钟设置为 now，并触发所有到期的计时器；时间不允许倒退
+func (c *ManualClock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if now.Before(c.now) {
+		return
+	}
+	c.now = now
+
+	remain := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(now) {
+			remain = append(remain, t)
+			continue
+		}
+		t.c <- now
+	}
+	c.timers = remain
+}
+
+// manualTimer 模拟时钟的计时器
+type manualTimer struct {
+	clock    *ManualClock
+	c        chan time.Time
+	deadline time.Time
+}
+
+func (t *manualTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *manualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for i, timer := range t.clock.timers {
+		if timer == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,59 @@
+package delayqueue
+
+import (
+	"container/heap"
+	"time"
+)
+
+// ClockJumps 返回检测到的时钟跳变次数，需要开启 WithClockWatchdog
+func (q *DelayQueue) ClockJumps() uint64 {
+	return q.clockJumps.Load()
+}
+
+// checkClock 比较两次检查之间系统时间与单调时钟的流逝，相差超过阈值时重新安排任务
+func (q *DelayQueue) checkClock(now time.Time) {
+	last := q.lastClockCheck
+	q.lastClockCheck = now
+	if q.clockJumpThreshold <= 0 || last.IsZero() {
+		return
+	}
+
+	// Round(0) 去掉单调时钟读数，两个时刻相减得到系统时间的流逝；没有单调时钟读数的时钟（如 ManualClock）偏差总是 0
+	offset := now.Round(0).Sub(last.Round(0)) - now.Sub(last)
+	if offset < q.clockJumpThreshold && offset > -q.clockJumpThreshold {
+		return
+	}
+
+	q.clockJumps.Add(1)
+	q.logger.Printf("system clock jumped by %s, rescheduling tasks", offset)
+	q.reconcileClock(now)
+}
+
+// reconcileClock 时钟跳变后重新安排任务列表与时间轮中的任务
+// 带有单调时钟读数的执行时间按剩余的等待时间换算到新的系统时间上，使其与按系统时间表示的任务可以正确比较；
+// 时间轮的槽位按系统时间划分，整个时间轮从 now 开始重建
+func (q *DelayQueue) reconcileClock(now time.Time) {
+	for _, t := range q.tasks {
+		rebaseExecTime(t, now)
+	}
+	heap.Init(&q.tasks)
+
+	if q.wheel == nil {
+		return
+	}
+	tasks := q.wheel.drain()
+	q.wheel = newTimingWheel(q.wheelTick, q.wheelSize, now)
+	for _, t := range tasks {
+		rebaseExecTime(t, now)
+		q.placeTask(t)
+	}
+}
+
+// rebaseExecTime 将带有单调时钟读数的执行时间换算为 now 加上剩余的等待时间
+func rebaseExecTime(t *task, now time.Time) {
+	if t.execTime == t.execTime.Round(0) {
+		// 按系统时间表示的执行时间保持不变
+		return
+	}
+	t.execTime = now.Add(t.execTime.Sub(now))
+}
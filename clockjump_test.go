@@ -0,0 +1,63 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClockWatchdogWakesScheduler(t *testing.T) {
+	q, clock := newTestQueue(t, WithClockWatchdog(time.Second, 5*time.Second))
+	h := q.Push(time.Hour, func() {})
+
+	// 最近的任务一小时后才到期，看门狗仍然每秒唤醒调度协程一次
+	for i := 0; i < 3; i++ {
+		fireNext(clock, time.Second)
+	}
+	clock.BlockUntil(1)
+	var checked time.Time
+	q.do(func() {
+		checked = q.lastClockCheck
+	})
+	if want := testStart.Add(3 * time.Second); !checked.Equal(want) {
+		t.Errorf("last clock check = %v, want %v", checked, want)
+	}
+	select {
+	case <-h.Done():
+		t.Fatal("task ran before it was due")
+	default:
+	}
+
+	// 模拟时钟没有单调时钟读数，系统时间与单调时钟的流逝总是一致，不会误判为跳变
+	if n := q.ClockJumps(); n != 0 {
+		t.Errorf("ClockJumps = %d, want 0", n)
+	}
+	clock.Set(testStart.Add(time.Hour))
+	receive(t, h.Done())
+}
+
+func TestReconcileClockKeepsTasks(t *testing.T) {
+	q, _ := newTestQueue(t, WithTimingWheel(time.Second, 8))
+	var want []string
+	for i := 1; i <= 6; i++ {
+		// 较远的任务进入时间轮，较近的任务留在堆中
+		want = append(want, q.Push(time.Duration(i*i)*time.Second, func() {}).ID())
+	}
+	settle(q)
+
+	// 时钟跳变后时间轮从当前时刻重建，任务与执行顺序保持不变
+	q.do(func() {
+		q.reconcileClock(q.clock.Now())
+	})
+	infos := q.Tasks()
+	if len(infos) != len(want) {
+		t.Fatalf("Tasks after reconcile = %d, want %d", len(infos), len(want))
+	}
+	for i, info := range infos {
+		if info.ID != want[i] {
+			t.Errorf("task %d = %s, want %s", i, info.ID, want[i])
+		}
+		if at := testStart.Add(time.Duration((i+1)*(i+1)) * time.Second); !info.ExecTime.Equal(at) {
+			t.Errorf("task %s exec time = %v, want %v", info.ID, info.ExecTime, at)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package delayqueue
+
+// Clone 将当前所有等待执行的任务复制到一个新队列中，原队列不受影响、继续运行
+// 新队列沿用当前队列的配置与已注册的具名处理函数，复制出的任务保持原有的 id 与执行时间，两个队列各自独立执行；
+// 执行函数是同一个闭包，任务在两个队列中都会执行，闭包的副作用也会发生两次。
+// 暂停、扣留的任务在副本中保持暂停与扣留，暂停中的标签与子队列在副本中同样暂停。
+// 配合模拟时钟可以在副本上推演调度，而不影响线上的队列；副本不使用持久化存储，不会影响原队列保存的任务。
+// 设置了 WithElector 时副本不单独竞选，只在原队列是 leader 时触发任务
+func (q *DelayQueue) Clone() *DelayQueue {
+	nq := q.derive(func(q *DelayQueue) {
+		q.storage = nil
+		q.deadLetterStorage = nil
+	})
+
+	var (
+		lists  pendingLists
+		pauses pausedSets
+	)
+	q.do(func() {
+		// 副本不能沿用原任务在堆与时间轮中的位置，也不共享原任务的句柄
+		clone := func(tasks []*task) []*task {
+			copies := make([]*task, 0, len(tasks))
+			for _, t := range tasks {
+				cp := t.copy()
+				cp.index = -1
+				cp.bucket = nil
+				cp.handle = nil
+				copies = append(copies, cp)
+			}
+			return copies
+		}
+		src := q.pendingLists()
+		lists = pendingLists{
+			ready:     clone(src.ready),
+			held:      clone(src.held),
+			scheduled: clone(src.scheduled),
+			paused:    clone(src.paused),
+		}
+		pauses = q.pausedSets()
+	})
+
+	nq.adopt(lists, pauses)
+	q.follow(nq)
+	return nq
+}
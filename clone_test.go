@@ -0,0 +1,60 @@
+package delayqueue
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCloneFiresIndependently(t *testing.T) {
+	type fire struct {
+		name string
+		at   time.Time
+	}
+	fired := make(chan fire, 4)
+	q, clock := newTestQueue(t, WithTimingWheel(time.Second, 8))
+	for _, name := range []string{"a", "b"} {
+		name := name
+		d := time.Second
+		if name == "b" {
+			d = 20 * time.Second
+		}
+		q.Push(d, func() { fired <- fire{name, clock.Now()} })
+	}
+
+	clone := q.Clone()
+	t.Cleanup(func() { stopQueue(t, clone) })
+	if n := clone.Len(); n != 2 {
+		t.Fatalf("clone Len = %d, want 2", n)
+	}
+
+	// 两个队列各自触发同一批任务，时刻相同
+	for _, want := range []fire{{"a", testStart.Add(time.Second)}, {"b", testStart.Add(20 * time.Second)}} {
+		clock.BlockUntil(2)
+		clock.Set(want.at)
+		got := []fire{receive(t, fired), receive(t, fired)}
+		sort.Slice(got, func(i, j int) bool { return got[i].name < got[j].name })
+		for _, f := range got {
+			if f != want {
+				t.Errorf("fired %v at %v, want %v at %v", f.name, f.at, want.name, want.at)
+			}
+		}
+	}
+
+	// 删除副本中的任务不影响原队列
+	h := q.Push(time.Second, func() {})
+	clone2 := q.Clone()
+	t.Cleanup(func() { stopQueue(t, clone2) })
+	if err := h.Cancel(); err != nil {
+		t.Fatalf("Cancel original: %v", err)
+	}
+	if n := clone2.Len(); n != 1 {
+		t.Errorf("clone Len after deleting from original = %d, want 1", n)
+	}
+}
+
+func TestCloneKeepsPausedTask(t *testing.T) {
+	testPausedTaskMoves(t, func(q *DelayQueue) *DelayQueue {
+		return q.Clone()
+	})
+}
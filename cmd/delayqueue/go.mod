@@ -0,0 +1,31 @@
+module github.com/gzltommy/delayqueue/cmd/delayqueue
+
+go 1.19
+
+require (
+	github.com/gzltommy/delayqueue v0.0.0
+	github.com/gzltommy/delayqueue/mongoqueue v0.0.0
+	github.com/redis/go-redis/v9 v9.0.5
+	go.mongodb.org/mongo-driver v1.11.6
+)
+
+require (
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/dgryski/go-rendezvous v0.0.0-20200823014737-9f7001d12a5f // indirect
+	github.com/golang/snappy v0.0.1 // indirect
+	github.com/klauspost/compress v1.13.6 // indirect
+	github.com/montanaflynn/stats v0.0.0-20171201202039-1bf9dbcd8cbe // indirect
+	github.com/pkg/errors v0.9.1 // indirect
+	github.com/xdg-go/pbkdf2 v1.0.0 // indirect
+	github.com/xdg-go/scram v1.1.1 // indirect
+	github.com/xdg-go/stringprep v1.0.3 // indirect
+	github.com/youmark/pkcs8 v0.0.0-20181117223130-1be2e3e5546d // indirect
+	golang.org/x/crypto v0.0.0-20220622213112-05595931fe9d // indirect
+	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c // indirect
+	golang.org/x/text v0.3.7 // indirect
+)
+
+replace (
+	github.com/gzltommy/delayqueue => ../../
+	github.com/gzltommy/delayqueue/mongoqueue => ../../mongoqueue
+)
@@ -0,0 +1,364 @@
+// Command delayqueue 查看与管理延时任务队列的持久化存储
+//
+// 用法：
+//
+//	delayqueue [-backend file|redis|mongo] [后端参数] [-dead 位置] [-create] [-json] <命令> [参数]
+//
+// 后端：
+//
+//	file   -dir DIR                             NewFileStorage 使用的目录（对应 WithStorage）
+//	redis  -redis ADDR -key KEY                 redisqueue 使用的有序集合 key，任务内容在 KEY:tasks 哈希表中（redisqueue.Storage）
+//	mongo  -mongo URI -db DB -collection NAME   mongoqueue 使用的集合（mongoqueue.Storage）
+//
+// -dead 指定同一后端中的死信存储：file 为目录，redis 为 key，mongo 为集合。
+// 目录或集合不存在时默认报错，避免写错名称时静默地操作一个空的存储；指定 -create 时自动创建。
+// Redis 的 key 在队列为空时不存在，无法区分，不做检查。
+//
+// 命令：
+//
+//	list                                     列出存储中的任务，按执行时间排序
+//	add -handler NAME [-delay D] [-payload S] 向存储中添加任务
+//	delete ID...                             从存储中删除任务
+//	redrive ID...                            将死信存储中的任务移回存储，立即执行
+//	stats                                    输出任务数量、最早与最晚的执行时间等统计信息
+//
+// 对于 file 后端，队列只在创建时加载存储中的任务，对正在运行的队列所做的修改需要在重启后生效，
+// 管理正在运行的队列请使用 admin 包提供的 HTTP 接口；redis 与 mongo 后端由各实例轮询，修改立即生效。
+//
+// 工具位于独立的模块中，Redis 与 mongo 驱动的依赖不会引入队列本身。
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+	"github.com/gzltommy/delayqueue/redisqueue"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "delayqueue:", err)
+		os.Exit(1)
+	}
+}
+
+// config 全局参数
+type config struct {
+	backend    string
+	dir        string
+	redisAddr  string
+	key        string
+	mongoURI   string
+	db         string
+	collection string
+	dead       string
+	create     bool
+	timeout    time.Duration
+}
+
+// run 解析全局参数并执行子命令
+func run(args []string, out io.Writer) error {
+	var cfg config
+	fs := flag.NewFlagSet("delayqueue", flag.ContinueOnError)
+	fs.StringVar(&cfg.backend, "backend", "file", "存储后端：file、redis 或 mongo")
+	fs.StringVar(&cfg.dir, "dir", "", "任务存储目录（file）")
+	fs.StringVar(&cfg.redisAddr, "redis", "localhost:6379", "Redis 地址（redis）")
+	fs.StringVar(&cfg.key, "key", "", "队列的有序集合 key（redis）")
+	fs.StringVar(&cfg.mongoURI, "mongo", "mongodb://localhost:27017", "MongoDB 连接串（mongo）")
+	fs.StringVar(&cfg.db, "db", "", "数据库名称（mongo）")
+	fs.StringVar(&cfg.collection, "collection", "", "队列的集合名称（mongo）")
+	fs.StringVar(&cfg.dead, "dead", "", "死信存储：file 为目录，redis 为 key，mongo 为集合")
+	fs.BoolVar(&cfg.create, "create", false, "目录或集合不存在时创建")
+	fs.DurationVar(&cfg.timeout, "timeout", 10*time.Second, "访问 Redis 或 MongoDB 的超时时间")
+	asJSON := fs.Bool("json", false, "以 JSON 格式输出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("missing command: list, add, delete, redrive or stats")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.timeout)
+	defer cancel()
+	c := &cli{out: out, json: *asJSON}
+	closeFn, err := c.open(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	cmd, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case "list":
+		return c.list()
+	case "add":
+		return c.add(cmdArgs)
+	case "delete":
+		return c.delete(cmdArgs)
+	case "redrive":
+		return c.redrive(cmdArgs)
+	case "stats":
+		return c.stats()
+	default:
+		return fmt.Errorf("unknown command %q", cmd)
+	}
+}
+
+// cli 子命令共用的状态
+type cli struct {
+	storage delayqueue.Storage
+	dead    delayqueue.Storage // 死信存储，未指定 -dead 时为 nil
+	out     io.Writer
+	json    bool
+}
+
+// open 按 cfg 连接存储与死信存储，返回释放连接的函数
+func (c *cli) open(ctx context.Context, cfg config) (func(), error) {
+	switch cfg.backend {
+	case "file":
+		if cfg.dir == "" {
+			return nil, errors.New("missing -dir")
+		}
+		var err error
+		if c.storage, err = openFileStorage(cfg.dir, cfg.create); err != nil {
+			return nil, err
+		}
+		if cfg.dead != "" {
+			if c.dead, err = openFileStorage(cfg.dead, cfg.create); err != nil {
+				return nil, err
+			}
+		}
+		return func() {}, nil
+
+	case "redis":
+		if cfg.key == "" {
+			return nil, errors.New("missing -key")
+		}
+		client := redis.NewClient(&redis.Options{Addr: cfg.redisAddr})
+		if err := client.Ping(ctx).Err(); err != nil {
+			client.Close()
+			return nil, err
+		}
+		c.storage = redisqueue.NewStorage(ctx, redisClient{client}, cfg.key)
+		if cfg.dead != "" {
+			c.dead = redisqueue.NewStorage(ctx, redisClient{client}, cfg.dead)
+		}
+		return func() { client.Close() }, nil
+
+	case "mongo":
+		if cfg.db == "" || cfg.collection == "" {
+			return nil, errors.New("missing -db or -collection")
+		}
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(cfg.mongoURI))
+		if err != nil {
+			return nil, err
+		}
+		closeFn := func() { _ = client.Disconnect(context.Background()) }
+		db := client.Database(cfg.db)
+		if c.storage, err = openMongoStorage(ctx, db, cfg.collection, cfg.create); err != nil {
+			closeFn()
+			return nil, err
+		}
+		if cfg.dead != "" {
+			if c.dead, err = openMongoStorage(ctx, db, cfg.dead, cfg.create); err != nil {
+				closeFn()
+				return nil, err
+			}
+		}
+		return closeFn, nil
+
+	default:
+		return nil, fmt.Errorf("unknown backend %q", cfg.backend)
+	}
+}
+
+// openFileStorage 打开目录 dir 上的存储，目录不存在时除非 create 为 true 否则返回错误
+func openFileStorage(dir string, create bool) (delayqueue.Storage, error) {
+	fi, err := os.Stat(dir)
+	switch {
+	case errors.Is(err, os.ErrNotExist) && !create:
+		return nil, fmt.Errorf("directory %s does not exist, use -create to create it", dir)
+	case err == nil && !fi.IsDir():
+		return nil, fmt.Errorf("%s is not a directory", dir)
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+	return delayqueue.NewFileStorage(dir)
+}
+
+// list 列出存储中的任务
+func (c *cli) list() error {
+	tasks, err := c.sortedTasks()
+	if err != nil {
+		return err
+	}
+	if c.json {
+		return c.writeJSON(tasks)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEXEC TIME\tIN\tHANDLER\tPAYLOAD")
+	now := time.Now()
+	for _, t := range tasks {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d bytes\n", t.ID, t.ExecTime.Format(time.RFC3339), t.ExecTime.Sub(now).Round(time.Second), t.Handler, len(t.Payload))
+	}
+	return w.Flush()
+}
+
+// add 向存储中添加任务
+func (c *cli) add(args []string) error {
+	fs := flag.NewFlagSet("add", flag.ContinueOnError)
+	handler := fs.String("handler", "", "具名处理函数的名称")
+	delay := fs.Duration("delay", 0, "延时")
+	payload := fs.String("payload", "", "传给处理函数的数据")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *handler == "" {
+		return errors.New("missing -handler")
+	}
+
+	task := delayqueue.PendingTask{
+		ID:       delayqueue.NewIDGenerator(delayqueue.IDFormatObjectID).NewID(),
+		ExecTime: time.Now().Add(*delay),
+		Handler:  *handler,
+		Payload:  []byte(*payload),
+	}
+	if err := c.storage.Save(task); err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, task.ID)
+	return nil
+}
+
+// delete 从存储中删除任务
+func (c *cli) delete(ids []string) error {
+	if len(ids) == 0 {
+		return errors.New("missing task id")
+	}
+	for _, id := range ids {
+		if _, err := c.storage.Load(id); err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		if err := c.storage.Remove(id); err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// redrive 将死信存储中的任务移回存储，执行时间设为当前时间
+func (c *cli) redrive(ids []string) error {
+	if c.dead == nil {
+		return errors.New("redrive requires -dead")
+	}
+	if len(ids) == 0 {
+		return errors.New("missing task id")
+	}
+	for _, id := range ids {
+		task, err := c.dead.Load(id)
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		task.ExecTime = time.Now()
+		// 先写入存储再从死信中移除，中途失败时任务不会丢失
+		if err := c.storage.Save(task); err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		if err := c.dead.Remove(id); err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// Stats 存储的统计信息
+type Stats struct {
+	Pending     int            `json:"pending"`                // 存储中的任务数量
+	Overdue     int            `json:"overdue"`                // 执行时间已过的任务数量
+	Earliest    *time.Time     `json:"earliest,omitempty"`     // 最早的执行时间
+	Latest      *time.Time     `json:"latest,omitempty"`       // 最晚的执行时间
+	Handlers    map[string]int `json:"handlers"`               // 各处理函数的任务数量
+	DeadLetters *int           `json:"dead_letters,omitempty"` // 死信任务数量，未指定 -dead 时不输出
+}
+
+// stats 输出存储的统计信息
+func (c *cli) stats() error {
+	tasks, err := c.sortedTasks()
+	if err != nil {
+		return err
+	}
+
+	st := Stats{Pending: len(tasks), Handlers: make(map[string]int)}
+	now := time.Now()
+	for _, t := range tasks {
+		if t.ExecTime.Before(now) {
+			st.Overdue++
+		}
+		st.Handlers[t.Handler]++
+	}
+	if len(tasks) > 0 {
+		st.Earliest, st.Latest = &tasks[0].ExecTime, &tasks[len(tasks)-1].ExecTime
+	}
+	if c.dead != nil {
+		letters, err := c.dead.List()
+		if err != nil {
+			return err
+		}
+		n := len(letters)
+		st.DeadLetters = &n
+	}
+	if c.json {
+		return c.writeJSON(st)
+	}
+
+	fmt.Fprintf(c.out, "pending:  %d\n", st.Pending)
+	fmt.Fprintf(c.out, "overdue:  %d\n", st.Overdue)
+	if st.Earliest != nil {
+		fmt.Fprintf(c.out, "earliest: %s\n", st.Earliest.Format(time.RFC3339))
+		fmt.Fprintf(c.out, "latest:   %s\n", st.Latest.Format(time.RFC3339))
+	}
+	if st.DeadLetters != nil {
+		fmt.Fprintf(c.out, "dead:     %d\n", *st.DeadLetters)
+	}
+	names := make([]string, 0, len(st.Handlers))
+	for name := range st.Handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(c.out, "handler %s: %d\n", name, st.Handlers[name])
+	}
+	return nil
+}
+
+// sortedTasks 返回存储中的任务，按执行时间排序
+func (c *cli) sortedTasks() ([]delayqueue.PendingTask, error) {
+	tasks, err := c.storage.List()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].ExecTime.Before(tasks[j].ExecTime)
+	})
+	return tasks, nil
+}
+
+// writeJSON 以 JSON 格式输出
+func (c *cli) writeJSON(v any) error {
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
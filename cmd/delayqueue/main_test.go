@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCLI 执行命令并返回输出
+func runCLI(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	var out bytes.Buffer
+	err := run(args, &out)
+	return out.String(), err
+}
+
+func TestMissingDirRequiresCreate(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "typo")
+
+	if _, err := runCLI(t, "-dir", dir, "list"); err == nil || !strings.Contains(err.Error(), "-create") {
+		t.Errorf("list on missing dir error = %v, want a hint to use -create", err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("missing dir was created without -create: %v", err)
+	}
+
+	if _, err := runCLI(t, "-dir", dir, "-create", "list"); err != nil {
+		t.Fatalf("list with -create error = %v", err)
+	}
+	if fi, err := os.Stat(dir); err != nil || !fi.IsDir() {
+		t.Errorf("-create did not create the directory: %v", err)
+	}
+}
+
+func TestMissingDeadDirRequiresCreate(t *testing.T) {
+	dir := t.TempDir()
+	dead := filepath.Join(t.TempDir(), "dead")
+	if _, err := runCLI(t, "-dir", dir, "-dead", dead, "stats"); err == nil {
+		t.Error("stats with missing -dead dir succeeded, want an error")
+	}
+}
+
+func TestAddListDelete(t *testing.T) {
+	dir := t.TempDir()
+
+	out, err := runCLI(t, "-dir", dir, "add", "-handler", "h", "-delay", "1h", "-payload", "x")
+	if err != nil {
+		t.Fatalf("add error = %v", err)
+	}
+	id := strings.TrimSpace(out)
+
+	out, err = runCLI(t, "-dir", dir, "-json", "list")
+	if err != nil {
+		t.Fatalf("list error = %v", err)
+	}
+	var tasks []struct{ ID, Handler string }
+	if err := json.Unmarshal([]byte(out), &tasks); err != nil || len(tasks) != 1 || tasks[0].ID != id || tasks[0].Handler != "h" {
+		t.Errorf("list = %s, want the added task", out)
+	}
+
+	if _, err := runCLI(t, "-dir", dir, "delete", id); err != nil {
+		t.Fatalf("delete error = %v", err)
+	}
+	if _, err := runCLI(t, "-dir", dir, "delete", id); err == nil {
+		t.Error("deleting a missing task succeeded, want an error")
+	}
+}
+
+func TestRedrive(t *testing.T) {
+	dir, dead := t.TempDir(), t.TempDir()
+	out, err := runCLI(t, "-dir", dead, "add", "-handler", "h", "-delay", "1h")
+	if err != nil {
+		t.Fatalf("add error = %v", err)
+	}
+	id := strings.TrimSpace(out)
+
+	if _, err := runCLI(t, "-dir", dir, "-dead", dead, "redrive", id); err != nil {
+		t.Fatalf("redrive error = %v", err)
+	}
+
+	out, err = runCLI(t, "-dir", dir, "-dead", dead, "-json", "stats")
+	if err != nil {
+		t.Fatalf("stats error = %v", err)
+	}
+	var st Stats
+	if err := json.Unmarshal([]byte(out), &st); err != nil || st.Pending != 1 || st.DeadLetters == nil || *st.DeadLetters != 0 {
+		t.Errorf("stats after redrive = %s, want 1 pending and 0 dead letters", out)
+	}
+}
+
+func TestBackendFlags(t *testing.T) {
+	for _, args := range [][]string{
+		{"-backend", "bogus", "list"},
+		{"-backend", "redis", "list"},
+		{"-backend", "mongo", "list"},
+		{"list"},
+	} {
+		if _, err := runCLI(t, args...); err == nil {
+			t.Errorf("run(%q) succeeded, want an error", args)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gzltommy/delayqueue"
+	"github.com/gzltommy/delayqueue/mongoqueue"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// openMongoStorage 打开集合 name 上的存储，集合不存在时除非 create 为 true 否则返回错误
+// 集合在第一次写入时由 MongoDB 自动创建
+func openMongoStorage(ctx context.Context, db *mongo.Database, name string, create bool) (delayqueue.Storage, error) {
+	if !create {
+		names, err := db.ListCollectionNames(ctx, bson.M{"name": name})
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("collection %s does not exist, use -create to create it", name)
+		}
+	}
+	return mongoqueue.NewStorage(ctx, mongoCollection{db.Collection(name)}), nil
+}
+
+// mongoCollection 将 *mongo.Collection 适配为 mongoqueue.StorageCollection
+type mongoCollection struct {
+	*mongo.Collection
+}
+
+var _ mongoqueue.StorageCollection = mongoCollection{}
+
+func (c mongoCollection) Upsert(ctx context.Context, filter, doc any) error {
+	_, err := c.Collection.ReplaceOne(ctx, filter, doc, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (c mongoCollection) FindOne(ctx context.Context, filter, result any) (bool, error) {
+	err := c.Collection.FindOne(ctx, filter).Decode(result)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (c mongoCollection) FindAll(ctx context.Context, filter, results any) error {
+	cur, err := c.Collection.Find(ctx, filter)
+	if err != nil {
+		return err
+	}
+	return cur.All(ctx, results)
+}
+
+func (c mongoCollection) DeleteOne(ctx context.Context, filter any) (int64, error) {
+	res, err := c.Collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
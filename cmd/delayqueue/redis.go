@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/gzltommy/delayqueue/redisqueue"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisClient 将 go-redis 的客户端适配为 redisqueue.StorageClient
+type redisClient struct {
+	*redis.Client
+}
+
+var _ redisqueue.StorageClient = redisClient{}
+
+func (c redisClient) ZAdd(ctx context.Context, key string, score float64, member string) error {
+	return c.Client.ZAdd(ctx, key, redis.Z{Score: score, Member: member}).Err()
+}
+
+func (c redisClient) ZRem(ctx context.Context, key string, member string) (int64, error) {
+	return c.Client.ZRem(ctx, key, member).Result()
+}
+
+func (c redisClient) ZRangeByScore(ctx context.Context, key string, max float64, count int64) ([]string, error) {
+	return c.Client.ZRangeByScore(ctx, key, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatFloat(max, 'f', -1, 64),
+		Count: count,
+	}).Result()
+}
+
+func (c redisClient) HSet(ctx context.Context, key string, field string, value string) error {
+	return c.Client.HSet(ctx, key, field, value).Err()
+}
+
+func (c redisClient) HGet(ctx context.Context, key string, field string) (string, bool, error) {
+	value, err := c.Client.HGet(ctx, key, field).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	return value, err == nil, err
+}
+
+func (c redisClient) HDel(ctx context.Context, key string, field string) error {
+	return c.Client.HDel(ctx, key, field).Err()
+}
+
+func (c redisClient) HVals(ctx context.Context, key string) ([]string, error) {
+	return c.Client.HVals(ctx, key).Result()
+}
@@ -0,0 +1,37 @@
+package delayqueue
+
+import "context"
+
+// Next 阻塞等待下一个到期的任务，将其从队列中取出并返回执行函数，需要配合 WithConsumerMode 使用
+// 返回的函数由调用方在自己的协程中调用，执行过程与自动执行相同（单飞、看门狗、执行记录等依然生效）；
+// 多个协程可以同时调用 Next 竞争消费，每个到期任务只会被其中一个取走；ctx 结束时返回 ctx.Err()，队列停止时返回 ErrClosed
+func (q *DelayQueue) Next(ctx context.Context) (func(), error) {
+	select {
+	case t := <-q.ready:
+		q.startRun(t.id)
+		return func() {
+			defer q.endRun(t.id)
+			q.execTask(t, q.clock.Now())
+		}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-q.quit:
+		return nil, ErrClosed
+	}
+}
+
+// removeReadyTask 从等待领取的任务中移除指定任务，返回任务是否存在
+func (q *DelayQueue) removeReadyTask(id string) bool {
+	return q.takeReadyTask(id) != nil
+}
+
+// takeReadyTask 从等待领取的任务中移除指定任务并返回，任务不存在时返回 nil
+func (q *DelayQueue) takeReadyTask(id string) *task {
+	for i, t := range q.readyTasks {
+		if t.id == id {
+			q.readyTasks = append(q.readyTasks[:i], q.readyTasks[i+1:]...)
+			return t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNextCompetingConsumers(t *testing.T) {
+	q, clock := newTestQueue(t, WithConsumerMode())
+
+	const n = 20
+	var (
+		mu   sync.Mutex
+		runs = make(map[int]int)
+	)
+	done := make(chan struct{}, n)
+	for i := 0; i < n; i++ {
+		i := i
+		q.Push(time.Second, func() {
+			mu.Lock()
+			runs[i]++
+			mu.Unlock()
+			done <- struct{}{}
+		})
+	}
+
+	// 两个消费者竞争领取到期的任务，各自执行取到的函数
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 2)
+	for c := 0; c < 2; c++ {
+		go func() {
+			for {
+				fn, err := q.Next(ctx)
+				if err != nil {
+					errs <- err
+					return
+				}
+				fn()
+			}
+		}()
+	}
+
+	fireNext(clock, time.Second)
+	for i := 0; i < n; i++ {
+		receive(t, done)
+	}
+	cancel()
+	for c := 0; c < 2; c++ {
+		if err := receive(t, errs); !errors.Is(err, context.Canceled) {
+			t.Errorf("Next after cancel error = %v, want context.Canceled", err)
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	for i := 0; i < n; i++ {
+		if runs[i] != 1 {
+			t.Errorf("task %d ran %d times, want 1", i, runs[i])
+		}
+	}
+}
+
+func TestNextClosed(t *testing.T) {
+	q, _ := newTestQueue(t, WithConsumerMode())
+	stopQueue(t, q)
+
+	if _, err := q.Next(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Next on stopped queue error = %v, want ErrClosed", err)
+	}
+}
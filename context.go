@@ -0,0 +1,115 @@
+package delayqueue
+
+import (
+	"context"
+	"time"
+)
+
+// PushContext 用户推送接收 context 的任务
+// 任务执行期间被 Delete 删除或者队列被 Stop 停止时，ctx 会被取消，执行函数可以据此尽快返回
+func (q *DelayQueue) PushContext(timeInterval time.Duration, f func(ctx context.Context), opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.ensureExtra().fx = f
+	return q.submit(t.apply(opts))
+}
+
+// PushTimeout 用户推送带有执行超时时间的任务，执行函数收到的 ctx 在 timeout 之后到期
+// 执行超时的任务立即视为执行失败，错误 ErrTaskTimeout 交给钩子、OnComplete 等执行结果的回调，并计入 ExecTimeouts；
+// Go 无法强制结束协程，没有响应 ctx 的执行函数会在后台继续执行到返回，队列停止时同样会等待它结束；
+// 等价于 PushContext(timeInterval, f, WithTimeout(timeout), opts...)
+func (q *DelayQueue) PushTimeout(timeout time.Duration, timeInterval time.Duration, f func(ctx context.Context), opts ...PushOption) string {
+	return q.PushContext(timeInterval, f, append([]PushOption{WithTimeout(timeout)}, opts...)...)
+}
+
+// runCancel 一次执行的取消函数，同一个周期任务的多次执行可能重叠，每次执行各自登记
+type runCancel struct {
+	cancel context.CancelFunc
+}
+
+// runWithContext 从 parent 为任务创建可取消的 context 并用它调用 fn，执行期间可以通过 cancelExecuting 取消
+// 任务设置了超时时间时，到期后不再等待执行函数返回，返回 ErrTaskTimeout
+func (q *DelayQueue) runWithContext(parent context.Context, task *task, fn func(ctx context.Context)) error {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+	)
+	if task.extra().timeout > 0 {
+		ctx, cancel = context.WithTimeout(parent, task.extra().timeout)
+	} else {
+		ctx, cancel = context.WithCancel(parent)
+	}
+	defer cancel()
+
+	run := &runCancel{cancel: cancel}
+	q.taskCancelsMu.Lock()
+	q.taskCancels[task.id] = append(q.taskCancels[task.id], run)
+	q.taskCancelsMu.Unlock()
+	defer q.untrackRun(task.id, run)
+
+	if task.extra().timeout <= 0 {
+		fn(ctx)
+		return nil
+	}
+
+	// 在单独的协程中执行，超时后立即返回；执行函数的 panic 转回当前协程，由 runTask 统一恢复
+	var (
+		done      = make(chan struct{})
+		recovered any
+	)
+	q.running.Add(1)
+	go func() {
+		defer q.running.Done()
+		defer func() {
+			recovered = recover()
+			close(done)
+		}()
+		fn(ctx)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			q.execTimeouts.Add(1)
+			q.logger.Printf("task %s timed out after %s", task.id, task.extra().timeout)
+			return ErrTaskTimeout
+		}
+		// 被删除或队列停止而取消，等待执行函数响应取消后返回
+		<-done
+	}
+	if recovered != nil {
+		panic(recovered)
+	}
+	return nil
+}
+
+// untrackRun 执行结束后移除这次执行登记的取消函数，同一任务其他仍在执行的登记保持不变
+func (q *DelayQueue) untrackRun(id string, run *runCancel) {
+	q.taskCancelsMu.Lock()
+	defer q.taskCancelsMu.Unlock()
+
+	runs := q.taskCancels[id]
+	for i, r := range runs {
+		if r == run {
+			runs = append(runs[:i], runs[i+1:]...)
+			break
+		}
+	}
+	if len(runs) == 0 {
+		delete(q.taskCancels, id)
+	} else {
+		q.taskCancels[id] = runs
+	}
+}
+
+// cancelExecuting 取消任务所有正在执行的 context，返回任务是否正在执行
+func (q *DelayQueue) cancelExecuting(id string) bool {
+	q.taskCancelsMu.Lock()
+	defer q.taskCancelsMu.Unlock()
+
+	runs := q.taskCancels[id]
+	for _, r := range runs {
+		r.cancel()
+	}
+	return len(runs) > 0
+}
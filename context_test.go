@@ -0,0 +1,100 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPushContextCanceledByDelete(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	started := make(chan struct{})
+	canceled := make(chan error, 1)
+	id := q.PushContext(time.Second, func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+		canceled <- ctx.Err()
+	})
+
+	fireNext(clock, time.Second)
+	receive(t, started)
+	if ok, err := q.Delete(id); !ok || err != nil {
+		t.Fatalf("Delete(running) = %v, %v, want true, nil", ok, err)
+	}
+	if err := receive(t, canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("ctx.Err() = %v, want context.Canceled", err)
+	}
+}
+
+func TestPushTimeout(t *testing.T) {
+	results := make(chan error, 1)
+	q, clock := newTestQueue(t, OnComplete(func(id string, d time.Duration, err error) {
+		results <- err
+	}))
+
+	release := make(chan struct{})
+	defer close(release)
+	q.PushTimeout(10*time.Millisecond, time.Second, func(ctx context.Context) {
+		// 执行函数不响应 ctx，超时后队列不再等待它返回
+		<-release
+	})
+
+	fireNext(clock, time.Second)
+	if err := receive(t, results); !errors.Is(err, ErrTaskTimeout) {
+		t.Errorf("result = %v, want ErrTaskTimeout", err)
+	}
+	if n := q.ExecTimeouts(); n != 1 {
+		t.Errorf("exec timeouts = %d, want 1", n)
+	}
+}
+
+func TestCancelExecutingOverlappingRuns(t *testing.T) {
+	q, _ := newTestQueue(t)
+
+	// 同一个任务的两次执行重叠，删除时两次执行的 ctx 都要取消
+	tk := &task{id: "overlap"}
+	started := make(chan struct{}, 2)
+	finished := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			finished <- q.runWithContext(context.Background(), tk, func(ctx context.Context) {
+				started <- struct{}{}
+				<-ctx.Done()
+			})
+		}()
+	}
+	receive(t, started)
+	receive(t, started)
+
+	if !q.cancelExecuting(tk.id) {
+		t.Fatal("cancelExecuting = false, want true")
+	}
+	for i := 0; i < 2; i++ {
+		if err := receive(t, finished); err != nil {
+			t.Errorf("run %d returned %v, want nil", i, err)
+		}
+	}
+	if q.cancelExecuting(tk.id) {
+		t.Error("cancelExecuting after both runs returned = true, want false")
+	}
+}
+
+func TestPushWithTimeout(t *testing.T) {
+	results := make(chan error, 1)
+	q, clock := newTestQueue(t, OnComplete(func(id string, d time.Duration, err error) {
+		results <- err
+	}))
+
+	release := make(chan struct{})
+	defer close(release)
+	q.Push(time.Second, func() {
+		<-release
+	}, WithTimeout(10*time.Millisecond))
+
+	fireNext(clock, time.Second)
+	if err := receive(t, results); !errors.Is(err, ErrTaskTimeout) {
+		t.Errorf("result = %v, want ErrTaskTimeout", err)
+	}
+}
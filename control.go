@@ -0,0 +1,76 @@
+package delayqueue
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// TaskControl 任务执行时用于控制自身的句柄
+type TaskControl struct {
+	q        *DelayQueue
+	id       string
+	canceled atomic.Bool
+}
+
+// newTaskControl 创建任务的控制句柄
+func newTaskControl(q *DelayQueue, id string) *TaskControl {
+	return &TaskControl{q: q, id: id}
+}
+
+// ID 返回任务id
+func (c *TaskControl) ID() string {
+	return c.id
+}
+
+// CancelSelf 取消任务后续的所有执行
+// 在执行函数中调用是安全的：周期任务已经安排好的下一次执行会被移除，之后也不会再重新安排；
+// 本次执行不受影响，会继续执行到返回
+func (c *TaskControl) CancelSelf() {
+	if c.canceled.Swap(true) {
+		return
+	}
+
+	// 直接从任务列表中移除下一次执行；即使移除前下一次执行已经到期，调度协程也会因为取消标记而跳过它
+	c.q.do(func() {
+		c.q.removeTask(c.id)
+	})
+}
+
+// Canceled 判断任务是否已经取消了自身
+func (c *TaskControl) Canceled() bool {
+	return c.canceled.Load()
+}
+
+// PushWithControl 用户推送可以控制自身的任务
+func (q *DelayQueue) PushWithControl(timeInterval time.Duration, f func(c *TaskControl), opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	ext := t.ensureExtra()
+	ext.fc = f
+	ext.ctl = newTaskControl(q, t.id)
+	return q.submit(t.apply(opts))
+}
+
+// PushPeriodicWithControl 用户推送可以控制自身的周期任务，周期与存活时间的语义与 PushPeriodicWithTTL 相同：
+// ttl <= 0 表示永不过期，0 < ttl <= period 时任务不会被推送，返回空的任务id
+// 执行函数中调用 c.CancelSelf() 可以可靠地停止后续的周期执行
+func (q *DelayQueue) PushPeriodicWithControl(period time.Duration, ttl time.Duration, f func(c *TaskControl), opts ...PushOption) string {
+	if period <= 0 {
+		panic("delayqueue: non-positive period for PushPeriodicWithControl")
+	}
+
+	t := q.newPushTask(period)
+	ext := t.ensureExtra()
+	ext.fc = f
+	ext.ctl = newTaskControl(q, t.id)
+	ext.period = period
+	ext.expireTime = expireAfter(t.pushTime, ttl)
+	t.apply(opts)
+
+	if !t.alive(t.execTime) {
+		// 第一次执行就已经超出了存活时间，任务不会被执行
+		q.logger.Printf("push task %s rejected: ttl %v does not cover the first run after %v", t.id, ttl, period)
+		return ""
+	}
+
+	return q.submit(t)
+}
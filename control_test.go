@@ -0,0 +1,64 @@
+package delayqueue
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPushWithControl(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	ids := make(chan string, 1)
+	id := q.PushWithControl(time.Second, func(c *TaskControl) { ids <- c.ID() })
+
+	fireNext(clock, time.Second)
+	if got := receive(t, ids); got != id {
+		t.Errorf("TaskControl.ID() = %q, want %q", got, id)
+	}
+}
+
+func TestPeriodicCancelSelf(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	var runs atomic.Int32
+	ran := make(chan struct{}, 3)
+	q.PushPeriodicWithControl(time.Second, 0, func(c *TaskControl) {
+		if runs.Add(1) == 2 {
+			c.CancelSelf()
+		}
+		ran <- struct{}{}
+	})
+
+	fireNext(clock, time.Second)
+	receive(t, ran)
+	fireNext(clock, time.Second)
+	receive(t, ran)
+
+	// 第二次执行时取消了自身，之后不再执行
+	settle(q)
+	if n := q.Len(); n != 0 {
+		t.Errorf("Len after CancelSelf = %d, want 0", n)
+	}
+	clock.Advance(time.Minute)
+	stopQueue(t, q)
+	if n := runs.Load(); n != 2 {
+		t.Errorf("runs = %d, want 2", n)
+	}
+}
+
+func TestPeriodicWithControlTTL(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	if id := q.PushPeriodicWithControl(10*time.Second, 5*time.Second, func(*TaskControl) {}); id != "" {
+		t.Errorf("PushPeriodicWithControl(ttl < period) = %q, want empty id", id)
+	}
+
+	// ttl <= 0 表示永不过期
+	ran := make(chan struct{}, 1)
+	q.PushPeriodicWithControl(time.Second, 0, func(*TaskControl) { ran <- struct{}{} })
+	for i := 0; i < 3; i++ {
+		fireNext(clock, time.Second)
+		receive(t, ran)
+	}
+}
@@ -0,0 +1,73 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// testStart 是 2024-01-01 周一 00:00:00
+	at := func(month time.Month, day, hour, min, sec int) time.Time {
+		return time.Date(2024, month, day, hour, min, sec, 0, time.UTC)
+	}
+	for _, tc := range []struct {
+		spec string
+		want time.Time
+	}{
+		{"*/15 * * * *", at(1, 1, 0, 15, 0)},
+		{"30 9 * * 1-5", at(1, 1, 9, 30, 0)},
+		{"0 0 1 * *", at(2, 1, 0, 0, 0)},
+		{"0 12 * * sun", at(1, 7, 12, 0, 0)},
+		{"0 0 * * 7", at(1, 7, 0, 0, 0)},
+		{"0 0 1 jan *", time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 * * * * *", at(1, 1, 0, 0, 30)},
+		// 日与周同时被限定时满足其一即可：1 月 5 日是周五
+		{"0 0 13 * 5", at(1, 5, 0, 0, 0)},
+		{"0 8-10/2 * * *", at(1, 1, 8, 0, 0)},
+	} {
+		s, err := parseCron(tc.spec)
+		if err != nil {
+			t.Errorf("parseCron(%q): %v", tc.spec, err)
+			continue
+		}
+		if got := s.next(testStart); !got.Equal(tc.want) {
+			t.Errorf("next(%q) = %v, want %v", tc.spec, got, tc.want)
+		}
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	for _, spec := range []string{"* * *", "60 * * * *", "*/0 * * * *", "foo * * * *", "0 0 * 13 *"} {
+		if _, err := parseCron(spec); err == nil {
+			t.Errorf("parseCron(%q) succeeded, want an error", spec)
+		}
+	}
+
+	q, _ := newTestQueue(t)
+	if id := q.PushCron("0 0 30 feb *", func() {}); id != "" {
+		t.Errorf("PushCron with a spec that never fires = %q, want empty", id)
+	}
+	if id := q.PushCron("bad", func() {}); id != "" {
+		t.Errorf("PushCron with an invalid spec = %q, want empty", id)
+	}
+}
+
+func TestPushCronRepeats(t *testing.T) {
+	q, clock := newTestQueue(t)
+	ran := make(chan time.Time, 2)
+	id := q.PushCron("*/10 * * * *", func() { ran <- clock.Now() })
+	if id == "" {
+		t.Fatal("PushCron returned an empty id")
+	}
+
+	for _, want := range []time.Time{testStart.Add(10 * time.Minute), testStart.Add(20 * time.Minute)} {
+		clock.BlockUntil(1)
+		clock.Set(want)
+		if got := receive(t, ran); !got.Equal(want) {
+			t.Errorf("cron task ran at %v, want %v", got, want)
+		}
+	}
+	if ok, err := q.Delete(id); !ok || err != nil {
+		t.Errorf("Delete(cron) = %v, %v, want true, nil", ok, err)
+	}
+}
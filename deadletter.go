@@ -0,0 +1,132 @@
+package delayqueue
+
+import "time"
+
+// DeadLetter 重试次数耗尽、最终执行失败的任务
+type DeadLetter struct {
+	ID       string    `json:"id"`                // 任务id
+	Handler  string    `json:"handler,omitempty"` // 具名处理函数的名称，基于闭包的任务为空
+	Payload  []byte    `json:"payload,omitempty"` // 传给具名处理函数的数据
+	Attempts int       `json:"attempts"`          // 已经执行的次数
+	Error    string    `json:"error,omitempty"`   // 最后一次执行的错误，从存储中加载的任务为空
+	FailedAt time.Time `json:"failed_at"`         // 最后一次执行失败的时间
+}
+
+// deadTask 死信列表中的任务，保留原始任务以便重新投递
+type deadTask struct {
+	letter DeadLetter
+	task   *task
+}
+
+// DeadLetters 返回所有死信任务，按进入死信列表的先后顺序排列
+func (q *DelayQueue) DeadLetters() []DeadLetter {
+	q.deadMu.Lock()
+	defer q.deadMu.Unlock()
+
+	letters := make([]DeadLetter, 0, len(q.deadTasks))
+	for _, d := range q.deadTasks {
+		letters = append(letters, d.letter)
+	}
+	return letters
+}
+
+// Redrive 将死信任务重新投递到队列中立即执行，重试次数重新计算；返回任务是否在死信列表中
+func (q *DelayQueue) Redrive(id string) bool {
+	d, ok := q.takeDeadLetter(id)
+	if !ok {
+		return false
+	}
+
+	now := q.clock.Now()
+	t := d.task.copy()
+	t.execTime = now
+	t.pushTime = now
+	t.fromEnqueue = false
+	t.ensureExtra().attempt = 0
+	q.submit(t)
+	return true
+}
+
+// DiscardDeadLetter 丢弃死信任务，返回任务是否在死信列表中
+func (q *DelayQueue) DiscardDeadLetter(id string) bool {
+	_, ok := q.takeDeadLetter(id)
+	return ok
+}
+
+// deadLetter 将最终执行失败的任务放入死信列表，具名处理函数任务会同时保存到死信存储中
+func (q *DelayQueue) deadLetter(t *task, attempts int, err error) {
+	d := &deadTask{
+		letter: DeadLetter{
+			ID:       t.id,
+			Handler:  t.handler,
+			Payload:  t.payload(),
+			Attempts: attempts,
+			Error:    err.Error(),
+			FailedAt: q.clock.Now(),
+		},
+		task: t,
+	}
+
+	if q.deadLetterStorage != nil && t.serializable() {
+		if err := q.deadLetterStorage.Save(t.pendingTask()); err != nil {
+			q.logger.Printf("save dead letter %s failed: %v", t.id, err)
+		}
+	}
+
+	q.deadMu.Lock()
+	defer q.deadMu.Unlock()
+	q.deadTasks = append(q.deadTasks, d)
+}
+
+// takeDeadLetter 从死信列表与死信存储中取出指定任务
+func (q *DelayQueue) takeDeadLetter(id string) (*deadTask, bool) {
+	q.deadMu.Lock()
+	var d *deadTask
+	for i, dt := range q.deadTasks {
+		if dt.letter.ID == id {
+			d = dt
+			q.deadTasks = append(q.deadTasks[:i], q.deadTasks[i+1:]...)
+			break
+		}
+	}
+	q.deadMu.Unlock()
+	if d == nil {
+		return nil, false
+	}
+
+	if q.deadLetterStorage != nil && d.task.serializable() {
+		if err := q.deadLetterStorage.Remove(id); err != nil {
+			q.logger.Printf("remove dead letter %s failed: %v", id, err)
+		}
+	}
+	return d, true
+}
+
+// loadDeadLetters 从死信存储中加载上次运行留下的死信任务，在队列创建时调用
+func (q *DelayQueue) loadDeadLetters() {
+	tasks, err := q.deadLetterStorage.List()
+	if err != nil {
+		q.logger.Printf("load dead letters failed: %v", err)
+		return
+	}
+
+	for _, pt := range tasks {
+		q.deadTasks = append(q.deadTasks, &deadTask{
+			letter: DeadLetter{
+				ID:       pt.ID,
+				Handler:  pt.Handler,
+				Payload:  pt.Payload,
+				FailedAt: pt.ExecTime,
+			},
+			task: &task{
+				id:       pt.ID,
+				execTime: pt.ExecTime,
+				handler:  pt.Handler,
+				arg:      pt.Payload,
+				ext: &taskExtra{
+					publish: pt.Publish,
+				},
+			},
+		})
+	}
+}
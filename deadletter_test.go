@@ -0,0 +1,91 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestDeadLettersRedriveAndDiscard(t *testing.T) {
+	gaveUp := make(chan string, 2)
+	q, clock := newTestQueue(t, OnGiveUp(func(id string, err error) {
+		gaveUp <- id
+	}))
+
+	var (
+		runs      atomic.Int32
+		succeeded = make(chan struct{})
+	)
+	policy := RetryPolicy{MaxAttempts: 2, Backoff: ConstantBackoff(time.Second)}
+	flaky := q.PushRetry(time.Second, func() error {
+		// 前两次执行失败，重新投递后成功
+		if runs.Add(1) <= 2 {
+			return errors.New("fail")
+		}
+		close(succeeded)
+		return nil
+	}, policy)
+	broken := q.PushRetry(time.Second, func() error { return errors.New("broken") }, policy)
+
+	fireNext(clock, time.Second)
+	// 两个任务的重试都回到队列后再推进时钟
+	deadline := time.Now().Add(5 * time.Second)
+	for q.Len() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("retries never came back to the queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	fireNext(clock, time.Second)
+	receive(t, gaveUp)
+	receive(t, gaveUp)
+
+	letters := q.DeadLetters()
+	if len(letters) != 2 {
+		t.Fatalf("DeadLetters = %+v, want 2 letters", letters)
+	}
+	for _, l := range letters {
+		if l.Attempts != 2 || l.Error == "" {
+			t.Errorf("letter %s = %d attempts, error %q, want 2 attempts and an error", l.ID, l.Attempts, l.Error)
+		}
+	}
+
+	if !q.Redrive(flaky) {
+		t.Fatal("Redrive(flaky) = false")
+	}
+	receive(t, succeeded)
+	if !q.DiscardDeadLetter(broken) {
+		t.Error("DiscardDeadLetter(broken) = false")
+	}
+	if q.Redrive(broken) || q.DiscardDeadLetter("missing") {
+		t.Error("taking a letter that is not in the list succeeded")
+	}
+	if letters := q.DeadLetters(); len(letters) != 0 {
+		t.Errorf("DeadLetters after redrive and discard = %+v, want none", letters)
+	}
+}
+
+func TestDeadLetterStorage(t *testing.T) {
+	storage := newTestStorage(t)
+	gaveUp := make(chan string, 1)
+	q, clock := newTestQueue(t, WithDeadLetterStorage(storage), OnGiveUp(func(id string, err error) {
+		gaveUp <- id
+	}))
+	q.RegisterHandlerContext("mail", func(ctx context.Context, payload []byte) error {
+		return errors.New("smtp down")
+	})
+	id := q.PushHandlerRetry(time.Second, "mail", []byte("hello"), RetryPolicy{MaxAttempts: 1})
+
+	fireNext(clock, time.Second)
+	receive(t, gaveUp)
+	stopQueue(t, q)
+
+	// 下次启动时从死信存储加载
+	q2, _ := newTestQueue(t, WithDeadLetterStorage(storage))
+	letters := q2.DeadLetters()
+	if len(letters) != 1 || letters[0].ID != id || letters[0].Handler != "mail" || string(letters[0].Payload) != "hello" {
+		t.Errorf("loaded dead letters = %+v, want %s mail hello", letters, id)
+	}
+}
@@ -0,0 +1,16 @@
+package delayqueue
+
+import "sort"
+
+// pendingRemovals 返回处于「待删除」状态的任务 id，按字典序排列，仅用于调试与测试删除先于后续执行到达的场景
+// 状态机的说明见 deleteTask
+func (q *DelayQueue) pendingRemovals() []string {
+	var ids []string
+	q.do(func() {
+		for id := range q.waitRemoveTaskMapping {
+			ids = append(ids, id)
+		}
+	})
+	sort.Strings(ids)
+	return ids
+}
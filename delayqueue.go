@@ -1,207 +1,1254 @@
+// Package delayqueue 提供进程内的延时任务队列
+//
+// 推送方法的返回值按调用方需要知道的信息分为三种约定：
+//
+//   - Push、PushAt、PushComputed 返回 *Task 句柄，句柄永远不为 nil，可以等待结果、取消或调整执行时间，
+//     推送被拒绝时 Err 返回拒绝的原因。新增的基础推送方法都使用这种约定。
+//   - TryPush、PushCtx、PushJSON、PushWithID、PushHandlerWithID 返回 error：调用方显式要求区分拒绝的原因
+//     （不阻塞、ctx 结束、编码失败、id 为空或冲突），错误就是调用的主要结果；PushWithID 的id由调用方指定，无需再返回。
+//   - 其余 PushXxx 变体返回任务id，被拒绝时返回空字符串并记录日志。这些变体早于任务句柄出现，
+//     admin、grpcserver 等调用方直接把返回值当作id使用，为保持兼容不再修改，之后通过 Delete、Reschedule 等按id的方法管理任务。
 package delayqueue
 
 import (
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"container/heap"
+	"container/list"
+	"context"
+	"io"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 // DelayQueue 延时任务对象
 type DelayQueue struct {
-	tasks                 []*task             // 存储任务列表的切片
-	add                   chan *task          // 用户添加任务的管道信号
-	remove                chan string         // 用户删除任务的管道信号
-	waitRemoveTaskMapping map[string]struct{} // 等待删除的任务 id 列表
+	tasks        taskHeap                  // 存储任务列表的最小堆，堆顶是最先到期的任务
+	add          chan *task                // 用户添加任务的管道信号
+	addBuffer    int                       // add 管道的容量
+	remove       chan removeRequest        // 用户删除任务的管道信号
+	removeBuffer int                       // remove 管道的容量
+	ops          chan func()               // 需要在调度协程中同步执行的操作
+	quit         chan struct{}             // 队列停止时关闭
+	loopDone     chan struct{}             // 调度协程退出时关闭
+	stopped      atomic.Bool               // 队列是否已经停止
+	stopOnce     sync.Once                 // 保证只停止一次
+	running      sync.WaitGroup            // 正在执行的任务
+	clock        Clock                     // 时钟
+	recording    atomic.Pointer[Recording] // 正在进行的操作录制
+	opts         []Option                  // 创建队列时的配置，派生新队列时沿用
+	name         string                    // 队列名称
+	ctx          context.Context           // 队列停止时取消，任务执行时的 context 由此派生
+	cancel       context.CancelFunc        // 取消 ctx
+	seq          uint64                    // 最近一次加入任务列表的序号
+
+	handlers   map[string]handlerFunc // 已注册的具名处理函数
+	handlersMu sync.RWMutex           // 保护 handlers
+
+	publisher Publisher // 发布消息的任务到期时使用的发布者
+
+	resultStore ResultStore // 任务执行结果的存储，为 nil 表示不记录
+
+	middlewares   atomic.Pointer[[]Middleware] // 任务执行的中间件，按添加的顺序由外到内包裹
+	middlewaresMu sync.Mutex                   // 保证并发调用 Use 时不丢失中间件
+
+	onPush    func(info TaskInfo)                // 任务推送成功的回调
+	onExecute func(info TaskInfo)                // 任务开始执行的回调
+	onDelete  func(info TaskInfo)                // 等待执行的任务被删除的回调
+	onDrop    func(info TaskInfo, reason string) // 任务被丢弃的回调
+
+	logger         Logger                                         // 日志输出
+	eventLogger    StructuredLogger                               // 结构化日志输出
+	missingHandler func(name string, task PendingTask)            // 具名处理函数缺失时的兜底处理
+	onResidence    func(id string, d time.Duration)               // 任务执行时回调其在队列中的停留时间
+	onPanic        func(id string, payload []byte, recovered any) // 执行函数 panic 时的回调
+	onGiveUp       func(id string, err error)                     // 任务重试次数耗尽时的回调
+	onComplete     func(id string, d time.Duration, err error)    // 任务执行完成时的回调
+
+	missedPolicy     MissedPolicy           // 恢复快照时过期任务的处理策略
+	missedGrace      time.Duration          // MissedFireWithinGrace 策略的宽限期
+	onRestoreSkipped func(task PendingTask) // 恢复快照时跳过过期任务的回调
+
+	maxExecDuration time.Duration   // 任务执行的最长时间，超过后视为执行超时
+	onExecTimeout   func(id string) // 任务执行超时的回调
+	execTimeouts    atomic.Uint64   // 执行超时的任务数量
+
+	delayFromEnqueue bool // 延时是否从队列接收任务时开始计算
+
+	executing atomic.Int64 // 正在执行的任务数量
+
+	hooks      []Hook // 任务生命周期的观测钩子
+	expvarName string // 通过 expvar 发布运行指标时使用的名称
+
+	executed atomic.Uint64 // 已经执行完成的任务数量
+	deleted  atomic.Uint64 // 被用户删除的任务数量
+
+	heartbeat        atomic.Int64  // 调度协程最近一次循环的时间，Unix 纳秒
+	healthTimeout    time.Duration // 健康检查等待调度协程响应的时长
+	healthSaturation float64       // 健康检查允许的 add、remove 管道占用比例
+
+	runs       map[string]*runState // 已经分发、还没有执行结束的任务
+	runsMu     sync.Mutex           // 保护 runs
+	executions executedIDs          // 最近执行结束的任务id，删除时用于区分已经执行与从未存在
+
+	waitRemoveTaskMapping map[string]*list.Element // 执行期间被删除的任务 id，指向 waitRemoveOrder 中的记录，状态机见 deleteTask
+	waitRemoveOrder       list.List                // 「待删除」记录（waitRemoveEntry），按收到删除信号的先后顺序排列
+	waitRemoveTTL         time.Duration            // 「待删除」记录的保留时长，超过后视为后续执行不会再到达，为 0 表示不清理
+	waitRemoveLimit       int                      // 「待删除」记录的数量上限，超出时清理最早的记录，为 0 表示不限制
+	lastWaitSweep         time.Time                // 上一次清理「待删除」记录的时间
+	waitRemoveSize        atomic.Int64             // 「待删除」记录的数量，供其他协程无锁读取
+	waitRemoveMatched     atomic.Uint64            // 等到了后续执行、将其丢弃的「待删除」记录数量
+	waitRemoveExpired     atomic.Uint64            // 超时或超出上限被清理的「待删除」记录数量
+
+	noLoopSupervision bool          // 是否关闭调度循环的监护，关闭后调度循环 panic 时不再重新启动
+	onLoopPanic       func(any)     // 调度循环 panic 后重新启动时的回调
+	loopRestarts      atomic.Uint64 // 调度循环重新启动的次数
+	failed            atomic.Uint64 // 执行失败的任务数量
+	retries           atomic.Uint64 // 安排重试的次数
+	driftCount        atomic.Uint64 // 记录了执行延迟的任务数量
+	driftTotal        atomic.Int64  // 执行延迟的总和，单位纳秒
+	driftMax          atomic.Int64  // 执行延迟的最大值，单位纳秒
+
+	maxConcurrency int         // 同时执行的任务数量上限，为 0 表示不限制
+	pool           *workerPool // 执行任务的协程池，为 nil 时每个任务单独开启协程
+
+	taskCancels   map[string][]*runCancel // 正在执行的 context 任务每次执行的取消函数
+	taskCancelsMu sync.Mutex              // 保护 taskCancels
+
+	idGenerator IDGenerator // 任务id生成器
+
+	singleFlight SingleFlightPolicy  // 同一 key 的任务并发执行时的策略
+	keyLocks     map[string]*keyLock // 正在使用中的 key 锁
+	keyLocksMu   sync.Mutex          // 保护 keyLocks
+
+	pushRate        int             // 每秒允许推送的任务数量，为 0 表示不限制
+	pushLimiter     *tokenBucket    // 推送限流
+	pushLimitPolicy RateLimitPolicy // 推送超过限流时的处理策略
+
+	execRate    float64      // 每秒允许执行的任务数量，为 0 表示不限制
+	execBurst   int          // 执行限流允许的突发量
+	execLimiter *tokenBucket // 执行限流
+
+	admission func(execTime time.Time) error // 推送时的准入控制
+
+	storage       Storage                 // 持久化存储
+	skipLoadStore bool                    // 创建时是否跳过加载存储中的任务，派生的队列不重复加载
+	deliveryMode  DeliveryMode            // 持久化任务的执行语义
+	storageMu     sync.Mutex              // 保护 deferred，并保证存储的写入与推迟的保存按顺序进行
+	deferred      map[string]deferredSave // 周期任务推迟到本次执行结束后保存的下一次执行
+
+	deadTasks         []*deadTask // 死信列表
+	deadMu            sync.Mutex  // 保护 deadTasks
+	deadLetterStorage Storage     // 死信任务的持久化存储
+
+	maxPending   int          // 等待执行的任务数量上限，为 0 表示不限制
+	pendingCount atomic.Int64 // 任务列表中的任务数量，由调度协程更新
+	peakPending  atomic.Int64 // 等待执行的任务数量的峰值
+
+	hadTasks     bool          // 队列是否曾经有过任务
+	firstEmpty   chan struct{} // 队列第一次由忙变空时关闭
+	firstEmptied bool          // firstEmpty 是否已经关闭
+
+	breakerConfig *CircuitBreakerConfig // 熔断器配置
+	breaker       *circuitBreaker       // 任务执行的熔断器
+
+	execLogWriter io.Writer     // 任务执行记录的输出目标
+	execLog       *executionLog // 任务执行记录的输出
+	execLogClose  sync.Once     // 保证每个队列只释放一次执行记录的输出
+	execLogErr    error         // 释放执行记录的输出时的错误
+
+	consumerMode bool          // 是否由消费者领取到期任务，而不是自动执行
+	ready        chan *task    // 消费者领取到期任务的管道
+	readyTasks   []*task       // 已经到期、等待消费者领取的任务
+	pullOnce     sync.Once     // 保证 Channel 只启动一个转发协程
+	pullC        chan TaskInfo // Channel 返回的管道
+
+	visibilityTimeout time.Duration              // 交出的任务等待确认的时长，为 0 表示交出即结束
+	inflight          map[string][]*inflightTask // 等待确认的任务，同一个id的多次交出按交出的顺序排列
+	inflightMu        sync.Mutex                 // 保护 inflight
+
+	fairWeights map[string]int // 公平调度时各标签的权重，为 nil 表示不开启公平调度
+
+	paused       bool         // 队列是否暂停
+	pausedAt     time.Time    // 队列暂停的时间
+	resumePolicy ResumePolicy // 恢复队列时暂停期间到期任务的处理策略
+
+	overduePolicy    OverduePolicy // 任务逾期时的处理策略
+	overdueThreshold time.Duration // 超过执行时间多久视为逾期
+
+	maxLateness time.Duration        // 任务到期时允许的最大延迟，为 0 表示不限制
+	onLate      func(tc TaskContext) // 处理延迟过大的任务的函数
+
+	pausedTasks []*task // 通过 PauseTask 暂停的任务，按暂停的先后顺序排列
+
+	topics       map[string]*topicState // 子队列的状态
+	topicsMu     sync.Mutex             // 保护 topics 与其中的并发限制
+	pausedTopics map[string]struct{}    // 暂停中的子队列
+
+	pausedTags map[string]struct{} // 暂停中的标签
+	heldTasks  []*task             // 因标签暂停而被扣留的到期任务，按到期顺序排列
+
+	taskIndex map[string]*task // 按id索引任务列表、被扣留与暂停的任务
+
+	uniqueKeys   map[string]*task // 按去重 key 索引等待执行的去重任务
+	uniquePolicy UniquePolicy     // 去重任务冲突时的处理策略
+
+	duplicateIDPolicy DuplicateIDPolicy // 调用方指定的任务id已经存在时的处理策略
+
+	jitter time.Duration // 推送时执行时间的随机抖动范围，为 0 表示不抖动
+
+	wheelTick time.Duration // 时间轮的刻度，为 0 表示不使用时间轮
+	wheelSize int           // 时间轮每层的槽位数量
+	wheel     *timingWheel  // 时间轮，为 nil 时所有任务都在任务堆中
+
+	clockCheckInterval time.Duration // 看门狗检查时钟跳变的间隔，为 0 表示不定期检查
+	clockJumpThreshold time.Duration // 系统时间与单调时钟的流逝相差超过该值时视为时钟跳变，为 0 表示不检测
+	lastClockCheck     time.Time     // 调度协程上一次检查时钟的时间
+	clockJumps         atomic.Uint64 // 检测到的时钟跳变次数
+
+	elector       Elector       // 选主，为 nil 表示不参与选主
+	electInterval time.Duration // 竞选与续期的间隔
+	leader        atomic.Bool   // 当前实例是否为 leader
+	standby       bool          // 是否处于待命状态，待命时不触发任务，由调度协程维护
+	electDone     chan struct{} // 竞选协程退出时关闭
+	electExternal bool          // 竞选由外层的 ShardedQueue 或派生出当前队列的队列统一进行，不启动竞选协程
+
+	owns func(id string) bool // 重新加载存储时只加载返回 true 的任务，ShardedQueue 的内部队列与 Partition 的新队列按路由设置；为 nil 表示全部加载
+
+	followers   []*DelayQueue // 由 Partition、Clone 派生的队列，leader 身份随当前队列切换
+	followersMu sync.Mutex    // 保护 followers，并保证派生的队列加入时与身份切换不交错
 }
 
 // task 任务对象
+// 大量任务只用到执行时间与执行函数，较少使用的属性放在按需分配的 taskExtra 中，减少每个任务占用的内存
 type task struct {
 	id       string    // 任务id
 	execTime time.Time // 执行时间
-	f        func()    // 执行函数
+
+	// 执行什么与用什么数据执行分开保存，大量任务共享同一个执行函数时不需要为每个任务创建闭包
+	fn      any    // 执行函数：func() 是任务自己的闭包，func(arg any) 是多个任务共享的函数；其他类型的任务为 nil
+	arg     any    // 共享执行函数的参数，或者具名处理函数、发布消息的任务的数据（[]byte）
+	handler string // 具名处理函数的名称，与 fn 二选一
+
+	index    int                // 任务在堆中的下标，由堆维护
+	bucket   map[*task]struct{} // 任务所在的时间轮槽位，不在时间轮中时为 nil
+	seq      uint64             // 任务加入任务列表的序号，执行时间与优先级都相同时序号小的先执行
+	priority int                // 任务的优先级，执行时间相同时优先级高的先执行
+
+	jitter time.Duration // 推送时执行时间的随机抖动范围，为 0 时使用队列的设置，为 noJitter 时不抖动
+
+	handle *Task // Push 返回给用户的任务句柄，为 nil 表示没有句柄
+	last   bool  // 本次执行是否是任务的最后一次执行，由调度协程在分发时设置
+
+	fromEnqueue bool      // 延时是否从调度协程接收任务时开始计算
+	pushTime    time.Time // 任务进入队列的时间
+
+	ext *taskExtra // 较少使用的属性，为 nil 表示都是零值
+}
+
+// taskExtra 任务较少使用的属性，只有用到其中的属性时才分配
+type taskExtra struct {
+	fc  func(c *TaskControl) // 可以控制自身的执行函数，与 fn 二选一
+	ctl *TaskControl         // 任务的控制句柄，周期任务的每一次执行共用同一个
+
+	fe func() error // 返回错误的执行函数，与 fn 二选一
+
+	fr     func() ([]byte, error) // 返回数据的执行函数，与 fn 二选一
+	output []byte                 // fr 最近一次执行返回的数据
+
+	fx      func(ctx context.Context) // 接收 context 的执行函数，与 fn 二选一
+	timeout time.Duration             // 执行超时时间，为 0 表示不限制
+
+	publish string // 到期时发布消息的主题，消息内容为 payload，与 fn 二选一
+
+	period     time.Duration // 周期任务的执行间隔，为 0 表示一次性任务
+	expireTime time.Time     // 周期任务的过期时间，为零值表示永不过期
+	remaining  int           // 重复任务剩余的执行次数（含本次），为 0 表示不限次数
+	cron       *cronSchedule // cron 任务的执行计划，与 period 二选一
+
+	retry   *RetryPolicy // 执行失败后的重试策略，为 nil 表示不重试
+	attempt int          // 已经失败的次数
+
+	key    string // 任务的业务 key，用于单飞执行等按 key 的控制
+	unique string // 任务的去重 key，为空表示不去重
+
+	customID bool   // 任务id是否由调用方指定，指定的id需要按 duplicateIDPolicy 检查冲突
+	tag      string // 任务的标签，用于按类别暂停等控制
+	topic    string // 任务所属的子队列
+
+	metadata map[string]string // 任务的元数据，推送后不再修改
+
+	pauseLeft time.Duration // 任务暂停时剩余的等待时间
+	paused    bool          // 任务是否被 PauseTask 暂停
+
+	trace *pushTrace // 推送时由钩子返回的 ctx，执行时传给钩子；没有设置钩子时为 nil
+
+	rerun bool // 是否为一次执行派生的后续执行（重试、熔断推迟），到达时遇到「待删除」记录直接丢弃
 }
 
+// call 调用任务的执行函数，共享执行函数带上任务自己的参数
+func (t *task) call() {
+	if fn, ok := t.fn.(func(arg any)); ok {
+		fn(t.arg)
+		return
+	}
+	t.fn.(func())()
+}
+
+// payload 返回具名处理函数、发布消息的任务的数据，带有执行函数的任务返回 nil
+func (t *task) payload() []byte {
+	if t.fn != nil {
+		return nil
+	}
+	data, _ := t.arg.([]byte)
+	return data
+}
+
+// noExtra 没有设置任何较少使用属性的任务共享的零值，只能读取
+var noExtra taskExtra
+
+// extra 返回任务较少使用的属性用于读取，没有分配时返回共享的零值，不能通过它修改
+func (t *task) extra() *taskExtra {
+	if t.ext == nil {
+		return &noExtra
+	}
+	return t.ext
+}
+
+// ensureExtra 返回任务较少使用的属性用于修改，没有分配时先分配
+func (t *task) ensureExtra() *taskExtra {
+	if t.ext == nil {
+		t.ext = &taskExtra{}
+	}
+	return t.ext
+}
+
+// copy 复制任务，较少使用的属性同样复制一份，副本的修改不会影响原任务
+func (t *task) copy() *task {
+	cp := *t
+	if t.ext != nil {
+		ext := *t.ext
+		cp.ext = &ext
+	}
+	return &cp
+}
+
+// add 与 remove 管道的默认容量
+const (
+	defaultAddBuffer    = 10000
+	defaultRemoveBuffer = 100
+)
+
+// 「待删除」记录的默认保留时长与数量上限
+const (
+	defaultWaitRemoveTTL   = time.Minute
+	defaultWaitRemoveLimit = 10000
+)
+
+// 健康检查的默认配置
+const (
+	defaultHealthTimeout    = time.Second
+	defaultHealthSaturation = 0.9
+)
+
 // NewDelayQueue 创建延时任务队列对象
-func NewDelayQueue() *DelayQueue {
+func NewDelayQueue(opts ...Option) *DelayQueue {
 	q := &DelayQueue{
-		add:                   make(chan *task, 10000),
-		remove:                make(chan string, 100),
-		waitRemoveTaskMapping: make(map[string]struct{}),
+		addBuffer:             defaultAddBuffer,
+		removeBuffer:          defaultRemoveBuffer,
+		healthTimeout:         defaultHealthTimeout,
+		healthSaturation:      defaultHealthSaturation,
+		runs:                  make(map[string]*runState),
+		deferred:              make(map[string]deferredSave),
+		waitRemoveTaskMapping: make(map[string]*list.Element),
+		waitRemoveTTL:         defaultWaitRemoveTTL,
+		waitRemoveLimit:       defaultWaitRemoveLimit,
+		taskIndex:             make(map[string]*task),
+		uniqueKeys:            make(map[string]*task),
+		ops:                   make(chan func()),
+		quit:                  make(chan struct{}),
+		loopDone:              make(chan struct{}),
+		handlers:              make(map[string]handlerFunc),
+		logger:                defaultLogger,
+		clock:                 realClock{},
+		idGenerator:           objectIDGenerator{},
+		keyLocks:              make(map[string]*keyLock),
+		pausedTags:            make(map[string]struct{}),
+		topics:                make(map[string]*topicState),
+		pausedTopics:          make(map[string]struct{}),
+		ready:                 make(chan *task),
+		firstEmpty:            make(chan struct{}),
+		taskCancels:           make(map[string][]*runCancel),
+		opts:                  opts,
+	}
+	q.ctx, q.cancel = context.WithCancel(context.Background())
+	for _, opt := range opts {
+		opt(q)
+	}
+	q.add = make(chan *task, q.addBuffer)
+	q.remove = make(chan removeRequest, q.removeBuffer)
+	if q.breakerConfig != nil {
+		q.breaker = newCircuitBreaker(*q.breakerConfig, q.clock)
+	}
+	if q.name != "" {
+		// 日志带上队列名称，便于区分多个队列的输出
+		q.logger = namedLogger{name: q.name, logger: q.logger}
+	}
+	if q.execLogWriter != nil && q.execLog == nil {
+		q.execLog = newExecutionLog(q.execLogWriter, q.logger)
+	}
+	if q.maxConcurrency > 0 {
+		q.pool = newWorkerPool(q.maxConcurrency)
+	}
+	if q.expvarName != "" {
+		q.PublishExpvar(q.expvarName)
+	}
+	if q.pushRate > 0 {
+		// 令牌桶依赖时钟，需要在所有配置生效之后创建
+		q.pushLimiter = newTokenBucket(q.clock, float64(q.pushRate), q.pushRate)
+	}
+	if q.execRate > 0 {
+		q.execLimiter = newTokenBucket(q.clock, q.execRate, q.execBurst)
+	}
+	if q.wheelTick > 0 && q.wheelSize > 0 {
+		q.wheel = newTimingWheel(q.wheelTick, q.wheelSize, q.clock.Now())
 	}
 
-	// 开启协程，监听任务相关信号
+	// 开启协程，监听任务相关信号；参与选主时先处于待命状态，成为 leader 之后才触发任务
+	q.standby = q.elector != nil
 	go q.start()
+	if q.elector != nil && !q.electExternal {
+		q.electDone = make(chan struct{})
+		go q.campaign(q.setLeader)
+	}
+	if q.deadLetterStorage != nil && !q.skipLoadStore {
+		q.loadDeadLetters()
+	}
+	if q.storage != nil && !q.skipLoadStore {
+		// 调度协程启动之后再加载，任务数量超过 add 管道的容量时也不会阻塞
+		q.loadStorage()
+	}
 	return q
 }
 
-// Delete 用户删除任务
-func (q *DelayQueue) Delete(id string) {
-	q.remove <- id
+// Name 返回队列名称
+func (q *DelayQueue) Name() string {
+	return q.name
 }
 
-// Push 用户推送任务
-func (q *DelayQueue) Push(timeInterval time.Duration, f func()) string {
-	// 生成一个任务id，方便删除使用
-	id := genTaskId()
-	t := &task{
-		id:       id,
-		execTime: time.Now().Add(timeInterval),
-		f:        f,
+// Delete 用户删除任务，等待调度协程处理完删除信号后返回结果
+// 任务仍在等待执行（包括刚刚推送、还没有被调度协程接收的任务）时将其移除并返回 true；
+// 任务已经到期、还没有执行结束时，本次执行失败后不再重试，接收 context 的任务同时取消其 context，同样返回 true；
+// 任务最近已经执行结束时返回 ErrTaskExecuted，id 不存在时返回 ErrTaskNotFound，队列已经停止时返回 ErrClosed；
+// 调度协程没有响应时会一直等待，需要限时的调用方使用 DeleteContext
+func (q *DelayQueue) Delete(id string) (bool, error) {
+	return q.DeleteContext(context.Background(), id)
+}
+
+// DeleteContext 与 Delete 相同，ctx 结束时不再等待调度协程的回复，返回 ctx.Err()
+// 删除信号已经发出时，调度协程之后仍然可能处理它并删除任务
+func (q *DelayQueue) DeleteContext(ctx context.Context, id string) (bool, error) {
+	if r := q.recording.Load(); r != nil {
+		r.recordDelete(id)
 	}
+	q.forget(id)
+
+	req := removeRequest{id: id, reply: make(chan bool, 1)}
+	select {
+	case q.remove <- req:
+	case <-q.quit:
+		// 队列已经停止，没有需要删除的任务了
+		return false, ErrClosed
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+
+	select {
+	case found := <-req.reply:
+		q.logEvent(LevelDebug, "task deleted", "id", id, "found", found)
+		if found {
+			return true, nil
+		}
+		if q.executions.has(id) {
+			return false, ErrTaskExecuted
+		}
+		return false, ErrTaskNotFound
+	case <-q.loopDone:
+		// 删除信号发出后队列停止，调度协程没有来得及处理
+		return false, ErrClosed
+	case <-ctx.Done():
+		return false, ctx.Err()
+	}
+}
+
+// removeRequest 删除信号，调度协程处理后通过 reply 回复任务是否存在
+type removeRequest struct {
+	id    string
+	reply chan bool
+}
+
+// Push 用户推送任务，返回任务句柄，返回值不会是 nil
+// 任务被拒绝时句柄的 Err 返回拒绝的原因，Done 立即关闭；opts 可以组合设置优先级、标签、超时时间等，见 PushOption
+func (q *DelayQueue) Push(timeInterval time.Duration, f func(), opts ...PushOption) *Task {
+	// 生成一个任务id，方便删除使用
+	t := q.newPushTask(timeInterval)
+	t.fn = f
+	t.apply(opts)
 
 	// 将任务推到 add 管道中
-	q.add <- t
-	return id
+	return q.submitTask(t)
 }
 
-// start 监听各种任务相关信号
-func (q *DelayQueue) start() {
-	for {
-		if len(q.tasks) == 0 {
-			// 任务列表为空的时候，只需要监听 add 管道
-			select {
-			case t := <-q.add:
-				// 添加任务
-				q.addTask(t)
+// PushAt 用户推送在指定时刻 execTime 执行的任务，适用于执行时间来自数据库字段或外部接口的场景
+// execTime 是绝对时间，不受 WithDelayFromEnqueue 的影响；已经过去的时刻会尽快执行。返回的任务句柄与 Push 相同
+func (q *DelayQueue) PushAt(execTime time.Time, f func(), opts ...PushOption) *Task {
+	t := q.newPushTaskAt(q.genTaskId(), execTime)
+	t.fn = f
+	return q.submitTask(t.apply(opts))
+}
+
+// PushWithID 用户推送使用指定id的任务，调用方可以直接用订单号等业务id删除任务，不需要另外维护id的映射
+// id 为空或任务被拒绝时返回错误；id 已经有等待执行的任务时按 WithDuplicateIDPolicy 处理，默认替换已有的任务
+func (q *DelayQueue) PushWithID(id string, timeInterval time.Duration, f func(), opts ...PushOption) error {
+	if id == "" {
+		return ErrEmptyID
+	}
+	t := q.newPushTaskWithID(id, timeInterval)
+	t.fn = f
+	t.ensureExtra().customID = true
+	return q.push(t.apply(opts))
+}
+
+// PushHandlerWithID 用户推送使用指定id、由具名处理函数执行的任务，与 PushWithID 相同
+func (q *DelayQueue) PushHandlerWithID(id string, timeInterval time.Duration, name string, payload []byte, opts ...PushOption) error {
+	if id == "" {
+		return ErrEmptyID
+	}
+	t := q.newPushTaskWithID(id, timeInterval)
+	t.handler = name
+	t.arg = payload
+	t.ensureExtra().customID = true
+	return q.push(t.apply(opts))
+}
+
+// PushComputed 用户推送由 delayFn 计算延时的任务，delayFn 只会在推送时调用一次
+// 适用于延时依赖当前状态的场景，例如 delay = base * 当前负载。返回的任务句柄与 Push 相同
+func (q *DelayQueue) PushComputed(delayFn func() time.Duration, f func(), opts ...PushOption) *Task {
+	t := q.newPushTask(delayFn())
+	t.fn = f
+	return q.submitTask(t.apply(opts))
+}
+
+// TryPush 用户推送任务，与 Push 相同，但从不阻塞：任务被拒绝时立即返回具体的错误
+// add 管道已满时返回 ErrQueueFull，推送限流的令牌不足时返回 ErrRateLimited
+func (q *DelayQueue) TryPush(timeInterval time.Duration, f func(), opts ...PushOption) (string, error) {
+	t := q.newPushTask(timeInterval)
+	t.fn = f
+	t.apply(opts)
+
+	if err := q.pushContext(context.Background(), t, false); err != nil {
+		return "", err
+	}
+	return t.id, nil
+}
+
+// PushCtx 用户推送任务，add 管道已满或推送限流时阻塞等待，ctx 结束时放弃推送并返回 ctx.Err()
+// ctx 同时会传给 WithHook 设置的钩子，执行时的链路追踪可以与推送方关联
+func (q *DelayQueue) PushCtx(ctx context.Context, timeInterval time.Duration, f func(), opts ...PushOption) (string, error) {
+	t := q.newPushTask(timeInterval)
+	t.fn = f
+	t.apply(opts)
+
+	if err := q.pushContext(ctx, t, true); err != nil {
+		return "", err
+	}
+	return t.id, nil
+}
+
+// submit 推送任务并返回任务id，任务被拒绝时记录日志并返回空字符串
+func (q *DelayQueue) submit(t *task) string {
+	if q.pushLogged(t) != nil {
+		return ""
+	}
+	return t.id
+}
+
+// submitTask 为任务创建句柄后推送，返回任务句柄，任务被拒绝时记录日志并将原因记在句柄上
+func (q *DelayQueue) submitTask(t *task) *Task {
+	t.handle = newTask(q, t.id)
+	if err := q.pushLogged(t); err != nil {
+		t.handle.reject(err)
+	}
+	return t.handle
+}
+
+// pushLogged 推送任务，任务被拒绝时记录日志
+func (q *DelayQueue) pushLogged(t *task) error {
+	err := q.push(t)
+	if err != nil {
+		q.logger.Printf("push task %s rejected: %v", t.id, err)
+		q.logEvent(LevelWarn, "task rejected", "id", t.id, "error", err)
+	}
+	return err
+}
+
+// push 经过准入控制后将任务推到 add 管道中，所有用户推送任务的入口最终都会走到这里
+func (q *DelayQueue) push(t *task) error {
+	return q.pushContext(context.Background(), t, true)
+}
+
+// pushContext 与 push 相同，wait 为 false 时不等待限流令牌与 add 管道的空位，wait 为 true 时等待直到 ctx 结束
+func (q *DelayQueue) pushContext(ctx context.Context, t *task, wait bool) error {
+	q.applyJitter(t)
+	if t.extra().customID {
+		if err := q.checkDuplicateID(t.id); err != nil {
+			return err
+		}
+	}
+	if t.extra().unique != "" {
+		if err := q.checkUniqueKey(t.extra().unique); err != nil {
+			return err
+		}
+	}
+	if q.admission != nil {
+		// 准入控制在调用方的协程中同步执行
+		if err := q.admission(t.execTime); err != nil {
+			return err
+		}
+	}
+
+	if q.maxPending > 0 && q.pending() >= q.maxPending {
+		return ErrQueueFull
+	}
+
+	if q.pushLimiter != nil {
+		if q.pushLimitPolicy == RateLimitReject || !wait {
+			if !q.pushLimiter.allow() {
+				return ErrRateLimited
 			}
-			continue
+		} else if err := q.pushLimiter.wait(ctx); err != nil {
+			return err
 		}
+	}
 
-		// 任务列表不为空的时候，需要监听所有管道
+	// 具名处理函数任务先保存再入队，保存失败时拒绝推送
+	if err := q.persist(t); err != nil {
+		return err
+	}
+	pushed := q.hookPushed(t)
+	execTime := t.execTime
+	if err := q.enqueueContext(ctx, t, wait); err != nil {
+		// 任务没有进入队列，撤销保存
+		if t.serializable() {
+			q.forget(t.id)
+		}
+		return err
+	}
+	q.logEvent(LevelDebug, "task pushed", "id", t.id, "exec_time", execTime)
+	pushed(ctx)
+	return nil
+}
 
-		// 任务的等待时间 = 任务的执行时间 - 当前的时间
-		currentTask := q.tasks[0]
-		timer := time.NewTimer(currentTask.execTime.Sub(time.Now()))
+// enqueue 将任务推到 add 管道中，队列已经停止时返回 ErrClosed
+func (q *DelayQueue) enqueue(t *task) error {
+	return q.enqueueContext(context.Background(), t, true)
+}
 
+// enqueueContext 与 enqueue 相同，wait 为 false 时 add 管道已满立即返回 ErrQueueFull，
+// wait 为 true 时等待 add 管道的空位直到 ctx 结束
+func (q *DelayQueue) enqueueContext(ctx context.Context, t *task, wait bool) error {
+	if q.stopped.Load() {
+		return ErrClosed
+	}
+
+	recorded := q.recordEnqueue(t)
+	if !wait {
 		select {
-		case now := <-timer.C:
-			timer.Stop()
-			if _, isRemove := q.waitRemoveTaskMapping[currentTask.id]; isRemove {
-				// 之前客户已经发出过该任务的删除信号，因此需要结束任务，刷新任务列表
-				q.endTask()
-				delete(q.waitRemoveTaskMapping, currentTask.id)
-				continue
+		case q.add <- t:
+			recorded()
+			return nil
+		default:
+			return ErrQueueFull
+		}
+	}
+
+	select {
+	case q.add <- t:
+		recorded()
+		return nil
+	case <-q.quit:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// recordEnqueue 正在录制时，在任务进入 add 管道之前复制任务，返回任务进入管道后将副本记录到录制中的函数
+// 任务进入管道后由调度协程接管，不能再读取
+func (q *DelayQueue) recordEnqueue(t *task) func() {
+	r := q.recording.Load()
+	if r == nil {
+		return func() {}
+	}
+	cp := t.copy()
+	cp.handle = nil
+	return func() {
+		r.recordPush(cp)
+	}
+}
+
+// start 运行调度循环，调度循环因为内部错误 panic 时恢复并重新启动，队列停止后退出
+func (q *DelayQueue) start() {
+	defer close(q.loopDone)
+	for !q.loop() {
+	}
+	q.closePending()
+}
+
+// closePending 队列停止后结束所有还没有执行的任务的句柄，Wait 返回 ErrClosed；任务保留在存储中，重启后继续执行
+func (q *DelayQueue) closePending() {
+	for len(q.add) > 0 {
+		(<-q.add).abort(ErrClosed)
+	}
+	for _, t := range q.pendingTasks() {
+		t.abort(ErrClosed)
+	}
+}
+
+// loop 监听各种任务相关信号，队列停止时返回 true
+// 调度循环 panic 时返回 false，任务列表等状态都保存在队列上，重新进入循环即可继续调度；关闭了监护时 panic 照常抛出
+func (q *DelayQueue) loop() (stopped bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			if q.noLoopSupervision {
+				panic(r)
+			}
+			q.loopRestarts.Add(1)
+			q.logger.Printf("scheduler loop panic: %v, restarting\n%s", r, debug.Stack())
+			q.logEvent(LevelError, "scheduler loop restarted", "panic", r)
+			if q.onLoopPanic != nil {
+				q.onLoopPanic(r)
 			}
+		}
+	}()
+
+	for {
+		q.heartbeat.Store(time.Now().UnixNano())
+
+		// 每一轮循环开始时优先处理所有已经发出的删除信号，避免大量的添加信号让删除信号迟迟得不到处理
+		q.drainRemove()
+
+		// 每一轮循环开始时同步任务数量，供其他协程无锁读取
+		q.pendingCount.Store(int64(q.taskCount()))
+		q.sweepWaitRemove()
+		q.checkFirstEmpty()
+
+		// 检查时钟是否跳变，时间轮转到当前时刻，进入当前刻度的任务移入任务堆
+		now := q.clock.Now()
+		q.checkClock(now)
+		q.advanceWheel(now)
+
+		// 任务列表不为空、队列没有暂停、不在待命的时候，才需要监听计时器
+		var (
+			currentTask *task
+			wait        time.Duration
+			waiting     bool
+			timer       Timer
+			timerC      <-chan time.Time
+		)
+		if wake, t := q.nextWake(); !wake.IsZero() && !q.paused && !q.standby {
+			// 任务的等待时间 = 任务的执行时间 - 当前的时间；时间轮先于任务到期时 currentTask 为 nil，只转动时间轮
+			currentTask, wait, waiting = t, wake.Sub(q.clock.Now()), true
+		}
+		if q.clockCheckInterval > 0 && (!waiting || wait > q.clockCheckInterval) {
+			// 看门狗定期唤醒调度协程检查时钟
+			currentTask, wait, waiting = nil, q.clockCheckInterval, true
+		}
+		if waiting {
+			timer = q.clock.NewTimer(wait)
+			timerC = timer.C()
+		}
 
-			// 开启协程，异步执行任务
-			go q.execTask(currentTask, now)
+		// 有到期任务等待消费者领取的时候，才需要监听 ready 管道
+		var (
+			readyTask *task
+			readyC    chan *task
+		)
+		if len(q.readyTasks) > 0 {
+			readyTask = q.readyTasks[0]
+			readyC = q.ready
+		}
 
-			// 任务结束，刷新任务列表
-			q.endTask()
-		case tsk := <-q.add:
+		select {
+		case now := <-timerC:
+			if currentTask != nil {
+				q.fire(currentTask, now)
+			}
+		case readyC <- readyTask:
+			// 到期任务被消费者领走
+			q.readyTasks = q.readyTasks[1:]
+		case t := <-q.add:
 			// 添加任务
-			timer.Stop()
-			q.addTask(tsk)
-		case id := <-q.remove:
+			q.acceptTask(t)
+		case req := <-q.remove:
 			// 删除任务
+			q.handleRemove(req)
+		case op := <-q.ops:
+			// 执行同步操作
+			q.runOp(op)
+		case <-q.quit:
+			// 队列停止，退出调度协程
+			if timer != nil {
+				timer.Stop()
+			}
+			return true
+		}
+
+		if timer != nil {
 			timer.Stop()
-			q.deleteTask(id)
 		}
 	}
 }
 
+// fire 任务列表中的第一个任务到期
+func (q *DelayQueue) fire(currentTask *task, now time.Time) {
+	// 到期执行之前再处理一次删除信号：在任务到期之前发出的删除，一定会在任务执行之前生效
+	q.drainRemove()
+	if len(q.tasks) == 0 || q.tasks[0] != currentTask {
+		// 当前任务已经被删除
+		return
+	}
+	if currentTask.execTime.After(now) {
+		// 计时器按单调时钟计时，系统时间回拨后按系统时间表示的任务还没有到期，由下一轮循环重新计时
+		return
+	}
+
+	if q.fairWeights == nil {
+		// 任务结束，刷新任务列表
+		q.endTask()
+		q.dispatch(currentTask, now)
+		return
+	}
+
+	// 开启了公平调度时，将所有已经到期的任务一起取出，按标签交错分发
+	for _, t := range q.fairOrder(q.popDue(now)) {
+		q.dispatch(t, now)
+	}
+}
+
+// dispatch 分发一个已经从任务列表中取出的到期任务
+func (q *DelayQueue) dispatch(currentTask *task, now time.Time) {
+	// 任务已经从任务列表中取出，被扣留时再重新记入索引
+	q.unindexTask(currentTask)
+
+	if currentTask.extra().ctl != nil && currentTask.extra().ctl.Canceled() {
+		// 任务在执行过程中取消了自身，不再执行也不再安排下一次执行
+		currentTask.complete()
+		return
+	}
+
+	if q.holdIfPaused(currentTask) {
+		// 任务所属的标签已暂停，扣留任务，等标签恢复后再执行
+		return
+	}
+
+	// 周期任务需要安排下一次执行，没有下一次执行时，本次执行结束后任务完成
+	// 在开始执行之前安排，保证 last 的写入先于执行协程的读取
+	next := q.reschedule(currentTask)
+	currentTask.last = next == nil
+	if next != nil {
+		q.saveNext(currentTask, next)
+	}
+
+	if q.isLate(currentTask, now) {
+		// 任务到期时的延迟过大，不再执行
+		q.running.Add(1)
+		go func() {
+			defer q.running.Done()
+			q.handleLate(currentTask, now)
+		}()
+	} else if q.consumerMode {
+		// 消费者模式下不自动执行，交给消费者领取
+		q.readyTasks = append(q.readyTasks, currentTask)
+	} else {
+		// 异步执行任务
+		q.running.Add(1)
+		q.startRun(currentTask.id)
+		job := func() {
+			defer q.running.Done()
+			defer q.endRun(currentTask.id)
+			q.execTask(currentTask, now)
+		}
+		if q.pool != nil {
+			q.pool.submit(job)
+		} else {
+			go job()
+		}
+	}
+}
+
+// drainRemove 处理 remove 管道中所有已经发出的删除信号
+func (q *DelayQueue) drainRemove() {
+	for len(q.remove) > 0 {
+		q.handleRemove(<-q.remove)
+	}
+}
+
+// handleRemove 处理一个删除信号并回复结果
+// 先把 add 管道中已经提交的任务收进任务列表：调用 Delete 之前推送返回的任务一定在管道或任务列表中，
+// 因此删除立即生效，回复的结果就是任务是否真的被删除，不会因为任务还在管道中而错误地回复不存在
+func (q *DelayQueue) handleRemove(req removeRequest) {
+	for len(q.add) > 0 {
+		q.acceptTask(<-q.add)
+	}
+	req.reply <- q.deleteTask(req.id)
+}
+
 // execTask 执行任务
 func (q *DelayQueue) execTask(task *task, currentTime time.Time) {
-	if task.execTime.After(currentTime) {
-		// 如果当前任务的执行时间落后于当前时间，则不执行
+	// 任务是否被重新加入队列（重试或熔断推迟），重新加入的任务还没有结束
+	requeued := false
+	// 周期任务的下一次执行以同一个id保存（见 saveNext），只有最后一次执行才从存储中移除
+	stored := task.last && task.serializable()
+	defer q.saveDeferred(task)
+	if stored && q.deliveryMode == AtMostOnce {
+		// 至多执行一次：执行之前先从存储中移除，崩溃后不会再次执行
+		q.forget(task.id)
+	} else if stored {
+		// 至少执行一次：任务执行完成之后，除非还要重试，不论是否真正执行都不再需要保存
+		defer func() {
+			if !requeued {
+				q.forget(task.id)
+			}
+		}()
+	}
+	defer func() {
+		if !requeued && task.last {
+			q.executions.add(task.id)
+		}
+		if !requeued && (task.last || task.extra().ctl != nil && task.extra().ctl.Canceled()) {
+			task.complete()
+		}
+	}()
+
+	if !q.handleOverdue(task, currentTime) {
+		// 任务逾期，按策略丢弃或放入死信列表
 		return
 	}
 
+	if task.extra().key != "" && q.singleFlight != SingleFlightOff {
+		// 同一个 key 的任务同一时刻只允许一个在执行
+		release, ok := q.acquireKey(task.extra().key)
+		if !ok {
+			q.logger.Printf("task %s dropped, another task with key %q is executing", task.id, task.extra().key)
+			q.logEvent(LevelWarn, "task dropped", "id", task.id, "reason", "single_flight", "key", task.extra().key)
+			q.fireDrop(task, "single_flight")
+			q.logExecution(task, currentTime, OutcomeDropped, nil)
+			return
+		}
+		defer release()
+	}
+
+	var (
+		outcome string
+		err     error
+	)
+	if task.extra().topic != "" {
+		// 子队列的并发限制与执行统计，等待并发配额的时间不计入执行时长
+		leave := q.enterTopic(task.extra().topic)
+		defer func() {
+			leave(err)
+		}()
+	}
+
+	// 记录正在执行的任务数量，任务 panic 时同样会被扣减
+	q.executing.Add(1)
+	defer q.executing.Add(-1)
+
+	if q.onResidence != nil {
+		// 停留时间 = 实际执行时间 - 进入队列的时间
+		q.onResidence(task.id, currentTime.Sub(task.pushTime))
+	}
+
+	if q.maxExecDuration > 0 {
+		// 开启看门狗，监控任务的执行时长
+		stop := q.watchExec(task.id)
+		defer stop()
+	}
+
+	if q.breaker != nil && task.returnsError() {
+		// 返回错误的任务受熔断器保护
+		if !q.breaker.allow(task.extra().key) {
+			requeued = q.shortCircuit(task, currentTime)
+			return
+		}
+	}
+
+	if q.execLimiter != nil {
+		// 等待执行限流的令牌，队列停止时同样按限流的速度执行完已经到期的任务
+		_ = q.execLimiter.wait(context.Background())
+	}
+
 	// 执行任务
-	task.f()
-	return
+	q.recordDrift(task)
+	q.fireExecute(task)
+	finished := q.hookStarted(task)
+	start := q.clock.Now()
+	outcome, err = q.runChain(task)
+	elapsed := q.clock.Now().Sub(start)
+	finished(err)
+	q.recordResult(err)
+	q.storeResult(task, start, elapsed, outcome, err)
+	q.logEvent(LevelDebug, "task executed", "id", task.id, "outcome", outcome, "error", err)
+	if q.breaker != nil && task.returnsError() {
+		q.breaker.record(task.extra().key, err)
+	}
+	q.logExecution(task, currentTime, outcome, err)
+	if err != nil && task.extra().retry != nil && !q.runCanceled(task.id) {
+		requeued = q.retryOrGiveUp(task, err)
+	}
+	if !requeued {
+		q.completed(task, elapsed, err)
+	}
 }
 
-// endTask 一个任务去执行了，刷新任务列表
-func (q *DelayQueue) endTask() {
-	if len(q.tasks) == 1 {
-		q.tasks = []*task{}
-		return
+// runTask 根据任务的类型调用对应的执行函数，返回执行结果；执行函数 panic 时恢复并返回 OutcomePanic
+func (q *DelayQueue) runTask(ctx context.Context, task *task) (outcome string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			outcome, err = OutcomePanic, q.recoverTask(task, r)
+		}
+	}()
+
+	switch {
+	case task.handler != "":
+		ok, err := q.execHandler(ctx, task)
+		if !ok {
+			return OutcomeMissingHandler, nil
+		}
+		if err != nil {
+			return OutcomeError, err
+		}
+	case task.extra().fc != nil:
+		task.extra().fc(task.extra().ctl)
+	case task.extra().fe != nil:
+		if err := task.extra().fe(); err != nil {
+			return OutcomeError, err
+		}
+	case task.extra().fr != nil:
+		out, err := task.extra().fr()
+		task.ensureExtra().output = out
+		if err != nil {
+			return OutcomeError, err
+		}
+	case task.extra().publish != "":
+		if err := q.publish(task); err != nil {
+			return OutcomeError, err
+		}
+	case task.extra().fx != nil:
+		if err := q.runWithContext(ctx, task, task.extra().fx); err != nil {
+			return OutcomeTimeout, err
+		}
+	case task.extra().timeout > 0:
+		if err := q.runWithContext(ctx, task, func(context.Context) { task.call() }); err != nil {
+			return OutcomeTimeout, err
+		}
+	default:
+		task.call()
 	}
+	return OutcomeOK, nil
+}
 
-	q.tasks = q.tasks[1:]
+// IsExecuting 判断当前是否有任务正在执行
+func (q *DelayQueue) IsExecuting() bool {
+	return q.ExecutingCount() > 0
 }
 
-// addTask 将任务添加到任务切片列表中
-func (q *DelayQueue) addTask(t *task) {
-	// 寻找新增任务的插入位置
-	insertIndex := q.getTaskInsertIndex(t, 0, len(q.tasks)-1)
-	// 找到了插入位置，更新任务列表
-	q.tasks = append(q.tasks, &task{})
-	copy(q.tasks[insertIndex+1:], q.tasks[insertIndex:])
-	q.tasks[insertIndex] = t
-}
-
-// deleteTask 删除指定任务
-// FIXME:注意，这里暂时不考虑，任务 id 非法的特殊情况
-func (q *DelayQueue) deleteTask(id string) {
-	deleteIndex := -1
-	for index, t := range q.tasks {
-		if t.id == id {
-			// 找到了在切片中需要删除的索引
-			deleteIndex = index
-			break
-		}
-	}
-
-	if deleteIndex == -1 {
-		// 如果没有找到删除的任务，说明任务还在 add 管道中，来不及更新到 tasks 中，这里我们就将这个删除 id 临时记录下来
-		// FIXME:注意，这里暂时不考虑，任务 id 非法的特殊情况
-		q.waitRemoveTaskMapping[id] = struct{}{}
+// ExecutingCount 返回当前正在执行的任务数量，设置了 WithMaxConcurrency 时不包括排队等待的任务
+func (q *DelayQueue) ExecutingCount() int {
+	return int(q.executing.Load())
+}
+
+// do 将操作投递到调度协程中同步执行，保证任务列表只在调度协程中被访问
+// 队列停止之后调度协程已经退出，操作不会被执行
+func (q *DelayQueue) do(fn func()) {
+	done := make(chan struct{})
+	select {
+	case q.ops <- func() {
+		// 操作 panic 时同样放行调用方，panic 由调度循环恢复
+		defer close(done)
+		fn()
+	}:
+	case <-q.quit:
 		return
 	}
+	<-done
+}
 
-	if len(q.tasks) == 1 {
-		// 删除后，任务列表就没有任务了
-		q.tasks = []*task{}
-		return
+// runOp 在调度协程中执行同步操作
+func (q *DelayQueue) runOp(op func()) {
+	// 先把 add 管道中已经提交的任务收进任务列表，保证操作能看到调用之前推送的所有任务
+	for len(q.add) > 0 {
+		q.acceptTask(<-q.add)
 	}
+	op()
+}
 
-	if deleteIndex == len(q.tasks)-1 {
-		// 如果删除的是，任务列表的最后一个元素，则执行下列代码
-		q.tasks = q.tasks[:len(q.tasks)-1]
-		return
+// taskCount 返回调度协程中的任务数量，包括被扣留、等待领取与暂停的任务
+func (q *DelayQueue) taskCount() int {
+	n := len(q.tasks) + len(q.heldTasks) + len(q.readyTasks) + len(q.pausedTasks)
+	if q.wheel != nil {
+		n += q.wheel.n
 	}
+	return n
+}
 
-	// 如果删除的是，任务列表的其他元素，则需要将 deleteIndex 之后的元素，全部向前挪动一位
-	copy(q.tasks[deleteIndex:len(q.tasks)-1], q.tasks[deleteIndex+1:len(q.tasks)-1])
-	q.tasks = q.tasks[:len(q.tasks)-1]
-	return
+// pendingLists 等待执行的任务，按所在的列表分组
+type pendingLists struct {
+	ready     []*task // 已经到期、等待消费者领取的任务
+	held      []*task // 因标签或子队列暂停而被扣留的任务
+	scheduled []*task // 任务堆与时间轮中的任务，按执行顺序排列
+	paused    []*task // 通过 PauseTask 暂停的任务
 }
 
-// getTaskInsertIndex 寻找任务的插入位置
-func (q *DelayQueue) getTaskInsertIndex(t *task, leftIndex, rightIndex int) (index int) {
-	// 使用二分法判断新增任务的插入位置
-	if len(q.tasks) == 0 {
-		return
+// all 按 pendingTasks 的顺序返回所有任务
+func (l pendingLists) all() []*task {
+	tasks := make([]*task, 0, len(l.ready)+len(l.held)+len(l.scheduled)+len(l.paused))
+	// 等待领取与被扣留的任务都已经到期，排在最前面，堆中的任务按到期顺序排列在后，暂停的任务排在最后
+	for _, list := range [][]*task{l.ready, l.held, l.scheduled, l.paused} {
+		tasks = append(tasks, list...)
 	}
+	return tasks
+}
+
+// pendingLists 返回按所在列表分组的等待执行的任务
+func (q *DelayQueue) pendingLists() pendingLists {
+	return pendingLists{
+		ready:     q.readyTasks,
+		held:      q.heldTasks,
+		scheduled: q.scheduledTasks(),
+		paused:    q.pausedTasks,
+	}
+}
+
+// pendingTasks 返回所有等待执行的任务，包括被扣留、等待领取与暂停的任务
+func (q *DelayQueue) pendingTasks() []*task {
+	return q.pendingLists().all()
+}
+
+// detachTasks 清空所有等待执行的任务并按所在的列表分组返回，任务的句柄不做处理，由调用方决定结束还是转移到其他队列
+func (q *DelayQueue) detachTasks() pendingLists {
+	lists := q.pendingLists()
+	if q.wheel != nil {
+		q.wheel.drain()
+	}
+	q.tasks = taskHeap{}
+	q.taskIndex = make(map[string]*task)
+	q.uniqueKeys = make(map[string]*task)
+	q.heldTasks = nil
+	q.readyTasks = nil
+	q.pausedTasks = nil
+	return lists
+}
 
-	length := rightIndex - leftIndex
-	if q.tasks[leftIndex].execTime.Sub(t.execTime) >= 0 {
-		// 如果当前切片中最小的元素都超过了插入的优先级，则插入位置应该是最左边
-		return leftIndex
+// endTask 一个任务去执行了，将堆顶的任务移出任务列表
+func (q *DelayQueue) endTask() {
+	heap.Pop(&q.tasks)
+}
+
+// acceptTask 调度协程从 add 管道中接收到任务
+func (q *DelayQueue) acceptTask(t *task) {
+	if t.fromEnqueue {
+		// 延时从队列接收任务时开始计算，将任务的各个时间整体平移到当前时刻
+		now := q.clock.Now()
+		shift := now.Sub(t.pushTime)
+		t.execTime = t.execTime.Add(shift)
+		if !t.extra().expireTime.IsZero() {
+			t.ensureExtra().expireTime = t.extra().expireTime.Add(shift)
+		}
+		t.pushTime = now
+		t.fromEnqueue = false
+	}
+	if t.extra().rerun && q.matchWaitRemove(t.id) {
+		// 删除信号先于后续执行到达，任务直接结束
+		q.logEvent(LevelDebug, "task dropped", "id", t.id, "reason", "deleted_before_arrival")
+		t.complete()
+		return
+	}
+	if t.extra().customID && !q.acceptCustomID(t) {
+		return
+	}
+	if t.extra().unique != "" && !q.acceptUnique(t) {
+		return
 	}
+	q.addTask(t)
+}
+
+// addTask 将任务添加到任务列表中
+func (q *DelayQueue) addTask(t *task) {
+	// 插入序号保证执行时间相同的任务按加入的先后顺序执行
+	q.indexTask(t)
+	q.seq++
+	t.seq = q.seq
+	q.placeTask(t)
 
-	if q.tasks[rightIndex].execTime.Sub(t.execTime) <= 0 {
-		// 如果当前切片中最大的元素都没超过插入的优先级，则插入位置应该是最右边
-		return rightIndex + 1
+	// 任务数量只会在插入时增长，在这里更新峰值
+	if n := int64(q.taskCount()); n > q.peakPending.Load() {
+		q.peakPending.Store(n)
 	}
+}
 
-	if length == 1 && q.tasks[leftIndex].execTime.Before(t.execTime) &&
-		q.tasks[rightIndex].execTime.Sub(t.execTime) >= 0 {
-		// 如果插入的优先级刚好在仅有的两个优先级之间，则中间的位置就是插入位置
-		return leftIndex + 1
+// deleteTask 删除指定任务，返回任务是否存在
+//
+// 调用之前 add 管道中的任务已经由 handleRemove 收进任务列表。已经分发、还没有执行结束的任务在执行失败后
+// 还可能有后续执行（重试、熔断推迟）经 add 管道重新加入，会经过 waitRemoveTaskMapping 中转，状态变化如下：
+//  1. 收到删除信号，任务在任务列表（或扣留、等待领取、暂停列表）中：直接移除，waitRemoveTaskMapping 不变
+//  2. 收到删除信号，任务已经分发、还没有执行结束：标记本次执行被删除，执行失败后不再重试，熔断时不再推迟，
+//     接收 context 的任务同时收到取消信号；并将 id 记入 waitRemoveTaskMapping，进入「待删除」状态
+//  3. 「待删除」任务的后续执行在标记之前已经发出、随后从 add 管道到达：不加入任务列表，直接结束，
+//     并从 waitRemoveTaskMapping 中移除记录，回到初始状态；调用方新推送的同 id 任务不受影响，照常加入
+//  4. 「待删除」任务没有后续执行（执行成功、重试已经被标记拦下）：超过 WithWaitRemoveTTL 设置的时长，
+//     或记录数量超出上限时移除记录，回到初始状态
+//  5. 都不是：任务不存在，不留下任何记录
+func (q *DelayQueue) deleteTask(id string) bool {
+	// 正在执行的任务会收到 context 的取消信号
+	if t := q.takeTask(id); t != nil {
+		q.cancelExecuting(id)
+		q.finishDeleted(t)
+		return true
 	}
+	return q.stopRun(id)
+}
 
-	middleVal := q.tasks[leftIndex+length/2].execTime
+// removeTask 从任务列表中移除指定任务，返回任务是否存在
+func (q *DelayQueue) removeTask(id string) bool {
+	return q.takeTask(id) != nil
+}
 
-	// 这里用二分法递归的方式，一直寻找正确的插入位置
-	if t.execTime.Sub(middleVal) <= 0 {
-		return q.getTaskInsertIndex(t, leftIndex, leftIndex+length/2)
-	} else {
-		return q.getTaskInsertIndex(t, leftIndex+length/2, rightIndex)
+// takeTask 从任务列表中移除指定任务并返回，任务不存在时返回 nil
+func (q *DelayQueue) takeTask(id string) *task {
+	t, ok := q.taskIndex[id]
+	if !ok {
+		// 已经到期、正在等待消费者领取的任务不在索引中
+		return q.takeReadyTask(id)
+	}
+
+	q.unindexTask(t)
+	switch {
+	case t.index >= 0:
+		heap.Remove(&q.tasks, t.index)
+	case t.bucket != nil:
+		q.wheel.remove(t)
+	case t.extra().paused:
+		q.removePausedTask(id)
+	default:
+		// 任务因为标签暂停被扣留了
+		q.removeHeldTask(id)
 	}
+	return t
 }
 
-func genTaskId() string {
-	return primitive.NewObjectID().Hex()
+// genTaskId 生成任务id
+func (q *DelayQueue) genTaskId() string {
+	return q.idGenerator.NewID()
 }
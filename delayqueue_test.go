@@ -0,0 +1,247 @@
+package delayqueue
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPushWithIDAfterDelete(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	ran := make(chan string, 2)
+	if err := q.PushWithID("order-1", time.Second, func() { ran <- "first" }); err != nil {
+		t.Fatalf("PushWithID: %v", err)
+	}
+	if ok, err := q.Delete("order-1"); !ok || err != nil {
+		t.Fatalf("Delete = %v, %v, want true, nil", ok, err)
+	}
+	// 再次删除已经不存在的 id 不能影响之后推送的同名任务
+	if _, err := q.Delete("order-1"); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("second Delete error = %v, want ErrTaskNotFound", err)
+	}
+
+	if err := q.PushWithID("order-1", 2*time.Second, func() { ran <- "second" }); err != nil {
+		t.Fatalf("PushWithID again: %v", err)
+	}
+	fireNext(clock, 2*time.Second)
+	if got := receive(t, ran); got != "second" {
+		t.Errorf("executed %q, want second", got)
+	}
+}
+
+func TestPushWithIDEmpty(t *testing.T) {
+	q, _ := newTestQueue(t)
+
+	if err := q.PushWithID("", time.Second, func() {}); !errors.Is(err, ErrEmptyID) {
+		t.Errorf("PushWithID(\"\") error = %v, want ErrEmptyID", err)
+	}
+}
+
+func TestAdmissionControl(t *testing.T) {
+	errTooLate := errors.New("too late")
+	q, clock := newTestQueue(t, WithAdmissionControl(func(execTime time.Time) error {
+		if execTime.After(testStart.Add(time.Minute)) {
+			return errTooLate
+		}
+		return nil
+	}))
+
+	ran := make(chan struct{}, 1)
+	accepted := q.Push(30*time.Second, func() { ran <- struct{}{} })
+	if err := accepted.Err(); err != nil {
+		t.Fatalf("accepted push error = %v, want nil", err)
+	}
+
+	// 被拒绝的任务不会进入队列，各种推送方法按各自的返回约定告知调用方
+	rejected := q.Push(2*time.Minute, func() { t.Error("rejected task executed") })
+	if !errors.Is(rejected.Err(), errTooLate) {
+		t.Errorf("rejected push error = %v, want errTooLate", rejected.Err())
+	}
+	receive(t, rejected.Done())
+	if _, err := q.TryPush(2*time.Minute, func() {}); !errors.Is(err, errTooLate) {
+		t.Errorf("TryPush error = %v, want errTooLate", err)
+	}
+	if id := q.PushKeyed("k", 2*time.Minute, func() {}); id != "" {
+		t.Errorf("PushKeyed returned id %q, want empty", id)
+	}
+	if n := q.Len(); n != 1 {
+		t.Fatalf("Len = %d, want 1", n)
+	}
+
+	fireNext(clock, 30*time.Second)
+	receive(t, ran)
+	receive(t, accepted.Done())
+}
+
+func TestOnResidence(t *testing.T) {
+	type residence struct {
+		id string
+		d  time.Duration
+	}
+	got := make(chan residence, 3)
+	q, clock := newTestQueue(t, OnResidence(func(id string, d time.Duration) {
+		got <- residence{id, d}
+	}))
+
+	delays := []time.Duration{time.Second, 2 * time.Second, 5 * time.Second}
+	ids := make(map[string]time.Duration)
+	for _, d := range delays {
+		ids[q.Push(d, func() {}).ID()] = d
+	}
+
+	// 按时执行的任务在队列中停留的时间就是推送时设置的延时
+	for _, d := range delays {
+		clock.BlockUntil(1)
+		clock.Set(testStart.Add(d))
+		r := receive(t, got)
+		if want := ids[r.id]; r.d != want {
+			t.Errorf("task %s residence = %v, want %v", r.id, r.d, want)
+		}
+	}
+}
+
+func TestExecutingCount(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	const n = 5
+	started := make(chan struct{}, n+1)
+	release := make(chan struct{})
+	var handles []*Task
+	for i := 0; i < n; i++ {
+		handles = append(handles, q.Push(time.Second, func() {
+			started <- struct{}{}
+			<-release
+		}))
+	}
+	// panic 的任务同样会被扣减
+	panicked := q.Push(time.Second, func() {
+		started <- struct{}{}
+		<-release
+		panic("boom")
+	})
+
+	if q.IsExecuting() {
+		t.Fatal("IsExecuting before any task is due")
+	}
+	fireNext(clock, time.Second)
+	for i := 0; i <= n; i++ {
+		receive(t, started)
+	}
+	if got := q.ExecutingCount(); got != n+1 {
+		t.Errorf("ExecutingCount with overlapping tasks = %d, want %d", got, n+1)
+	}
+	if !q.IsExecuting() {
+		t.Error("IsExecuting = false while tasks are running")
+	}
+
+	close(release)
+	for _, h := range append(handles, panicked) {
+		receive(t, h.Done())
+	}
+	if got := q.ExecutingCount(); got != 0 {
+		t.Errorf("ExecutingCount after all tasks finished = %d, want 0", got)
+	}
+}
+
+func TestPushComputed(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	calls := 0
+	ran := make(chan time.Time, 1)
+	h := q.PushComputed(func() time.Duration {
+		calls++
+		return 3 * time.Second
+	}, func() {
+		ran <- clock.Now()
+	})
+	if h.Err() != nil {
+		t.Fatalf("PushComputed: %v", h.Err())
+	}
+
+	// 延时只在推送时计算一次，任务在计算出的时间执行
+	fireNext(clock, 2*time.Second)
+	settle(q)
+	select {
+	case <-ran:
+		t.Fatal("task ran before the computed delay")
+	default:
+	}
+	fireNext(clock, time.Second)
+	if at := receive(t, ran); !at.Equal(testStart.Add(3 * time.Second)) {
+		t.Errorf("task ran at %v, want %v", at, testStart.Add(3*time.Second))
+	}
+	receive(t, h.Done())
+	if calls != 1 {
+		t.Errorf("delayFn called %d times, want 1", calls)
+	}
+}
+
+func TestDelayFromEnqueue(t *testing.T) {
+	for name, tc := range map[string]struct {
+		opts []Option
+		want time.Duration
+	}{
+		"from push":    {nil, 5 * time.Second},
+		"from enqueue": {[]Option{WithDelayFromEnqueue()}, 8 * time.Second},
+	} {
+		t.Run(name, func(t *testing.T) {
+			q, clock := newTestQueue(t, tc.opts...)
+
+			// 阻塞调度协程，推送的任务停留在 add 管道中
+			blocked, release := make(chan struct{}), make(chan struct{})
+			go q.do(func() {
+				close(blocked)
+				<-release
+			})
+			<-blocked
+			id := q.Push(5*time.Second, func() {}).ID()
+
+			// 任务被接收之前过去了 3 秒
+			clock.Advance(3 * time.Second)
+			close(release)
+			settle(q)
+
+			info, ok := q.Get(id)
+			if !ok {
+				t.Fatal("task not found")
+			}
+			if want := testStart.Add(tc.want); !info.ExecTime.Equal(want) {
+				t.Errorf("exec time = %v, want %v", info.ExecTime, want)
+			}
+		})
+	}
+}
+
+func TestLoopRestartsAfterPanic(t *testing.T) {
+	var buf bytes.Buffer
+	recovered := make(chan any, 1)
+	q, clock := newTestQueue(t, WithLogger(log.New(&buf, "", 0)), OnLoopPanic(func(r any) {
+		recovered <- r
+	}))
+	h := q.Push(time.Second, func() {})
+
+	// 调度协程中的操作 panic，循环恢复后重新启动，任务列表保持不变
+	q.do(func() { panic("boom") })
+	if r := receive(t, recovered); r != "boom" {
+		t.Errorf("OnLoopPanic got %v, want boom", r)
+	}
+	if n := q.Metrics().LoopRestarts; n != 1 {
+		t.Errorf("LoopRestarts = %d, want 1", n)
+	}
+	if n := q.Len(); n != 1 {
+		t.Errorf("Len after restart = %d, want 1", n)
+	}
+	if !strings.Contains(buf.String(), "scheduler loop panic: boom") {
+		t.Errorf("log = %q, want the loop panic", buf.String())
+	}
+
+	fireNext(clock, time.Second)
+	receive(t, h.Done())
+	if err := q.Healthy(); err != nil {
+		t.Errorf("Healthy after restart: %v", err)
+	}
+}
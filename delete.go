@@ -0,0 +1,320 @@
+package delayqueue
+
+import (
+	"container/heap"
+	"container/list"
+	"sync"
+	"time"
+)
+
+// DeleteBatch 批量删除任务，返回实际删除的任务数量
+// 所有任务在调度协程的一次操作中移除，只需遍历一遍任务列表，比逐个 Delete 少了大量的查找；
+// 已经分发、还没有执行结束的任务与 Delete 一样处理：收到 context 的取消信号，不再执行也不再重复，同样计入返回值。不存在的 id 会被忽略
+func (q *DelayQueue) DeleteBatch(ids []string) int {
+	if len(ids) == 0 {
+		return 0
+	}
+
+	set := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		set[id] = struct{}{}
+	}
+	return q.deleteWhere(func(t *task) bool {
+		_, ok := set[t.id]
+		return ok
+	}, ids)
+}
+
+// DeleteFunc 删除所有满足 predicate 的等待执行的任务，返回删除的任务数量
+// predicate 在调度协程中调用，不能在其中调用队列的方法，否则会造成死锁
+func (q *DelayQueue) DeleteFunc(predicate func(TaskInfo) bool) int {
+	now := q.clock.Now()
+	return q.deleteWhere(func(t *task) bool {
+		return predicate(q.taskInfo(t, now))
+	}, nil)
+}
+
+// Purge 删除所有等待执行的任务，返回删除的任务数量；正在执行的任务不受影响
+// 用于依赖的外部系统被重置、已经安排的任务全部失效的场景
+func (q *DelayQueue) Purge() int {
+	return q.deleteWhere(func(t *task) bool {
+		return true
+	}, nil)
+}
+
+// PurgeBefore 删除执行时间早于 before 的所有等待执行的任务，返回删除的任务数量
+func (q *DelayQueue) PurgeBefore(before time.Time) int {
+	return q.deleteWhere(func(t *task) bool {
+		return t.execTime.Before(before)
+	}, nil)
+}
+
+// deleteWhere 移除所有满足 match 的任务，cancel 中已经分发的任务按 Delete 的方式取消，返回删除的任务数量
+func (q *DelayQueue) deleteWhere(match func(t *task) bool, cancel []string) int {
+	var removed []string
+	executing := 0
+	q.do(func() {
+		removed = q.removeWhere(match)
+		pending := make(map[string]struct{}, len(removed))
+		for _, id := range removed {
+			pending[id] = struct{}{}
+		}
+		for _, id := range cancel {
+			if _, ok := pending[id]; ok {
+				q.cancelExecuting(id)
+				continue
+			}
+			if q.stopRun(id) {
+				executing++
+			}
+		}
+	})
+
+	q.forgetDeleted(removed)
+	q.logEvent(LevelDebug, "tasks deleted", "count", len(removed), "executing", executing)
+	return len(removed) + executing
+}
+
+// forgetDeleted 在调度协程之外处理被 removeWhere 移除的任务：写入录制并从存储中移除
+func (q *DelayQueue) forgetDeleted(removed []string) {
+	r := q.recording.Load()
+	for _, id := range removed {
+		if r != nil {
+			r.recordDelete(id)
+		}
+		q.forget(id)
+	}
+}
+
+// removeWhere 从任务列表中移除所有满足 match 的任务，返回被移除的任务id
+// 堆中的任务过滤后整体重建堆，复杂度为 O(n)
+func (q *DelayQueue) removeWhere(match func(t *task) bool) []string {
+	var removed []string
+	// 所有列表中被移除的任务都经过同样的处理
+	drop := func(t *task) {
+		removed = append(removed, t.id)
+		q.unindexTask(t)
+		q.finishDeleted(t)
+	}
+	filter := func(list []*task) []*task {
+		remain := list[:0]
+		for _, t := range list {
+			if match(t) {
+				t.index = -1
+				drop(t)
+				continue
+			}
+			remain = append(remain, t)
+		}
+		// 清理尾部的引用，避免被移除的任务无法回收
+		for i := len(remain); i < len(list); i++ {
+			list[i] = nil
+		}
+		return remain
+	}
+
+	q.readyTasks = filter(q.readyTasks)
+	q.heldTasks = filter(q.heldTasks)
+	q.pausedTasks = filter(q.pausedTasks)
+
+	if q.wheel != nil {
+		for _, t := range q.wheel.tasks() {
+			if match(t) {
+				q.wheel.remove(t)
+				drop(t)
+			}
+		}
+	}
+
+	n := len(removed)
+	q.tasks = filter(q.tasks)
+	if len(removed) > n {
+		for i, t := range q.tasks {
+			t.index = i
+		}
+		heap.Init(&q.tasks)
+	}
+	return removed
+}
+
+// finishDeleted 等待执行的任务被删除：关闭句柄的 Done，计入删除数量并回调 OnDelete
+func (q *DelayQueue) finishDeleted(t *task) {
+	t.complete()
+	q.deleted.Add(1)
+	q.fireDelete(t)
+}
+
+// executedHistory 删除时能够识别为已经执行的任务数量，更早执行结束的任务按不存在处理
+const executedHistory = 1024
+
+// executedIDs 最近执行结束的任务id，容量固定，超出后淘汰最早的记录
+// 只用于让 Delete 区分任务已经执行与从未存在，不影响之后推送的同 id 任务
+type executedIDs struct {
+	mu   sync.Mutex
+	ring []string
+	next int
+	seen map[string]int // 任务id在 ring 中出现的次数
+}
+
+// add 记录一个执行结束的任务
+func (e *executedIDs) add(id string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ring == nil {
+		e.ring = make([]string, executedHistory)
+		e.seen = make(map[string]int)
+	}
+
+	if old := e.ring[e.next]; old != "" {
+		if e.seen[old]--; e.seen[old] <= 0 {
+			delete(e.seen, old)
+		}
+	}
+	e.ring[e.next] = id
+	e.seen[id]++
+	e.next = (e.next + 1) % len(e.ring)
+}
+
+// has 判断任务最近是否执行结束过
+func (e *executedIDs) has(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.seen[id] > 0
+}
+
+// runState 已经分发、还没有执行结束的任务
+type runState struct {
+	n        int  // 同一个id还没有结束的执行次数，周期任务的多次执行可能重叠
+	canceled bool // 执行期间收到了删除信号，执行失败后不再重试，熔断时不再推迟
+}
+
+// startRun 记录任务开始执行，在调度协程中分发任务时调用
+func (q *DelayQueue) startRun(id string) {
+	q.runsMu.Lock()
+	defer q.runsMu.Unlock()
+	r := q.runs[id]
+	if r == nil {
+		r = &runState{}
+		q.runs[id] = r
+	}
+	r.n++
+}
+
+// endRun 记录任务执行结束，重试与推迟的任务已经在此之前推到了 add 管道中
+func (q *DelayQueue) endRun(id string) {
+	q.runsMu.Lock()
+	defer q.runsMu.Unlock()
+	if r := q.runs[id]; r != nil {
+		r.n--
+		if r.n <= 0 {
+			delete(q.runs, id)
+		}
+	}
+}
+
+// stopRun 取消不在任务列表中、已经分发的任务，返回任务是否还在执行
+// 标记为已删除并记入「待删除」，执行结束后不再重试或安排下一次执行
+func (q *DelayQueue) stopRun(id string) bool {
+	executing := q.cancelExecuting(id)
+	if q.cancelRun(id) {
+		q.addWaitRemove(id)
+		return true
+	}
+	return executing
+}
+
+// cancelRun 将还没有执行结束的任务标记为已删除，返回任务是否还没有执行结束
+func (q *DelayQueue) cancelRun(id string) bool {
+	q.runsMu.Lock()
+	defer q.runsMu.Unlock()
+	r := q.runs[id]
+	if r == nil {
+		return false
+	}
+	r.canceled = true
+	return true
+}
+
+// runActive 判断任务是否已经分发、还没有执行结束
+func (q *DelayQueue) runActive(id string) bool {
+	q.runsMu.Lock()
+	defer q.runsMu.Unlock()
+	return q.runs[id] != nil
+}
+
+// runCanceled 判断任务的执行期间是否收到了删除信号
+func (q *DelayQueue) runCanceled(id string) bool {
+	q.runsMu.Lock()
+	defer q.runsMu.Unlock()
+	r := q.runs[id]
+	return r != nil && r.canceled
+}
+
+// waitRemoveEntry 一条「待删除」记录
+type waitRemoveEntry struct {
+	id string
+	at time.Time // 收到删除信号的时间
+}
+
+// addWaitRemove 将执行期间被删除的任务 id 记入「待删除」记录，记录数量超出上限时清理最早的记录
+// 记录按收到删除信号的先后顺序保存在链表中，清理最早的记录不需要遍历
+func (q *DelayQueue) addWaitRemove(id string) {
+	entry := waitRemoveEntry{id: id, at: q.clock.Now()}
+	if e, ok := q.waitRemoveTaskMapping[id]; ok {
+		// 再次收到删除信号，按新的时间移到最后
+		e.Value = entry
+		q.waitRemoveOrder.MoveToBack(e)
+	} else {
+		q.waitRemoveTaskMapping[id] = q.waitRemoveOrder.PushBack(entry)
+	}
+
+	if q.waitRemoveLimit > 0 && len(q.waitRemoveTaskMapping) > q.waitRemoveLimit {
+		oldest := q.waitRemoveOrder.Front()
+		q.removeWaitRemove(oldest)
+		q.waitRemoveExpired.Add(1)
+		q.logEvent(LevelDebug, "wait remove evicted", "id", oldest.Value.(waitRemoveEntry).id)
+	}
+	q.waitRemoveSize.Store(int64(len(q.waitRemoveTaskMapping)))
+}
+
+// removeWaitRemove 移除一条「待删除」记录
+func (q *DelayQueue) removeWaitRemove(e *list.Element) {
+	q.waitRemoveOrder.Remove(e)
+	delete(q.waitRemoveTaskMapping, e.Value.(waitRemoveEntry).id)
+}
+
+// matchWaitRemove 后续执行到达时检查「待删除」记录，存在时移除记录并返回 true
+func (q *DelayQueue) matchWaitRemove(id string) bool {
+	e, ok := q.waitRemoveTaskMapping[id]
+	if !ok {
+		return false
+	}
+	q.removeWaitRemove(e)
+	q.waitRemoveSize.Store(int64(len(q.waitRemoveTaskMapping)))
+	q.waitRemoveMatched.Add(1)
+	return true
+}
+
+// sweepWaitRemove 清理超过保留时长的「待删除」记录，并同步记录数量
+// 每隔半个保留时长最多清理一次，避免每一轮调度循环都遍历记录
+func (q *DelayQueue) sweepWaitRemove() {
+	if q.waitRemoveTTL <= 0 || len(q.waitRemoveTaskMapping) == 0 {
+		return
+	}
+	now := q.clock.Now()
+	if now.Sub(q.lastWaitSweep) < q.waitRemoveTTL/2 {
+		return
+	}
+	q.lastWaitSweep = now
+
+	for e := q.waitRemoveOrder.Front(); e != nil; {
+		next := e.Next()
+		if entry := e.Value.(waitRemoveEntry); now.Sub(entry.at) >= q.waitRemoveTTL {
+			q.removeWaitRemove(e)
+			q.waitRemoveExpired.Add(1)
+			q.logEvent(LevelDebug, "wait remove expired", "id", entry.id)
+		}
+		e = next
+	}
+	q.waitRemoveSize.Store(int64(len(q.waitRemoveTaskMapping)))
+}
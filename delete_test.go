@@ -0,0 +1,430 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeleteRunningTaskCancelsRetry(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	runs := 0
+	id := q.PushRetry(time.Second, func() error {
+		runs++
+		started <- struct{}{}
+		<-release
+		return errors.New("fail")
+	}, RetryPolicy{MaxAttempts: 3, Backoff: ConstantBackoff(time.Second)})
+
+	fireNext(clock, time.Second)
+	receive(t, started)
+
+	ok, err := q.Delete(id)
+	if !ok || err != nil {
+		t.Fatalf("Delete(running) = %v, %v, want true, nil", ok, err)
+	}
+	close(release)
+	stopQueue(t, q)
+
+	if runs != 1 {
+		t.Errorf("runs = %d, want 1", runs)
+	}
+	if n := q.Metrics().Retries; n != 0 {
+		t.Errorf("retries = %d, want 0", n)
+	}
+}
+
+func TestDeleteUnknownIDLeavesNoState(t *testing.T) {
+	q, _ := newTestQueue(t)
+
+	if _, err := q.Delete("missing"); !errors.Is(err, ErrTaskNotFound) {
+		t.Fatalf("Delete(missing) error = %v, want ErrTaskNotFound", err)
+	}
+
+	q.runsMu.Lock()
+	n := len(q.runs)
+	q.runsMu.Unlock()
+	if n != 0 {
+		t.Errorf("runs = %d, want 0", n)
+	}
+	if ids := q.pendingRemovals(); len(ids) != 0 {
+		t.Errorf("pendingRemovals = %v, want none", ids)
+	}
+}
+
+// deleteDuringRun 推送一个失败后重试的任务，在它执行期间删除它，返回任务id与放行执行的管道
+func deleteDuringRun(t *testing.T, q *DelayQueue, clock *ManualClock) (string, chan struct{}) {
+	t.Helper()
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	id := q.PushRetry(time.Second, func() error {
+		started <- struct{}{}
+		<-release
+		return errors.New("fail")
+	}, RetryPolicy{MaxAttempts: 3, Backoff: ConstantBackoff(time.Second)})
+
+	fireNext(clock, time.Second)
+	receive(t, started)
+	if ids := q.pendingRemovals(); len(ids) != 0 {
+		t.Fatalf("pendingRemovals before delete = %v, want none", ids)
+	}
+	if ok, err := q.Delete(id); !ok || err != nil {
+		t.Fatalf("Delete(running) = %v, %v, want true, nil", ok, err)
+	}
+	return id, release
+}
+
+// followUp 构造任务 id 的一次后续执行，模拟删除之前已经发出、还在 add 管道中的重试
+func followUp(q *DelayQueue, id string, ran chan<- struct{}) *task {
+	now := q.clock.Now()
+	t := &task{id: id, execTime: now.Add(time.Second), fn: func() { ran <- struct{}{} }, pushTime: now}
+	t.ensureExtra().rerun = true
+	return t
+}
+
+func TestWaitRemoveDropsFollowUp(t *testing.T) {
+	q, clock := newTestQueue(t)
+	id, release := deleteDuringRun(t, q, clock)
+	defer close(release)
+
+	if ids := q.pendingRemovals(); len(ids) != 1 || ids[0] != id {
+		t.Fatalf("pendingRemovals after delete = %v, want [%s]", ids, id)
+	}
+	if n := q.Metrics().WaitRemove; n != 1 {
+		t.Errorf("WaitRemove = %d, want 1", n)
+	}
+
+	ran := make(chan struct{}, 1)
+	if err := q.enqueue(followUp(q, id, ran)); err != nil {
+		t.Fatal(err)
+	}
+	settle(q)
+
+	if ids := q.pendingRemovals(); len(ids) != 0 {
+		t.Errorf("pendingRemovals after follow-up = %v, want none", ids)
+	}
+	if n := q.Len(); n != 0 {
+		t.Errorf("Len = %d, want 0", n)
+	}
+	m := q.Metrics()
+	if m.WaitRemove != 0 || m.WaitRemoveMatched != 1 {
+		t.Errorf("WaitRemove, WaitRemoveMatched = %d, %d, want 0, 1", m.WaitRemove, m.WaitRemoveMatched)
+	}
+}
+
+func TestWaitRemoveKeepsFreshPush(t *testing.T) {
+	q, clock := newTestQueue(t)
+	id, release := deleteDuringRun(t, q, clock)
+	defer close(release)
+
+	// 调用方新推送的同 id 任务不是后续执行，照常加入，记录继续等待后续执行
+	if err := q.PushWithID(id, time.Minute, func() {}); err != nil {
+		t.Fatal(err)
+	}
+	settle(q)
+	if n := q.Len(); n != 1 {
+		t.Errorf("Len = %d, want 1", n)
+	}
+	if ids := q.pendingRemovals(); len(ids) != 1 || ids[0] != id {
+		t.Errorf("pendingRemovals = %v, want [%s]", ids, id)
+	}
+}
+
+func TestWaitRemoveExpires(t *testing.T) {
+	q, clock := newTestQueue(t, WithWaitRemoveTTL(time.Minute))
+	id, release := deleteDuringRun(t, q, clock)
+	close(release)
+
+	clock.Advance(59 * time.Second)
+	settle(q)
+	if ids := q.pendingRemovals(); len(ids) != 1 || ids[0] != id {
+		t.Fatalf("pendingRemovals before TTL = %v, want [%s]", ids, id)
+	}
+
+	// 清理每隔半个保留时长最多进行一次，上一次清理之后再过半个保留时长
+	clock.Advance(30 * time.Second)
+	settle(q)
+	if ids := q.pendingRemovals(); len(ids) != 0 {
+		t.Errorf("pendingRemovals after TTL = %v, want none", ids)
+	}
+	m := q.Metrics()
+	if m.WaitRemove != 0 || m.WaitRemoveExpired != 1 {
+		t.Errorf("WaitRemove, WaitRemoveExpired = %d, %d, want 0, 1", m.WaitRemove, m.WaitRemoveExpired)
+	}
+}
+
+func TestWaitRemoveLimit(t *testing.T) {
+	q, clock := newTestQueue(t, WithWaitRemoveLimit(2))
+	for _, id := range []string{"a", "b", "c"} {
+		q.do(func() { q.addWaitRemove(id) })
+		clock.Advance(time.Second)
+	}
+
+	if ids := q.pendingRemovals(); len(ids) != 2 || ids[0] != "b" || ids[1] != "c" {
+		t.Errorf("pendingRemovals = %v, want [b c]", ids)
+	}
+	if n := q.Metrics().WaitRemoveExpired; n != 1 {
+		t.Errorf("WaitRemoveExpired = %d, want 1", n)
+	}
+}
+
+func TestWaitRemoveLimitEvictsInOrder(t *testing.T) {
+	q, clock := newTestQueue(t, WithWaitRemoveLimit(2))
+	// 再次收到删除信号的记录移到最后，超出上限时清理的是 b
+	for _, id := range []string{"a", "b", "a", "c"} {
+		q.do(func() { q.addWaitRemove(id) })
+		clock.Advance(time.Second)
+	}
+
+	if ids := q.pendingRemovals(); len(ids) != 2 || ids[0] != "a" || ids[1] != "c" {
+		t.Errorf("pendingRemovals = %v, want [a c]", ids)
+	}
+	if m := q.Metrics(); m.WaitRemove != 2 || m.WaitRemoveExpired != 1 {
+		t.Errorf("WaitRemove, WaitRemoveExpired = %d, %d, want 2, 1", m.WaitRemove, m.WaitRemoveExpired)
+	}
+}
+
+func TestDeleteBufferedAdd(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	ran := make(chan struct{}, 1)
+	if err := q.PushWithID("order-1", time.Second, func() { ran <- struct{}{} }); err != nil {
+		t.Fatalf("PushWithID: %v", err)
+	}
+	// 推送后立即删除，任务可能还在 add 管道中
+	ok, err := q.Delete("order-1")
+	if !ok || err != nil {
+		t.Fatalf("Delete(buffered) = %v, %v, want true, nil", ok, err)
+	}
+
+	clock.Advance(time.Second)
+	stopQueue(t, q)
+	select {
+	case <-ran:
+		t.Error("deleted task was executed")
+	default:
+	}
+	if n := q.Stats().Deleted; n != 1 {
+		t.Errorf("deleted = %d, want 1", n)
+	}
+}
+
+// deleteCounter 记录 OnDelete 回调的次数
+func deleteCounter() (Option, func() int) {
+	var (
+		mu sync.Mutex
+		n  int
+	)
+	opt := OnDelete(func(TaskInfo) {
+		mu.Lock()
+		n++
+		mu.Unlock()
+	})
+	return opt, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return n
+	}
+}
+
+func TestDeleteBatchAndFunc(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"heap":  nil,
+		"wheel": {WithTimingWheel(time.Second, 8)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			onDelete, deletes := deleteCounter()
+			q, _ := newTestQueue(t, append(opts, onDelete)...)
+
+			var handles []*Task
+			for i := 1; i <= 6; i++ {
+				// 较远的任务进入时间轮，较近的任务留在堆中
+				handles = append(handles, q.Push(time.Duration(i*i)*time.Second, func() {}))
+			}
+
+			if n := q.DeleteBatch([]string{handles[0].ID(), handles[5].ID(), "missing"}); n != 2 {
+				t.Errorf("DeleteBatch = %d, want 2", n)
+			}
+			target := handles[4].ID()
+			if n := q.DeleteFunc(func(info TaskInfo) bool { return info.ID == target }); n != 1 {
+				t.Errorf("DeleteFunc = %d, want 1", n)
+			}
+
+			for _, i := range []int{0, 4, 5} {
+				receive(t, handles[i].Done())
+			}
+			if n := q.Len(); n != 3 {
+				t.Errorf("Len = %d, want 3", n)
+			}
+			if n := q.Stats().Deleted; n != 3 {
+				t.Errorf("deleted = %d, want 3", n)
+			}
+			if n := deletes(); n != 3 {
+				t.Errorf("OnDelete calls = %d, want 3", n)
+			}
+		})
+	}
+}
+
+func TestDeleteBatchRunningTaskCancelsRetry(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	runs := 0
+	id := q.PushRetry(time.Second, func() error {
+		runs++
+		started <- struct{}{}
+		<-release
+		return errors.New("fail")
+	}, RetryPolicy{MaxAttempts: 3, Backoff: ConstantBackoff(time.Second)})
+
+	fireNext(clock, time.Second)
+	receive(t, started)
+
+	// 与 Delete 相同，执行中的任务被标记为已删除，执行失败后不再重试
+	if n := q.DeleteBatch([]string{id}); n != 1 {
+		t.Fatalf("DeleteBatch(running) = %d, want 1", n)
+	}
+	if ids := q.pendingRemovals(); len(ids) != 1 || ids[0] != id {
+		t.Errorf("pendingRemovals after DeleteBatch = %v, want [%s]", ids, id)
+	}
+	close(release)
+	stopQueue(t, q)
+
+	if runs != 1 {
+		t.Errorf("runs = %d, want 1", runs)
+	}
+	if n := q.Metrics().Retries; n != 0 {
+		t.Errorf("retries = %d, want 0", n)
+	}
+}
+
+func TestPurge(t *testing.T) {
+	for name, opts := range map[string][]Option{
+		"heap":  nil,
+		"wheel": {WithTimingWheel(time.Second, 8)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			onDelete, deletes := deleteCounter()
+			q, _ := newTestQueue(t, append(opts, onDelete)...)
+
+			var handles []*Task
+			for i := 1; i <= 6; i++ {
+				handles = append(handles, q.Push(time.Duration(i*i)*time.Second, func() {}))
+			}
+
+			// 1s、4s、9s 的任务早于 10s
+			if n := q.PurgeBefore(testStart.Add(10 * time.Second)); n != 3 {
+				t.Errorf("PurgeBefore = %d, want 3", n)
+			}
+			if n := q.Len(); n != 3 {
+				t.Errorf("Len after PurgeBefore = %d, want 3", n)
+			}
+			if n := q.Purge(); n != 3 {
+				t.Errorf("Purge = %d, want 3", n)
+			}
+
+			for _, h := range handles {
+				receive(t, h.Done())
+			}
+			if n := q.Len(); n != 0 {
+				t.Errorf("Len after Purge = %d, want 0", n)
+			}
+			if n := q.Stats().Deleted; n != 6 {
+				t.Errorf("deleted = %d, want 6", n)
+			}
+			if n := deletes(); n != 6 {
+				t.Errorf("OnDelete calls = %d, want 6", n)
+			}
+		})
+	}
+}
+
+func TestDeleteExecutedTask(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	h := q.Push(time.Second, func() {})
+	fireNext(clock, time.Second)
+	receive(t, h.Done())
+
+	if ok, err := q.Delete(h.ID()); ok || !errors.Is(err, ErrTaskExecuted) {
+		t.Errorf("Delete(executed) = %v, %v, want false, ErrTaskExecuted", ok, err)
+	}
+	if _, err := q.Delete("never-pushed"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("Delete(unknown) error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestDeleteContextWedgedLoop(t *testing.T) {
+	q, _ := newTestQueue(t)
+
+	// 占住调度协程，模拟卡住的调度循环
+	block := make(chan struct{})
+	entered := make(chan struct{})
+	go q.do(func() {
+		close(entered)
+		<-block
+	})
+	receive(t, entered)
+	defer close(block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.DeleteContext(ctx, "any"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("DeleteContext error = %v, want context.DeadlineExceeded", err)
+	}
+}
+
+func TestDeleteBeforeFireUnderAddFlood(t *testing.T) {
+	q, clock := newTestQueue(t, WithAddBuffer(1000))
+
+	fired := make(chan struct{}, 1)
+	target := q.Push(time.Second, func() { fired <- struct{}{} })
+	settle(q)
+
+	// 让调度协程停在一个同步操作中，期间 add 管道被推送灌满
+	entered := make(chan struct{})
+	block := make(chan struct{})
+	go q.do(func() {
+		close(entered)
+		<-block
+	})
+	<-entered
+	for {
+		if _, err := q.TryPush(time.Hour, func() {}); errors.Is(err, ErrQueueFull) {
+			break
+		}
+	}
+
+	// 删除信号在任务到期之前发出，之后任务的计时器才触发
+	deleted := make(chan bool, 1)
+	go func() {
+		ok, _ := q.Delete(target.ID())
+		deleted <- ok
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(q.remove) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("delete signal was never sent")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	clock.Set(testStart.Add(time.Second))
+	close(block)
+
+	if !receive(t, deleted) {
+		t.Fatal("Delete issued before the task was due reported it missing")
+	}
+	stopQueue(t, q)
+	select {
+	case <-fired:
+		t.Error("task fired although it was deleted before its exec time")
+	default:
+	}
+}
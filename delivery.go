@@ -0,0 +1,70 @@
+package delayqueue
+
+// DeliveryMode 设置了持久化存储时，具名处理函数任务的执行语义
+//
+// 两种语义的差别只在进程于执行过程中崩溃时体现：
+//   - AtLeastOnce 执行完成之后才从存储中移除任务，崩溃后重新加载时任务会再次执行，
+//     处理函数需要保证幂等；这是默认的语义，适合不能丢失的任务
+//   - AtMostOnce 执行之前先从存储中移除任务，崩溃后任务不会再次执行，但本次执行可能没有完成，
+//     适合重复执行代价高于丢失的任务，例如发送通知
+//
+// 失败后安排的重试是一次新的执行，两种语义下都会重新保存到存储中。
+// 周期任务的各次执行使用同一个id，存储中只有一条记录：AtLeastOnce 在本次执行结束之后才将记录更新为下一次执行，
+// 崩溃后重新加载时本次执行会再次执行；AtMostOnce 在分发本次执行时就将记录更新为下一次执行，崩溃时本次执行丢失
+type DeliveryMode int
+
+const (
+	AtLeastOnce DeliveryMode = iota // 至少执行一次，默认语义
+	AtMostOnce                      // 至多执行一次
+)
+
+// WithDeliveryMode 设置具名处理函数任务的执行语义，只在设置了 WithStorage 时生效
+func WithDeliveryMode(mode DeliveryMode) Option {
+	return func(q *DelayQueue) {
+		q.deliveryMode = mode
+	}
+}
+
+// deferredSave 推迟到某一次执行结束后才保存的周期任务
+type deferredSave struct {
+	run *task       // 推迟保存所等待的那一次执行
+	pt  PendingTask // 要保存的下一次执行
+}
+
+// saveNext 保存周期任务分发 run 时安排的下一次执行 next，两者使用同一个id，保存会覆盖本次执行的记录
+// 至多执行一次时立即保存，崩溃后从下一次执行开始恢复；至少执行一次时推迟到本次执行结束之后保存，
+// 期间存储中保留的仍是本次执行，崩溃后重新加载时本次执行会再次执行
+func (q *DelayQueue) saveNext(run, next *task) {
+	if q.storage == nil || !next.serializable() {
+		return
+	}
+
+	if q.deliveryMode == AtLeastOnce {
+		q.storageMu.Lock()
+		q.deferred[next.id] = deferredSave{run: run, pt: next.pendingTask()}
+		q.storageMu.Unlock()
+		return
+	}
+	if err := q.persist(next); err != nil {
+		q.logger.Printf("save task %s to storage failed: %v", next.id, err)
+	}
+}
+
+// saveDeferred 执行 run 结束后保存推迟的下一次执行
+// 执行期间任务被删除时推迟的保存已经取消；下一次执行在本次执行结束之前已经分发时，由后一次执行负责保存
+func (q *DelayQueue) saveDeferred(run *task) {
+	if q.storage == nil {
+		return
+	}
+
+	q.storageMu.Lock()
+	defer q.storageMu.Unlock()
+	d, ok := q.deferred[run.id]
+	if !ok || d.run != run {
+		return
+	}
+	delete(q.deferred, run.id)
+	if err := q.storage.Save(d.pt); err != nil {
+		q.logger.Printf("save task %s to storage failed: %v", run.id, err)
+	}
+}
@@ -0,0 +1,92 @@
+package delayqueue
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newPeriodicStorageQueue 创建带存储的队列并恢复一个每 10 秒执行一次的周期任务 tick，
+// 每次执行开始时向 started 发送信号，收到 release 之后才返回
+func newPeriodicStorageQueue(t *testing.T, mode DeliveryMode) (*DelayQueue, *ManualClock, *FileStorage, chan struct{}, chan struct{}) {
+	t.Helper()
+	storage := newTestStorage(t)
+	started := make(chan struct{}, 4)
+	release := make(chan struct{}, 4)
+	q, clock := newTestQueue(t, WithStorage(storage), WithDeliveryMode(mode), WithHandler("tick", func([]byte) {
+		started <- struct{}{}
+		<-release
+	}))
+	q.Restore([]PendingTask{
+		{ID: "tick", ExecTime: testStart.Add(10 * time.Second), Handler: "tick", Period: 10 * time.Second},
+	})
+	return q, clock, storage, started, release
+}
+
+// waitSaved 等待存储中 id 的执行时间变为 want
+func waitSaved(t *testing.T, storage *FileStorage, id string, want time.Time) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		pt, err := storage.Load(id)
+		if err == nil && pt.ExecTime.Equal(want) {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("saved task %s = %+v, %v, want exec time %v", id, pt, err, want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPeriodicAtLeastOnceKeepsRunningRecord(t *testing.T) {
+	q, clock, storage, started, release := newPeriodicStorageQueue(t, AtLeastOnce)
+
+	fireNext(clock, 10*time.Second)
+	receive(t, started)
+	settle(q)
+
+	// 执行期间存储中仍是本次执行，崩溃后重新加载时本次执行会再次执行
+	pt, err := storage.Load("tick")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := testStart.Add(10 * time.Second); !pt.ExecTime.Equal(want) {
+		t.Errorf("saved exec time during the run = %v, want %v", pt.ExecTime, want)
+	}
+
+	// 执行结束后更新为下一次执行
+	release <- struct{}{}
+	waitSaved(t, storage, "tick", testStart.Add(20*time.Second))
+
+	// 执行期间删除的任务不会在执行结束后重新写回存储
+	fireNext(clock, 10*time.Second)
+	receive(t, started)
+	if ok, err := q.Delete("tick"); !ok || err != nil {
+		t.Fatalf("Delete = %v, %v, want true, nil", ok, err)
+	}
+	release <- struct{}{}
+	stopQueue(t, q)
+	if _, err := storage.Load("tick"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("load deleted task error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestPeriodicAtMostOnceSavesNextOnDispatch(t *testing.T) {
+	_, clock, storage, started, release := newPeriodicStorageQueue(t, AtMostOnce)
+
+	fireNext(clock, 10*time.Second)
+	receive(t, started)
+
+	// 分发时已经更新为下一次执行，崩溃时本次执行丢失
+	pt, err := storage.Load("tick")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := testStart.Add(20 * time.Second); !pt.ExecTime.Equal(want) {
+		t.Errorf("saved exec time during the run = %v, want %v", pt.ExecTime, want)
+	}
+
+	release <- struct{}{}
+	waitSaved(t, storage, "tick", testStart.Add(20*time.Second))
+}
@@ -0,0 +1,68 @@
+package delayqueue
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ExportDOT 将当前等待执行的任务导出为 Graphviz DOT 格式的时间线，便于调试复杂的调度
+// 每个任务是一个节点，标注任务id与相对当前时间的执行时间，周期任务额外标注周期；
+// 节点按执行时间从左到右用虚线串联，带有相同 key 的任务归入同一个分组
+func (q *DelayQueue) ExportDOT() string {
+	var tasks []task
+	q.do(func() {
+		for _, t := range q.pendingTasks() {
+			tasks = append(tasks, *t)
+		}
+	})
+	// 等待领取、被扣留与暂停的任务不在任务堆中，统一按执行顺序排列后再串联
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return tasks[i].before(&tasks[j])
+	})
+	now := q.clock.Now()
+
+	var b strings.Builder
+	b.WriteString("digraph delayqueue {\n")
+	b.WriteString("\trankdir=LR;\n")
+	b.WriteString("\tnode [shape=box];\n")
+
+	// 按 key 分组输出节点
+	groups := make(map[string][]int)
+	var keys []string
+	for i, t := range tasks {
+		if _, ok := groups[t.extra().key]; !ok {
+			keys = append(keys, t.extra().key)
+		}
+		groups[t.extra().key] = append(groups[t.extra().key], i)
+	}
+	sort.Strings(keys)
+	for index, key := range keys {
+		indent := "\t"
+		if key != "" {
+			fmt.Fprintf(&b, "\tsubgraph cluster_%d {\n", index)
+			fmt.Fprintf(&b, "\t\tlabel=%q;\n", "key: "+key)
+			indent = "\t\t"
+		}
+		for _, i := range groups[key] {
+			t := tasks[i]
+			label := fmt.Sprintf("%s\\n+%s", strings.ReplaceAll(t.id, `"`, `\"`), t.execTime.Sub(now).Round(time.Millisecond))
+			if t.extra().period > 0 {
+				label += fmt.Sprintf("\\nevery %s", t.extra().period)
+			}
+			fmt.Fprintf(&b, "%s%q [label=\"%s\"];\n", indent, t.id, label)
+		}
+		if key != "" {
+			b.WriteString("\t}\n")
+		}
+	}
+
+	// 按执行时间串联成时间线
+	for i := 1; i < len(tasks); i++ {
+		fmt.Fprintf(&b, "\t%q -> %q [style=dashed];\n", tasks[i-1].id, tasks[i].id)
+	}
+
+	b.WriteString("}\n")
+	return b.String()
+}
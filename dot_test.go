@@ -0,0 +1,52 @@
+package delayqueue
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestExportDOT(t *testing.T) {
+	var n int
+	q, _ := newTestQueue(t, WithIDGenerator(IDGeneratorFunc(func() string {
+		n++
+		return fmt.Sprintf("t%d", n)
+	})))
+
+	q.Push(3*time.Second, func() {})
+	q.PushKeyed("k", time.Second, func() {})
+	q.PushRepeating(2*time.Second, func() {})
+	paused := q.Push(500*time.Millisecond, func() {})
+	if err := q.PauseTask(paused.ID()); err != nil {
+		t.Fatalf("PauseTask: %v", err)
+	}
+
+	// 暂停的任务不在任务堆中，时间线仍然按执行时间串联
+	want := `digraph delayqueue {
+	rankdir=LR;
+	node [shape=box];
+	"t4" [label="t4\n+500ms"];
+	"t3" [label="t3\n+2s\nevery 2s"];
+	"t1" [label="t1\n+3s"];
+	subgraph cluster_1 {
+		label="key: k";
+		"t2" [label="t2\n+1s"];
+	}
+	"t4" -> "t2" [style=dashed];
+	"t2" -> "t3" [style=dashed];
+	"t3" -> "t1" [style=dashed];
+}
+`
+	if got := q.ExportDOT(); got != want {
+		t.Errorf("ExportDOT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestExportDOTEmpty(t *testing.T) {
+	q, _ := newTestQueue(t)
+
+	want := "digraph delayqueue {\n\trankdir=LR;\n\tnode [shape=box];\n}\n"
+	if got := q.ExportDOT(); got != want {
+		t.Errorf("ExportDOT() on empty queue =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,39 @@
+package delayqueue
+
+// DuplicateIDPolicy 调用方指定的任务id已经有等待执行的任务时的处理策略
+type DuplicateIDPolicy int
+
+const (
+	DuplicateIDReplace DuplicateIDPolicy = iota // 删除已有的任务，保留新推送的任务，默认策略
+	DuplicateIDReject                           // 保留已有的任务，拒绝新推送的任务并返回 ErrDuplicateID
+)
+
+// checkDuplicateID 推送前在调度协程中检查指定的id是否已经存在，DuplicateIDReject 策略下存在时返回 ErrDuplicateID
+// 并发推送同一个id的竞争由调度协程接收任务时兜底
+func (q *DelayQueue) checkDuplicateID(id string) error {
+	if q.duplicateIDPolicy != DuplicateIDReject {
+		return nil
+	}
+	var exists bool
+	q.do(func() {
+		exists = q.findTask(id) != nil
+	})
+	if exists {
+		return ErrDuplicateID
+	}
+	return nil
+}
+
+// acceptCustomID 调度协程接收指定了id的任务时处理id的冲突，返回新任务是否需要加入任务列表
+// DuplicateIDReplace 策略下由 indexTask 替换已有的任务
+func (q *DelayQueue) acceptCustomID(t *task) bool {
+	if q.duplicateIDPolicy != DuplicateIDReject || q.findTask(t.id) == nil {
+		return true
+	}
+
+	q.logger.Printf("task %s dropped, another task with the same id is pending", t.id)
+	q.logEvent(LevelDebug, "task dropped", "id", t.id, "reason", "duplicate_id")
+	q.fireDrop(t, "duplicate_id")
+	t.complete()
+	return false
+}
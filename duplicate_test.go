@@ -0,0 +1,39 @@
+package delayqueue
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDuplicateIDPolicy(t *testing.T) {
+	for name, tc := range map[string]struct {
+		policy  DuplicateIDPolicy
+		wantErr error
+		wantRun string
+	}{
+		"replace": {DuplicateIDReplace, nil, "second"},
+		"reject":  {DuplicateIDReject, ErrDuplicateID, "first"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			q, clock := newTestQueue(t, WithDuplicateIDPolicy(tc.policy))
+
+			ran := make(chan string, 2)
+			if err := q.PushWithID("webhook-1", time.Second, func() { ran <- "first" }); err != nil {
+				t.Fatalf("first PushWithID: %v", err)
+			}
+			err := q.PushWithID("webhook-1", time.Second, func() { ran <- "second" })
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("second PushWithID = %v, want %v", err, tc.wantErr)
+			}
+			if n := q.Len(); n != 1 {
+				t.Errorf("Len = %d, want 1", n)
+			}
+
+			fireNext(clock, time.Second)
+			if got := receive(t, ran); got != tc.wantRun {
+				t.Errorf("ran %q, want %q", got, tc.wantRun)
+			}
+		})
+	}
+}
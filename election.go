@@ -0,0 +1,173 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+)
+
+// Elector 多实例部署时的选主接口
+//
+// 多个副本共享同一个持久化存储时，只应有一个副本触发到期的任务。设置了选主的队列创建后处于待命状态：
+// 照常接受推送与删除，但不触发任何任务；定期调用 Campaign 竞选，成为 leader 后才开始触发，
+// 失去 leader 身份（续期失败、Campaign 返回错误）时立即回到待命状态。leader 崩溃后租约过期，
+// 其他副本在下一次竞选时接替，并按存储中的任务重建任务列表：继续执行原 leader 没有执行完的任务，
+// 丢弃原 leader 已经执行或删除、不再保存在存储中的任务。
+// 存储只在成为 leader 时重新加载一次，续期时不再加载：待命副本推送的任务写入共享存储并留在它自己的任务列表中，
+// 由下一次接替的 leader 触发，需要及时执行的任务应当推送到 leader（IsLeader）上；没有设置存储的任务只会留在推送它的副本中。
+// ShardedQueue 的所有内部队列共用一次竞选，成为 leader 时每个内部队列只加载路由到自己的任务；
+// Partition 与 Clone 派生的队列同样不单独竞选，随原队列切换身份，Partition 的新队列只加载分配到自己的任务。
+//
+// redisqueue 与 mongoqueue 包提供了基于 Redis SETNX 与 MongoDB 文档的实现。
+type Elector interface {
+	// Campaign 竞选 leader，已经是 leader 时为租约续期，返回本次调用之后当前实例是否为 leader
+	Campaign(ctx context.Context) (bool, error)
+	// Resign 主动放弃 leader 身份，队列停止时调用，其他实例无需等待租约过期即可接替
+	Resign(ctx context.Context) error
+}
+
+// IsLeader 判断当前实例是否为 leader，没有设置 WithElector 时总是返回 true
+func (q *DelayQueue) IsLeader() bool {
+	return q.elector == nil || q.leader.Load()
+}
+
+// campaign 定期竞选 leader，通过 apply 切换身份，队列停止时放弃 leader 身份后退出
+// ShardedQueue 在第一个内部队列上运行竞选，apply 同时切换所有内部队列的身份
+func (q *DelayQueue) campaign(apply func(leader bool)) {
+	defer close(q.electDone)
+
+	for {
+		ok, err := q.elector.Campaign(q.ctx)
+		if err != nil {
+			// 无法确认自己仍是 leader 时主动退回待命，宁可短暂无人触发也不能两个实例同时触发
+			q.logger.Printf("leader election failed: %v", err)
+			ok = false
+		}
+		apply(ok)
+
+		timer := q.clock.NewTimer(q.electInterval)
+		select {
+		case <-timer.C():
+		case <-q.quit:
+			timer.Stop()
+			if q.leader.Load() {
+				ctx, cancel := context.WithTimeout(context.Background(), q.electInterval)
+				if err := q.elector.Resign(ctx); err != nil {
+					q.logger.Printf("resign leadership failed: %v", err)
+				}
+				cancel()
+			}
+			// 不再续期，仍在运行的派生队列回到待命，避免与接替的实例同时触发
+			apply(false)
+			return
+		}
+	}
+}
+
+// setLeader 切换 leader 身份，成为 leader 时重新加载存储中的任务；派生的队列随之切换
+func (q *DelayQueue) setLeader(leader bool) {
+	q.followersMu.Lock()
+	defer q.followersMu.Unlock()
+	for _, f := range q.followers {
+		if !f.stopped.Load() {
+			f.setLeader(leader)
+		}
+	}
+
+	if q.leader.Swap(leader) == leader {
+		return
+	}
+
+	if leader {
+		q.logger.Printf("became leader, start firing tasks")
+		q.reloadStorage()
+	} else {
+		q.logger.Printf("lost leadership, stop firing tasks")
+	}
+	q.logEvent(LevelInfo, "leadership changed", "leader", leader)
+	q.do(func() {
+		q.standby = !leader
+	})
+}
+
+// follow 让派生的队列 nq 不再单独竞选，而是随当前队列切换 leader 身份，并立即切换到当前的身份
+// nq 的任务转移完成之后才调用，成为 leader 时重新加载存储不会与转移过来的任务重复
+func (q *DelayQueue) follow(nq *DelayQueue) {
+	if q.elector == nil {
+		return
+	}
+	q.followersMu.Lock()
+	defer q.followersMu.Unlock()
+	q.followers = append(q.followers, nq)
+	nq.setLeader(q.leader.Load())
+}
+
+// reloadStorage 按存储中的任务重建当前实例的任务列表：加载由其他实例推送、当前实例还不知道的任务，
+// 丢弃已经不在存储中的可序列化任务（其他实例已经执行或删除了它们），基于闭包的任务不在存储中，保持不变
+// 正在执行或等待确认的任务同样是已知的；读取列表之后才执行完成或才推送的任务，加载与丢弃之前都逐个向存储确认
+func (q *DelayQueue) reloadStorage() {
+	if q.storage == nil {
+		return
+	}
+	tasks, err := q.storage.List()
+	if err != nil {
+		q.logger.Printf("load tasks from storage failed: %v", err)
+		return
+	}
+
+	var (
+		unknown []PendingTask
+		stale   []*task
+	)
+	q.do(func() {
+		stored := make(map[string]struct{}, len(tasks))
+		for _, pt := range tasks {
+			if q.owns != nil && !q.owns(pt.ID) {
+				// 路由到其他内部队列的任务由对应的内部队列加载
+				continue
+			}
+			stored[pt.ID] = struct{}{}
+			if q.findTask(pt.ID) == nil && !q.runActive(pt.ID) && !q.isInflight(pt.ID) {
+				unknown = append(unknown, pt)
+			}
+		}
+		for _, t := range q.pendingTasks() {
+			if _, ok := stored[t.id]; !ok && t.serializable() {
+				stale = append(stale, t)
+			}
+		}
+	})
+
+	missing := unknown[:0]
+	for _, pt := range unknown {
+		if _, err := q.storage.Load(pt.ID); err == nil {
+			missing = append(missing, pt)
+		}
+	}
+	gone := stale[:0]
+	for _, t := range stale {
+		// 读取列表之后推送的任务先保存再入队，存储中能读到的任务不是过期的
+		if _, err := q.storage.Load(t.id); errors.Is(err, ErrTaskNotFound) {
+			gone = append(gone, t)
+		}
+	}
+	q.dropStale(gone)
+	q.restore(missing, false)
+}
+
+// dropStale 从任务列表中移除已经不在存储中的任务，任务的句柄关闭 Done
+// 确认之后同 id 的任务可能被重新推送，只移除确认时的那一个任务
+func (q *DelayQueue) dropStale(tasks []*task) {
+	if len(tasks) == 0 {
+		return
+	}
+	q.do(func() {
+		for _, t := range tasks {
+			if q.findTask(t.id) != t {
+				continue
+			}
+			q.takeTask(t.id)
+			t.complete()
+			q.logEvent(LevelDebug, "stale task dropped", "id", t.id)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package delayqueue
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// staticElector 竞选结果固定的选主实现
+type staticElector bool
+
+func (e staticElector) Campaign(context.Context) (bool, error) { return bool(e), nil }
+
+func (e staticElector) Resign(context.Context) error { return nil }
+
+// switchElector 竞选结果可以在测试中切换的选主实现
+type switchElector struct {
+	leader atomic.Bool
+}
+
+func (e *switchElector) Campaign(context.Context) (bool, error) { return e.leader.Load(), nil }
+
+func (e *switchElector) Resign(context.Context) error { return nil }
+
+func TestFailoverDoesNotRerunExecutedTask(t *testing.T) {
+	storage, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	pt := PendingTask{ID: "order-42", ExecTime: testStart.Add(1500 * time.Millisecond), Handler: "order", Payload: []byte("42")}
+	if err := storage.Save(pt); err != nil {
+		t.Fatal(err)
+	}
+
+	var runs atomic.Int32
+	ran := make(chan string, 3)
+	clock := NewManualClock(testStart)
+	handler := WithHandler("order", func(payload []byte) {
+		runs.Add(1)
+		ran <- string(payload)
+	})
+
+	// 两个副本启动时都从共享存储加载了任务，只有 a 是 leader
+	ea, eb := &switchElector{}, &switchElector{}
+	ea.leader.Store(true)
+	a := NewDelayQueue(WithClock(clock), WithStorage(storage), handler, WithElector(ea, time.Second))
+	b := NewDelayQueue(WithClock(clock), WithStorage(storage), handler, WithElector(eb, time.Second))
+	t.Cleanup(func() { stopQueue(t, b) })
+
+	// 两个竞选计时器与 a 的任务计时器都就绪后，推进到任务的执行时间，任务在 a 上执行
+	clock.BlockUntil(3)
+	clock.Set(pt.ExecTime)
+	if got := receive(t, ran); got != "42" {
+		t.Fatalf("leader executed payload %q, want 42", got)
+	}
+
+	// a 停止，b 在下一次竞选时接替；b 待命期间推送的任务作为哨兵
+	stopQueue(t, a)
+	eb.leader.Store(true)
+	if err := b.PushHandlerWithID("sentinel", 1500*time.Millisecond, "order", []byte("sentinel")); err != nil {
+		t.Fatal(err)
+	}
+	clock.Set(testStart.Add(2500 * time.Millisecond))
+	clock.BlockUntil(2)
+	clock.Set(testStart.Add(3 * time.Second))
+	receive(t, ran)
+
+	// 等 b 上已经分发的任务都执行结束后再计数，过期的任务在 b 上再次执行时 runs 为 3
+	stopQueue(t, b)
+	if n := runs.Load(); n != 2 {
+		t.Errorf("handler ran %d times, want 2 (task once, sentinel once)", n)
+	}
+}
+
+func TestLeaderLoadsFollowerPushesOnTakeover(t *testing.T) {
+	storage, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ran := make(chan string, 1)
+	clock := NewManualClock(testStart)
+	handler := WithHandler("order", func(payload []byte) { ran <- string(payload) })
+
+	e := &switchElector{}
+	q := NewDelayQueue(WithClock(clock), WithStorage(storage), handler, WithElector(e, time.Second))
+	t.Cleanup(func() { stopQueue(t, q) })
+
+	// 待命期间模拟其他副本的推送：任务只写入共享存储
+	clock.BlockUntil(1)
+	pt := PendingTask{ID: "order-42", ExecTime: testStart.Add(2 * time.Second), Handler: "order", Payload: []byte("42")}
+	if err := storage.Save(pt); err != nil {
+		t.Fatalf("save follower task: %v", err)
+	}
+
+	// 下一次竞选时成为 leader 并加载任务，再推进到任务的执行时间
+	e.leader.Store(true)
+	clock.Advance(time.Second)
+	clock.BlockUntil(2)
+	if !q.IsLeader() {
+		t.Fatal("queue did not become leader")
+	}
+	clock.Advance(time.Second)
+	if got := receive(t, ran); got != "42" {
+		t.Errorf("leader executed payload %q, want 42", got)
+	}
+}
+
+// signalElector 每次竞选时发出通知的选主实现，测试据此确认竞选协程已经完成了上一次的身份切换
+type signalElector struct {
+	switchElector
+	calls chan struct{}
+}
+
+func (e *signalElector) Campaign(context.Context) (bool, error) {
+	select {
+	case e.calls <- struct{}{}:
+	default:
+	}
+	return e.leader.Load(), nil
+}
+
+// nextCampaign 逐秒推进时钟，直到选主收到下一次竞选
+// 计时器可能在推进之后才设置好，这次推进落空时下一次推进仍会触发它
+func nextCampaign(t *testing.T, clock *ManualClock, e *signalElector) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		clock.Advance(time.Second)
+		select {
+		case <-e.calls:
+			return
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	t.Fatal("elector was not called")
+}
+
+func TestShardedElectionFiresOnce(t *testing.T) {
+	storage, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runs atomic.Int32
+	ran := make(chan struct{}, 20)
+	clock := NewManualClock(testStart)
+	e := &signalElector{calls: make(chan struct{}, 1)}
+	s := NewShardedQueue(4, WithClock(clock), WithStorage(storage), WithElector(e, time.Second),
+		WithHandler("h", func([]byte) {
+			runs.Add(1)
+			ran <- struct{}{}
+		}))
+
+	// 待命期间推送的任务留在各内部队列中，其他副本推送的任务只写入共享存储
+	receive(t, e.calls)
+	for i := 0; i < 10; i++ {
+		s.PushHandler(time.Hour, "h", nil)
+	}
+	for i := 0; i < 5; i++ {
+		pt := PendingTask{ID: fmt.Sprintf("follower-%d", i), ExecTime: testStart.Add(time.Hour), Handler: "h"}
+		if err := storage.Save(pt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 成为 leader 后经过多次续期，每个任务仍然只在路由到的内部队列中存在一份
+	e.leader.Store(true)
+	for i := 0; i < 4; i++ {
+		nextCampaign(t, clock, e)
+	}
+	if n := s.Len(); n != 15 {
+		t.Fatalf("Len = %d, want 15", n)
+	}
+
+	clock.Set(testStart.Add(time.Hour))
+	for i := 0; i < 15; i++ {
+		receive(t, ran)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := runs.Load(); n != 15 {
+		t.Errorf("handler ran %d times, want 15", n)
+	}
+}
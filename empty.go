@@ -0,0 +1,30 @@
+package delayqueue
+
+import "context"
+
+// WaitFirstEmpty 等待队列第一次由忙变空：队列中曾经有过任务，之后所有任务都已到期取出或被删除
+// 与队列当前是否为空无关，在推送任何任务之前调用也会一直等待，直到真正发生一次由忙变空；
+// 这里的空只看等待执行的任务，不等待已经开始执行的任务结束。ctx 结束时返回 ctx.Err()
+func (q *DelayQueue) WaitFirstEmpty(ctx context.Context) error {
+	select {
+	case <-q.firstEmpty:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// checkFirstEmpty 在调度协程中检查队列是否第一次由忙变空
+func (q *DelayQueue) checkFirstEmpty() {
+	if q.firstEmptied {
+		return
+	}
+	if q.taskCount() > 0 {
+		q.hadTasks = true
+		return
+	}
+	if q.hadTasks && len(q.add) == 0 {
+		q.firstEmptied = true
+		close(q.firstEmpty)
+	}
+}
@@ -0,0 +1,50 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestWaitFirstEmptyReturnsAtDrain(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- q.WaitFirstEmpty(context.Background())
+	}()
+	notYet := func(stage string) {
+		t.Helper()
+		settle(q)
+		select {
+		case err := <-done:
+			t.Fatalf("WaitFirstEmpty returned %v %s", err, stage)
+		default:
+		}
+	}
+
+	// 推送任务之前的空队列不算由忙变空
+	notYet("before any push")
+	q.Push(time.Second, func() {})
+	q.Push(2*time.Second, func() {})
+	notYet("with two tasks pending")
+	fireNext(clock, time.Second)
+	notYet("with one task pending")
+
+	fireNext(clock, time.Second)
+	if err := receive(t, done); err != nil {
+		t.Errorf("WaitFirstEmpty error = %v", err)
+	}
+}
+
+func TestWaitFirstEmptyContext(t *testing.T) {
+	q, _ := newTestQueue(t)
+	q.Push(time.Hour, func() {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := q.WaitFirstEmpty(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitFirstEmpty error = %v, want context.DeadlineExceeded", err)
+	}
+}
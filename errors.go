@@ -0,0 +1,44 @@
+package delayqueue
+
+import "errors"
+
+var (
+	// ErrRateLimited 推送速度超过了 WithPushRateLimit 设置的限制
+	ErrRateLimited = errors.New("delayqueue: push rate limited")
+
+	// ErrQueueFull 等待执行的任务数量达到了上限
+	ErrQueueFull = errors.New("delayqueue: queue is full")
+
+	// ErrClosed 队列已经停止
+	ErrClosed = errors.New("delayqueue: queue is closed")
+
+	// ErrOverdue 任务开始执行时已经逾期
+	ErrOverdue = errors.New("delayqueue: task overdue")
+
+	// ErrTaskTimeout 任务执行超过了 PushTimeout 设置的时长
+	ErrTaskTimeout = errors.New("delayqueue: task execution timed out")
+
+	// ErrNoPublisher 发布消息的任务到期时队列没有设置 WithPublisher
+	ErrNoPublisher = errors.New("delayqueue: no publisher configured")
+
+	// ErrEmptyID 指定的任务id为空
+	ErrEmptyID = errors.New("delayqueue: empty task id")
+
+	// ErrDuplicateID 调用方指定的任务id已经有等待执行的任务，且设置了 DuplicateIDReject 策略
+	ErrDuplicateID = errors.New("delayqueue: duplicate task id")
+
+	// ErrDuplicateKey 去重 key 已经有等待执行的任务，且设置了 UniqueReject 策略
+	ErrDuplicateKey = errors.New("delayqueue: duplicate unique key")
+
+	// ErrUnhealthy 健康检查没有通过，Healthy 返回的错误包装了该错误
+	ErrUnhealthy = errors.New("delayqueue: unhealthy")
+
+	// ErrTaskNotFound 任务不存在
+	ErrTaskNotFound = errors.New("delayqueue: task not found")
+
+	// ErrNotSerializable 任务基于闭包，没有可以读取或替换的数据
+	ErrNotSerializable = errors.New("delayqueue: task is not serializable")
+
+	// ErrTaskExecuted 任务已经到期并执行结束，无法再删除
+	ErrTaskExecuted = errors.New("delayqueue: task already executed")
+)
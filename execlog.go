@@ -0,0 +1,183 @@
+package delayqueue
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// 任务的执行结果
+const (
+	OutcomeOK             = "ok"              // 执行完成
+	OutcomeDropped        = "dropped"         // 被丢弃，未执行
+	OutcomeMissingHandler = "missing_handler" // 具名处理函数不存在
+	OutcomeError          = "error"           // 执行函数返回了错误
+	OutcomeShortCircuited = "short_circuited" // 熔断器打开，未执行
+	OutcomePanic          = "panic"           // 执行函数 panic
+	OutcomeLate           = "late"            // 到期时的延迟过大，未执行
+	OutcomeTimeout        = "timeout"         // 执行超过了任务的超时时间
+	OutcomeDelivered      = "delivered"       // 通过 PopDue 或 Channel 交给了消费者
+)
+
+// ExecutionEvent 一次任务执行的记录
+type ExecutionEvent struct {
+	Queue     string            `json:"queue,omitempty"`    // 队列名称
+	ID        string            `json:"id"`                 // 任务id
+	Scheduled time.Time         `json:"scheduled"`          // 计划执行时间
+	Actual    time.Time         `json:"actual"`             // 实际执行时间
+	Outcome   string            `json:"outcome"`            // 执行结果
+	Error     string            `json:"error,omitempty"`    // 执行函数返回的错误
+	Metadata  map[string]string `json:"metadata,omitempty"` // 任务的元数据，可以作为监控指标的标签
+}
+
+// executionLog 以每行一个 JSON 的格式异步输出执行记录
+type executionLog struct {
+	events chan ExecutionEvent
+	w      *bufio.Writer
+	logger Logger
+	done   chan struct{}
+	err    error
+
+	mu     sync.RWMutex // 保护 closed 与 refs，避免关闭后继续写入
+	closed bool
+	refs   int // 共用该输出的队列数量，ShardedQueue 的内部队列与派生的队列共用同一个输出
+}
+
+// executionLogBuffer 执行记录缓冲区的大小
+const executionLogBuffer = 4096
+
+// executionLogFlushInterval 执行记录定时刷新到 io.Writer 的间隔
+const executionLogFlushInterval = time.Second
+
+// newExecutionLog 创建执行记录输出，并开启后台写入协程
+func newExecutionLog(w io.Writer, logger Logger) *executionLog {
+	l := &executionLog{
+		events: make(chan ExecutionEvent, executionLogBuffer),
+		w:      bufio.NewWriter(w),
+		logger: logger,
+		done:   make(chan struct{}),
+		refs:   1,
+	}
+	go l.run()
+	return l
+}
+
+// write 提交一条执行记录，缓冲区已满时直接丢弃，不阻塞任务的执行
+func (l *executionLog) write(e ExecutionEvent) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return
+	}
+
+	select {
+	case l.events <- e:
+	default:
+		l.logger.Printf("execution log buffer is full, drop event of task %s", e.ID)
+	}
+}
+
+// run 后台写入协程，定时将缓冲的内容刷新到 io.Writer
+func (l *executionLog) run() {
+	defer close(l.done)
+
+	ticker := time.NewTicker(executionLogFlushInterval)
+	defer ticker.Stop()
+
+	enc := json.NewEncoder(l.w)
+	for {
+		select {
+		case e, ok := <-l.events:
+			if !ok {
+				l.flush()
+				return
+			}
+			if err := enc.Encode(e); err != nil {
+				l.logger.Printf("write execution log failed: %v", err)
+			}
+		case <-ticker.C:
+			l.flush()
+		}
+	}
+}
+
+// flush 将缓冲的内容刷新到 io.Writer
+func (l *executionLog) flush() {
+	if err := l.w.Flush(); err != nil {
+		l.err = err
+		l.logger.Printf("flush execution log failed: %v", err)
+	}
+}
+
+// acquire 让另一个队列共用该输出，多个 bufio.Writer 同时写同一个 io.Writer 会交错出损坏的行；
+// 输出已经关闭时返回 nil，调用方自行创建新的输出
+func (l *executionLog) acquire() *executionLog {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	l.refs++
+	return l
+}
+
+// release 一个共用的队列不再使用该输出，最后一个队列释放时关闭输出
+func (l *executionLog) release() error {
+	l.mu.Lock()
+	l.refs--
+	last := l.refs <= 0
+	l.mu.Unlock()
+	if !last {
+		return nil
+	}
+	return l.close()
+}
+
+// close 停止接收新的记录，等待已提交的记录全部写出并刷新
+func (l *executionLog) close() error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.events)
+	}
+	l.mu.Unlock()
+
+	<-l.done
+	return l.err
+}
+
+// logExecution 记录一次任务执行
+func (q *DelayQueue) logExecution(t *task, actual time.Time, outcome string, err error) {
+	if q.execLog == nil {
+		return
+	}
+	var errMsg string
+	if err != nil {
+		errMsg = err.Error()
+	}
+	q.execLog.write(ExecutionEvent{
+		Queue:     q.name,
+		ID:        t.id,
+		Scheduled: t.execTime,
+		Actual:    actual,
+		Outcome:   outcome,
+		Error:     errMsg,
+		Metadata:  t.extra().metadata,
+	})
+}
+
+// CloseExecutionLog 停止输出执行记录，并将已缓冲的记录全部写出
+// 关闭之后仍有任务执行时不会再输出记录；未设置 WithExecutionLog 时直接返回 nil。
+// ShardedQueue 的内部队列以及 Partition、Clone 派生的队列与原队列共用同一个输出，
+// 最后一个关闭的队列负责写出全部记录，其他队列关闭时直接返回 nil
+func (q *DelayQueue) CloseExecutionLog() error {
+	if q.execLog == nil {
+		return nil
+	}
+	q.execLogClose.Do(func() {
+		q.execLogErr = q.execLog.release()
+	})
+	return q.execLogErr
+}
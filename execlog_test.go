@@ -0,0 +1,127 @@
+package delayqueue
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExecutionLogWritesNDJSON(t *testing.T) {
+	var buf bytes.Buffer
+	q, clock := newTestQueue(t, WithExecutionLog(&buf), WithName("orders"))
+
+	want := map[string]string{
+		q.Push(time.Second, func() {}).ID():                                   OutcomeOK,
+		q.PushErrFunc(2*time.Second, func() error { return errors.New("x") }): OutcomeError,
+		q.Push(3*time.Second, func() { panic("boom") }).ID():                  OutcomePanic,
+	}
+	for i := 0; i < len(want); i++ {
+		fireNext(clock, time.Second)
+	}
+
+	// 停止队列时写出所有执行记录
+	stopQueue(t, q)
+	scanner := bufio.NewScanner(&buf)
+	lines := 0
+	for scanner.Scan() {
+		lines++
+		var e ExecutionEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("line %d is not JSON: %v: %s", lines, err, scanner.Text())
+		}
+		outcome, ok := want[e.ID]
+		if !ok {
+			t.Errorf("unexpected task %s in execution log", e.ID)
+			continue
+		}
+		delete(want, e.ID)
+		if e.Outcome != outcome {
+			t.Errorf("task %s outcome = %q, want %q", e.ID, e.Outcome, outcome)
+		}
+		if e.Queue != "orders" {
+			t.Errorf("task %s queue = %q, want orders", e.ID, e.Queue)
+		}
+		if !e.Actual.Equal(e.Scheduled) {
+			t.Errorf("task %s actual %v, scheduled %v, want equal", e.ID, e.Actual, e.Scheduled)
+		}
+		if outcome == OutcomeError && e.Error != "x" {
+			t.Errorf("task %s error = %q, want x", e.ID, e.Error)
+		}
+	}
+	if len(want) != 0 {
+		t.Errorf("tasks missing from execution log: %v", want)
+	}
+}
+
+// countLogLines 检查每一行都是完整的执行记录，返回记录的数量
+func countLogLines(t *testing.T, buf *bytes.Buffer) int {
+	t.Helper()
+	scanner := bufio.NewScanner(buf)
+	lines := 0
+	for scanner.Scan() {
+		lines++
+		var e ExecutionEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil || e.Outcome != OutcomeOK {
+			t.Fatalf("line %d is not a complete event: %v: %s", lines, err, scanner.Text())
+		}
+	}
+	return lines
+}
+
+func TestShardedExecutionLogShared(t *testing.T) {
+	var buf bytes.Buffer
+	clock := NewManualClock(testStart)
+	s := NewShardedQueue(4, WithClock(clock), WithExecutionLog(&buf))
+
+	var handles []*Task
+	for i := 0; i < 40; i++ {
+		handles = append(handles, s.Push(time.Second, func() {}))
+	}
+	for _, q := range s.Shards() {
+		settle(q)
+	}
+	clock.Advance(time.Second)
+	for _, h := range handles {
+		receive(t, h.Done())
+	}
+
+	// 各内部队列并发停止，最后一个停止的内部队列写出全部记录
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if n := countLogLines(t, &buf); n != 40 {
+		t.Errorf("execution log has %d lines, want 40", n)
+	}
+}
+
+func TestPartitionExecutionLogShared(t *testing.T) {
+	var buf bytes.Buffer
+	q, clock := newTestQueue(t, WithExecutionLog(&buf))
+	var handles []*Task
+	for i := 0; i < 10; i++ {
+		handles = append(handles, q.Push(time.Second, func() {}))
+	}
+	settle(q)
+
+	parts := q.Partition(2, func(id string) int { return int(id[len(id)-1]) })
+	stopQueue(t, q)
+	for _, p := range parts {
+		settle(p)
+	}
+	clock.Advance(time.Second)
+	for _, h := range handles {
+		receive(t, h.Done())
+	}
+	for _, p := range parts {
+		stopQueue(t, p)
+	}
+	if n := countLogLines(t, &buf); n != 10 {
+		t.Errorf("execution log has %d lines, want 10", n)
+	}
+}
@@ -0,0 +1,54 @@
+package delayqueue
+
+import (
+	"container/heap"
+	"time"
+)
+
+// popDue 从任务列表中取出所有在 now 之前（含）到期的任务，保持到期顺序
+func (q *DelayQueue) popDue(now time.Time) []*task {
+	var due []*task
+	for len(q.tasks) > 0 && !q.tasks[0].execTime.After(now) {
+		due = append(due, heap.Pop(&q.tasks).(*task))
+	}
+	return due
+}
+
+// fairOrder 按标签对同时到期的任务做加权轮询排序
+// 每一轮按标签首次出现的顺序，依次从每个标签中取出「权重」个任务，同一标签内保持原有的到期顺序；
+// 未配置权重的标签（包括没有标签的任务）权重为 1
+func (q *DelayQueue) fairOrder(due []*task) []*task {
+	if len(due) <= 1 {
+		return due
+	}
+
+	var tags []string
+	groups := make(map[string][]*task)
+	for _, t := range due {
+		if _, ok := groups[t.extra().tag]; !ok {
+			tags = append(tags, t.extra().tag)
+		}
+		groups[t.extra().tag] = append(groups[t.extra().tag], t)
+	}
+	if len(tags) == 1 {
+		return due
+	}
+
+	ordered := make([]*task, 0, len(due))
+	for len(ordered) < len(due) {
+		for _, tag := range tags {
+			weight := q.fairWeights[tag]
+			if weight < 1 {
+				weight = 1
+			}
+
+			group := groups[tag]
+			if weight > len(group) {
+				weight = len(group)
+			}
+			ordered = append(ordered, group[:weight]...)
+			groups[tag] = group[weight:]
+		}
+	}
+	return ordered
+}
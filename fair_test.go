@@ -0,0 +1,50 @@
+package delayqueue
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFairDispatchInterleavesByWeight(t *testing.T) {
+	q, clock := newTestQueue(t, WithFairDispatch(map[string]int{"a": 2}), WithMaxConcurrency(1))
+
+	ran := make(chan string, 9)
+	push := func(tag string, n int) {
+		for i := 0; i < n; i++ {
+			q.PushTagged(tag, time.Second, func() { ran <- tag })
+		}
+	}
+	// 标签 a 的任务先推送，不开启公平调度时会全部排在 b 之前
+	push("a", 6)
+	push("b", 3)
+
+	fireNext(clock, time.Second)
+	var order []string
+	for i := 0; i < 9; i++ {
+		order = append(order, receive(t, ran))
+	}
+	if got, want := strings.Join(order, ""), "aabaabaab"; got != want {
+		t.Errorf("execution order = %s, want %s", got, want)
+	}
+}
+
+func TestFairDispatchUnweightedTags(t *testing.T) {
+	q, clock := newTestQueue(t, WithFairDispatch(nil), WithMaxConcurrency(1))
+
+	ran := make(chan string, 5)
+	for _, tag := range []string{"x", "x", "x", "y", ""} {
+		tag := tag
+		q.PushTagged(tag, time.Second, func() { ran <- tag + "." })
+	}
+
+	// 未配置权重的标签（包括没有标签的任务）权重都是 1，剩余的任务排在最后
+	fireNext(clock, time.Second)
+	var order []string
+	for i := 0; i < 5; i++ {
+		order = append(order, receive(t, ran))
+	}
+	if got, want := strings.Join(order, ""), "x.y..x.x."; got != want {
+		t.Errorf("execution order = %s, want %s", got, want)
+	}
+}
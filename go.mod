@@ -1,5 +1,3 @@
 module github.com/gzltommy/delayqueue
 
 go 1.19
-
-require go.mongodb.org/mongo-driver v1.11.6
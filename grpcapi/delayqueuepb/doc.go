@@ -0,0 +1,6 @@
+// Package delayqueuepb 延时任务队列远程调度接口的 protobuf 定义与生成的代码
+//
+// 修改 delayqueue.proto 后安装 protoc、protoc-gen-go 与 protoc-gen-go-grpc，执行 go generate 重新生成。
+package delayqueuepb
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative delayqueue.proto
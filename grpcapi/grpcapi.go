@@ -0,0 +1,106 @@
+// Package grpcapi 将 grpcserver.Service 注册为 gRPC 服务，接口定义见 delayqueuepb/delayqueue.proto
+//
+// gRPC 与 protobuf 的依赖只在这个独立的模块中引入，只使用 grpcserver 或队列本身不会依赖 gRPC：
+//
+//	svc := grpcserver.NewService(q)
+//	svc.RegisterHandler("send_email", sendEmail)
+//	s := grpc.NewServer()
+//	grpcapi.Register(s, svc)
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+	"github.com/gzltommy/delayqueue/grpcapi/delayqueuepb"
+	"github.com/gzltommy/delayqueue/grpcserver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Register 将远程调度服务注册到 gRPC 服务器
+func Register(gs *grpc.Server, svc *grpcserver.Service) {
+	delayqueuepb.RegisterDelayQueueServer(gs, &server{svc: svc})
+}
+
+// server 将 gRPC 请求转换为 Service 的调用
+type server struct {
+	delayqueuepb.UnimplementedDelayQueueServer
+	svc *grpcserver.Service
+}
+
+func (s *server) Push(ctx context.Context, req *delayqueuepb.PushRequest) (*delayqueuepb.PushResponse, error) {
+	if req.GetHandler() == "" {
+		return nil, status.Error(codes.InvalidArgument, "missing handler")
+	}
+	execTime := req.GetExecTime()
+	var at time.Time
+	if execTime != nil {
+		at = execTime.AsTime()
+	}
+
+	id, err := s.svc.Push(req.GetHandler(), req.GetPayload(), req.GetDelay().AsDuration(), at, req.GetMetadata())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &delayqueuepb.PushResponse{Id: id}, nil
+}
+
+func (s *server) Delete(ctx context.Context, req *delayqueuepb.DeleteRequest) (*delayqueuepb.DeleteResponse, error) {
+	if err := s.svc.Delete(req.GetId()); err != nil {
+		return nil, toStatus(err)
+	}
+	return &delayqueuepb.DeleteResponse{}, nil
+}
+
+func (s *server) Get(ctx context.Context, req *delayqueuepb.GetRequest) (*delayqueuepb.Task, error) {
+	info, err := s.svc.Get(req.GetId())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toTask(info), nil
+}
+
+func (s *server) List(ctx context.Context, req *delayqueuepb.ListRequest) (*delayqueuepb.ListResponse, error) {
+	infos := s.svc.List()
+	tasks := make([]*delayqueuepb.Task, len(infos))
+	for i, info := range infos {
+		tasks[i] = toTask(info)
+	}
+	return &delayqueuepb.ListResponse{Tasks: tasks}, nil
+}
+
+// toTask 将任务信息转换为 protobuf 消息
+func toTask(info delayqueue.TaskInfo) *delayqueuepb.Task {
+	return &delayqueuepb.Task{
+		Id:        info.ID,
+		ExecTime:  timestamppb.New(info.ExecTime),
+		Remaining: durationpb.New(info.Remaining),
+		Handler:   info.Handler,
+		Priority:  int32(info.Priority),
+		Metadata:  info.Metadata,
+	}
+}
+
+// toStatus 将错误转换为 gRPC 状态码
+func toStatus(err error) error {
+	switch {
+	case errors.Is(err, grpcserver.ErrUnknownHandler):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, grpcserver.ErrRejected):
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case errors.Is(err, delayqueue.ErrTaskNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, delayqueue.ErrTaskExecuted):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, delayqueue.ErrClosed):
+		return status.Error(codes.Unavailable, err.Error())
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
@@ -0,0 +1,104 @@
+package grpcapi
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+	"github.com/gzltommy/delayqueue/grpcapi/delayqueuepb"
+	"github.com/gzltommy/delayqueue/grpcserver"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// newTestClient 通过内存连接启动 gRPC 服务，返回连接到它的客户端，测试结束时关闭服务与队列
+func newTestClient(t *testing.T) (*delayqueue.DelayQueue, delayqueuepb.DelayQueueClient) {
+	t.Helper()
+	q := delayqueue.NewDelayQueue(delayqueue.WithClock(delayqueue.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))))
+	svc := grpcserver.NewService(q)
+	svc.RegisterHandler("h", func(payload []byte) {})
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	Register(gs, svc)
+	go gs.Serve(lis)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		conn.Close()
+		gs.Stop()
+		_ = q.Stop(context.Background())
+	})
+	return q, delayqueuepb.NewDelayQueueClient(conn)
+}
+
+// wantCode 检查 err 携带的 gRPC 状态码
+func wantCode(t *testing.T, op string, err error, code codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != code {
+		t.Errorf("%s error = %v, want code %v", op, err, code)
+	}
+}
+
+func TestPushGetListDelete(t *testing.T) {
+	_, c := newTestClient(t)
+	ctx := context.Background()
+
+	pushed, err := c.Push(ctx, &delayqueuepb.PushRequest{
+		Handler:  "h",
+		Payload:  []byte("x"),
+		Delay:    durationpb.New(time.Hour),
+		Metadata: map[string]string{"k": "v"},
+	})
+	if err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	task, err := c.Get(ctx, &delayqueuepb.GetRequest{Id: pushed.GetId()})
+	if err != nil || task.GetHandler() != "h" || task.GetMetadata()["k"] != "v" || task.GetRemaining().AsDuration() != time.Hour {
+		t.Errorf("Get() = %v, %v, want handler h with metadata and 1h remaining", task, err)
+	}
+
+	list, err := c.List(ctx, &delayqueuepb.ListRequest{})
+	if err != nil || len(list.GetTasks()) != 1 || list.GetTasks()[0].GetId() != pushed.GetId() {
+		t.Errorf("List() = %v, %v, want the pushed task", list, err)
+	}
+
+	if _, err := c.Delete(ctx, &delayqueuepb.DeleteRequest{Id: pushed.GetId()}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	_, err = c.Get(ctx, &delayqueuepb.GetRequest{Id: pushed.GetId()})
+	wantCode(t, "Get() after delete", err, codes.NotFound)
+}
+
+func TestErrorCodes(t *testing.T) {
+	q, c := newTestClient(t)
+	ctx := context.Background()
+
+	_, err := c.Push(ctx, &delayqueuepb.PushRequest{})
+	wantCode(t, "Push(no handler)", err, codes.InvalidArgument)
+
+	_, err = c.Push(ctx, &delayqueuepb.PushRequest{Handler: "missing"})
+	wantCode(t, "Push(missing)", err, codes.InvalidArgument)
+
+	_, err = c.Delete(ctx, &delayqueuepb.DeleteRequest{Id: "missing"})
+	wantCode(t, "Delete(missing)", err, codes.NotFound)
+
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	_, err = c.Push(ctx, &delayqueuepb.PushRequest{Handler: "h", Delay: durationpb.New(time.Minute)})
+	wantCode(t, "Push() after stop", err, codes.ResourceExhausted)
+}
@@ -0,0 +1,101 @@
+// Package grpcserver 对外提供延时任务队列的远程调度，接口定义见 grpcapi/delayqueuepb/delayqueue.proto
+//
+// 执行函数无法跨进程传递，远程推送的任务只能由服务端注册的具名处理函数执行：
+// 服务端通过 Service.RegisterHandler 注册处理函数，其他服务按名称推送任务，并可以删除与查询任务。
+//
+// Service 与传输无关，本包不依赖 gRPC；gRPC 的适配层与生成的代码位于独立的模块
+// github.com/gzltommy/delayqueue/grpcapi 中，由它引入 gRPC 与 protobuf 的依赖：
+//
+//	svc := grpcserver.NewService(q)
+//	svc.RegisterHandler("send_email", sendEmail)
+//	s := grpc.NewServer()
+//	grpcapi.Register(s, svc)
+package grpcserver
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+)
+
+var (
+	// ErrUnknownHandler 推送的任务引用了服务端没有注册的处理函数
+	ErrUnknownHandler = errors.New("grpcserver: unknown handler")
+
+	// ErrRejected 任务被队列拒绝：队列已经停止，或者被准入控制、限流、数量上限拒绝
+	ErrRejected = errors.New("grpcserver: task rejected")
+)
+
+// Queue 远程调度依赖的队列能力，*delayqueue.DelayQueue 满足该接口
+type Queue interface {
+	RegisterHandler(name string, fn func(payload []byte))
+	PushHandlerMeta(metadata map[string]string, timeInterval time.Duration, name string, payload []byte, opts ...delayqueue.PushOption) string
+	Delete(id string) (bool, error)
+	Get(id string) (delayqueue.TaskInfo, bool)
+	Tasks() []delayqueue.TaskInfo
+}
+
+// Service 与传输无关的远程调度服务，gRPC 适配层将请求转换后调用它
+type Service struct {
+	q       Queue
+	names   map[string]struct{} // 已注册的处理函数名称
+	namesMu sync.RWMutex        // 保护 names
+}
+
+// NewService 创建队列 q 的远程调度服务
+func NewService(q Queue) *Service {
+	return &Service{
+		q:     q,
+		names: make(map[string]struct{}),
+	}
+}
+
+// RegisterHandler 在队列上注册具名处理函数，并允许远程推送引用该名称的任务
+func (s *Service) RegisterHandler(name string, fn func(payload []byte)) {
+	s.q.RegisterHandler(name, fn)
+
+	s.namesMu.Lock()
+	defer s.namesMu.Unlock()
+	s.names[name] = struct{}{}
+}
+
+// Push 推送由具名处理函数执行的任务，execTime 不为零值时按执行时间推送，否则按延时 delay 推送
+func (s *Service) Push(handler string, payload []byte, delay time.Duration, execTime time.Time, metadata map[string]string) (string, error) {
+	s.namesMu.RLock()
+	_, ok := s.names[handler]
+	s.namesMu.RUnlock()
+	if !ok {
+		return "", ErrUnknownHandler
+	}
+
+	if !execTime.IsZero() {
+		delay = time.Until(execTime)
+	}
+	id := s.q.PushHandlerMeta(metadata, delay, handler, payload)
+	if id == "" {
+		return "", ErrRejected
+	}
+	return id, nil
+}
+
+// Delete 删除任务，任务不存在时返回 delayqueue.ErrTaskNotFound，已经执行结束时返回 delayqueue.ErrTaskExecuted
+func (s *Service) Delete(id string) error {
+	_, err := s.q.Delete(id)
+	return err
+}
+
+// Get 查询等待执行的任务，任务不存在时返回 delayqueue.ErrTaskNotFound
+func (s *Service) Get(id string) (delayqueue.TaskInfo, error) {
+	info, ok := s.q.Get(id)
+	if !ok {
+		return delayqueue.TaskInfo{}, delayqueue.ErrTaskNotFound
+	}
+	return info, nil
+}
+
+// List 列出所有等待执行的任务，按执行顺序排列
+func (s *Service) List() []delayqueue.TaskInfo {
+	return s.q.Tasks()
+}
@@ -0,0 +1,89 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+)
+
+// newTestService 创建使用模拟时钟的队列与它的远程调度服务，测试结束时停止队列
+func newTestService(t *testing.T) (*delayqueue.DelayQueue, *delayqueue.ManualClock, *Service) {
+	t.Helper()
+	clock := delayqueue.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	q := delayqueue.NewDelayQueue(delayqueue.WithClock(clock))
+	t.Cleanup(func() { _ = q.Stop(context.Background()) })
+	return q, clock, NewService(q)
+}
+
+func TestPushUnknownHandler(t *testing.T) {
+	q, _, svc := newTestService(t)
+	if _, err := svc.Push("missing", nil, time.Minute, time.Time{}, nil); !errors.Is(err, ErrUnknownHandler) {
+		t.Errorf("Push(missing) error = %v, want ErrUnknownHandler", err)
+	}
+	if n := q.Len(); n != 0 {
+		t.Errorf("Len() = %d, want 0", n)
+	}
+}
+
+func TestPushRunsRegisteredHandler(t *testing.T) {
+	_, clock, svc := newTestService(t)
+	got := make(chan []byte, 1)
+	svc.RegisterHandler("h", func(payload []byte) { got <- payload })
+
+	if _, err := svc.Push("h", []byte("x"), time.Minute, time.Time{}, nil); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+	clock.BlockUntil(1)
+	clock.Advance(time.Minute)
+
+	select {
+	case payload := <-got:
+		if string(payload) != "x" {
+			t.Errorf("payload = %q, want %q", payload, "x")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("handler did not run")
+	}
+}
+
+func TestGetListDelete(t *testing.T) {
+	_, _, svc := newTestService(t)
+	svc.RegisterHandler("h", func(payload []byte) {})
+
+	id, err := svc.Push("h", []byte("x"), time.Hour, time.Time{}, map[string]string{"k": "v"})
+	if err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	info, err := svc.Get(id)
+	if err != nil || info.Handler != "h" || info.Metadata["k"] != "v" {
+		t.Errorf("Get() = %+v, %v, want handler h with metadata", info, err)
+	}
+	if list := svc.List(); len(list) != 1 || list[0].ID != id {
+		t.Errorf("List() = %+v, want the pushed task", list)
+	}
+
+	if err := svc.Delete(id); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := svc.Get(id); !errors.Is(err, delayqueue.ErrTaskNotFound) {
+		t.Errorf("Get() after delete error = %v, want ErrTaskNotFound", err)
+	}
+	if err := svc.Delete("missing"); !errors.Is(err, delayqueue.ErrTaskNotFound) {
+		t.Errorf("Delete(missing) error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestPushRejectedAfterStop(t *testing.T) {
+	q, _, svc := newTestService(t)
+	svc.RegisterHandler("h", func(payload []byte) {})
+	if err := q.Stop(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Push("h", nil, time.Minute, time.Time{}, nil); !errors.Is(err, ErrRejected) {
+		t.Errorf("Push() after stop error = %v, want ErrRejected", err)
+	}
+}
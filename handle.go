@@ -0,0 +1,116 @@
+package delayqueue
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Task Push、PushAt 与 PushComputed 返回的任务句柄，用于管理单个任务；其他推送方法的返回约定见包文档
+type Task struct {
+	q        atomic.Pointer[DelayQueue] // 任务所在的队列，Partition 转移任务时改为新队列
+	id       string
+	done     chan struct{}
+	doneOnce sync.Once
+
+	duration time.Duration // 最后一次执行的耗时，在 done 关闭之前写入
+	err      error         // 最后一次执行返回的错误，在 done 关闭之前写入
+	rejected error         // 推送被拒绝的原因，在句柄返回给调用方之前写入
+}
+
+// newTask 创建任务句柄
+func newTask(q *DelayQueue, id string) *Task {
+	h := &Task{id: id, done: make(chan struct{})}
+	h.q.Store(q)
+	return h
+}
+
+// ID 返回任务id
+func (h *Task) ID() string {
+	return h.id
+}
+
+// Err 返回任务推送被拒绝的原因，例如 ErrClosed、ErrQueueFull；任务成功推送时返回 nil
+func (h *Task) Err() error {
+	return h.rejected
+}
+
+// Cancel 取消任务，与 Delete 相同：任务已经执行结束时返回 ErrTaskExecuted，不存在时返回 ErrTaskNotFound，
+// 队列已经停止时返回 ErrClosed
+func (h *Task) Cancel() error {
+	_, err := h.q.Load().Delete(h.id)
+	return err
+}
+
+// Reschedule 将任务调整为 d 之后执行，与 DelayQueue.Reschedule 相同
+func (h *Task) Reschedule(d time.Duration) error {
+	return h.q.Load().Reschedule(h.id, d)
+}
+
+// Done 返回任务结束时关闭的管道
+// 任务执行完成（包括重试结束）、被取消或被丢弃时关闭；队列停止时还没有执行的任务同样关闭，Wait 返回 ErrClosed
+func (h *Task) Done() <-chan struct{} {
+	return h.done
+}
+
+// Wait 阻塞等待任务结束，返回最后一次执行的错误；任务被取消或丢弃时返回 nil，推送被拒绝时返回拒绝的原因，
+// 队列停止时还没有执行时返回 ErrClosed，ctx 结束时返回 ctx.Err()
+func (h *Task) Wait(ctx context.Context) error {
+	select {
+	case <-h.done:
+		return h.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Result 返回最后一次执行的耗时与错误，任务结束之前或没有执行过时返回零值
+func (h *Task) Result() (time.Duration, error) {
+	select {
+	case <-h.done:
+		return h.duration, h.err
+	default:
+		return 0, nil
+	}
+}
+
+// reject 任务推送被拒绝，记录原因并关闭 Done
+func (h *Task) reject(err error) {
+	h.rejected, h.err = err, err
+	h.doneOnce.Do(func() {
+		close(h.done)
+	})
+}
+
+// completed 任务的一次执行完成且不再重试，记录执行结果并回调 OnComplete
+// 结果写入句柄之后才会关闭 Done，等待方在 Done 关闭后读取结果不会产生竞争
+func (q *DelayQueue) completed(t *task, d time.Duration, err error) {
+	if t.handle != nil && t.last {
+		t.handle.duration, t.handle.err = d, err
+	}
+	if q.onComplete != nil {
+		q.onComplete(t.id, d, err)
+	}
+}
+
+// abort 任务没有执行就结束，将 err 记为执行结果并关闭句柄的 Done 管道，句柄已经结束时不做处理
+func (t *task) abort(err error) {
+	if t.handle == nil {
+		return
+	}
+	t.handle.doneOnce.Do(func() {
+		t.handle.err = err
+		close(t.handle.done)
+	})
+}
+
+// complete 任务结束，关闭句柄的 Done 管道，没有句柄的任务不做处理
+func (t *task) complete() {
+	if t.handle == nil {
+		return
+	}
+	t.handle.doneOnce.Do(func() {
+		close(t.handle.done)
+	})
+}
@@ -0,0 +1,85 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPushHandleRejected(t *testing.T) {
+	q, _ := newTestQueue(t)
+	stopQueue(t, q)
+
+	for name, h := range map[string]*Task{
+		"Push":         q.Push(time.Second, func() {}),
+		"PushAt":       q.PushAt(testStart.Add(time.Second), func() {}),
+		"PushComputed": q.PushComputed(func() time.Duration { return time.Second }, func() {}),
+	} {
+		if h == nil {
+			t.Fatalf("%s returned nil handle", name)
+		}
+		if !errors.Is(h.Err(), ErrClosed) {
+			t.Errorf("%s: Err() = %v, want ErrClosed", name, h.Err())
+		}
+		if err := h.Wait(context.Background()); !errors.Is(err, ErrClosed) {
+			t.Errorf("%s: Wait() = %v, want ErrClosed", name, err)
+		}
+	}
+}
+
+func TestPushHandleDone(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	h := q.Push(time.Second, func() {})
+	if h.Err() != nil {
+		t.Fatalf("Err() = %v, want nil", h.Err())
+	}
+	select {
+	case <-h.Done():
+		t.Fatal("Done closed before the task ran")
+	default:
+	}
+
+	fireNext(clock, time.Second)
+	receive(t, h.Done())
+	if err := h.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() = %v, want nil", err)
+	}
+}
+
+func TestPushHandleClosedOnStop(t *testing.T) {
+	q, _ := newTestQueue(t, WithTimingWheel(time.Second, 8))
+
+	near := q.Push(time.Second, func() {})
+	far := q.Push(time.Hour, func() {})
+	paused := q.Push(time.Minute, func() {})
+	if err := q.PauseTask(paused.ID()); err != nil {
+		t.Fatal(err)
+	}
+	stopQueue(t, q)
+
+	// 队列停止时还没有执行的任务同样结束，推送本身没有被拒绝
+	for name, h := range map[string]*Task{"heap": near, "wheel": far, "paused": paused} {
+		receive(t, h.Done())
+		if err := h.Wait(context.Background()); !errors.Is(err, ErrClosed) {
+			t.Errorf("%s: Wait() = %v, want ErrClosed", name, err)
+		}
+		if h.Err() != nil {
+			t.Errorf("%s: Err() = %v, want nil", name, h.Err())
+		}
+	}
+}
+
+func TestPushHandleCancel(t *testing.T) {
+	q, _ := newTestQueue(t)
+
+	h := q.Push(time.Second, func() {})
+	if err := h.Cancel(); err != nil {
+		t.Fatalf("Cancel() = %v", err)
+	}
+	receive(t, h.Done())
+	if err := h.Cancel(); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("second Cancel() = %v, want ErrTaskNotFound", err)
+	}
+}
@@ -0,0 +1,105 @@
+package delayqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// RegisterHandler 注册具名处理函数
+// 通过名称引用处理函数的任务可以被序列化，从而支持快照的导出与恢复；重复注册同一名称会覆盖之前的处理函数
+func (q *DelayQueue) RegisterHandler(name string, fn func(payload []byte)) {
+	q.RegisterHandlerContext(name, adaptHandler(fn))
+}
+
+// RegisterHandlerContext 注册接收 ctx 并返回错误的具名处理函数
+// ctx 与 PushContext 的任务相同，在队列停止时取消；返回的错误会记录在执行记录中，并按任务的重试策略重试
+func (q *DelayQueue) RegisterHandlerContext(name string, fn func(ctx context.Context, payload []byte) error) {
+	q.handlersMu.Lock()
+	defer q.handlersMu.Unlock()
+	q.handlers[name] = fn
+}
+
+// handlerFunc 队列内部保存的具名处理函数
+type handlerFunc = func(ctx context.Context, payload []byte) error
+
+// adaptHandler 将不返回错误的处理函数适配为 handlerFunc
+func adaptHandler(fn func(payload []byte)) handlerFunc {
+	return func(_ context.Context, payload []byte) error {
+		fn(payload)
+		return nil
+	}
+}
+
+// PushHandler 用户推送由具名处理函数执行的任务
+func (q *DelayQueue) PushHandler(timeInterval time.Duration, name string, payload []byte, opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.handler = name
+	t.arg = payload
+	return q.submit(t.apply(opts))
+}
+
+// PushJSON 用户推送由具名处理函数执行的任务，payload 编码为 JSON 后作为任务数据；编码失败或任务被拒绝时返回错误
+// 处理函数可以通过 JSONHandler 直接接收解码后的值
+func (q *DelayQueue) PushJSON(timeInterval time.Duration, name string, payload any, opts ...PushOption) (string, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+
+	t := q.newPushTask(timeInterval)
+	t.handler = name
+	t.arg = data
+	if err := q.push(t.apply(opts)); err != nil {
+		return "", err
+	}
+	return t.id, nil
+}
+
+// JSONHandler 将接收类型化数据的函数适配为具名处理函数，配合 PushJSON 使用
+// 任务数据无法解码为 T 时处理函数会 panic，由队列恢复后交给 OnPanic 设置的回调，失败的任务同样可以重试
+func JSONHandler[T any](fn func(v T)) func(payload []byte) {
+	return func(payload []byte) {
+		var v T
+		if err := json.Unmarshal(payload, &v); err != nil {
+			panic(fmt.Errorf("delayqueue: decode payload as %T: %w", v, err))
+		}
+		fn(v)
+	}
+}
+
+// PushWith 用户推送由共享执行函数处理的任务
+// 大量任务使用同一个执行函数、只是参数不同时，任务只保存函数引用与参数，不需要为每个任务创建新的闭包；
+// fn 应当是包级函数或复用的函数变量，在调用处现写的匿名函数字面量依然会产生闭包
+func (q *DelayQueue) PushWith(timeInterval time.Duration, fn func(arg any), arg any, opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.fn = fn
+	t.arg = arg
+	return q.submit(t.apply(opts))
+}
+
+// PushErrFunc 用户推送返回错误的任务，返回的错误会被记录在执行记录中，并由熔断器统计
+func (q *DelayQueue) PushErrFunc(timeInterval time.Duration, f func() error, opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.ensureExtra().fe = f
+	return q.submit(t.apply(opts))
+}
+
+// execHandler 执行具名处理函数任务，返回处理函数是否存在以及处理函数返回的错误
+func (q *DelayQueue) execHandler(ctx context.Context, t *task) (bool, error) {
+	q.handlersMu.RLock()
+	fn, ok := q.handlers[t.handler]
+	q.handlersMu.RUnlock()
+	if ok {
+		return true, fn(ctx, t.payload())
+	}
+
+	// 处理函数不存在，交给兜底处理
+	if q.missingHandler != nil {
+		q.missingHandler(t.handler, t.pendingTask())
+		return false, nil
+	}
+	q.logger.Printf("handler %q not registered, drop task %s", t.handler, t.id)
+	return false, nil
+}
@@ -0,0 +1,205 @@
+package delayqueue
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unsafe"
+)
+
+func TestMissingHandlerFallback(t *testing.T) {
+	type missing struct {
+		name string
+		task PendingTask
+		at   time.Time
+	}
+	got := make(chan missing, 2)
+	var clock *ManualClock
+	q, clock := newTestQueue(t, WithHandler("known", func([]byte) {}), WithMissingHandler(func(name string, task PendingTask) {
+		got <- missing{name, task, clock.Now()}
+	}))
+
+	// 快照中的处理函数在新版本中已经不存在
+	tasks := []PendingTask{
+		{ID: "a", ExecTime: testStart.Add(time.Second), Handler: "removed", Payload: []byte("1")},
+		{ID: "b", ExecTime: testStart.Add(3 * time.Second), Handler: "renamed", Payload: []byte("2")},
+	}
+	q.Restore(tasks)
+
+	for _, want := range tasks {
+		clock.BlockUntil(1)
+		clock.Set(want.ExecTime)
+		m := receive(t, got)
+		if m.name != want.Handler || m.task.ID != want.ID || string(m.task.Payload) != string(want.Payload) {
+			t.Errorf("fallback got %q %+v, want %q %+v", m.name, m.task, want.Handler, want)
+		}
+		if !m.at.Equal(want.ExecTime) {
+			t.Errorf("fallback for %s called at %v, want %v", want.ID, m.at, want.ExecTime)
+		}
+	}
+}
+
+func TestPushWithSharedFunction(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	got := make(chan int, 2)
+	shared := func(arg any) { got <- arg.(int) }
+	first := q.PushWith(time.Second, shared, 1)
+	q.PushWith(2*time.Second, shared, 2)
+
+	// 只有具名处理函数与发布消息的任务有数据，共享执行函数的参数不会被替换
+	if err := q.UpdatePayload(first, []byte("x")); !errors.Is(err, ErrNotSerializable) {
+		t.Fatalf("UpdatePayload error = %v, want ErrNotSerializable", err)
+	}
+	for want := 1; want <= 2; want++ {
+		fireNext(clock, time.Second)
+		if arg := receive(t, got); arg != want {
+			t.Errorf("shared function got %d, want %d", arg, want)
+		}
+	}
+}
+
+func TestUpdatePayload(t *testing.T) {
+	got := make(chan string, 2)
+	q, clock := newTestQueue(t, WithHandler("send", func(payload []byte) { got <- string(payload) }))
+	named := q.PushHandler(time.Second, "send", []byte("old"))
+	closure := q.Push(time.Second, func() { got <- "closure" }).ID()
+
+	if err := q.UpdatePayload(named, []byte("new")); err != nil {
+		t.Fatalf("UpdatePayload: %v", err)
+	}
+	// 基于闭包的任务没有数据，返回错误且任务保持不变
+	if err := q.UpdatePayload(closure, []byte("x")); !errors.Is(err, ErrNotSerializable) {
+		t.Errorf("UpdatePayload of a closure task error = %v, want ErrNotSerializable", err)
+	}
+
+	fireNext(clock, time.Second)
+	ran := map[string]bool{receive(t, got): true, receive(t, got): true}
+	if !ran["new"] || !ran["closure"] {
+		t.Errorf("ran %v, want new and closure", ran)
+	}
+}
+
+type reminder struct {
+	User string    `json:"user"`
+	At   time.Time `json:"at"`
+}
+
+func TestPushJSON(t *testing.T) {
+	got := make(chan reminder, 1)
+	q, clock := newTestQueue(t, WithHandler("remind", JSONHandler(func(v reminder) { got <- v })))
+
+	want := reminder{User: "alice", At: testStart.Add(time.Hour)}
+	if _, err := q.PushJSON(time.Second, "remind", want); err != nil {
+		t.Fatalf("PushJSON: %v", err)
+	}
+	fireNext(clock, time.Second)
+	if v := receive(t, got); v.User != want.User || !v.At.Equal(want.At) {
+		t.Errorf("handler got %+v, want %+v", v, want)
+	}
+
+	// 无法编码的数据不会推送
+	if _, err := q.PushJSON(time.Second, "remind", func() {}); err == nil {
+		t.Error("PushJSON of a func succeeded, want an encode error")
+	}
+	if n := q.Len(); n != 0 {
+		t.Errorf("Len after a failed PushJSON = %d, want 0", n)
+	}
+}
+
+func TestJSONHandlerDecodeError(t *testing.T) {
+	recovered := make(chan any, 1)
+	called := false
+	q, clock := newTestQueue(t,
+		WithHandler("remind", JSONHandler(func(reminder) { called = true })),
+		OnPanic(func(_ string, _ []byte, r any) { recovered <- r }),
+	)
+
+	// 数据无法解码时不调用 fn，解码错误通过 panic 交给 OnPanic
+	q.PushHandler(time.Second, "remind", []byte(`{"user": 42}`))
+	fireNext(clock, time.Second)
+	r := receive(t, recovered)
+	var typeErr *json.UnmarshalTypeError
+	if err, ok := r.(error); !ok || !errors.As(err, &typeErr) {
+		t.Errorf("recovered %v, want a wrapped *json.UnmarshalTypeError", r)
+	}
+	if called {
+		t.Error("fn called with an undecodable payload")
+	}
+}
+
+func TestTaskSize(t *testing.T) {
+	if unsafe.Sizeof(uintptr(0)) != 8 {
+		t.Skip("size checked on 64-bit platforms only")
+	}
+	// 每个任务都会分配 task，较少使用的属性应当放在 taskExtra 中
+	if size := unsafe.Sizeof(task{}); size > 176 {
+		t.Errorf("task is %d bytes, want at most 176", size)
+	}
+}
+
+// sameHandlerTasks 共享执行函数的基准测试中推送的任务数量
+const sameHandlerTasks = 100000
+
+// benchOrder 基准测试中任务处理的业务对象，推送之前已经存在
+type benchOrder struct {
+	id     int
+	amount int64
+}
+
+var handledAmount atomic.Int64
+
+// handleOrder 大量任务共享的执行函数
+func handleOrder(arg any) {
+	handledAmount.Add(arg.(*benchOrder).amount)
+}
+
+// benchmarkSameHandler 推送 sameHandlerTasks 个任务，报告每个任务在队列中占用的堆内存
+func benchmarkSameHandler(b *testing.B, push func(q *DelayQueue, o *benchOrder)) {
+	orders := make([]*benchOrder, sameHandlerTasks)
+	for i := range orders {
+		orders[i] = &benchOrder{id: i, amount: int64(i)}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var perTask float64
+	for n := 0; n < b.N; n++ {
+		q := NewDelayQueue(WithClock(NewManualClock(testStart)), WithIDFormat(IDFormatNumeric))
+
+		var before, after runtime.MemStats
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+		for _, o := range orders {
+			push(q, o)
+		}
+		// 所有任务都已经进入任务列表
+		q.Len()
+		runtime.GC()
+		runtime.ReadMemStats(&after)
+		perTask += float64(after.HeapAlloc-before.HeapAlloc) / sameHandlerTasks
+
+		b.StopTimer()
+		_ = q.Stop(context.Background())
+		b.StartTimer()
+	}
+	b.ReportMetric(perTask/float64(b.N), "heap-B/task")
+}
+
+// BenchmarkSameHandlerClosure 每个任务一个捕获业务对象的闭包
+func BenchmarkSameHandlerClosure(b *testing.B) {
+	benchmarkSameHandler(b, func(q *DelayQueue, o *benchOrder) {
+		_, _ = q.PushCtx(context.Background(), time.Hour, func() { handleOrder(o) })
+	})
+}
+
+// BenchmarkSameHandlerPushWith 所有任务共享同一个执行函数，只保存函数引用与参数
+func BenchmarkSameHandlerPushWith(b *testing.B) {
+	benchmarkSameHandler(b, func(q *DelayQueue, o *benchOrder) {
+		q.PushWith(time.Hour, handleOrder, o)
+	})
+}
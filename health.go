@@ -0,0 +1,68 @@
+package delayqueue
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// healthProbeID 健康检查读取存储时使用的任务id，正常情况下不存在
+const healthProbeID = "delayqueue-health-probe"
+
+// Healthy 检查队列是否健康，可以作为 Kubernetes 的就绪与存活探针，不健康时返回包装了 ErrUnhealthy 的错误：
+//   - 队列已经停止
+//   - 调度协程在 WithHealthCheck 设置的时长内没有响应，错误中带有距离上一次循环的时间
+//   - add 或 remove 管道的占用比例达到了 WithHealthCheck 设置的阈值
+//   - 设置了持久化存储时，存储无法访问
+//
+// 调度协程可能长时间休眠等待下一个任务，因此不只看心跳时间，而是向调度协程投递一个空操作确认其仍在响应
+func (q *DelayQueue) Healthy() error {
+	if q.stopped.Load() {
+		return fmt.Errorf("%w: queue is closed", ErrUnhealthy)
+	}
+
+	if err := q.checkSaturation("add", len(q.add), cap(q.add)); err != nil {
+		return err
+	}
+	if err := q.checkSaturation("remove", len(q.remove), cap(q.remove)); err != nil {
+		return err
+	}
+	if err := q.probeScheduler(); err != nil {
+		return err
+	}
+
+	if q.storage != nil {
+		// 读取一个不存在的任务，Storage 约定此时返回 ErrTaskNotFound，其他错误说明存储无法访问
+		if _, err := q.storage.Load(healthProbeID); err != nil && !errors.Is(err, ErrTaskNotFound) {
+			return fmt.Errorf("%w: storage: %v", ErrUnhealthy, err)
+		}
+	}
+	return nil
+}
+
+// checkSaturation 检查管道的占用比例是否达到阈值
+func (q *DelayQueue) checkSaturation(name string, n, capacity int) error {
+	if capacity == 0 || q.healthSaturation <= 0 {
+		return nil
+	}
+	if float64(n)/float64(capacity) >= q.healthSaturation {
+		return fmt.Errorf("%w: %s channel saturated (%d/%d)", ErrUnhealthy, name, n, capacity)
+	}
+	return nil
+}
+
+// probeScheduler 向调度协程投递空操作，超过 healthTimeout 没有被接收时视为调度协程失去响应
+func (q *DelayQueue) probeScheduler() error {
+	timer := time.NewTimer(q.healthTimeout)
+	defer timer.Stop()
+
+	select {
+	case q.ops <- func() {}:
+		return nil
+	case <-q.quit:
+		return fmt.Errorf("%w: queue is closed", ErrUnhealthy)
+	case <-timer.C:
+		since := time.Since(time.Unix(0, q.heartbeat.Load()))
+		return fmt.Errorf("%w: scheduler not responding, last loop %s ago", ErrUnhealthy, since.Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,50 @@
+package delayqueue
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// brokenStorage 读取任务总是失败的存储
+type brokenStorage struct {
+	*FileStorage
+}
+
+func (brokenStorage) Load(id string) (PendingTask, error) {
+	return PendingTask{}, errors.New("connection refused")
+}
+
+func TestHealthy(t *testing.T) {
+	q, _ := newTestQueue(t, WithHealthCheck(50*time.Millisecond, 0.9))
+	if err := q.Healthy(); err != nil {
+		t.Fatalf("Healthy = %v, want nil", err)
+	}
+
+	// 调度协程被占住，探测超时
+	release := make(chan struct{})
+	busy := make(chan struct{})
+	go q.do(func() {
+		close(busy)
+		<-release
+	})
+	receive(t, busy)
+	err := q.Healthy()
+	close(release)
+	if !errors.Is(err, ErrUnhealthy) || !strings.Contains(err.Error(), "not responding") {
+		t.Errorf("Healthy with a busy scheduler = %v, want not responding", err)
+	}
+
+	stopQueue(t, q)
+	if err := q.Healthy(); !errors.Is(err, ErrUnhealthy) {
+		t.Errorf("Healthy after Stop = %v, want ErrUnhealthy", err)
+	}
+}
+
+func TestHealthyStorage(t *testing.T) {
+	q, _ := newTestQueue(t, WithStorage(brokenStorage{newTestStorage(t)}))
+	if err := q.Healthy(); !errors.Is(err, ErrUnhealthy) || !strings.Contains(err.Error(), "connection refused") {
+		t.Errorf("Healthy with broken storage = %v, want the storage error", err)
+	}
+}
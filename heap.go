@@ -0,0 +1,58 @@
+package delayqueue
+
+import "sort"
+
+// taskHeap 按执行时间排列的任务最小堆，实现了 heap.Interface
+// 执行时间相同的任务按优先级从高到低排列，优先级也相同时按加入任务列表的序号排列，保证先加入的先执行
+type taskHeap []*task
+
+func (h taskHeap) Len() int {
+	return len(h)
+}
+
+func (h taskHeap) Less(i, j int) bool {
+	return h[i].before(h[j])
+}
+
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *taskHeap) Push(x any) {
+	t := x.(*task)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *taskHeap) Pop() any {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil // 避免内存泄漏
+	t.index = -1
+	*h = old[:n-1]
+	return t
+}
+
+// before 判断任务 t 是否应该排在任务 o 之前执行
+func (t *task) before(o *task) bool {
+	if !t.execTime.Equal(o.execTime) {
+		return t.execTime.Before(o.execTime)
+	}
+	if t.priority != o.priority {
+		return t.priority > o.priority
+	}
+	return t.seq < o.seq
+}
+
+// sorted 返回按执行顺序排列的任务副本，堆本身不受影响
+func (h taskHeap) sorted() []*task {
+	tasks := make([]*task, len(h))
+	copy(tasks, h)
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].before(tasks[j])
+	})
+	return tasks
+}
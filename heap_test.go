@@ -0,0 +1,223 @@
+package delayqueue
+
+import (
+	"container/heap"
+	"context"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+)
+
+// heapBenchSizes 插入基准测试中任务列表已有的任务数量
+var heapBenchSizes = []struct {
+	name string
+	n    int
+}{
+	{"1k", 1_000},
+	{"100k", 100_000},
+}
+
+// randomTasks 生成 n 个执行时间随机分布在一小时之内的任务
+func randomTasks(n int) []*task {
+	r := rand.New(rand.NewSource(1))
+	tasks := make([]*task, n)
+	for i := range tasks {
+		tasks[i] = &task{
+			execTime: testStart.Add(time.Duration(r.Int63n(int64(time.Hour)))),
+			seq:      uint64(i),
+		}
+	}
+	return tasks
+}
+
+func TestTaskHeapOrder(t *testing.T) {
+	var h taskHeap
+	for _, task := range randomTasks(1000) {
+		heap.Push(&h, task)
+	}
+
+	// 依次弹出的任务按执行顺序排列，下标始终与位置一致
+	var prev *task
+	for h.Len() > 0 {
+		for i, task := range h {
+			if task.index != i {
+				t.Fatalf("task at %d has index %d", i, task.index)
+			}
+		}
+		task := heap.Pop(&h).(*task)
+		if prev != nil && task.before(prev) {
+			t.Fatalf("task %v popped after %v", task.execTime, prev.execTime)
+		}
+		prev = task
+	}
+}
+
+func TestTaskHeapTieBreak(t *testing.T) {
+	// 执行时间相同的任务按优先级从高到低弹出，优先级相同时按序号先进先出
+	tasks := []*task{
+		{id: "low-1", execTime: testStart, priority: 0, seq: 1},
+		{id: "high-1", execTime: testStart, priority: 5, seq: 2},
+		{id: "low-2", execTime: testStart, priority: 0, seq: 3},
+		{id: "high-2", execTime: testStart, priority: 5, seq: 4},
+		{id: "mid", execTime: testStart, priority: 1, seq: 5},
+		{id: "earlier", execTime: testStart.Add(-time.Second), priority: -1, seq: 6},
+	}
+	want := []string{"earlier", "high-1", "high-2", "mid", "low-1", "low-2"}
+
+	// 插入顺序不影响弹出顺序
+	for _, perm := range [][]int{{0, 1, 2, 3, 4, 5}, {5, 4, 3, 2, 1, 0}, {3, 0, 5, 1, 4, 2}} {
+		var h taskHeap
+		for _, i := range perm {
+			task := *tasks[i]
+			heap.Push(&h, &task)
+		}
+		for i, id := range want {
+			if got := heap.Pop(&h).(*task).id; got != id {
+				t.Fatalf("insert order %v: pop %d = %s, want %s", perm, i, got, id)
+			}
+		}
+	}
+}
+
+// BenchmarkHeapPush 在已有 n 个任务的堆中插入执行时间随机的任务，每次插入为 O(log n)
+func BenchmarkHeapPush(b *testing.B) {
+	for _, size := range heapBenchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			base := randomTasks(size.n)
+			extra := randomTasks(1024)
+			h := make(taskHeap, 0, size.n+b.N)
+			for _, t := range base {
+				heap.Push(&h, t)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				t := *extra[i%len(extra)]
+				heap.Push(&h, &t)
+			}
+		})
+	}
+}
+
+// BenchmarkHeapPushPop 堆的大小保持不变，每次取出最早到期的任务，再作为下一次执行重新插入，模拟稳定运行时的负载
+func BenchmarkHeapPushPop(b *testing.B) {
+	for _, size := range heapBenchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			var h taskHeap
+			for _, t := range randomTasks(size.n) {
+				heap.Push(&h, t)
+			}
+			r := rand.New(rand.NewSource(2))
+			periods := make([]time.Duration, 1024)
+			for i := range periods {
+				periods[i] = time.Duration(r.Int63n(int64(time.Hour)))
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				t := heap.Pop(&h).(*task)
+				t.execTime = t.execTime.Add(periods[i%len(periods)])
+				heap.Push(&h, t)
+			}
+		})
+	}
+}
+
+// sortedTasks 换成堆之前的任务列表：按执行顺序排列的切片，二分查找插入位置后挪动其后的所有元素，
+// 每次插入为 O(n)，只作为基准测试中与堆对照的基线
+type sortedTasks []*task
+
+// newSortedTasks 将 tasks 排好序作为任务列表
+func newSortedTasks(tasks []*task, capacity int) sortedTasks {
+	s := make(sortedTasks, len(tasks), capacity)
+	copy(s, tasks)
+	sort.Slice(s, func(i, j int) bool {
+		return s[i].before(s[j])
+	})
+	return s
+}
+
+func (s *sortedTasks) push(t *task) {
+	i := sort.Search(len(*s), func(i int) bool {
+		return t.before((*s)[i])
+	})
+	*s = append(*s, nil)
+	copy((*s)[i+1:], (*s)[i:])
+	(*s)[i] = t
+}
+
+func (s *sortedTasks) pop() *task {
+	t := (*s)[0]
+	*s = (*s)[1:]
+	return t
+}
+
+// BenchmarkSortedSlicePush 与 BenchmarkHeapPush 相同的负载，在有序切片中插入
+// 每插入 1024 个任务将切片恢复为 n 个任务，避免切片随 b.N 增长，插入的代价与 n 无关
+func BenchmarkSortedSlicePush(b *testing.B) {
+	for _, size := range heapBenchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			extra := randomTasks(1024)
+			base := newSortedTasks(randomTasks(size.n), size.n)
+			s := make(sortedTasks, 0, size.n+len(extra))
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if i%len(extra) == 0 {
+					b.StopTimer()
+					s = append(s[:0], base...)
+					b.StartTimer()
+				}
+				t := *extra[i%len(extra)]
+				s.push(&t)
+			}
+		})
+	}
+}
+
+// BenchmarkSortedSlicePushPop 与 BenchmarkHeapPushPop 相同的负载，在有序切片中取出与重新插入
+func BenchmarkSortedSlicePushPop(b *testing.B) {
+	for _, size := range heapBenchSizes {
+		b.Run(size.name, func(b *testing.B) {
+			s := newSortedTasks(randomTasks(size.n), size.n)
+			r := rand.New(rand.NewSource(2))
+			periods := make([]time.Duration, 1024)
+			for i := range periods {
+				periods[i] = time.Duration(r.Int63n(int64(time.Hour)))
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				t := s.pop()
+				t.execTime = t.execTime.Add(periods[i%len(periods)])
+				s.push(t)
+			}
+		})
+	}
+}
+
+// BenchmarkPushRandomDelay 通过队列推送延时随机的任务，包括管道往返与调度协程中的堆插入
+func BenchmarkPushRandomDelay(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	delays := make([]time.Duration, 1024)
+	for i := range delays {
+		delays[i] = time.Duration(r.Int63n(int64(time.Hour))) + time.Minute
+	}
+	q := NewDelayQueue(WithClock(NewManualClock(testStart)), WithIDFormat(IDFormatNumeric))
+	defer func() {
+		_ = q.Stop(context.Background())
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.Push(delays[i%len(delays)], func() {})
+	}
+	// 等待所有任务进入任务列表
+	q.Len()
+}
@@ -0,0 +1,69 @@
+package delayqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// testStart 测试使用的模拟时钟的起始时间
+var testStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// newTestQueue 创建使用模拟时钟的队列，测试结束时停止队列
+func newTestQueue(t *testing.T, opts ...Option) (*DelayQueue, *ManualClock) {
+	t.Helper()
+	clock := NewManualClock(testStart)
+	q := NewDelayQueue(append([]Option{WithClock(clock)}, opts...)...)
+	t.Cleanup(func() {
+		stopQueue(t, q)
+	})
+	return q, clock
+}
+
+// stopQueue 停止队列并等待正在执行的任务结束
+func stopQueue(t *testing.T, q *DelayQueue) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.Stop(ctx); err != nil {
+		t.Fatalf("stop queue: %v", err)
+	}
+}
+
+// fireNext 等待队列为最近的任务设置好计时器后，将时钟推进 d
+func fireNext(clock *ManualClock, d time.Duration) {
+	clock.BlockUntil(1)
+	clock.Advance(d)
+}
+
+// receive 等待管道中的下一个值，超时后测试失败
+func receive[T any](t *testing.T, c <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-c:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for value")
+		var zero T
+		return zero
+	}
+}
+
+// settle 等待调度协程处理完之前发出的所有信号，并在新一轮循环开始时同步任务数量
+// 第一次调用让调度协程收下 add 管道中的任务，第二次调用返回时调度协程已经完成了一轮循环
+func settle(q *DelayQueue) {
+	q.Len()
+	q.Len()
+}
+
+// waitFor 等待 cond 成立，超时后测试失败
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,79 @@
+package delayqueue
+
+import (
+	"context"
+	"time"
+)
+
+// Hook 任务生命周期的观测钩子，可以用来接入 OpenTelemetry 等链路追踪，或者自定义的监控
+//
+// 接入链路追踪时，TaskPushed 在调用方的 ctx 下创建推送的 span 并返回携带该 span 的 ctx；
+// 任务到期执行时，TaskStarted 收到的正是这个 ctx，可以据此创建执行的 span 并与推送的 span 关联，
+// TaskFinished 结束执行的 span。钩子在推送与执行的协程中同步调用，应当尽快返回
+type Hook interface {
+	// TaskPushed 任务被推送时调用，返回的 ctx 会随任务保存，任务被拒绝时不会调用
+	TaskPushed(ctx context.Context, info TaskInfo) context.Context
+	// TaskStarted 任务开始执行时调用，actual 为实际开始执行的时间，返回的 ctx 会传给 TaskFinished
+	TaskStarted(ctx context.Context, info TaskInfo, actual time.Time) context.Context
+	// TaskFinished 任务执行结束时调用，err 为执行函数返回的错误或 panic 对应的错误
+	TaskFinished(ctx context.Context, info TaskInfo, err error)
+}
+
+// pushTrace 推送时由钩子返回的 ctx
+// 钩子在任务进入队列之后才调用，此时任务可能已经到期开始执行，执行时需要等待 ready 关闭后再读取 ctx
+type pushTrace struct {
+	ctx   context.Context
+	ready chan struct{}
+}
+
+// hookPushed 在任务进入队列之前调用，返回任务成功进入队列后依次调用所有钩子与 OnPush 的函数，
+// 被拒绝的任务不会调用钩子。任务进入队列后由调度协程接管，任务信息与保存推送 ctx 的位置都需要在这之前准备好
+func (q *DelayQueue) hookPushed(t *task) func(ctx context.Context) {
+	if len(q.hooks) == 0 && q.onPush == nil {
+		return noopPushed
+	}
+
+	var trace *pushTrace
+	if len(q.hooks) > 0 {
+		trace = &pushTrace{ctx: context.Background(), ready: make(chan struct{})}
+		t.ensureExtra().trace = trace
+	}
+	info := q.taskInfo(t, q.clock.Now())
+	return func(ctx context.Context) {
+		if trace != nil {
+			defer close(trace.ready)
+			for _, h := range q.hooks {
+				ctx = h.TaskPushed(ctx, info)
+			}
+			trace.ctx = ctx
+		}
+		q.firePush(info)
+	}
+}
+
+// noopPushed 没有设置钩子与 OnPush 时 hookPushed 返回的函数
+func noopPushed(context.Context) {}
+
+// hookStarted 任务开始执行时依次调用所有钩子，返回执行结束时需要调用的函数
+func (q *DelayQueue) hookStarted(t *task) func(err error) {
+	if len(q.hooks) == 0 {
+		return func(error) {}
+	}
+
+	ctx := context.Background()
+	if t.extra().trace != nil {
+		<-t.extra().trace.ready
+		ctx = t.extra().trace.ctx
+	}
+	now := q.clock.Now()
+	info := q.taskInfo(t, now)
+	ctxs := make([]context.Context, len(q.hooks))
+	for i, h := range q.hooks {
+		ctxs[i] = h.TaskStarted(ctx, info, now)
+	}
+	return func(err error) {
+		for i, h := range q.hooks {
+			h.TaskFinished(ctxs[i], info, err)
+		}
+	}
+}
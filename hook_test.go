@@ -0,0 +1,81 @@
+package delayqueue
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+type ctxKey struct{}
+
+// recordingHook 记录收到的推送与开始执行的任务
+type recordingHook struct {
+	mu      sync.Mutex
+	pushed  []string
+	started chan any
+}
+
+func (h *recordingHook) TaskPushed(ctx context.Context, info TaskInfo) context.Context {
+	h.mu.Lock()
+	h.pushed = append(h.pushed, info.ID)
+	h.mu.Unlock()
+	return context.WithValue(ctx, ctxKey{}, "span-"+info.ID)
+}
+
+func (h *recordingHook) TaskStarted(ctx context.Context, info TaskInfo, actual time.Time) context.Context {
+	h.started <- ctx.Value(ctxKey{})
+	return ctx
+}
+
+func (h *recordingHook) TaskFinished(ctx context.Context, info TaskInfo, err error) {}
+
+func (h *recordingHook) pushedIDs() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.pushed...)
+}
+
+func TestHookPushedCtxReachesStarted(t *testing.T) {
+	hook := &recordingHook{started: make(chan any, 1)}
+	q, clock := newTestQueue(t, WithHook(hook))
+
+	h := q.Push(time.Second, func() {})
+	fireNext(clock, time.Second)
+	if got, want := receive(t, hook.started), "span-"+h.ID(); got != want {
+		t.Errorf("TaskStarted ctx value = %v, want %v", got, want)
+	}
+}
+
+func TestHookNotCalledForRejectedPush(t *testing.T) {
+	hook := &recordingHook{started: make(chan any, 1)}
+	pushes := 0
+	q, _ := newTestQueue(t, WithHook(hook), OnPush(func(TaskInfo) { pushes++ }))
+	stopQueue(t, q)
+
+	if err := q.Push(time.Second, func() {}).Err(); err == nil {
+		t.Fatal("Push on stopped queue succeeded")
+	}
+	if ids := q.PushBatch([]PushItem{{Delay: time.Second, Func: func() {}}}); ids[0] != "" {
+		t.Fatalf("PushBatch on stopped queue returned id %q", ids[0])
+	}
+	if ids := hook.pushedIDs(); len(ids) != 0 {
+		t.Errorf("TaskPushed called for rejected pushes: %v", ids)
+	}
+	if pushes != 0 {
+		t.Errorf("OnPush called %d times for rejected pushes", pushes)
+	}
+}
+
+func TestHookCalledForBatch(t *testing.T) {
+	hook := &recordingHook{started: make(chan any, 2)}
+	q, _ := newTestQueue(t, WithHook(hook))
+
+	ids := q.PushBatch([]PushItem{
+		{Delay: time.Second, Func: func() {}},
+		{Delay: 2 * time.Second, Func: func() {}},
+	})
+	if got := hook.pushedIDs(); len(got) != 2 || got[0] != ids[0] || got[1] != ids[1] {
+		t.Errorf("TaskPushed ids = %v, want %v", got, ids)
+	}
+}
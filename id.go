@@ -0,0 +1,302 @@
+package delayqueue
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	mathrand "math/rand"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// IDGenerator 任务id生成器，需要保证并发安全且生成的id不重复
+type IDGenerator interface {
+	NewID() string
+}
+
+// IDGeneratorFunc 将普通函数适配为 IDGenerator
+type IDGeneratorFunc func() string
+
+// NewID 生成任务id
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// IDFormat 内置的任务id格式
+type IDFormat int
+
+const (
+	IDFormatObjectID  IDFormat = iota // MongoDB ObjectID 的十六进制形式，默认格式
+	IDFormatNumeric                   // 从 1 开始自增的十进制数字
+	IDFormatUUID                      // 随机生成的 UUIDv4
+	IDFormatULID                      // ULID，字典序与生成时间一致
+	IDFormatUUIDv7                    // UUIDv7，前 48 位是毫秒时间戳，字典序与生成时间一致
+	IDFormatKSUID                     // KSUID，27 个字符的 base62 字符串，按秒排序
+	IDFormatSnowflake                 // 雪花算法生成的十进制数字，节点号为 0，多个进程需要通过 NewSnowflakeIDGenerator 指定不同的节点号
+)
+
+// NewIDGenerator 创建指定格式的任务id生成器
+func NewIDGenerator(format IDFormat) IDGenerator {
+	switch format {
+	case IDFormatNumeric:
+		return &numericIDGenerator{}
+	case IDFormatUUID:
+		return uuidGenerator{}
+	case IDFormatULID:
+		return &ulidGenerator{}
+	case IDFormatUUIDv7:
+		return uuidV7Generator{}
+	case IDFormatKSUID:
+		return ksuidGenerator{}
+	case IDFormatSnowflake:
+		return NewSnowflakeIDGenerator(0)
+	default:
+		return objectIDGenerator{}
+	}
+}
+
+// objectIDGenerator 生成 MongoDB ObjectID 形式的任务id
+// 与 mongo 驱动的生成方式相同：4 字节秒级时间戳 + 5 字节进程随机数 + 3 字节自增计数，不依赖 mongo 驱动
+type objectIDGenerator struct{}
+
+var (
+	objectIDProcess [5]byte       // 进程随机数，进程内生成一次
+	objectIDCounter atomic.Uint32 // 自增计数，从随机值开始
+	objectIDOnce    sync.Once
+)
+
+func (objectIDGenerator) NewID() string {
+	objectIDOnce.Do(func() {
+		mustReadRand(objectIDProcess[:])
+		var b [4]byte
+		mustReadRand(b[:])
+		objectIDCounter.Store(binary.BigEndian.Uint32(b[:]))
+	})
+
+	var b [12]byte
+	binary.BigEndian.PutUint32(b[0:4], uint32(time.Now().Unix()))
+	copy(b[4:9], objectIDProcess[:])
+	c := objectIDCounter.Add(1)
+	b[9] = byte(c >> 16)
+	b[10] = byte(c >> 8)
+	b[11] = byte(c)
+	return hex.EncodeToString(b[:])
+}
+
+// numericIDGenerator 生成自增数字形式的任务id
+type numericIDGenerator struct {
+	n atomic.Uint64
+}
+
+func (g *numericIDGenerator) NewID() string {
+	return strconv.FormatUint(g.n.Add(1), 10)
+}
+
+// uuidGenerator 生成 UUIDv4 形式的任务id
+type uuidGenerator struct{}
+
+func (uuidGenerator) NewID() string {
+	var b [16]byte
+	mustReadRand(b[:])
+	b[6] = b[6]&0x0f | 0x40 // version 4
+	b[8] = b[8]&0x3f | 0x80 // variant RFC 4122
+	return formatUUID(b)
+}
+
+// uuidV7Generator 生成 UUIDv7 形式的任务id
+type uuidV7Generator struct{}
+
+func (uuidV7Generator) NewID() string {
+	var b [16]byte
+	mustReadRand(b[6:])
+	ms := uint64(time.Now().UnixMilli())
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	b[6] = b[6]&0x0f | 0x70 // version 7
+	b[8] = b[8]&0x3f | 0x80 // variant RFC 4122
+	return formatUUID(b)
+}
+
+// formatUUID 将 16 字节编码为带连字符的 UUID 字符串
+func formatUUID(b [16]byte) string {
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+	return string(s[:])
+}
+
+// ksuidGenerator 生成 KSUID 形式的任务id
+type ksuidGenerator struct{}
+
+// ksuidEpoch KSUID 时间戳的起始时间，2014-05-13 16:53:20 UTC
+const ksuidEpoch = 1400000000
+
+// base62 KSUID 使用的 base62 字母表
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+func (ksuidGenerator) NewID() string {
+	// 4 字节时间戳 + 16 字节随机数，共 160 位，编码为定长 27 个字符
+	var b [20]byte
+	binary.BigEndian.PutUint32(b[0:4], uint32(time.Now().Unix()-ksuidEpoch))
+	mustReadRand(b[4:])
+
+	var s [27]byte
+	n := b[:]
+	for i := len(s) - 1; i >= 0; i-- {
+		// n 除以 62，余数为当前位
+		var rem uint32
+		for j, c := range n {
+			acc := rem<<8 | uint32(c)
+			n[j] = byte(acc / 62)
+			rem = acc % 62
+		}
+		s[i] = base62[rem]
+	}
+	return string(s[:])
+}
+
+// NewSnowflakeIDGenerator 创建雪花算法的任务id生成器，node 为 0 到 1023 之间的节点号，超出范围时取低 10 位
+// id 由 41 位毫秒时间戳、10 位节点号与 12 位序号组成，以十进制表示；多个进程共享存储时，各进程需要使用不同的节点号
+func NewSnowflakeIDGenerator(node int64) IDGenerator {
+	return &snowflakeGenerator{node: node & 0x3ff}
+}
+
+// snowflakeEpoch 雪花算法时间戳的起始时间，2020-01-01 00:00:00 UTC
+const snowflakeEpoch = 1577836800000
+
+// snowflakeGenerator 雪花算法的任务id生成器
+type snowflakeGenerator struct {
+	mu     sync.Mutex
+	node   int64
+	lastMs int64
+	seq    int64
+}
+
+func (g *snowflakeGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	ms := time.Now().UnixMilli() - snowflakeEpoch
+	if ms < g.lastMs {
+		// 时钟回拨时沿用上一次的时间戳，由序号保证不重复
+		ms = g.lastMs
+	}
+	if ms == g.lastMs {
+		g.seq = (g.seq + 1) & 0xfff
+		if g.seq == 0 {
+			// 同一毫秒内的序号用完，等待下一毫秒
+			for ms <= g.lastMs {
+				time.Sleep(time.Millisecond)
+				ms = time.Now().UnixMilli() - snowflakeEpoch
+			}
+		}
+	} else {
+		g.seq = 0
+	}
+	g.lastMs = ms
+	return strconv.FormatInt(ms<<22|g.node<<12|g.seq, 10)
+}
+
+// ulidGenerator 生成 ULID 形式的任务id
+// 同一毫秒内生成的 ULID 在上一个的随机部分上递增，保证字典序与生成顺序严格一致
+type ulidGenerator struct {
+	mu      sync.Mutex
+	lastMs  uint64
+	lastHi  uint16 // 随机部分的高 16 位
+	lastLow uint64 // 随机部分的低 64 位
+}
+
+// crockford ULID 使用的 Crockford Base32 字母表
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+func (g *ulidGenerator) NewID() string {
+	g.mu.Lock()
+	ms := uint64(time.Now().UnixMilli())
+	if ms <= g.lastMs {
+		// 同一毫秒（或时钟回拨）内递增随机部分
+		ms = g.lastMs
+		g.lastLow++
+		if g.lastLow == 0 {
+			g.lastHi++
+		}
+	} else {
+		var b [10]byte
+		mustReadRand(b[:])
+		g.lastMs = ms
+		g.lastHi = binary.BigEndian.Uint16(b[0:2])
+		g.lastLow = binary.BigEndian.Uint64(b[2:])
+	}
+	hi, low := g.lastHi, g.lastLow
+	g.mu.Unlock()
+
+	// 48 位时间戳 + 80 位随机数，共 128 位，按 5 位一组编码为 26 个字符
+	var b [16]byte
+	b[0] = byte(ms >> 40)
+	b[1] = byte(ms >> 32)
+	b[2] = byte(ms >> 24)
+	b[3] = byte(ms >> 16)
+	b[4] = byte(ms >> 8)
+	b[5] = byte(ms)
+	binary.BigEndian.PutUint16(b[6:8], hi)
+	binary.BigEndian.PutUint64(b[8:], low)
+
+	var s [26]byte
+	s[0] = crockford[b[0]>>5]
+	s[1] = crockford[b[0]&31]
+	// 剩余 120 位按 5 位一组依次编码
+	var acc uint64
+	bits := 0
+	pos := 2
+	for _, c := range b[1:] {
+		acc = acc<<8 | uint64(c)
+		bits += 8
+		for bits >= 5 {
+			bits -= 5
+			s[pos] = crockford[(acc>>bits)&31]
+			pos++
+		}
+	}
+	return string(s[:])
+}
+
+// SeededIDGenerator 创建由种子决定的任务id生成器，相同种子的生成器产生完全相同的id序列，适合在测试中断言具体的id
+// id 由 8 位十六进制的序号与 16 位十六进制的伪随机数组成，同一个生成器内不会重复；生成的id可以预测，不要在生产环境中使用
+func SeededIDGenerator(seed int64) IDGenerator {
+	return &seededIDGenerator{r: mathrand.New(mathrand.NewSource(seed))}
+}
+
+// seededIDGenerator 由种子决定的任务id生成器
+type seededIDGenerator struct {
+	mu sync.Mutex
+	r  *mathrand.Rand
+	n  uint32
+}
+
+func (g *seededIDGenerator) NewID() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.n++
+	return fmt.Sprintf("%08x%016x", g.n, g.r.Uint64())
+}
+
+// mustReadRand 读取密码学安全的随机数
+func mustReadRand(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		panic("delayqueue: read random failed: " + err.Error())
+	}
+}
@@ -0,0 +1,112 @@
+package delayqueue
+
+import (
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestIDFormatsUnique(t *testing.T) {
+	formats := map[string]IDFormat{
+		"objectid":  IDFormatObjectID,
+		"numeric":   IDFormatNumeric,
+		"uuid":      IDFormatUUID,
+		"ulid":      IDFormatULID,
+		"uuidv7":    IDFormatUUIDv7,
+		"ksuid":     IDFormatKSUID,
+		"snowflake": IDFormatSnowflake,
+	}
+	for name, format := range formats {
+		gen := NewIDGenerator(format)
+
+		// 多个协程并发生成，所有id都不重复
+		const workers, perWorker = 4, 2000
+		ids := make(chan string, workers*perWorker)
+		var wg sync.WaitGroup
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < perWorker; i++ {
+					ids <- gen.NewID()
+				}
+			}()
+		}
+		wg.Wait()
+		close(ids)
+
+		seen := make(map[string]struct{}, workers*perWorker)
+		for id := range ids {
+			if _, dup := seen[id]; dup {
+				t.Errorf("%s: duplicate id %q", name, id)
+				break
+			}
+			seen[id] = struct{}{}
+		}
+	}
+}
+
+func TestIDFormatShapes(t *testing.T) {
+	tests := []struct {
+		format IDFormat
+		re     string
+	}{
+		{IDFormatNumeric, `^[1-9][0-9]*$`},
+		{IDFormatUUID, `^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`},
+		{IDFormatULID, `^[0-9A-HJKMNP-TV-Z]{26}$`},
+		{IDFormatObjectID, `^[0-9a-f]{24}$`},
+	}
+	for _, tt := range tests {
+		if id := NewIDGenerator(tt.format).NewID(); !regexp.MustCompile(tt.re).MatchString(id) {
+			t.Errorf("format %d generated %q, want match %s", tt.format, id, tt.re)
+		}
+	}
+}
+
+func TestULIDTimeOrdered(t *testing.T) {
+	gen := NewIDGenerator(IDFormatULID)
+
+	// 同一毫秒内与跨越毫秒生成的 ULID 都按生成顺序递增
+	var ids []string
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 100; j++ {
+			ids = append(ids, gen.NewID())
+		}
+		time.Sleep(2 * time.Millisecond)
+	}
+	for i := 1; i < len(ids); i++ {
+		if ids[i] <= ids[i-1] {
+			t.Fatalf("ULID %d %q not after %q", i, ids[i], ids[i-1])
+		}
+	}
+}
+
+func TestSeededIDGeneratorDeterministic(t *testing.T) {
+	a, b := SeededIDGenerator(42), SeededIDGenerator(42)
+	other := SeededIDGenerator(43)
+
+	// 相同种子产生完全相同的序列，不同种子的序列不同
+	diverged := false
+	for i := 0; i < 100; i++ {
+		idA, idB := a.NewID(), b.NewID()
+		if idA != idB {
+			t.Fatalf("id %d: %q != %q with same seed", i, idA, idB)
+		}
+		if other.NewID() != idA {
+			diverged = true
+		}
+	}
+	if !diverged {
+		t.Error("different seeds generated identical sequences")
+	}
+}
+
+func TestSeededIDGeneratorQueue(t *testing.T) {
+	want := SeededIDGenerator(7).NewID()
+
+	q, _ := newTestQueue(t, WithIDGenerator(SeededIDGenerator(7)))
+	if id := q.Push(time.Second, func() {}).ID(); id != want {
+		t.Errorf("task id = %q, want %q", id, want)
+	}
+}
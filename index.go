@@ -0,0 +1,40 @@
+package delayqueue
+
+// indexTask 将任务记入id索引，去重任务同时记入去重索引
+// 同一个id已经有另一个等待执行的任务时，后加入的任务替换先前的任务，与存储中按id覆盖保存的结果一致
+func (q *DelayQueue) indexTask(t *task) {
+	if old, ok := q.taskIndex[t.id]; ok && old != t {
+		q.takeTask(t.id)
+		old.complete()
+		q.logger.Printf("task %s replaced by a later task with the same id", t.id)
+		q.fireDrop(old, "replaced")
+	}
+	q.taskIndex[t.id] = t
+	if t.extra().unique != "" {
+		q.uniqueKeys[t.extra().unique] = t
+	}
+}
+
+// unindexTask 将任务移出id索引与去重索引，索引中已经是另一个任务时保持不变
+func (q *DelayQueue) unindexTask(t *task) {
+	if q.taskIndex[t.id] == t {
+		delete(q.taskIndex, t.id)
+	}
+	if t.extra().unique != "" && q.uniqueKeys[t.extra().unique] == t {
+		delete(q.uniqueKeys, t.extra().unique)
+	}
+}
+
+// findTask 查找等待执行的任务，包括被扣留、等待领取与暂停的任务
+// 等待领取的任务不在索引中：消费者模式下周期任务的本次执行在等待领取时，下一次执行已经加入了任务列表，两者id相同
+func (q *DelayQueue) findTask(id string) *task {
+	if t, ok := q.taskIndex[id]; ok {
+		return t
+	}
+	for _, t := range q.readyTasks {
+		if t.id == id {
+			return t
+		}
+	}
+	return nil
+}
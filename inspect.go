@@ -0,0 +1,86 @@
+package delayqueue
+
+import "time"
+
+// TaskInfo 等待执行的任务的信息，用于调试与监控
+type TaskInfo struct {
+	Queue     string            // 任务所在队列的名称，即 WithName 设置的名称，可以作为监控指标的标签
+	ID        string            // 任务id
+	ExecTime  time.Time         // 计划执行时间
+	Remaining time.Duration     // 距离执行还剩余的时间，已经到期的任务为 0
+	Handler   string            // 具名处理函数的名称，基于闭包的任务为空
+	Key       string            // 任务的业务 key
+	Tag       string            // 任务的标签
+	Topic     string            // 任务所属的子队列
+	Period    time.Duration     // 周期任务的执行间隔，一次性任务为 0
+	Retries   int               // 已经重试的次数
+	Priority  int               // 任务的优先级
+	Metadata  map[string]string // 任务的元数据，修改返回的副本不影响任务
+	Payload   []byte            // 具名处理函数任务的数据，与任务共享，不要修改
+}
+
+// taskInfo 生成任务的信息，带上队列名称
+func (q *DelayQueue) taskInfo(t *task, now time.Time) TaskInfo {
+	info := t.info(now)
+	info.Queue = q.name
+	return info
+}
+
+// info 生成任务的信息
+func (t *task) info(now time.Time) TaskInfo {
+	remaining := t.execTime.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+	x := t.extra()
+	return TaskInfo{
+		ID:        t.id,
+		ExecTime:  t.execTime,
+		Remaining: remaining,
+		Handler:   t.handler,
+		Key:       x.key,
+		Tag:       x.tag,
+		Topic:     x.topic,
+		Period:    x.period,
+		Retries:   x.attempt,
+		Priority:  t.priority,
+		Metadata:  copyMetadata(x.metadata),
+		Payload:   t.payload(),
+	}
+}
+
+// Len 返回等待执行的任务数量，包括被扣留、等待领取与暂停的任务
+// 只统计各个列表的长度，不需要收集与排序任务，复杂度为 O(1)
+func (q *DelayQueue) Len() int {
+	var n int
+	q.do(func() {
+		n = q.taskCount()
+	})
+	return n
+}
+
+// Tasks 返回所有等待执行的任务的信息，按执行顺序排列
+func (q *DelayQueue) Tasks() []TaskInfo {
+	var infos []TaskInfo
+	q.do(func() {
+		now := q.clock.Now()
+		for _, t := range q.pendingTasks() {
+			infos = append(infos, q.taskInfo(t, now))
+		}
+	})
+	return infos
+}
+
+// Get 返回指定任务的信息，任务不在等待执行时返回 false
+func (q *DelayQueue) Get(id string) (TaskInfo, bool) {
+	var (
+		info  TaskInfo
+		found bool
+	)
+	q.do(func() {
+		if t := q.findTask(id); t != nil {
+			info, found = q.taskInfo(t, q.clock.Now()), true
+		}
+	})
+	return info, found
+}
@@ -0,0 +1,31 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLenCountsEveryList(t *testing.T) {
+	q, clock := newTestQueue(t, WithConsumerMode(), WithTimingWheel(time.Second, 10))
+
+	// 到期后分别进入等待领取与扣留列表
+	q.Push(time.Second, func() {})
+	q.PauseTag("reports")
+	q.PushTagged("reports", time.Second, func() {})
+	fireNext(clock, time.Second)
+	settle(q)
+
+	// 任务堆、时间轮与暂停列表中各一个
+	q.Push(500*time.Millisecond, func() {})
+	q.Push(time.Minute, func() {})
+	if err := q.PauseTask(q.Push(time.Hour, func() {}).ID()); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := q.Len(); n != 5 {
+		t.Errorf("Len = %d, want 5", n)
+	}
+	if n := len(q.Tasks()); n != q.Len() {
+		t.Errorf("Len = %d, but Tasks lists %d", q.Len(), n)
+	}
+}
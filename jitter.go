@@ -0,0 +1,34 @@
+package delayqueue
+
+import (
+	"math/rand"
+	"time"
+)
+
+// PushJitter 用户推送执行时间带有随机抖动的任务，实际执行时间在延时之后的 [0, jitter) 范围内随机分布
+// 大量任务被安排在同一个整点时刻时，抖动可以把执行分散开，避免瞬间的压力；jitter 覆盖 WithJitter 的设置
+func (q *DelayQueue) PushJitter(jitter time.Duration, timeInterval time.Duration, f func(), opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.fn = f
+	return q.submit(t.apply([]PushOption{WithTaskJitter(jitter)}).apply(opts))
+}
+
+// noJitter 任务的执行时间是调用方指定的绝对时刻，或者已经抖动过，推送时不再抖动
+const noJitter time.Duration = -1
+
+// applyJitter 推送时按任务或队列的抖动范围随机推迟任务的执行时间
+// 只作用于第一次执行，周期任务之后的执行以抖动后的时间为基准按周期推算；
+// 只有按延时推送的任务会抖动，PushAt、PushCron 等指定了绝对时刻的任务与重放的任务不受影响
+func (q *DelayQueue) applyJitter(t *task) {
+	window := t.jitter
+	if window < 0 {
+		return
+	}
+	if window == 0 {
+		window = q.jitter
+	}
+	if window <= 0 {
+		return
+	}
+	t.execTime = t.execTime.Add(time.Duration(rand.Int63n(int64(window))))
+}
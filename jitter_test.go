@@ -0,0 +1,47 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestJitterRelativePush(t *testing.T) {
+	q, _ := newTestQueue(t, WithJitter(time.Minute))
+
+	for i := 0; i < 20; i++ {
+		id := q.Push(time.Second, func() {}).ID()
+		info, _ := q.Get(id)
+		at := info.ExecTime.Sub(testStart)
+		if at < time.Second || at >= time.Second+time.Minute {
+			t.Fatalf("jittered delay = %v, want [1s, 1m1s)", at)
+		}
+	}
+}
+
+func TestJitterSkipsAbsolutePush(t *testing.T) {
+	q, _ := newTestQueue(t, WithJitter(time.Hour))
+
+	at := testStart.Add(time.Minute)
+	h := q.PushAt(at, func() {})
+	info, ok := q.Get(h.ID())
+	if !ok {
+		t.Fatal("task not found")
+	}
+	if !info.ExecTime.Equal(at) {
+		t.Errorf("PushAt exec time = %v, want %v", info.ExecTime, at)
+	}
+}
+
+func TestJitterKeepsWindowedTaskInWindow(t *testing.T) {
+	q, _ := newTestQueue(t, WithJitter(2*time.Hour))
+
+	windows := []TimeWindow{{Start: 9 * time.Hour, End: 10 * time.Hour, Location: time.UTC}}
+	for i := 0; i < 50; i++ {
+		id := q.PushWindowed(time.Hour, windows, func() {})
+		info, _ := q.Get(id)
+		start := testStart.Add(9 * time.Hour)
+		if info.ExecTime.Before(start) || !info.ExecTime.Before(start.Add(time.Hour)) {
+			t.Fatalf("windowed exec time = %v, want within 09:00-10:00", info.ExecTime)
+		}
+	}
+}
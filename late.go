@@ -0,0 +1,45 @@
+package delayqueue
+
+import "time"
+
+// TaskContext 延迟过大的任务的上下文，传给 OnLate 设置的函数
+type TaskContext struct {
+	Task     TaskInfo      // 任务的信息
+	Payload  []byte        // 传给具名处理函数的数据，基于闭包的任务为空
+	FiredAt  time.Time     // 调度协程发现任务到期的时间
+	Lateness time.Duration // 发现任务到期时超过执行时间的时长
+}
+
+// isLate 判断任务到期时的延迟是否超过 WithMaxLateness 的设置
+func (q *DelayQueue) isLate(t *task, now time.Time) bool {
+	return q.maxLateness > 0 && now.Sub(t.execTime) > q.maxLateness
+}
+
+// handleLate 将延迟过大的任务交给 OnLate 设置的函数处理，没有设置时丢弃
+// 任务视为已经处理完成：具名处理函数任务从存储中移除，周期任务的下一次执行不受影响
+func (q *DelayQueue) handleLate(t *task, now time.Time) {
+	lateness := now.Sub(t.execTime)
+	if t.last && t.serializable() {
+		q.forget(t.id)
+	}
+	q.saveDeferred(t)
+	if t.last {
+		q.executions.add(t.id)
+		defer t.complete()
+	}
+
+	q.logEvent(LevelWarn, "task late", "id", t.id, "lateness", lateness)
+	q.fireDrop(t, "late")
+	q.logExecution(t, now, OutcomeLate, nil)
+	if q.onLate == nil {
+		q.logger.Printf("task %s dropped, late by %s", t.id, lateness)
+		return
+	}
+
+	q.onLate(TaskContext{
+		Task:     q.taskInfo(t, now),
+		Payload:  t.payload(),
+		FiredAt:  now,
+		Lateness: lateness,
+	})
+}
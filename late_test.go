@@ -0,0 +1,35 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaxLatenessRoutesToOnLate(t *testing.T) {
+	late := make(chan TaskContext, 1)
+	ran := make(chan string, 2)
+	q, clock := newTestQueue(t,
+		WithMaxLateness(time.Minute),
+		OnLate(func(tc TaskContext) { late <- tc }),
+		WithHandler("mail", func(payload []byte) { ran <- string(payload) }),
+	)
+	id := q.PushHandler(time.Second, "mail", []byte("stale"))
+
+	// 进程停顿两分钟之后才发现任务到期，任务不再执行
+	clock.BlockUntil(1)
+	clock.Advance(2 * time.Minute)
+	tc := receive(t, late)
+	if tc.Task.ID != id || string(tc.Payload) != "stale" {
+		t.Errorf("OnLate got %s %q, want %s stale", tc.Task.ID, tc.Payload, id)
+	}
+	if want := 2*time.Minute - time.Second; tc.Lateness != want {
+		t.Errorf("lateness = %v, want %v", tc.Lateness, want)
+	}
+
+	// 按时到期的任务照常执行
+	q.PushHandler(time.Second, "mail", []byte("fresh"))
+	fireNext(clock, time.Second)
+	if got := receive(t, ran); got != "fresh" {
+		t.Errorf("ran %q, want fresh", got)
+	}
+}
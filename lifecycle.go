@@ -0,0 +1,29 @@
+package delayqueue
+
+// firePush 回调 OnPush
+func (q *DelayQueue) firePush(info TaskInfo) {
+	if q.onPush != nil {
+		q.onPush(info)
+	}
+}
+
+// fireExecute 回调 OnExecute
+func (q *DelayQueue) fireExecute(t *task) {
+	if q.onExecute != nil {
+		q.onExecute(q.taskInfo(t, q.clock.Now()))
+	}
+}
+
+// fireDelete 回调 OnDelete
+func (q *DelayQueue) fireDelete(t *task) {
+	if q.onDelete != nil {
+		q.onDelete(q.taskInfo(t, q.clock.Now()))
+	}
+}
+
+// fireDrop 回调 OnDrop
+func (q *DelayQueue) fireDrop(t *task, reason string) {
+	if q.onDrop != nil {
+		q.onDrop(q.taskInfo(t, q.clock.Now()), reason)
+	}
+}
@@ -0,0 +1,67 @@
+package delayqueue
+
+import (
+	"log"
+	"os"
+)
+
+// Logger 日志输出接口，标准库的 *log.Logger 即满足该接口
+type Logger interface {
+	Printf(format string, v ...any)
+}
+
+// namedLogger 在每条日志前加上队列名称
+type namedLogger struct {
+	name   string
+	logger Logger
+}
+
+func (l namedLogger) Printf(format string, v ...any) {
+	l.logger.Printf("[%s] "+format, append([]any{l.name}, v...)...)
+}
+
+// defaultLogger 默认的日志输出
+var defaultLogger Logger = log.New(os.Stderr, "[delayqueue] ", log.LstdFlags)
+
+// LogLevel 结构化日志的级别
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota // 调试信息，例如每个任务的推送与执行
+	LevelInfo                  // 一般信息，例如安排重试
+	LevelWarn                  // 需要关注的情况，例如任务被丢弃
+	LevelError                 // 错误，例如任务最终执行失败
+)
+
+// String 返回级别的名称
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// StructuredLogger 结构化日志输出接口，keyvals 为交替出现的键与值，键均为字符串
+// 队列会为任务的推送、执行、删除、重试与丢弃输出事件，便于排查问题；可以方便地适配到 zap、logrus 等日志库
+type StructuredLogger interface {
+	Log(level LogLevel, msg string, keyvals ...any)
+}
+
+// logEvent 输出一条结构化日志，未设置 WithStructuredLogger 时不输出
+func (q *DelayQueue) logEvent(level LogLevel, msg string, keyvals ...any) {
+	if q.eventLogger == nil {
+		return
+	}
+	if q.name != "" {
+		keyvals = append([]any{"queue", q.name}, keyvals...)
+	}
+	q.eventLogger.Log(level, msg, keyvals...)
+}
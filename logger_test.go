@@ -0,0 +1,126 @@
+package delayqueue
+
+import (
+	"bytes"
+	"log"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// syncBuffer 并发安全的日志缓冲区
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestNamedQueueLogs(t *testing.T) {
+	var buf syncBuffer
+	q, _ := newTestQueue(t, WithName("tenant-a"), WithLogger(log.New(&buf, "", 0)))
+
+	// 无法解析的 cron 表达式会输出一条拒绝推送的日志
+	if id := q.PushCron("not a cron spec", func() {}); id != "" {
+		t.Fatalf("PushCron accepted invalid spec, id %q", id)
+	}
+	if out := buf.String(); !strings.HasPrefix(out, "[tenant-a] ") {
+		t.Errorf("log output %q does not start with the queue name", out)
+	}
+}
+
+func TestNamedQueueMetrics(t *testing.T) {
+	infos := make(chan TaskInfo, 1)
+	q, clock := newTestQueue(t, WithName("tenant-a"), OnExecute(func(info TaskInfo) { infos <- info }))
+
+	if name := q.Metrics().Name; name != "tenant-a" {
+		t.Errorf("Metrics().Name = %q, want tenant-a", name)
+	}
+	if name := q.Stats().Name; name != "tenant-a" {
+		t.Errorf("Stats().Name = %q, want tenant-a", name)
+	}
+
+	q.Push(time.Second, func() {})
+	fireNext(clock, time.Second)
+	if info := receive(t, infos); info.Queue != "tenant-a" {
+		t.Errorf("TaskInfo.Queue = %q, want tenant-a", info.Queue)
+	}
+}
+
+type loggedEvent struct {
+	level   LogLevel
+	msg     string
+	keyvals []any
+}
+
+// captureLogger 记录所有结构化日志事件
+type captureLogger struct {
+	mu     sync.Mutex
+	events []loggedEvent
+}
+
+func (l *captureLogger) Log(level LogLevel, msg string, keyvals ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, loggedEvent{level: level, msg: msg, keyvals: keyvals})
+}
+
+// find 返回第一条消息为 msg 的事件
+func (l *captureLogger) find(msg string) (loggedEvent, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, e := range l.events {
+		if e.msg == msg {
+			return e, true
+		}
+	}
+	return loggedEvent{}, false
+}
+
+func TestStructuredLogger(t *testing.T) {
+	logger := &captureLogger{}
+	q, clock := newTestQueue(t, WithName("tenant-a"), WithStructuredLogger(logger))
+
+	fired := q.Push(time.Second, func() {})
+	deleted := q.Push(time.Minute, func() {}).ID()
+	q.Delete(deleted)
+	fireNext(clock, time.Second)
+	receive(t, fired.Done())
+	waitFor(t, "the executed event", func() bool {
+		_, ok := logger.find("task executed")
+		return ok
+	})
+
+	// 每个事件都以队列名称开头，之后是事件自己的键值对
+	tests := []struct {
+		msg     string
+		level   LogLevel
+		keyvals []any
+	}{
+		{"task pushed", LevelDebug, []any{"queue", "tenant-a", "id", fired.ID(), "exec_time", testStart.Add(time.Second)}},
+		{"task deleted", LevelDebug, []any{"queue", "tenant-a", "id", deleted, "found", true}},
+		{"task executed", LevelDebug, []any{"queue", "tenant-a", "id", fired.ID(), "outcome", OutcomeOK, "error", nil}},
+	}
+	for _, tt := range tests {
+		e, ok := logger.find(tt.msg)
+		if !ok {
+			t.Errorf("no %q event", tt.msg)
+			continue
+		}
+		if e.level != tt.level || !reflect.DeepEqual(e.keyvals, tt.keyvals) {
+			t.Errorf("%q event = %v %v, want %v %v", tt.msg, e.level, e.keyvals, tt.level, tt.keyvals)
+		}
+	}
+}
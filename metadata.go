@@ -0,0 +1,39 @@
+package delayqueue
+
+import "time"
+
+// PushMeta 用户推送带有元数据的任务
+// 元数据随任务出现在 TaskInfo、钩子与执行记录中，可以用于多租户的筛选，例如通过 DeleteByMeta 删除某个客户的所有任务
+func (q *DelayQueue) PushMeta(metadata map[string]string, timeInterval time.Duration, f func(), opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.fn = f
+	return q.submit(t.apply([]PushOption{WithMetadata(metadata)}).apply(opts))
+}
+
+// PushHandlerMeta 用户推送带有元数据、由具名处理函数执行的任务，元数据随任务一起持久化
+func (q *DelayQueue) PushHandlerMeta(metadata map[string]string, timeInterval time.Duration, name string, payload []byte, opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.handler = name
+	t.arg = payload
+	return q.submit(t.apply([]PushOption{WithMetadata(metadata)}).apply(opts))
+}
+
+// DeleteByMeta 删除元数据中 key 的值为 value 的所有等待执行的任务，返回删除的任务数量
+func (q *DelayQueue) DeleteByMeta(key, value string) int {
+	return q.deleteWhere(func(t *task) bool {
+		v, ok := t.extra().metadata[key]
+		return ok && v == value
+	}, nil)
+}
+
+// copyMetadata 复制元数据，避免调用方修改传入或返回的 map 影响任务
+func copyMetadata(metadata map[string]string) map[string]string {
+	if len(metadata) == 0 {
+		return nil
+	}
+	cp := make(map[string]string, len(metadata))
+	for k, v := range metadata {
+		cp[k] = v
+	}
+	return cp
+}
@@ -0,0 +1,57 @@
+package delayqueue
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPushMeta(t *testing.T) {
+	q, _ := newTestQueue(t)
+	meta := map[string]string{"tenant": "acme"}
+	id := q.PushMeta(meta, time.Minute, func() {})
+
+	// 元数据被复制，推送后修改传入的 map 不影响任务
+	meta["tenant"] = "other"
+	info, ok := q.Get(id)
+	if !ok {
+		t.Fatalf("task %s not found", id)
+	}
+	if want := map[string]string{"tenant": "acme"}; !reflect.DeepEqual(info.Metadata, want) {
+		t.Errorf("Metadata = %v, want %v", info.Metadata, want)
+	}
+}
+
+func TestDeleteByMeta(t *testing.T) {
+	q, clock := newTestQueue(t, WithHandler("bill", func([]byte) {}))
+	ran := make(chan string, 4)
+	q.PushMeta(map[string]string{"tenant": "acme"}, time.Second, func() { ran <- "acme" })
+	q.PushHandlerMeta(map[string]string{"tenant": "acme", "plan": "pro"}, time.Second, "bill", nil)
+	q.PushMeta(map[string]string{"tenant": "globex"}, time.Second, func() { ran <- "globex" })
+	q.PushMeta(map[string]string{"plan": "acme"}, time.Second, func() { ran <- "plan" })
+	q.PushMeta(map[string]string{"tenant": ""}, time.Second, func() { ran <- "empty" })
+
+	// 只删除 key 与 value 都匹配的任务，同值的其他 key 不受影响
+	if n := q.DeleteByMeta("tenant", "acme"); n != 2 {
+		t.Errorf("DeleteByMeta(tenant, acme) = %d, want 2", n)
+	}
+	if n := q.DeleteByMeta("tenant", "acme"); n != 0 {
+		t.Errorf("second DeleteByMeta = %d, want 0", n)
+	}
+	// 空值只匹配显式设置为空的 key，不匹配没有该 key 的任务
+	if n := q.DeleteByMeta("tenant", ""); n != 1 {
+		t.Errorf("DeleteByMeta(tenant, \"\") = %d, want 1", n)
+	}
+
+	fireNext(clock, time.Second)
+	got := map[string]bool{receive(t, ran): true, receive(t, ran): true}
+	if !got["globex"] || !got["plan"] {
+		t.Errorf("ran %v, want globex and plan", got)
+	}
+	settle(q)
+	select {
+	case name := <-ran:
+		t.Errorf("deleted task %s ran", name)
+	default:
+	}
+}
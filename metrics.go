@@ -0,0 +1,78 @@
+package delayqueue
+
+import (
+	"expvar"
+	"time"
+)
+
+// Metrics 队列的运行指标，可以定期采集后接入 Prometheus 等监控系统
+type Metrics struct {
+	Name              string        `json:"name,omitempty"`      // 队列名称，即 WithName 设置的名称，可以作为监控指标的标签
+	Pending           int           `json:"pending"`             // 等待执行的任务数量（近似值）
+	Executing         int           `json:"executing"`           // 正在执行的任务数量
+	Executed          uint64        `json:"executed"`            // 已经执行完成的任务数量，包括执行失败的任务
+	Failed            uint64        `json:"failed"`              // 执行失败（返回错误或 panic）的任务数量
+	Retries           uint64        `json:"retries"`             // 安排重试的次数
+	AddQueueDepth     int           `json:"add_queue_depth"`     // add 管道中积压、尚未被调度协程接收的任务数量
+	DriftAvg          time.Duration `json:"drift_avg"`           // 实际开始执行时间相对计划执行时间的平均延迟
+	DriftMax          time.Duration `json:"drift_max"`           // 实际开始执行时间相对计划执行时间的最大延迟
+	LoopRestarts      uint64        `json:"loop_restarts"`       // 调度循环 panic 后重新启动的次数
+	WaitRemove        int           `json:"wait_remove"`         // 执行期间被删除、等待拦截后续执行的「待删除」记录数量
+	WaitRemoveMatched uint64        `json:"wait_remove_matched"` // 拦截到后续执行、将其丢弃的「待删除」记录数量
+	WaitRemoveExpired uint64        `json:"wait_remove_expired"` // 一直没有等到后续执行、超时或超出上限被清理的「待删除」记录数量
+}
+
+// Metrics 返回队列当前的运行指标，读取只涉及原子变量，可以高频调用
+func (q *DelayQueue) Metrics() Metrics {
+	m := Metrics{
+		Name:              q.name,
+		Pending:           q.pending(),
+		Executing:         q.ExecutingCount(),
+		Executed:          q.executed.Load(),
+		Failed:            q.failed.Load(),
+		Retries:           q.retries.Load(),
+		AddQueueDepth:     len(q.add),
+		DriftMax:          time.Duration(q.driftMax.Load()),
+		LoopRestarts:      q.loopRestarts.Load(),
+		WaitRemove:        int(q.waitRemoveSize.Load()),
+		WaitRemoveMatched: q.waitRemoveMatched.Load(),
+		WaitRemoveExpired: q.waitRemoveExpired.Load(),
+	}
+	if n := q.driftCount.Load(); n > 0 {
+		m.DriftAvg = time.Duration(q.driftTotal.Load() / int64(n))
+	}
+	return m
+}
+
+// PublishExpvar 以 name 为名称通过 expvar 发布队列的运行指标，可以在 /debug/vars 中查看
+// 与 expvar.Publish 相同，同一个名称重复发布会 panic
+func (q *DelayQueue) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any {
+		return q.Metrics()
+	}))
+}
+
+// recordDrift 记录任务实际开始执行时间相对计划执行时间的延迟
+func (q *DelayQueue) recordDrift(t *task) {
+	drift := int64(q.clock.Now().Sub(t.execTime))
+	if drift < 0 {
+		drift = 0
+	}
+
+	q.driftCount.Add(1)
+	q.driftTotal.Add(drift)
+	for {
+		max := q.driftMax.Load()
+		if drift <= max || q.driftMax.CompareAndSwap(max, drift) {
+			return
+		}
+	}
+}
+
+// recordResult 记录一次任务执行的结果
+func (q *DelayQueue) recordResult(err error) {
+	q.executed.Add(1)
+	if err != nil {
+		q.failed.Add(1)
+	}
+}
@@ -0,0 +1,62 @@
+package delayqueue
+
+import "context"
+
+// TaskHandler 执行一次任务，ctx 由队列的 ctx 派生，接收 context 的任务执行时收到的 ctx 由它派生
+type TaskHandler func(ctx context.Context, info TaskInfo) error
+
+// Middleware 任务执行的中间件，与 HTTP 中间件类似，可以在 next 前后加入日志、指标、鉴权、注入租户信息等逻辑；
+// 不调用 next 时任务不会执行，返回的错误视为本次执行的错误
+type Middleware func(next TaskHandler) TaskHandler
+
+// Use 添加任务执行的中间件，对之后开始执行的所有任务生效，包括基于闭包的任务与具名处理函数任务
+// 先添加的中间件在外层；执行函数的 panic 在最内层就已经被恢复，以错误的形式返回给中间件
+func (q *DelayQueue) Use(middleware ...func(next TaskHandler) TaskHandler) {
+	q.middlewaresMu.Lock()
+	defer q.middlewaresMu.Unlock()
+
+	var chain []Middleware
+	if old := q.middlewares.Load(); old != nil {
+		chain = append(chain, *old...)
+	}
+	for _, mw := range middleware {
+		chain = append(chain, mw)
+	}
+	q.middlewares.Store(&chain)
+}
+
+// runChain 经过中间件执行任务，返回执行结果
+func (q *DelayQueue) runChain(task *task) (outcome string, err error) {
+	chain := q.middlewares.Load()
+	if chain == nil {
+		return q.runTask(q.ctx, task)
+	}
+
+	called := false
+	var h TaskHandler = func(ctx context.Context, info TaskInfo) error {
+		called = true
+		var runErr error
+		outcome, runErr = q.runTask(ctx, task)
+		return runErr
+	}
+	for i := len(*chain) - 1; i >= 0; i-- {
+		h = (*chain)[i](h)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			// 中间件自身 panic
+			outcome, err = OutcomePanic, q.recoverTask(task, r)
+		}
+	}()
+	err = h(q.ctx, q.taskInfo(task, q.clock.Now()))
+	switch {
+	case !called && err == nil:
+		// 中间件没有调用 next，任务被拦截
+		outcome = OutcomeDropped
+	case !called || err != nil && outcome == OutcomeOK:
+		// 中间件拦截或者改写了执行结果
+		outcome = OutcomeError
+	}
+	return outcome, err
+}
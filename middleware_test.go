@@ -0,0 +1,70 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestUseMiddlewareOrder(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	var (
+		mu    sync.Mutex
+		trace []string
+	)
+	record := func(s string) {
+		mu.Lock()
+		trace = append(trace, s)
+		mu.Unlock()
+	}
+	wrap := func(name string) func(next TaskHandler) TaskHandler {
+		return func(next TaskHandler) TaskHandler {
+			return func(ctx context.Context, info TaskInfo) error {
+				record(name + " before " + info.Tag)
+				err := next(ctx, info)
+				record(name + " after")
+				return err
+			}
+		}
+	}
+	q.Use(wrap("outer"))
+	q.Use(wrap("inner"))
+
+	h := q.Push(time.Second, func() { record("run") }, WithTag("mail"))
+	fireNext(clock, time.Second)
+	receive(t, h.Done())
+
+	// 先添加的中间件在外层
+	want := []string{"outer before mail", "inner before mail", "run", "inner after", "outer after"}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(trace, want) {
+		t.Errorf("trace = %v, want %v", trace, want)
+	}
+}
+
+func TestUseMiddlewareRejects(t *testing.T) {
+	q, clock := newTestQueue(t)
+	errDenied := errors.New("denied")
+	q.Use(func(next TaskHandler) TaskHandler {
+		return func(ctx context.Context, info TaskInfo) error {
+			return errDenied
+		}
+	})
+
+	ran := false
+	h := q.Push(time.Second, func() { ran = true })
+	fireNext(clock, time.Second)
+
+	// 中间件没有调用 next，任务不执行，返回的错误作为本次执行的错误
+	if err := h.Wait(context.Background()); !errors.Is(err, errDenied) {
+		t.Errorf("Wait = %v, want errDenied", err)
+	}
+	if ran {
+		t.Error("task ran although the middleware rejected it")
+	}
+}
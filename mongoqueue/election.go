@@ -0,0 +1,71 @@
+package mongoqueue
+
+import (
+	"context"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// lockDocument 选主使用的锁文档
+type lockDocument struct {
+	ID        string    `bson:"_id"`
+	Holder    string    `bson:"holder"`     // 持有锁的实例id
+	ExpiresAt time.Time `bson:"expires_at"` // 租约到期时间
+}
+
+// Elector 基于 MongoDB 文档的选主，实现 delayqueue.Elector
+// 以 name 为 _id 的文档记录 leader 的实例id与租约到期时间：leader 每次竞选时续期，
+// 租约过期后其他实例用 findOneAndUpdate 接管，文档不存在时由 InsertOne 创建，_id 唯一索引保证只有一个实例成功
+type Elector struct {
+	coll       Collection
+	name       string
+	ttl        time.Duration
+	clock      delayqueue.Clock
+	instanceID string
+}
+
+// NewElector 创建使用集合 coll 中名为 name 的文档作为锁的选主，ttl 为租约时长，需要明显长于竞选间隔
+// 锁文档可以与任务放在同一个集合中，_id 不会与任务id冲突即可；各实例的时钟需要保持一致
+func NewElector(coll Collection, name string, ttl time.Duration) *Elector {
+	return &Elector{
+		coll:       coll,
+		name:       name,
+		ttl:        ttl,
+		clock:      delayqueue.SystemClock(),
+		instanceID: delayqueue.NewIDGenerator(delayqueue.IDFormatObjectID).NewID(),
+	}
+}
+
+// Campaign 持有锁或者锁已经过期时续期或接管，锁文档不存在时尝试创建
+func (e *Elector) Campaign(ctx context.Context) (bool, error) {
+	now := e.clock.Now()
+	filter := bson.M{
+		"_id": e.name,
+		"$or": bson.A{
+			bson.M{"holder": e.instanceID},
+			bson.M{"expires_at": bson.M{"$lte": now}},
+		},
+	}
+	update := bson.M{"$set": bson.M{"holder": e.instanceID, "expires_at": now.Add(e.ttl)}}
+
+	var doc lockDocument
+	ok, err := e.coll.FindOneAndUpdate(ctx, filter, update, bson.D{}, &doc)
+	if err != nil || ok {
+		return ok, err
+	}
+
+	// 锁由其他实例持有，或者锁文档还不存在；前者插入会因为 _id 重复而失败，视为竞选失败
+	// Collection 不区分错误的类型，其他原因的插入失败同样视为竞选失败，等待下一次竞选
+	if err := e.coll.InsertOne(ctx, lockDocument{ID: e.name, Holder: e.instanceID, ExpiresAt: now.Add(e.ttl)}); err != nil {
+		return false, nil
+	}
+	return true, nil
+}
+
+// Resign 删除自己持有的锁文档
+func (e *Elector) Resign(ctx context.Context) error {
+	_, err := e.coll.DeleteOne(ctx, bson.M{"_id": e.name, "holder": e.instanceID})
+	return err
+}
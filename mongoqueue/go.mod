@@ -0,0 +1,10 @@
+module github.com/gzltommy/delayqueue/mongoqueue
+
+go 1.19
+
+require (
+	github.com/gzltommy/delayqueue v0.0.0
+	go.mongodb.org/mongo-driver v1.11.6
+)
+
+replace github.com/gzltommy/delayqueue => ../
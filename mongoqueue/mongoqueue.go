@@ -0,0 +1,336 @@
+// Package mongoqueue 提供基于 MongoDB 集合的分布式延时任务队列
+//
+// 多个服务实例使用同一个集合共享一个队列：每个任务是集合中的一个文档，以任务id作为 _id；
+// 各实例定时用 findOneAndUpdate 领取执行时间已到、没有被领取或领取已经过期的任务，
+// 领取时写入实例id与租约到期时间，执行完成后删除文档。
+//
+// 实例在领取之后、执行完成之前崩溃时，租约到期后任务会被其他实例（或重启后的实例）重新领取执行，
+// 因此任务至少执行一次，处理函数需要保证幂等。任务保存在集合中，进程重启后无需额外的恢复步骤。
+//
+// 执行函数无法跨进程共享，分布式模式只支持具名处理函数任务，PushHandler、Delete 的签名与返回约定与
+// delayqueue.DelayQueue 相同，可以直接替换；需要传入 ctx 的调用方使用 PushHandlerCtx、DeleteContext。
+//
+// Storage 以相同的文档结构实现了 delayqueue.Storage，可以直接查看与修改队列中的任务。
+//
+// 本包不依赖 mongo 驱动的连接部分，使用方需要将 *mongo.Collection 适配为 Collection 接口。
+//
+// mongo 驱动的依赖只在这个独立的模块中引入，只使用队列本身不会依赖 mongo 驱动。
+package mongoqueue
+
+import (
+	"context"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Collection 分布式队列依赖的集合操作，使用方将 *mongo.Collection 适配为该接口
+type Collection interface {
+	// InsertOne 对应 InsertOne(ctx, doc)
+	InsertOne(ctx context.Context, doc any) error
+	// FindOneAndUpdate 对应 FindOneAndUpdate(ctx, filter, update)，并设置 SetSort(sort) 与 SetReturnDocument(options.After)；
+	// 更新之后的文档解码到 result 中，没有匹配的文档时（mongo.ErrNoDocuments）返回 ok 为 false
+	FindOneAndUpdate(ctx context.Context, filter, update, sort, result any) (ok bool, err error)
+	// DeleteOne 对应 DeleteOne(ctx, filter)，返回实际删除的数量
+	DeleteOne(ctx context.Context, filter any) (int64, error)
+	// CreateIndex 对应 Indexes().CreateOne(ctx, mongo.IndexModel{Keys: keys})，
+	// expireAfter 大于 0 时设置 SetExpireAfterSeconds，创建 TTL 索引
+	CreateIndex(ctx context.Context, keys bson.D, expireAfter time.Duration) error
+}
+
+// document 任务在集合中的文档
+type document struct {
+	ID           string    `bson:"_id"`
+	ExecTime     time.Time `bson:"exec_time"`
+	Handler      string    `bson:"handler"`
+	Payload      []byte    `bson:"payload"`
+	ClaimedBy    string    `bson:"claimed_by"`    // 领取任务的实例id，未领取时为空
+	ClaimedUntil time.Time `bson:"claimed_until"` // 领取的租约到期时间，未领取时为零值
+	Attempts     int       `bson:"attempts"`      // 被领取的次数
+}
+
+// Option 分布式队列的可选配置
+type Option func(q *MongoDelayQueue)
+
+// WithPollInterval 设置查询到期任务的间隔，默认 100 毫秒；间隔越短，任务执行越及时，数据库的压力也越大
+func WithPollInterval(d time.Duration) Option {
+	return func(q *MongoDelayQueue) {
+		q.pollInterval = d
+	}
+}
+
+// WithBatchSize 设置每次轮询最多领取的任务数量，默认 100
+func WithBatchSize(n int) Option {
+	return func(q *MongoDelayQueue) {
+		q.batchSize = n
+	}
+}
+
+// WithLease 设置领取任务的租约时长，默认 5 分钟
+// 租约到期时任务还没有执行完成，会被其他实例重新领取，因此租约需要长于处理函数的最长执行时间
+func WithLease(d time.Duration) Option {
+	return func(q *MongoDelayQueue) {
+		q.lease = d
+	}
+}
+
+// WithRetention 在执行时间上创建 TTL 索引，超过执行时间 d 之后仍未执行完成的任务由 MongoDB 自动清理
+// 用于兜底清理处理函数已经下线、永远不会被执行的任务；默认不清理
+func WithRetention(d time.Duration) Option {
+	return func(q *MongoDelayQueue) {
+		q.retention = d
+	}
+}
+
+// WithLogger 设置日志输出
+func WithLogger(logger delayqueue.Logger) Option {
+	return func(q *MongoDelayQueue) {
+		q.logger = logger
+	}
+}
+
+// WithClock 设置计算执行时间与轮询使用的时钟，默认使用系统时间
+// 多个实例共享队列时，各实例的时钟需要保持一致
+func WithClock(clock delayqueue.Clock) Option {
+	return func(q *MongoDelayQueue) {
+		q.clock = clock
+	}
+}
+
+// WithIDGenerator 设置任务id生成器，多个实例共享队列时需要保证生成的id全局唯一
+func WithIDGenerator(gen delayqueue.IDGenerator) Option {
+	return func(q *MongoDelayQueue) {
+		q.idGenerator = gen
+	}
+}
+
+// MongoDelayQueue 基于 MongoDB 的分布式延时任务队列
+type MongoDelayQueue struct {
+	coll       Collection
+	instanceID string // 当前实例的id，领取任务时写入文档
+
+	pollInterval time.Duration
+	batchSize    int
+	lease        time.Duration
+	retention    time.Duration
+	logger       delayqueue.Logger
+	idGenerator  delayqueue.IDGenerator
+	clock        delayqueue.Clock
+
+	handlers   map[string]func(payload []byte) // 已注册的具名处理函数
+	handlersMu sync.RWMutex                    // 保护 handlers
+
+	quit     chan struct{}
+	stopOnce sync.Once
+	running  sync.WaitGroup // 轮询协程与正在执行的任务
+}
+
+// NewMongoDelayQueue 创建使用集合 coll 存储任务的分布式延时任务队列，创建所需的索引后开始轮询到期的任务
+// 集合中已有的任务（包括之前的实例领取后没有执行完成的任务）会在到期或租约到期后被执行；创建索引失败时返回错误
+func NewMongoDelayQueue(ctx context.Context, coll Collection, opts ...Option) (*MongoDelayQueue, error) {
+	q := &MongoDelayQueue{
+		coll:         coll,
+		instanceID:   delayqueue.NewIDGenerator(delayqueue.IDFormatObjectID).NewID(),
+		pollInterval: 100 * time.Millisecond,
+		batchSize:    100,
+		lease:        5 * time.Minute,
+		logger:       noopLogger{},
+		idGenerator:  delayqueue.NewIDGenerator(delayqueue.IDFormatObjectID),
+		clock:        delayqueue.SystemClock(),
+		handlers:     make(map[string]func(payload []byte)),
+		quit:         make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	// 领取时按租约到期时间与执行时间过滤，按执行时间排序
+	if err := q.coll.CreateIndex(ctx, bson.D{{Key: "claimed_until", Value: 1}, {Key: "exec_time", Value: 1}}, 0); err != nil {
+		return nil, err
+	}
+	if q.retention > 0 {
+		if err := q.coll.CreateIndex(ctx, bson.D{{Key: "exec_time", Value: 1}}, q.retention); err != nil {
+			return nil, err
+		}
+	}
+
+	q.running.Add(1)
+	go q.poll()
+	return q, nil
+}
+
+// RegisterHandler 注册具名处理函数，共享队列的每个实例都需要注册相同的处理函数
+func (q *MongoDelayQueue) RegisterHandler(name string, fn func(payload []byte)) {
+	q.handlersMu.Lock()
+	defer q.handlersMu.Unlock()
+	q.handlers[name] = fn
+}
+
+// PushHandler 推送由具名处理函数执行的任务，返回任务id，与 delayqueue.DelayQueue.PushHandler 相同
+// 写入数据库失败时记录日志并返回空字符串；需要限时或区分失败原因的调用方使用 PushHandlerCtx
+// opts 只为与内存队列的签名保持一致：推送配置作用于内存中的任务，无法随任务写入数据库，传入时任务被拒绝
+func (q *MongoDelayQueue) PushHandler(timeInterval time.Duration, name string, payload []byte, opts ...delayqueue.PushOption) string {
+	if len(opts) > 0 {
+		q.logger.Printf("push task rejected: push options are not supported")
+		return ""
+	}
+	id, err := q.PushHandlerCtx(context.Background(), timeInterval, name, payload)
+	if err != nil {
+		q.logger.Printf("push task rejected: %v", err)
+		return ""
+	}
+	return id
+}
+
+// PushHandlerCtx 与 PushHandler 相同，数据库操作使用 ctx，写入失败时返回错误
+func (q *MongoDelayQueue) PushHandlerCtx(ctx context.Context, timeInterval time.Duration, name string, payload []byte) (string, error) {
+	doc := document{
+		ID:       q.idGenerator.NewID(),
+		ExecTime: q.clock.Now().Add(timeInterval),
+		Handler:  name,
+		Payload:  payload,
+	}
+	if err := q.coll.InsertOne(ctx, doc); err != nil {
+		return "", err
+	}
+	return doc.ID, nil
+}
+
+// Delete 删除任务，与 delayqueue.DelayQueue.Delete 相同，任务在被领取之前删除时返回 true
+// 已经被领取、正在执行的任务不会被删除；任务不存在或者已经被领取时返回 delayqueue.ErrTaskNotFound，访问数据库失败时返回该错误
+func (q *MongoDelayQueue) Delete(id string) (bool, error) {
+	return q.DeleteContext(context.Background(), id)
+}
+
+// DeleteContext 与 Delete 相同，数据库操作使用 ctx
+func (q *MongoDelayQueue) DeleteContext(ctx context.Context, id string) (bool, error) {
+	n, err := q.coll.DeleteOne(ctx, bson.M{
+		"_id":           id,
+		"claimed_until": bson.M{"$lte": q.clock.Now()},
+	})
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, delayqueue.ErrTaskNotFound
+	}
+	return true, nil
+}
+
+// Stop 停止轮询并等待正在执行的任务结束，ctx 结束时不再等待，返回 ctx.Err()
+func (q *MongoDelayQueue) Stop(ctx context.Context) error {
+	q.stopOnce.Do(func() {
+		close(q.quit)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		q.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// poll 定时领取到期的任务
+func (q *MongoDelayQueue) poll() {
+	defer q.running.Done()
+
+	for {
+		timer := q.clock.NewTimer(q.pollInterval)
+		select {
+		case <-timer.C():
+			q.fireDue()
+		case <-q.quit:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// fireDue 领取到期的任务并执行，每轮最多领取 batchSize 个
+func (q *MongoDelayQueue) fireDue() {
+	ctx := context.Background()
+	for i := 0; i < q.batchSize; i++ {
+		select {
+		case <-q.quit:
+			return
+		default:
+		}
+
+		doc, ok := q.claim(ctx)
+		if !ok {
+			return
+		}
+		q.execute(ctx, doc)
+	}
+}
+
+// claim 领取一个到期的任务，没有可领取的任务或者领取失败时返回 false
+// 执行时间已到、且没有被领取或者租约已经到期的任务都可以被领取，findOneAndUpdate 保证同一时刻只有一个实例领取成功
+func (q *MongoDelayQueue) claim(ctx context.Context) (document, bool) {
+	now := q.clock.Now()
+	filter := bson.M{
+		"exec_time":     bson.M{"$lte": now},
+		"claimed_until": bson.M{"$lte": now},
+	}
+	update := bson.M{
+		"$set": bson.M{"claimed_by": q.instanceID, "claimed_until": now.Add(q.lease)},
+		"$inc": bson.M{"attempts": 1},
+	}
+
+	var doc document
+	ok, err := q.coll.FindOneAndUpdate(ctx, filter, update, bson.D{{Key: "exec_time", Value: 1}}, &doc)
+	if err != nil {
+		q.logger.Printf("claim due task failed: %v", err)
+		return document{}, false
+	}
+	return doc, ok
+}
+
+// execute 异步执行领取到的任务，执行完成后删除文档
+func (q *MongoDelayQueue) execute(ctx context.Context, doc document) {
+	q.handlersMu.RLock()
+	fn, ok := q.handlers[doc.Handler]
+	q.handlersMu.RUnlock()
+	if !ok {
+		// 保留文档，租约到期后可以被注册了处理函数的实例领取
+		q.logger.Printf("handler %q not registered, task %s left for other instances", doc.Handler, doc.ID)
+		return
+	}
+
+	q.running.Add(1)
+	go func() {
+		defer q.running.Done()
+		q.run(doc.ID, fn, doc.Payload)
+
+		// panic 的任务同样删除，否则每次租约到期都会被重新领取并再次 panic
+		// 只删除仍由当前实例持有的任务：租约到期后被其他实例重新领取的任务交给对方处理
+		if _, err := q.coll.DeleteOne(ctx, bson.M{"_id": doc.ID, "claimed_by": q.instanceID, "attempts": doc.Attempts}); err != nil {
+			q.logger.Printf("ack task %s failed: %v", doc.ID, err)
+		}
+	}()
+}
+
+// run 执行任务，处理函数 panic 时恢复并记录日志，不影响其他任务与轮询
+func (q *MongoDelayQueue) run(id string, fn func(payload []byte), payload []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			q.logger.Printf("task %s panic: %v\n%s", id, r, debug.Stack())
+		}
+	}()
+	fn(payload)
+}
+
+// noopLogger 默认不输出日志
+type noopLogger struct{}
+
+func (noopLogger) Printf(string, ...any) {}
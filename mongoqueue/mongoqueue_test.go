@@ -0,0 +1,133 @@
+package mongoqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// fakeCollection 只记录插入与删除操作的 Collection 实现，领取时总是没有到期的任务，deleteN 为删除时返回的数量
+type fakeCollection struct {
+	mu       sync.Mutex
+	inserted []any
+	deleted  []any
+	deleteN  int64
+}
+
+func (c *fakeCollection) InsertOne(_ context.Context, doc any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.inserted = append(c.inserted, doc)
+	return nil
+}
+
+func (c *fakeCollection) FindOneAndUpdate(context.Context, any, any, any, any) (bool, error) {
+	return false, nil
+}
+
+func (c *fakeCollection) DeleteOne(_ context.Context, filter any) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleted = append(c.deleted, filter)
+	return c.deleteN, nil
+}
+
+func (c *fakeCollection) CreateIndex(context.Context, bson.D, time.Duration) error { return nil }
+
+// logBuffer 收集日志的 Logger
+type logBuffer struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *logBuffer) Printf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func TestHandlerPanicRecovered(t *testing.T) {
+	coll := &fakeCollection{deleteN: 1}
+	logs := &logBuffer{}
+	clock := delayqueue.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	q, err := NewMongoDelayQueue(context.Background(), coll, WithClock(clock), WithLogger(logs))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q.RegisterHandler("boom", func([]byte) { panic("boom") })
+
+	// panic 被恢复并记录，任务照常确认删除，不会在租约到期后被反复领取
+	q.execute(context.Background(), document{ID: "t1", Handler: "boom", Attempts: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(coll.deleted) != 1 {
+		t.Errorf("DeleteOne called %d times, want 1", len(coll.deleted))
+	}
+	logged := false
+	for _, line := range logs.lines {
+		logged = logged || strings.Contains(line, "task t1 panic: boom")
+	}
+	if !logged {
+		t.Errorf("panic not logged: %v", logs.lines)
+	}
+}
+
+// scheduler 分布式队列与内存队列共有的推送与删除方法，两者可以互相替换
+type scheduler interface {
+	PushHandler(timeInterval time.Duration, name string, payload []byte, opts ...delayqueue.PushOption) string
+	Delete(id string) (bool, error)
+}
+
+var (
+	_ scheduler = (*MongoDelayQueue)(nil)
+	_ scheduler = (*delayqueue.DelayQueue)(nil)
+)
+
+func TestPushHandlerAndDelete(t *testing.T) {
+	coll := &fakeCollection{deleteN: 1}
+	clock := delayqueue.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	q, err := NewMongoDelayQueue(context.Background(), coll, WithClock(clock), WithPollInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = q.Stop(context.Background()) })
+
+	id := q.PushHandler(time.Minute, "order", []byte("42"))
+	if id == "" || len(coll.inserted) != 1 {
+		t.Fatalf("PushHandler returned %q with %d inserts, want an id and 1 insert", id, len(coll.inserted))
+	}
+	if ok, err := q.Delete(id); !ok || err != nil {
+		t.Fatalf("Delete = %v, %v, want true, nil", ok, err)
+	}
+	// 与内存队列相同，不存在或已经被领取的任务返回 ErrTaskNotFound
+	coll.deleteN = 0
+	if ok, err := q.Delete(id); ok || !errors.Is(err, delayqueue.ErrTaskNotFound) {
+		t.Errorf("second Delete = %v, %v, want false, ErrTaskNotFound", ok, err)
+	}
+}
+
+func TestPushHandlerRejectsOptions(t *testing.T) {
+	coll := &fakeCollection{}
+	clock := delayqueue.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	q, err := NewMongoDelayQueue(context.Background(), coll, WithClock(clock), WithPollInterval(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = q.Stop(context.Background()) })
+
+	// 推送配置无法随任务写入数据库，任务被拒绝而不是悄悄丢掉配置
+	if id := q.PushHandler(time.Minute, "order", nil, delayqueue.WithTag("mail")); id != "" || len(coll.inserted) != 0 {
+		t.Errorf("PushHandler with options = %q with %d inserts, want rejected", id, len(coll.inserted))
+	}
+}
@@ -0,0 +1,85 @@
+package mongoqueue
+
+import (
+	"context"
+
+	"github.com/gzltommy/delayqueue"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// StorageCollection Storage 依赖的集合操作，使用方将 *mongo.Collection 适配为该接口
+type StorageCollection interface {
+	// Upsert 对应 ReplaceOne(ctx, filter, doc, options.Replace().SetUpsert(true))
+	Upsert(ctx context.Context, filter, doc any) error
+	// FindOne 对应 FindOne(ctx, filter).Decode(result)，没有匹配的文档时（mongo.ErrNoDocuments）返回 ok 为 false
+	FindOne(ctx context.Context, filter, result any) (ok bool, err error)
+	// FindAll 对应 Find(ctx, filter) 并通过 cursor.All 将所有文档解码到 results 中
+	FindAll(ctx context.Context, filter, results any) error
+	// DeleteOne 对应 DeleteOne(ctx, filter)，返回实际删除的数量
+	DeleteOne(ctx context.Context, filter any) (int64, error)
+}
+
+// Storage 按 MongoDelayQueue 的结构读写任务的 delayqueue.Storage 实现，每个任务是集合中的一个文档
+// 可以作为 delayqueue.WithStorage 的存储，也可以用来查看、修改分布式队列中的任务；只保存执行时间、处理函数与数据
+type Storage struct {
+	ctx  context.Context
+	coll StorageCollection
+}
+
+// NewStorage 创建读写集合 coll 中任务的存储，所有数据库操作使用 ctx
+func NewStorage(ctx context.Context, coll StorageCollection) *Storage {
+	return &Storage{ctx: ctx, coll: coll}
+}
+
+// Save 写入任务，已经存在的文档被覆盖，领取状态随之清空，任务可以立即被实例领取
+func (s *Storage) Save(task delayqueue.PendingTask) error {
+	doc := document{
+		ID:       task.ID,
+		ExecTime: task.ExecTime,
+		Handler:  task.Handler,
+		Payload:  task.Payload,
+	}
+	return s.coll.Upsert(s.ctx, bson.M{"_id": task.ID}, doc)
+}
+
+// Load 读取指定 id 的任务，任务不存在时返回 delayqueue.ErrTaskNotFound
+func (s *Storage) Load(id string) (delayqueue.PendingTask, error) {
+	var doc document
+	ok, err := s.coll.FindOne(s.ctx, bson.M{"_id": id}, &doc)
+	if err != nil {
+		return delayqueue.PendingTask{}, err
+	}
+	if !ok {
+		return delayqueue.PendingTask{}, delayqueue.ErrTaskNotFound
+	}
+	return doc.pendingTask(), nil
+}
+
+// Remove 删除任务的文档，任务不存在时不返回错误
+func (s *Storage) Remove(id string) error {
+	_, err := s.coll.DeleteOne(s.ctx, bson.M{"_id": id})
+	return err
+}
+
+// List 返回集合中的所有任务，包括已经被实例领取、正在执行的任务
+func (s *Storage) List() ([]delayqueue.PendingTask, error) {
+	var docs []document
+	if err := s.coll.FindAll(s.ctx, bson.M{}, &docs); err != nil {
+		return nil, err
+	}
+	tasks := make([]delayqueue.PendingTask, len(docs))
+	for i, doc := range docs {
+		tasks[i] = doc.pendingTask()
+	}
+	return tasks, nil
+}
+
+// pendingTask 将文档转换为可序列化的任务
+func (d document) pendingTask() delayqueue.PendingTask {
+	return delayqueue.PendingTask{
+		ID:       d.ID,
+		ExecTime: d.ExecTime,
+		Handler:  d.Handler,
+		Payload:  d.Payload,
+	}
+}
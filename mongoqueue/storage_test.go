@@ -0,0 +1,108 @@
+package mongoqueue
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+var _ delayqueue.Storage = (*Storage)(nil)
+
+// fakeStorageCollection 内存中的 StorageCollection 实现，按 _id 保存文档
+type fakeStorageCollection struct {
+	mu   sync.Mutex
+	docs map[string]document
+}
+
+func (c *fakeStorageCollection) Upsert(_ context.Context, filter, doc any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.docs[filter.(bson.M)["_id"].(string)] = doc.(document)
+	return nil
+}
+
+func (c *fakeStorageCollection) FindOne(_ context.Context, filter, result any) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	doc, ok := c.docs[filter.(bson.M)["_id"].(string)]
+	if ok {
+		*result.(*document) = doc
+	}
+	return ok, nil
+}
+
+func (c *fakeStorageCollection) FindAll(_ context.Context, _, results any) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	docs := results.(*[]document)
+	for _, doc := range c.docs {
+		*docs = append(*docs, doc)
+	}
+	sort.Slice(*docs, func(i, j int) bool { return (*docs)[i].ID < (*docs)[j].ID })
+	return nil
+}
+
+func (c *fakeStorageCollection) DeleteOne(_ context.Context, filter any) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	id := filter.(bson.M)["_id"].(string)
+	if _, ok := c.docs[id]; !ok {
+		return 0, nil
+	}
+	delete(c.docs, id)
+	return 1, nil
+}
+
+func TestStorageSaveLoadListRemove(t *testing.T) {
+	coll := &fakeStorageCollection{docs: make(map[string]document)}
+	s := NewStorage(context.Background(), coll)
+
+	execTime := time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC)
+	for _, id := range []string{"t1", "t2"} {
+		if err := s.Save(delayqueue.PendingTask{ID: id, ExecTime: execTime, Handler: "order", Payload: []byte(id)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 覆盖已经被领取的任务时领取状态被清空
+	coll.docs["t1"] = document{ID: "t1", ClaimedBy: "other", ClaimedUntil: execTime}
+	if err := s.Save(delayqueue.PendingTask{ID: "t1", ExecTime: execTime, Handler: "order", Payload: []byte("t1")}); err != nil {
+		t.Fatal(err)
+	}
+	if doc := coll.docs["t1"]; doc.ClaimedBy != "" || !doc.ClaimedUntil.IsZero() {
+		t.Errorf("claim survived Save: %+v", doc)
+	}
+
+	pt, err := s.Load("t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt.ID != "t1" || pt.Handler != "order" || string(pt.Payload) != "t1" || !pt.ExecTime.Equal(execTime) {
+		t.Errorf("Load = %+v", pt)
+	}
+
+	list, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 || list[0].ID != "t1" || list[1].ID != "t2" {
+		t.Errorf("List = %+v, want t1 and t2", list)
+	}
+
+	if err := s.Remove("t1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Load("t1"); !errors.Is(err, delayqueue.ErrTaskNotFound) {
+		t.Errorf("Load after Remove error = %v, want ErrTaskNotFound", err)
+	}
+	// 任务不存在时不返回错误
+	if err := s.Remove("t1"); err != nil {
+		t.Errorf("second Remove error = %v", err)
+	}
+}
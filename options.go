@@ -0,0 +1,482 @@
+package delayqueue
+
+import (
+	"io"
+	"time"
+)
+
+// Option 创建延时任务队列时的可选配置，NewDelayQueue 不传入任何配置时使用默认值
+// 新增的功能都以配置的形式提供，不会破坏已有的调用
+type Option func(q *DelayQueue)
+
+// WithLogger 设置日志输出，默认输出到标准错误
+func WithLogger(logger Logger) Option {
+	return func(q *DelayQueue) {
+		q.logger = logger
+	}
+}
+
+// WithStructuredLogger 设置结构化日志输出，队列会输出任务的推送、执行、删除、重试与丢弃事件
+// 与 WithLogger 互不影响：WithLogger 设置的日志只输出异常情况
+func WithStructuredLogger(logger StructuredLogger) Option {
+	return func(q *DelayQueue) {
+		q.eventLogger = logger
+	}
+}
+
+// WithMissingHandler 设置具名处理函数缺失时的兜底处理
+// 从快照恢复的任务所引用的处理函数可能已经不存在（例如代码变更后），这类任务到期时会交给 fn 处理；
+// 未设置时，任务会被丢弃并记录一条日志
+func WithMissingHandler(fn func(name string, task PendingTask)) Option {
+	return func(q *DelayQueue) {
+		q.missingHandler = fn
+	}
+}
+
+// WithHandler 在创建队列时注册具名处理函数，与 RegisterHandler 相同
+// 队列创建时就会从存储中加载任务，其中已经过期的任务会立即执行，它们的处理函数需要通过该选项提前注册
+func WithHandler(name string, fn func(payload []byte)) Option {
+	return func(q *DelayQueue) {
+		q.handlers[name] = adaptHandler(fn)
+	}
+}
+
+// OnResidence 设置任务停留时间的观察回调
+// 任务执行时，fn 会收到该任务从进入队列到实际执行所经过的时间；周期任务的后续执行从上一次到期开始计算
+func OnResidence(fn func(id string, d time.Duration)) Option {
+	return func(q *DelayQueue) {
+		q.onResidence = fn
+	}
+}
+
+// OnPanic 设置执行函数 panic 时的回调
+// 队列会恢复执行函数中的 panic，不会导致进程退出；fn 会收到任务id、具名处理函数任务的数据（其他任务为 nil）
+// 以及 recover 得到的值。未设置时 panic 会连同调用栈一起记录到日志中
+func OnPanic(fn func(id string, payload []byte, recovered any)) Option {
+	return func(q *DelayQueue) {
+		q.onPanic = fn
+	}
+}
+
+// OnGiveUp 设置任务重试次数耗尽时的回调，fn 会收到任务id与最后一次执行的错误
+// 未设置时放弃的任务会记录一条日志
+func OnGiveUp(fn func(id string, err error)) Option {
+	return func(q *DelayQueue) {
+		q.onGiveUp = fn
+	}
+}
+
+// OnComplete 设置任务执行完成时的回调，fn 会收到任务id、执行耗时与执行返回的错误
+// 失败后还要重试的执行不会回调，重试结束后以最后一次执行的结果回调一次；周期任务每次执行都会回调。
+// fn 在执行任务的协程中调用，耗时的处理会占用执行协程
+func OnComplete(fn func(id string, d time.Duration, err error)) Option {
+	return func(q *DelayQueue) {
+		q.onComplete = fn
+	}
+}
+
+// WithClock 设置队列使用的时钟，默认使用系统时间；测试中可以传入 ManualClock 控制时间的流逝
+func WithClock(clock Clock) Option {
+	return func(q *DelayQueue) {
+		q.clock = clock
+	}
+}
+
+// WithMaxExecDuration 设置任务执行的最长时间
+// 任务开始执行后超过 d 仍未返回时，会记录日志、计入 ExecTimeouts 并回调 OnExecTimeout 设置的函数
+func WithMaxExecDuration(d time.Duration) Option {
+	return func(q *DelayQueue) {
+		q.maxExecDuration = d
+	}
+}
+
+// OnExecTimeout 设置任务执行超时的回调，需要配合 WithMaxExecDuration 使用
+func OnExecTimeout(fn func(id string)) Option {
+	return func(q *DelayQueue) {
+		q.onExecTimeout = fn
+	}
+}
+
+// WithDelayFromEnqueue 设置延时从队列接收任务时开始计算，而不是从调用 Push 时开始计算
+// add 管道已满时 Push 会阻塞，默认情况下阻塞的时间也计入延时，任务被接收时可能已经过期；
+// 开启后，Push、PushHandler 与 PushPeriodicWithTTL 推送的任务在被调度协程接收时才开始计时
+func WithDelayFromEnqueue() Option {
+	return func(q *DelayQueue) {
+		q.delayFromEnqueue = true
+	}
+}
+
+// WithIDGenerator 设置自定义的任务id生成器
+func WithIDGenerator(gen IDGenerator) Option {
+	return func(q *DelayQueue) {
+		q.idGenerator = gen
+	}
+}
+
+// WithIDFormat 使用内置格式的任务id生成器
+// 生成器在调用 WithIDFormat 时创建，使用同一组配置派生出的队列（例如 Partition）共享同一个生成器，id 不会重复
+func WithIDFormat(format IDFormat) Option {
+	return WithIDGenerator(NewIDGenerator(format))
+}
+
+// WithSingleFlightKey 开启按 key 的单飞执行，同一个 key 的任务同一时刻只会有一个在执行
+// policy 决定后到期的任务是等待（SingleFlightWait）还是被丢弃（SingleFlightDrop）；只对带 key 推送的任务生效
+func WithSingleFlightKey(policy SingleFlightPolicy) Option {
+	return func(q *DelayQueue) {
+		q.singleFlight = policy
+	}
+}
+
+// WithPushRateLimit 限制每秒最多推送 perSecond 个任务，允许的突发量同样为 perSecond
+// 用于保护队列不被失控的生产者压垮，与任务执行的并发控制无关；超过限制时的行为由 WithPushRateLimitPolicy 决定
+// 从快照恢复的任务不受该限制
+func WithPushRateLimit(perSecond int) Option {
+	return func(q *DelayQueue) {
+		q.pushRate = perSecond
+	}
+}
+
+// WithRateLimit 限制每秒最多开始执行 perSecond 个任务，允许的突发量为 burst
+// 大量任务同时到期时，超出的任务在执行协程中等待令牌，按限定的速度依次执行，适合执行函数调用有频率限制的外部接口的场景；
+// 等待令牌的时间计入任务的执行延迟。perSecond <= 0 表示不限制，burst < 1 时按 1 处理
+func WithRateLimit(perSecond float64, burst int) Option {
+	return func(q *DelayQueue) {
+		q.execRate = perSecond
+		q.execBurst = burst
+	}
+}
+
+// WithJitter 为推送的任务增加 [0, d) 范围内的随机延时，避免大量安排在同一时刻的任务同时执行
+// 只对按延时推送的任务生效，PushAt、PushCron 等指定了绝对时刻的任务与从快照恢复、重放的任务不受影响；
+// PushWindowed 的任务抖动后依然落在允许时间段内。PushJitter 可以为单个任务指定不同的抖动范围
+func WithJitter(d time.Duration) Option {
+	return func(q *DelayQueue) {
+		q.jitter = d
+	}
+}
+
+// WithTimingWheel 使用分层时间轮安排任务，tick 为时间轮的刻度，size 为每层的槽位数量
+// 默认所有任务都保存在按执行时间排列的堆中，推送与删除的复杂度为 O(log n)；开启后执行时间在当前刻度之后的任务
+// 先放入时间轮，推送与删除变为 O(1)，到达所在刻度时才移入堆中精确排序，适合大量短延时任务的场景。
+// 执行时间的精度不受刻度影响，但调度协程每隔一个刻度至少会被唤醒一次，tick 不宜过小；tick 或 size <= 0 时不开启
+func WithTimingWheel(tick time.Duration, size int) Option {
+	return func(q *DelayQueue) {
+		q.wheelTick = tick
+		q.wheelSize = size
+	}
+}
+
+// WithClockWatchdog 开启时钟跳变看门狗，调度协程至少每隔 interval 醒来一次，比较系统时间与单调时钟的流逝，
+// 两者相差超过 threshold 时（NTP 校时、虚拟机挂起与恢复等）视为时钟跳变，计入 ClockJumps 并重新安排任务：
+// 按延时推送的任务保持单调时钟上剩余的等待时间，按系统时间表示的任务（从存储恢复、cron 等）按新的系统时间触发。
+// 不开启时，系统时间回拨后按系统时间表示的任务同样不会提前执行，但系统时间前跳或挂起恢复后，任务可能要等到原先的计时器到期才执行
+func WithClockWatchdog(interval, threshold time.Duration) Option {
+	return func(q *DelayQueue) {
+		q.clockCheckInterval = interval
+		q.clockJumpThreshold = threshold
+	}
+}
+
+// WithUniquePolicy 设置 PushUnique 推送的任务与已有的同 key 任务冲突时的处理策略，默认保留新推送的任务；
+// 同样适用于通过 WithUniqueKey 设置了去重 key 的任务
+func WithUniquePolicy(policy UniquePolicy) Option {
+	return func(q *DelayQueue) {
+		q.uniquePolicy = policy
+	}
+}
+
+// WithDuplicateIDPolicy 设置调用方指定的任务id已经有等待执行的任务时的处理策略，默认替换已有的任务
+// DuplicateIDReject 策略适合 webhook 重试等需要幂等推送的场景，重复的推送返回 ErrDuplicateID
+func WithDuplicateIDPolicy(policy DuplicateIDPolicy) Option {
+	return func(q *DelayQueue) {
+		q.duplicateIDPolicy = policy
+	}
+}
+
+// WithPushRateLimitPolicy 设置推送超过限流时的处理策略，默认阻塞等待
+// RateLimitReject 策略下推送被拒绝，原因为 ErrRateLimited：Push 等返回句柄的方法通过句柄的 Err 返回，
+// TryPush 等返回 error 的方法直接返回，返回任务id的方法记录日志并返回空字符串
+func WithPushRateLimitPolicy(policy RateLimitPolicy) Option {
+	return func(q *DelayQueue) {
+		q.pushLimitPolicy = policy
+	}
+}
+
+// WithMissedPolicy 设置恢复快照时对已经过期任务的处理策略，默认立即执行
+// grace 只在 MissedFireWithinGrace 策略下生效，过期不超过 grace 的任务仍会立即执行
+func WithMissedPolicy(policy MissedPolicy, grace time.Duration) Option {
+	return func(q *DelayQueue) {
+		q.missedPolicy = policy
+		q.missedGrace = grace
+	}
+}
+
+// OnRestoreSkipped 设置恢复快照时，过期任务被 WithMissedPolicy 的策略跳过后的回调，便于记录或转投
+func OnRestoreSkipped(fn func(task PendingTask)) Option {
+	return func(q *DelayQueue) {
+		q.onRestoreSkipped = fn
+	}
+}
+
+// WithAdmissionControl 设置推送任务时的准入控制
+// fn 在调用方的协程中、任务进入队列之前同步执行，返回非 nil 的错误表示拒绝该任务，按包文档中的返回约定告知调用方：
+// Push 等返回句柄的方法通过句柄的 Err 返回该错误，TryPush 等返回 error 的方法原样返回该错误，返回任务id的方法记录日志并返回空字符串
+func WithAdmissionControl(fn func(execTime time.Time) error) Option {
+	return func(q *DelayQueue) {
+		q.admission = fn
+	}
+}
+
+// WithMaxPending 设置等待执行的任务数量上限，达到上限后新的推送会被拒绝并返回 ErrQueueFull
+// 上限的检查不经过调度协程，并发推送时可能会少量超出
+func WithMaxPending(n int) Option {
+	return func(q *DelayQueue) {
+		q.maxPending = n
+	}
+}
+
+// WithExecutionLog 将每一次任务执行以一行 JSON 的格式写入 w，作为只追加的审计记录
+// 写入在后台协程中缓冲进行，不会阻塞任务的执行，缓冲区已满时记录会被丢弃；
+// 调用 CloseExecutionLog 可以将缓冲的记录全部写出
+func WithExecutionLog(w io.Writer) Option {
+	return func(q *DelayQueue) {
+		q.execLogWriter = w
+	}
+}
+
+// WithName 设置队列名称，日志、执行记录、Metrics、Stats 以及钩子收到的 TaskInfo 中会带上该名称，
+// 便于区分同一进程中的多个队列，也可以作为监控指标的标签
+func WithName(name string) Option {
+	return func(q *DelayQueue) {
+		q.name = name
+	}
+}
+
+// WithConsumerMode 开启消费者模式：到期的任务不再自动执行，而是等待通过 Next 领取后由调用方执行
+func WithConsumerMode() Option {
+	return func(q *DelayQueue) {
+		q.consumerMode = true
+	}
+}
+
+// WithVisibilityTimeout 开启消费者确认：通过 PopDue 或 Channel 交出的任务需要调用 Ack 确认，
+// 超过 d 仍未确认（或者调用了 Nack）的任务重新交给消费者。任务在确认之前保留在存储中，进程崩溃后重新加载时会再次交出
+func WithVisibilityTimeout(d time.Duration) Option {
+	return func(q *DelayQueue) {
+		q.visibilityTimeout = d
+	}
+}
+
+// WithHealthCheck 设置 Healthy 的检查条件：调度协程在 timeout 内没有响应，或者 add、remove 管道的占用比例
+// 达到 saturation（0 到 1 之间）时视为不健康；默认分别为 1 秒与 0.9
+func WithHealthCheck(timeout time.Duration, saturation float64) Option {
+	return func(q *DelayQueue) {
+		q.healthTimeout = timeout
+		q.healthSaturation = saturation
+	}
+}
+
+// WithoutLoopSupervision 关闭调度循环的监护：默认情况下调度循环因为队列内部的错误 panic 时，
+// 队列会恢复 panic、记录日志并重新进入调度循环；关闭后 panic 直接抛出，进程退出
+func WithoutLoopSupervision() Option {
+	return func(q *DelayQueue) {
+		q.noLoopSupervision = true
+	}
+}
+
+// OnLoopPanic 设置调度循环 panic 后重新启动时的回调，fn 会收到 recover 得到的值，可以用于告警
+// 回调在调度协程中调用，不能在其中调用队列的方法，否则会造成死锁
+func OnLoopPanic(fn func(recovered any)) Option {
+	return func(q *DelayQueue) {
+		q.onLoopPanic = fn
+	}
+}
+
+// WithWaitRemoveTTL 设置「待删除」记录的保留时长，默认 1 分钟，为 0 表示不清理
+// 执行期间被删除的任务 id 会被记录下来，拦截删除之前已经发出的重试或熔断推迟；没有后续执行时记录永远等不到匹配，
+// 超过 d 后被清理，避免无限累积。d 需要明显长于任务执行结束到后续执行被调度协程接收之间可能的延迟
+func WithWaitRemoveTTL(d time.Duration) Option {
+	return func(q *DelayQueue) {
+		q.waitRemoveTTL = d
+	}
+}
+
+// WithWaitRemoveLimit 设置「待删除」记录的数量上限，默认 10000，超出时清理最早的记录，为 0 表示不限制
+func WithWaitRemoveLimit(n int) Option {
+	return func(q *DelayQueue) {
+		q.waitRemoveLimit = n
+	}
+}
+
+// WithCircuitBreaker 为返回错误的任务开启熔断器，包括注册的处理函数、PushErrFunc、返回结果的任务、带上下文的任务与发布到主题的任务
+// 连续失败达到阈值后熔断器打开，期间到期的任务被短路（跳过或推迟）；冷却时间过后放行一个任务试探，
+// 试探成功则恢复，失败则重新熔断。其他类型的任务不受熔断器影响
+func WithCircuitBreaker(config CircuitBreakerConfig) Option {
+	return func(q *DelayQueue) {
+		q.breakerConfig = &config
+	}
+}
+
+// WithFairDispatch 开启按标签的加权公平调度
+// 大量不同标签的任务同时到期时，按权重在标签之间轮流分发，避免某一个标签的任务独占执行资源；
+// weights 中未出现的标签权重为 1，传入空的 map 表示所有标签权重相同
+func WithFairDispatch(weights map[string]int) Option {
+	return func(q *DelayQueue) {
+		q.fairWeights = make(map[string]int, len(weights))
+		for tag, weight := range weights {
+			q.fairWeights[tag] = weight
+		}
+	}
+}
+
+// WithStorage 设置持久化存储，具名处理函数任务在推送时保存、执行或删除后移除
+// 队列创建时会加载存储中的任务并重新安排执行，加载的过期任务按 WithMissedPolicy 设置的策略处理
+func WithStorage(storage Storage) Option {
+	return func(q *DelayQueue) {
+		q.storage = storage
+	}
+}
+
+// WithMaxConcurrency 限制同时执行的任务数量，到期的任务由 n 个固定的执行协程依次执行
+// 大量任务同时到期时，超出的任务按到期顺序排队，而不是各自开启一个协程；n <= 0 表示不限制
+func WithMaxConcurrency(n int) Option {
+	return func(q *DelayQueue) {
+		q.maxConcurrency = n
+	}
+}
+
+// WithDeadLetterStorage 设置死信任务的持久化存储，具名处理函数任务进入死信列表时保存、重新投递或丢弃时移除
+// 队列创建时会从存储中加载上次运行留下的死信任务；基于闭包的死信任务只保存在内存中
+func WithDeadLetterStorage(storage Storage) Option {
+	return func(q *DelayQueue) {
+		q.deadLetterStorage = storage
+	}
+}
+
+// WithResumePolicy 设置 Resume 恢复队列时，对暂停期间到期的任务的处理策略，默认立即执行
+func WithResumePolicy(policy ResumePolicy) Option {
+	return func(q *DelayQueue) {
+		q.resumePolicy = policy
+	}
+}
+
+// WithOverduePolicy 设置任务逾期时的处理策略：任务开始执行时超过执行时间 threshold 以上视为逾期
+// 默认策略 OverdueExecute 照常执行所有逾期任务
+func WithOverduePolicy(policy OverduePolicy, threshold time.Duration) Option {
+	return func(q *DelayQueue) {
+		q.overduePolicy = policy
+		q.overdueThreshold = threshold
+	}
+}
+
+// WithMaxLateness 设置任务到期时允许的最大延迟
+// 调度协程发现任务到期时已经超过执行时间 d 以上（长时间 GC 停顿、进程重新加载后恢复等），任务不再执行，
+// 交给 OnLate 设置的函数处理；与 WithOverduePolicy 不同，延迟在任务到期时计算，不包括在协程池中排队的时间
+func WithMaxLateness(d time.Duration) Option {
+	return func(q *DelayQueue) {
+		q.maxLateness = d
+	}
+}
+
+// OnLate 设置处理延迟过大的任务的函数，需要配合 WithMaxLateness 使用；不设置时延迟过大的任务被丢弃
+func OnLate(fn func(tc TaskContext)) Option {
+	return func(q *DelayQueue) {
+		q.onLate = fn
+	}
+}
+
+// WithElector 设置选主，多个副本共享同一个持久化存储时只有 leader 触发到期的任务
+// interval 为竞选与续期的间隔，<= 0 时为 1 秒；选主实现的租约时长需要明显长于 interval，
+// 否则 leader 可能在续期之前失去租约，短时间内出现两个实例同时触发
+func WithElector(e Elector, interval time.Duration) Option {
+	return func(q *DelayQueue) {
+		if interval <= 0 {
+			interval = time.Second
+		}
+		q.elector = e
+		q.electInterval = interval
+	}
+}
+
+// WithPublisher 设置发布消息的任务到期时使用的发布者，参见 PushPublish
+func WithPublisher(p Publisher) Option {
+	return func(q *DelayQueue) {
+		q.publisher = p
+	}
+}
+
+// WithResultStore 设置任务执行结果的存储，每次执行的开始时间、耗时、结果与错误都会被记录，
+// 可以通过 Results 与 RecentResults 查询；NewMemoryResultStore 提供只保留最近结果的内存实现
+func WithResultStore(s ResultStore) Option {
+	return func(q *DelayQueue) {
+		q.resultStore = s
+	}
+}
+
+// OnPush 设置任务推送成功的回调，在推送的协程中调用
+func OnPush(fn func(info TaskInfo)) Option {
+	return func(q *DelayQueue) {
+		q.onPush = fn
+	}
+}
+
+// OnExecute 设置任务开始执行的回调，在执行任务的协程中调用，重试与周期任务的每一次执行都会回调
+func OnExecute(fn func(info TaskInfo)) Option {
+	return func(q *DelayQueue) {
+		q.onExecute = fn
+	}
+}
+
+// OnDelete 设置等待执行的任务被删除的回调，Delete、DeleteBatch、DeleteFunc、Purge 等删除方式以及 ReplaceAll 替换旧任务时都会回调
+// 回调在调度协程中调用，不能在其中调用队列的方法，否则会造成死锁
+func OnDelete(fn func(info TaskInfo)) Option {
+	return func(q *DelayQueue) {
+		q.onDelete = fn
+	}
+}
+
+// OnDrop 设置任务没有执行就被丢弃的回调，reason 与结构化日志中的 reason 相同：
+// overdue（逾期）、late（到期时延迟过大）、single_flight（同 key 的任务正在执行）、paused（暂停期间到期）、
+// unique（与已有的去重任务冲突）、replaced（被同 id 的任务替换）、duplicate_id（指定的id已经存在）
+// 部分回调在调度协程中调用，不能在其中调用队列的方法，否则会造成死锁
+func OnDrop(fn func(info TaskInfo, reason string)) Option {
+	return func(q *DelayQueue) {
+		q.onDrop = fn
+	}
+}
+
+// WithHook 添加任务生命周期的观测钩子，可以多次设置，钩子按设置的顺序调用
+func WithHook(hook Hook) Option {
+	return func(q *DelayQueue) {
+		q.hooks = append(q.hooks, hook)
+	}
+}
+
+// WithAddBuffer 设置 add 管道的容量，默认 10000
+// 管道已满时 Push 会阻塞，TryPush 返回 ErrQueueFull；n <= 0 表示不缓冲，每次推送都要等待调度协程接收
+func WithAddBuffer(n int) Option {
+	return func(q *DelayQueue) {
+		if n < 0 {
+			n = 0
+		}
+		q.addBuffer = n
+	}
+}
+
+// WithRemoveBuffer 设置 remove 管道的容量，默认 100；n <= 0 表示不缓冲
+func WithRemoveBuffer(n int) Option {
+	return func(q *DelayQueue) {
+		if n < 0 {
+			n = 0
+		}
+		q.removeBuffer = n
+	}
+}
+
+// WithExpvar 创建队列时以 name 为名称通过 expvar 发布运行指标，见 PublishExpvar
+// 派生的队列（Partition、Clone）不会重复发布
+func WithExpvar(name string) Option {
+	return func(q *DelayQueue) {
+		q.expvarName = name
+	}
+}
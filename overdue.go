@@ -0,0 +1,35 @@
+package delayqueue
+
+import "time"
+
+// OverduePolicy 任务开始执行时已经超过执行时间一定阈值（逾期）时的处理策略
+// 进程负载过高、执行协程池排队或者从持久化存储恢复时，任务都可能逾期
+type OverduePolicy int
+
+const (
+	OverdueExecute    OverduePolicy = iota // 照常执行，默认策略
+	OverdueDrop                            // 丢弃，记录为 OutcomeDropped
+	OverdueDeadLetter                      // 放入死信列表，之后可以通过 Redrive 重新投递
+)
+
+// handleOverdue 按逾期策略处理任务，返回任务是否还需要执行
+func (q *DelayQueue) handleOverdue(t *task, currentTime time.Time) bool {
+	if q.overduePolicy == OverdueExecute {
+		return true
+	}
+
+	lateness := q.clock.Now().Sub(t.execTime)
+	if lateness <= q.overdueThreshold {
+		return true
+	}
+
+	if q.overduePolicy == OverdueDeadLetter {
+		q.deadLetter(t, t.extra().attempt, ErrOverdue)
+	} else {
+		q.logger.Printf("task %s dropped, overdue by %s", t.id, lateness)
+	}
+	q.logEvent(LevelWarn, "task dropped", "id", t.id, "reason", "overdue", "lateness", lateness)
+	q.fireDrop(t, "overdue")
+	q.logExecution(t, currentTime, OutcomeDropped, ErrOverdue)
+	return false
+}
@@ -0,0 +1,57 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOverduePolicy(t *testing.T) {
+	for name, policy := range map[string]OverduePolicy{
+		"execute":     OverdueExecute,
+		"drop":        OverdueDrop,
+		"dead letter": OverdueDeadLetter,
+	} {
+		t.Run(name, func(t *testing.T) {
+			q, clock := newTestQueue(t, WithOverduePolicy(policy, 10*time.Second))
+			ran := make(chan struct{}, 1)
+			h := q.Push(time.Second, func() { ran <- struct{}{} })
+
+			// 任务开始执行时已经超过执行时间 59 秒
+			clock.BlockUntil(1)
+			clock.Advance(time.Minute)
+			receive(t, h.Done())
+
+			executed := false
+			select {
+			case <-ran:
+				executed = true
+			default:
+			}
+			if want := policy == OverdueExecute; executed != want {
+				t.Errorf("executed = %v, want %v", executed, want)
+			}
+
+			letters := q.DeadLetters()
+			if policy != OverdueDeadLetter {
+				if len(letters) != 0 {
+					t.Errorf("DeadLetters = %v, want none", letters)
+				}
+				return
+			}
+			if len(letters) != 1 || letters[0].ID != h.ID() || letters[0].Error != ErrOverdue.Error() {
+				t.Errorf("DeadLetters = %+v, want %s with ErrOverdue", letters, h.ID())
+			}
+		})
+	}
+}
+
+func TestOverdueWithinThresholdExecutes(t *testing.T) {
+	q, clock := newTestQueue(t, WithOverduePolicy(OverdueDrop, 10*time.Second))
+	ran := make(chan struct{}, 1)
+	q.Push(time.Second, func() { ran <- struct{}{} })
+
+	// 延迟没有超过阈值，照常执行
+	clock.BlockUntil(1)
+	clock.Advance(5 * time.Second)
+	receive(t, ran)
+}
@@ -0,0 +1,17 @@
+package delayqueue
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// recoverTask 恢复执行函数中的 panic，交给 OnPanic 设置的回调处理，返回 panic 对应的错误
+// 需要在执行任务的协程中通过 defer 调用
+func (q *DelayQueue) recoverTask(task *task, r any) error {
+	if q.onPanic != nil {
+		q.onPanic(task.id, task.payload(), r)
+	} else {
+		q.logger.Printf("task %s panic: %v\n%s", task.id, r, debug.Stack())
+	}
+	return fmt.Errorf("panic: %v", r)
+}
@@ -0,0 +1,49 @@
+package delayqueue
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type recoveredPanic struct {
+	id        string
+	payload   string
+	recovered any
+}
+
+func TestOnPanic(t *testing.T) {
+	panics := make(chan recoveredPanic, 2)
+	errBoom := errors.New("boom")
+	q, clock := newTestQueue(t,
+		WithHandler("charge", func([]byte) { panic("charge failed") }),
+		OnPanic(func(id string, payload []byte, recovered any) {
+			panics <- recoveredPanic{id: id, payload: string(payload), recovered: recovered}
+		}),
+	)
+
+	closure := q.Push(time.Second, func() { panic(errBoom) }).ID()
+	fireNext(clock, time.Second)
+	p := receive(t, panics)
+	if p.id != closure || p.payload != "" || p.recovered != errBoom {
+		t.Errorf("OnPanic got %+v, want id %s, no payload and %v", p, closure, errBoom)
+	}
+
+	// 具名处理函数任务同时收到任务数据
+	named := q.PushHandler(time.Second, "charge", []byte("order-42"))
+	fireNext(clock, time.Second)
+	p = receive(t, panics)
+	if p.id != named || p.payload != "order-42" || p.recovered != "charge failed" {
+		t.Errorf("OnPanic got %+v, want id %s, payload order-42 and charge failed", p, named)
+	}
+
+	// panic 之后队列照常分发任务
+	ran := make(chan struct{}, 1)
+	q.Push(time.Second, func() { ran <- struct{}{} })
+	fireNext(clock, time.Second)
+	receive(t, ran)
+	waitFor(t, "both panics to be counted as failures", func() bool { return q.Metrics().Failed == 2 })
+	if n := q.Metrics().LoopRestarts; n != 0 {
+		t.Errorf("LoopRestarts = %d, want 0", n)
+	}
+}
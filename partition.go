@@ -0,0 +1,144 @@
+package delayqueue
+
+// Partition 将当前队列中所有等待执行的任务按 keyFn 分配到 n 个新队列中，并清空当前队列
+// keyFn 根据任务 id 返回目标队列的下标，超出 [0, n) 的结果会按 n 取模；
+// 新队列沿用当前队列的配置与已注册的具名处理函数，任务保持原有的 id、执行时间与执行函数，
+// 每个任务只会被移动到一个新队列中，不会重复执行；任务的句柄随任务转移，在新队列中执行结束时关闭 Done；
+// 暂停、扣留的任务在新队列中保持暂停与扣留，暂停中的标签与子队列在新队列中同样暂停。
+// 设置了 WithElector 时新队列不单独竞选，随当前队列切换 leader 身份，成为 leader 时只重新加载 keyFn 分配到自己的任务，
+// 当前队列需要保持运行；之后推送到当前队列、保存在存储中的任务在重新加载时同样按 keyFn 由新队列加载
+func (q *DelayQueue) Partition(n int, keyFn func(id string) int) []*DelayQueue {
+	if n <= 0 {
+		return nil
+	}
+
+	index := func(id string) int {
+		i := keyFn(id) % n
+		if i < 0 {
+			i += n
+		}
+		return i
+	}
+
+	parts := make([]pendingLists, n)
+	var (
+		pauses pausedSets
+		owns   func(id string) bool
+	)
+	q.do(func() {
+		pauses = q.pausedSets()
+		lists := q.detachTasks()
+		split := func(tasks []*task, list func(p *pendingLists) *[]*task) {
+			for _, t := range tasks {
+				l := list(&parts[index(t.id)])
+				*l = append(*l, t)
+			}
+		}
+		split(lists.ready, func(p *pendingLists) *[]*task { return &p.ready })
+		split(lists.held, func(p *pendingLists) *[]*task { return &p.held })
+		split(lists.scheduled, func(p *pendingLists) *[]*task { return &p.scheduled })
+		split(lists.paused, func(p *pendingLists) *[]*task { return &p.paused })
+
+		// 存储中的任务从此由新队列按分配加载，当前队列重新加载存储时不再加载任何任务
+		owns = q.owns
+		q.owns = func(string) bool { return false }
+	})
+
+	queues := make([]*DelayQueue, n)
+	for i := range queues {
+		i := i
+		queues[i] = q.derive(func(nq *DelayQueue) {
+			nq.owns = func(id string) bool {
+				return (owns == nil || owns(id)) && index(id) == i
+			}
+		})
+		queues[i].adopt(parts[i], pauses)
+		q.follow(queues[i])
+	}
+	return queues
+}
+
+// derive 创建沿用当前队列的配置与已注册具名处理函数的新队列，extra 中的配置在原有配置之后生效
+// 派生的队列不会重复加载存储中的任务，任务由调用方转移过来；也不会重复通过 expvar 发布运行指标，执行记录与原队列共用同一个输出；
+// 设置了 WithElector 时不单独竞选，调用方转移完任务之后通过 follow 让它随原队列切换 leader 身份
+func (q *DelayQueue) derive(extra ...Option) *DelayQueue {
+	opts := make([]Option, 0, len(q.opts)+len(extra)+1)
+	opts = append(opts, q.opts...)
+	opts = append(opts, extra...)
+	opts = append(opts, func(nq *DelayQueue) {
+		nq.skipLoadStore = true
+		nq.expvarName = ""
+		nq.electExternal = true
+		if q.execLog != nil {
+			// 与原队列共用执行记录的输出，避免两个缓冲同时写同一个 io.Writer
+			nq.execLog = q.execLog.acquire()
+		}
+	})
+	nq := NewDelayQueue(opts...)
+
+	q.handlersMu.RLock()
+	defer q.handlersMu.RUnlock()
+	for name, fn := range q.handlers {
+		nq.RegisterHandlerContext(name, fn)
+	}
+	return nq
+}
+
+// pausedSets 暂停中的标签与子队列
+type pausedSets struct {
+	tags   map[string]struct{}
+	topics map[string]struct{}
+}
+
+// pausedSets 复制当前暂停中的标签与子队列
+func (q *DelayQueue) pausedSets() pausedSets {
+	p := pausedSets{
+		tags:   make(map[string]struct{}, len(q.pausedTags)),
+		topics: make(map[string]struct{}, len(q.pausedTopics)),
+	}
+	for tag := range q.pausedTags {
+		p.tags[tag] = struct{}{}
+	}
+	for topic := range q.pausedTopics {
+		p.topics[topic] = struct{}{}
+	}
+	return p
+}
+
+// adopt 将从其他队列转移过来的任务加入当前队列，任务的句柄与控制句柄会重新绑定到当前队列上
+// 任务放回与原队列相同的列表：暂停的任务保留剩余的等待时间，仍然需要 ResumeTask 恢复；
+// 扣留与等待领取的任务保持到期的状态。原队列暂停中的标签与子队列在当前队列中同样暂停，扣留的任务由 ResumeTag 等放行
+func (q *DelayQueue) adopt(lists pendingLists, pauses pausedSets) {
+	for _, t := range lists.all() {
+		if t.handle != nil {
+			t.handle.q.Store(q)
+		}
+		if t.extra().ctl != nil {
+			ctl := newTaskControl(q, t.id)
+			ctl.canceled.Store(t.extra().ctl.Canceled())
+			t.ensureExtra().ctl = ctl
+		}
+	}
+
+	q.do(func() {
+		for tag := range pauses.tags {
+			q.pausedTags[tag] = struct{}{}
+		}
+		for topic := range pauses.topics {
+			q.pausedTopics[topic] = struct{}{}
+		}
+		for _, t := range lists.scheduled {
+			q.addTask(t)
+		}
+		for _, t := range lists.held {
+			q.heldTasks = append(q.heldTasks, t)
+			q.indexTask(t)
+		}
+		// 等待领取的任务不在索引中
+		q.readyTasks = append(q.readyTasks, lists.ready...)
+		for _, t := range lists.paused {
+			q.pausedTasks = append(q.pausedTasks, t)
+			q.indexTask(t)
+		}
+	})
+}
@@ -0,0 +1,174 @@
+package delayqueue
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPartitionMovesHandles(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	ran := make(chan string, 2)
+	a := q.Push(time.Second, func() { ran <- "a" })
+	b := q.Push(2*time.Second, func() { ran <- "b" })
+
+	queues := q.Partition(2, func(id string) int { return 0 })
+	for _, nq := range queues {
+		nq := nq
+		t.Cleanup(func() { stopQueue(t, nq) })
+	}
+	for _, h := range []*Task{a, b} {
+		select {
+		case <-h.Done():
+			t.Fatalf("task %s done after Partition, want pending", h.ID())
+		default:
+		}
+	}
+	if n := queues[0].Len(); n != 2 {
+		t.Fatalf("partition 0 has %d tasks, want 2", n)
+	}
+
+	// 句柄随任务转移，可以在新队列中取消任务
+	if err := b.Cancel(); err != nil {
+		t.Fatalf("Cancel moved task: %v", err)
+	}
+	receive(t, b.Done())
+
+	fireNext(clock, time.Second)
+	if got := receive(t, ran); got != "a" {
+		t.Errorf("executed %q, want a", got)
+	}
+	receive(t, a.Done())
+}
+
+func TestPartitionKeepsPausedTask(t *testing.T) {
+	testPausedTaskMoves(t, func(q *DelayQueue) *DelayQueue {
+		return q.Partition(1, func(id string) int { return 0 })[0]
+	})
+}
+
+// testPausedTaskMoves 暂停任务后由 move 转移或复制到新队列，任务在新队列中保持暂停，恢复后从剩余的等待时间继续倒计时
+func testPausedTaskMoves(t *testing.T, move func(q *DelayQueue) *DelayQueue) {
+	t.Helper()
+	q, clock := newTestQueue(t)
+
+	ran := make(chan string, 2)
+	h := q.Push(10*time.Second, func() { ran <- "paused" })
+	if err := q.PauseTask(h.ID()); err != nil {
+		t.Fatalf("PauseTask: %v", err)
+	}
+
+	nq := move(q)
+	t.Cleanup(func() { stopQueue(t, nq) })
+	nq.Push(15*time.Second, func() { ran <- "sentinel" })
+
+	// 越过暂停任务原来的执行时间，只有哨兵任务执行
+	fireNext(clock, 20*time.Second)
+	if got := receive(t, ran); got != "sentinel" {
+		t.Fatalf("executed %q, want sentinel", got)
+	}
+	if err := nq.ResumeTask(h.ID()); err != nil {
+		t.Fatalf("ResumeTask in new queue: %v", err)
+	}
+
+	fireNext(clock, 10*time.Second)
+	if got := receive(t, ran); got != "paused" {
+		t.Errorf("executed %q, want paused", got)
+	}
+}
+
+func TestPartitionElectionFiresOnce(t *testing.T) {
+	storage, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		pt := PendingTask{ID: fmt.Sprintf("task-%d", i), ExecTime: testStart.Add(time.Hour), Handler: "h"}
+		if err := storage.Save(pt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var runs atomic.Int32
+	ran := make(chan struct{}, 20)
+	clock := NewManualClock(testStart)
+	e := &signalElector{calls: make(chan struct{}, 1)}
+	q := NewDelayQueue(WithClock(clock), WithStorage(storage), WithElector(e, time.Second),
+		WithHandler("h", func([]byte) {
+			runs.Add(1)
+			ran <- struct{}{}
+		}))
+	receive(t, e.calls)
+
+	queues := q.Partition(3, func(id string) int {
+		i, _ := strconv.Atoi(strings.TrimPrefix(id, "task-"))
+		return i
+	})
+
+	// 成为 leader 后经过多次续期，每个任务仍然只在分配到的新队列中存在一份，原队列不再加载任何任务
+	e.leader.Store(true)
+	for i := 0; i < 3; i++ {
+		nextCampaign(t, clock, e)
+	}
+	total := q.Len()
+	for i, nq := range queues {
+		if !nq.IsLeader() {
+			t.Errorf("partition %d is not leader", i)
+		}
+		if n := nq.Len(); n != 2 {
+			t.Errorf("partition %d has %d tasks, want 2", i, n)
+		}
+		total += nq.Len()
+	}
+	if total != 6 {
+		t.Fatalf("total pending = %d, want 6", total)
+	}
+
+	clock.Set(testStart.Add(time.Hour))
+	for i := 0; i < 6; i++ {
+		receive(t, ran)
+	}
+	for _, nq := range queues {
+		stopQueue(t, nq)
+	}
+	stopQueue(t, q)
+	if n := runs.Load(); n != 6 {
+		t.Errorf("handler ran %d times, want 6", n)
+	}
+}
+
+func TestPartitionFollowsSourceLeadership(t *testing.T) {
+	e := &signalElector{calls: make(chan struct{}, 1)}
+	e.leader.Store(true)
+	q, clock := newTestQueue(t, WithElector(e, time.Second))
+	receive(t, e.calls)
+	nextCampaign(t, clock, e)
+
+	nq := q.Partition(1, func(id string) int { return 0 })[0]
+	t.Cleanup(func() { stopQueue(t, nq) })
+	if !nq.IsLeader() {
+		t.Fatal("partition is not leader while source is leader")
+	}
+
+	// 原队列失去 leader 身份时新队列一起回到待命，原队列停止后新队列同样待命
+	e.leader.Store(false)
+	nextCampaign(t, clock, e)
+	nextCampaign(t, clock, e)
+	if nq.IsLeader() {
+		t.Fatal("partition is still leader after source lost leadership")
+	}
+	e.leader.Store(true)
+	nextCampaign(t, clock, e)
+	nextCampaign(t, clock, e)
+	if !nq.IsLeader() {
+		t.Fatal("partition did not follow source back to leader")
+	}
+	stopQueue(t, q)
+	if nq.IsLeader() {
+		t.Error("partition is still leader after source stopped")
+	}
+}
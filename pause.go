@@ -0,0 +1,98 @@
+package delayqueue
+
+import "time"
+
+// ResumePolicy 恢复队列时，对暂停期间到期的任务的处理策略
+type ResumePolicy int
+
+const (
+	ResumeFire  ResumePolicy = iota // 立即执行，默认策略
+	ResumeDrop                      // 丢弃，周期任务跳过错过的执行，从下一次执行开始恢复
+	ResumeDelay                     // 所有任务整体推迟暂停的时长，相当于暂停期间时间停止流逝
+)
+
+// Pause 暂停队列：不再触发到期的任务，推送与删除照常进行，重复调用是安全的
+// 暂停期间到期的任务在 Resume 时按 WithResumePolicy 设置的策略处理；已经到期、等待领取或被扣留的任务不受影响
+func (q *DelayQueue) Pause() {
+	q.do(func() {
+		if q.paused {
+			return
+		}
+		q.paused = true
+		q.pausedAt = q.clock.Now()
+	})
+}
+
+// Resume 恢复被 Pause 暂停的队列，队列没有暂停时不做任何处理
+func (q *DelayQueue) Resume() {
+	q.do(func() {
+		if !q.paused {
+			return
+		}
+		q.paused = false
+
+		now := q.clock.Now()
+		switch q.resumePolicy {
+		case ResumeDrop:
+			q.dropDue(now)
+		case ResumeDelay:
+			q.shiftTasks(now.Sub(q.pausedAt))
+		}
+	})
+}
+
+// IsPaused 判断队列是否处于暂停状态
+func (q *DelayQueue) IsPaused() bool {
+	var paused bool
+	q.do(func() {
+		paused = q.paused
+	})
+	return paused
+}
+
+// dropDue 丢弃所有已经到期的任务，周期任务安排下一次执行
+func (q *DelayQueue) dropDue(now time.Time) {
+	q.advanceWheel(now)
+	for len(q.tasks) > 0 && !q.tasks[0].execTime.After(now) {
+		t := q.tasks[0]
+		q.endTask()
+		q.unindexTask(t)
+		q.forget(t.id)
+		q.logEvent(LevelWarn, "task dropped", "id", t.id, "reason", "paused")
+		q.fireDrop(t, "paused")
+		if next := q.reschedule(t); next == nil {
+			t.complete()
+		} else if err := q.persist(next); err != nil {
+			q.logger.Printf("save task %s to storage failed: %v", t.id, err)
+		}
+	}
+}
+
+// shiftTasks 将任务列表中所有任务的执行时间推迟 d，所有任务同时平移，堆的顺序保持不变
+// 先平移任务堆中的任务，时间轮中的任务之后再重新放置，期间任务堆的顺序始终有效
+func (q *DelayQueue) shiftTasks(d time.Duration) {
+	for _, t := range q.tasks {
+		t.execTime = t.execTime.Add(d)
+		if !t.extra().expireTime.IsZero() {
+			t.ensureExtra().expireTime = t.extra().expireTime.Add(d)
+		}
+		if err := q.persist(t); err != nil {
+			q.logger.Printf("save task %s to storage failed: %v", t.id, err)
+		}
+	}
+
+	if q.wheel == nil {
+		return
+	}
+	// 时间轮中的任务平移后所在的槽位发生了变化，需要重新放置
+	for _, t := range q.wheel.drain() {
+		t.execTime = t.execTime.Add(d)
+		if !t.extra().expireTime.IsZero() {
+			t.ensureExtra().expireTime = t.extra().expireTime.Add(d)
+		}
+		if err := q.persist(t); err != nil {
+			q.logger.Printf("save task %s to storage failed: %v", t.id, err)
+		}
+		q.placeTask(t)
+	}
+}
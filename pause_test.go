@@ -0,0 +1,54 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPauseResumePolicies(t *testing.T) {
+	for name, policy := range map[string]ResumePolicy{
+		"fire":  ResumeFire,
+		"drop":  ResumeDrop,
+		"delay": ResumeDelay,
+	} {
+		t.Run(name, func(t *testing.T) {
+			q, clock := newTestQueue(t, WithResumePolicy(policy))
+			ran := make(chan struct{}, 1)
+			h := q.Push(time.Second, func() { ran <- struct{}{} })
+
+			q.Pause()
+			if !q.IsPaused() {
+				t.Fatal("IsPaused = false after Pause")
+			}
+			// 暂停期间不设置计时器，任务到期也不会执行
+			clock.Set(testStart.Add(5 * time.Second))
+			settle(q)
+			select {
+			case <-ran:
+				t.Fatal("task ran while the queue was paused")
+			default:
+			}
+
+			q.Resume()
+			switch policy {
+			case ResumeFire:
+				receive(t, ran)
+			case ResumeDrop:
+				receive(t, h.Done())
+				select {
+				case <-ran:
+					t.Error("dropped task ran")
+				default:
+				}
+			case ResumeDelay:
+				// 整体推迟暂停的 5 秒
+				info, ok := q.Get(h.ID())
+				if want := testStart.Add(6 * time.Second); !ok || !info.ExecTime.Equal(want) {
+					t.Fatalf("exec time after Resume = %v, %v, want %v", info.ExecTime, ok, want)
+				}
+				fireNext(clock, time.Second)
+				receive(t, ran)
+			}
+		})
+	}
+}
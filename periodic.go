@@ -0,0 +1,188 @@
+package delayqueue
+
+import (
+	"container/heap"
+	"time"
+)
+
+// PushPeriodicWithTTL 用户推送带有存活时间的周期任务
+// 任务自推送时刻起，每隔 period 执行一次，直到 ttl 耗尽后自动停止；期间可以通过 Delete 提前停止
+// 边界说明：存活区间为左闭右开，执行时间恰好等于「推送时刻 + ttl」的那一次不会执行；
+// 因此 0 < ttl <= period 时第一次执行就已经超出存活时间，任务不会被推送，返回空的任务id；ttl <= 0 表示永不过期
+func (q *DelayQueue) PushPeriodicWithTTL(period time.Duration, ttl time.Duration, f func(), opts ...PushOption) string {
+	if period <= 0 {
+		panic("delayqueue: non-positive period for PushPeriodicWithTTL")
+	}
+
+	t := q.newPushTask(period)
+	t.fn = f
+	ext := t.ensureExtra()
+	ext.period = period
+	ext.expireTime = expireAfter(t.pushTime, ttl)
+	t.apply(opts)
+
+	if !t.alive(t.execTime) {
+		// 第一次执行就已经超出了存活时间，任务不会被执行
+		q.logger.Printf("push task %s rejected: ttl %v does not cover the first run after %v", t.id, ttl, period)
+		return ""
+	}
+
+	return q.submit(t)
+}
+
+// RepeatOption 重复任务的可选配置，与 PushOption 是同一类型，可以和其他推送配置一起传给 PushRepeating
+type RepeatOption = PushOption
+
+// WithMaxRepeats 设置重复任务最多执行 n 次，执行满 n 次后自动结束；n <= 0 表示不限次数
+func WithMaxRepeats(n int) RepeatOption {
+	return func(t *task) {
+		if n > 0 {
+			t.ensureExtra().remaining = n
+		}
+	}
+}
+
+// PushRepeating 用户推送重复执行的任务，任务自推送时刻起每隔 interval 执行一次，直到被 Delete 删除
+// 任务每次执行后由队列自动安排下一次执行，始终使用同一个任务id，删除一次即可停止后续所有执行
+func (q *DelayQueue) PushRepeating(interval time.Duration, f func(), opts ...PushOption) string {
+	if interval <= 0 {
+		panic("delayqueue: non-positive interval for PushRepeating")
+	}
+
+	t := q.newPushTask(interval)
+	t.fn = f
+	t.ensureExtra().period = interval
+	return q.submit(t.apply(opts))
+}
+
+// expireAfter 返回自 now 起存活 ttl 的周期任务的过期时间，ttl <= 0 表示永不过期，返回零值
+func expireAfter(now time.Time, ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return now.Add(ttl)
+}
+
+// alive 判断周期任务在指定的执行时间是否仍然存活
+func (t *task) alive(execTime time.Time) bool {
+	return t.extra().expireTime.IsZero() || execTime.Before(t.extra().expireTime)
+}
+
+// reschedule 周期任务执行后，计算下一次执行时间并重新加入任务列表，返回下一次执行，没有下一次执行时返回 nil
+// 下一次执行由调用方保存到存储中
+func (q *DelayQueue) reschedule(t *task) *task {
+	if (t.extra().period <= 0 && t.extra().cron == nil) || t.extra().remaining == 1 {
+		// 一次性任务，或者已经执行满最大次数的重复任务
+		return nil
+	}
+
+	// 以上一次的计划执行时间为基准累加，避免误差累积
+	execTime := t.execTime.Add(t.extra().period)
+	if t.extra().cron != nil {
+		execTime = t.extra().cron.next(t.execTime)
+	}
+	if execTime.IsZero() || !t.alive(execTime) {
+		// 超出存活时间，周期任务自然结束
+		return nil
+	}
+
+	// 下一次执行的停留时间从本次到期开始计算
+	next := t.copy()
+	next.execTime = execTime
+	next.pushTime = t.execTime
+	if t.extra().remaining > 0 {
+		next.ensureExtra().remaining = t.extra().remaining - 1
+	}
+	q.addTask(next)
+	return next
+}
+
+// AlignPeriodic 将所有周期任务的下一次执行时间对齐到 boundary 的整数倍上
+// 对齐后的时间为当前时间之后最近的整数倍时刻，周期保持不变，一次性任务不受影响
+// 例如 boundary 为 time.Minute 时，周期任务会在整分钟执行，便于合并同一时刻的执行；
+// 因标签暂停而被扣留的周期任务同样对齐，不再立即执行；通过 PauseTask 暂停的周期任务对齐剩余的等待时间，恢复后在对齐的时刻执行。
+// 对齐后的执行时间会保存到存储中，对齐后超出存活时间的任务从存储中移除
+func (q *DelayQueue) AlignPeriodic(boundary time.Duration) {
+	if boundary <= 0 {
+		return
+	}
+
+	var expired []string
+	q.do(func() {
+		now := q.clock.Now()
+		execTime := now.Truncate(boundary).Add(boundary)
+		if q.wheel != nil {
+			// 时间轮中的任务先全部移入任务堆，与堆中的任务一起调整后重新建堆
+			for _, t := range q.wheel.drain() {
+				t.index = len(q.tasks)
+				q.tasks = append(q.tasks, t)
+			}
+		}
+
+		// align 对齐周期任务的执行时间，返回任务是否仍然存活
+		align := func(t *task) bool {
+			if !t.alive(execTime) {
+				// 对齐后超出了存活时间，周期任务结束
+				t.index = -1
+				q.unindexTask(t)
+				t.complete()
+				expired = append(expired, t.id)
+				return false
+			}
+			if t.extra().paused {
+				t.ensureExtra().pauseLeft = execTime.Sub(now)
+			}
+			t.execTime = execTime
+			if err := q.persist(t); err != nil {
+				q.logger.Printf("save task %s to storage failed: %v", t.id, err)
+			}
+			return true
+		}
+
+		tasks := q.tasks[:0]
+		for _, t := range q.tasks {
+			if t.extra().period > 0 && !align(t) {
+				continue
+			}
+			t.index = len(tasks)
+			tasks = append(tasks, t)
+		}
+
+		// 被扣留的周期任务对齐后还没有到期，回到任务堆中
+		held := q.heldTasks[:0]
+		for _, t := range q.heldTasks {
+			if t.extra().period <= 0 {
+				held = append(held, t)
+				continue
+			}
+			if align(t) {
+				t.index = len(tasks)
+				tasks = append(tasks, t)
+			}
+		}
+		for i := len(held); i < len(q.heldTasks); i++ {
+			q.heldTasks[i] = nil
+		}
+		q.heldTasks = held
+
+		paused := q.pausedTasks[:0]
+		for _, t := range q.pausedTasks {
+			if t.extra().period > 0 && !align(t) {
+				continue
+			}
+			paused = append(paused, t)
+		}
+		for i := len(paused); i < len(q.pausedTasks); i++ {
+			q.pausedTasks[i] = nil
+		}
+		q.pausedTasks = paused
+
+		// 执行时间发生了变化，重新建堆
+		q.tasks = tasks
+		heap.Init(&q.tasks)
+	})
+
+	for _, id := range expired {
+		q.forget(id)
+	}
+}
@@ -0,0 +1,169 @@
+package delayqueue
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestAlignPeriodic(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	// 从不在整分钟上的时刻开始推送
+	clock.Set(testStart.Add(7 * time.Second))
+	ran := make(chan time.Time, 1)
+	periodic := q.PushRepeating(10*time.Second, func() { ran <- clock.Now() })
+	once := q.Push(6*time.Second, func() {}).ID()
+
+	q.AlignPeriodic(time.Minute)
+
+	for _, info := range q.Tasks() {
+		switch info.ID {
+		case periodic:
+			if want := testStart.Add(time.Minute); !info.ExecTime.Equal(want) {
+				t.Errorf("periodic task next fire = %v, want %v", info.ExecTime, want)
+			}
+			if info.Period != 10*time.Second {
+				t.Errorf("periodic task period = %v, want 10s", info.Period)
+			}
+		case once:
+			if want := testStart.Add(13 * time.Second); !info.ExecTime.Equal(want) {
+				t.Errorf("one-shot task exec time = %v, want unchanged %v", info.ExecTime, want)
+			}
+		}
+	}
+
+	// 对齐之后按原有的周期继续执行
+	for _, want := range []time.Time{testStart.Add(time.Minute), testStart.Add(70 * time.Second)} {
+		clock.BlockUntil(1)
+		clock.Set(want)
+		if at := receive(t, ran); !at.Equal(want) {
+			t.Errorf("periodic task fired at %v, want %v", at, want)
+		}
+	}
+}
+
+func TestAlignPeriodicHeldPausedAndStored(t *testing.T) {
+	storage := newTestStorage(t)
+	ran := make(chan string, 4)
+	q, clock := newTestQueue(t, WithStorage(storage), WithHandler("tick", func(payload []byte) {
+		ran <- string(payload)
+	}))
+
+	q.Restore([]PendingTask{
+		{ID: "held", ExecTime: testStart.Add(time.Second), Handler: "tick", Payload: []byte("held"), Tag: "report", Period: 10 * time.Second},
+		{ID: "paused", ExecTime: testStart.Add(20 * time.Second), Handler: "tick", Payload: []byte("paused"), Period: 10 * time.Second},
+		{ID: "expiring", ExecTime: testStart.Add(30 * time.Second), Handler: "tick", Payload: []byte("expiring"), Period: 10 * time.Second},
+	})
+	q.do(func() {
+		q.taskIndex["expiring"].ensureExtra().expireTime = testStart.Add(40 * time.Second)
+	})
+	q.PauseTag("report")
+	sentinel := make(chan struct{})
+	q.Push(2*time.Second, func() { close(sentinel) })
+
+	// held 到期后被扣留，paused 被暂停
+	fireNext(clock, 2*time.Second)
+	receive(t, sentinel)
+	if err := q.PauseTask("paused"); err != nil {
+		t.Fatal(err)
+	}
+
+	q.AlignPeriodic(time.Minute)
+
+	want := testStart.Add(time.Minute)
+	for _, id := range []string{"held", "paused"} {
+		pt, err := storage.Load(id)
+		if err != nil {
+			t.Fatalf("load %s: %v", id, err)
+		}
+		if !pt.ExecTime.Equal(want) {
+			t.Errorf("saved exec time of %s = %v, want %v", id, pt.ExecTime, want)
+		}
+	}
+	if _, err := storage.Load("expiring"); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("load expired task error = %v, want ErrTaskNotFound", err)
+	}
+
+	// 恢复之后两个任务都等到对齐的时刻才执行
+	q.ResumeTag("report")
+	if err := q.ResumeTask("paused"); err != nil {
+		t.Fatal(err)
+	}
+	clock.BlockUntil(1)
+	settle(q)
+	select {
+	case got := <-ran:
+		t.Fatalf("%s ran before the aligned time", got)
+	default:
+	}
+
+	clock.Set(want)
+	got := map[string]bool{receive(t, ran): true, receive(t, ran): true}
+	if !got["held"] || !got["paused"] {
+		t.Errorf("ran %v at the aligned time, want held and paused", got)
+	}
+}
+
+func TestPushPeriodicWithTTLFiresInsideWindow(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	ran := make(chan time.Time, 4)
+	id := q.PushPeriodicWithTTL(10*time.Second, 35*time.Second, func() { ran <- clock.Now() })
+	if id == "" {
+		t.Fatal("PushPeriodicWithTTL returned an empty id")
+	}
+
+	// 存活区间 [0, 35s) 内执行 3 次
+	for i := 1; i <= 3; i++ {
+		fireNext(clock, 10*time.Second)
+		if at, want := receive(t, ran), testStart.Add(time.Duration(i)*10*time.Second); !at.Equal(want) {
+			t.Errorf("run %d fired at %v, want %v", i, at, want)
+		}
+	}
+	settle(q)
+	if n := q.Len(); n != 0 {
+		t.Errorf("Len after TTL = %d, want 0", n)
+	}
+
+	clock.Advance(time.Minute)
+	stopQueue(t, q)
+	if n := len(ran); n != 0 {
+		t.Errorf("task fired %d times after its TTL", n)
+	}
+}
+
+func TestPushPeriodicWithTTLTooShort(t *testing.T) {
+	q, _ := newTestQueue(t)
+
+	for _, ttl := range []time.Duration{time.Nanosecond, 5 * time.Second, 10 * time.Second} {
+		if id := q.PushPeriodicWithTTL(10*time.Second, ttl, func() {}); id != "" {
+			t.Errorf("PushPeriodicWithTTL(ttl=%v) = %q, want empty id", ttl, id)
+		}
+	}
+	if n := q.Len(); n != 0 {
+		t.Errorf("Len = %d, want 0", n)
+	}
+}
+
+func TestPeriodicNonPositiveTTLNeverExpires(t *testing.T) {
+	// PushPeriodicWithTTL 与 PushPeriodicWithControl 对 ttl <= 0 的含义相同，都是永不过期
+	for _, ttl := range []time.Duration{-time.Second, 0} {
+		q, clock := newTestQueue(t)
+
+		ran := make(chan string, 2)
+		if id := q.PushPeriodicWithTTL(time.Second, ttl, func() { ran <- "ttl" }); id == "" {
+			t.Fatalf("PushPeriodicWithTTL(ttl=%v) returned an empty id", ttl)
+		}
+		if id := q.PushPeriodicWithControl(time.Second, ttl, func(*TaskControl) { ran <- "control" }); id == "" {
+			t.Fatalf("PushPeriodicWithControl(ttl=%v) returned an empty id", ttl)
+		}
+		for i := 0; i < 3; i++ {
+			fireNext(clock, time.Second)
+			got := map[string]bool{receive(t, ran): true, receive(t, ran): true}
+			if !got["ttl"] || !got["control"] {
+				t.Fatalf("ttl=%v run %d executed %v, want both tasks", ttl, i, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package delayqueue
+
+import "sync"
+
+// workerPool 固定数量的执行协程，到期的任务排队等待空闲的执行协程
+// 排队的任务没有上限，调度协程提交任务时永远不会阻塞
+type workerPool struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	jobs   []func() // 等待执行的任务，按提交顺序排列
+	closed bool
+}
+
+// newWorkerPool 创建并启动 n 个执行协程
+func newWorkerPool(n int) *workerPool {
+	p := &workerPool{}
+	p.cond = sync.NewCond(&p.mu)
+	for i := 0; i < n; i++ {
+		go p.work()
+	}
+	return p
+}
+
+// submit 提交一个任务
+func (p *workerPool) submit(job func()) {
+	p.mu.Lock()
+	p.jobs = append(p.jobs, job)
+	p.mu.Unlock()
+	p.cond.Signal()
+}
+
+// queued 返回排队等待执行的任务数量
+func (p *workerPool) queued() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.jobs)
+}
+
+// close 关闭执行协程池，已经提交的任务会全部执行完之后执行协程才退出
+func (p *workerPool) close() {
+	p.mu.Lock()
+	p.closed = true
+	p.mu.Unlock()
+	p.cond.Broadcast()
+}
+
+// work 执行协程的主循环
+func (p *workerPool) work() {
+	for {
+		p.mu.Lock()
+		for len(p.jobs) == 0 && !p.closed {
+			p.cond.Wait()
+		}
+		if len(p.jobs) == 0 {
+			p.mu.Unlock()
+			return
+		}
+		job := p.jobs[0]
+		p.jobs[0] = nil
+		p.jobs = p.jobs[1:]
+		p.mu.Unlock()
+
+		job()
+	}
+}
+
+// QueuedCount 返回已经到期、正在排队等待执行协程的任务数量，未设置 WithMaxConcurrency 时始终为 0
+func (q *DelayQueue) QueuedCount() int {
+	if q.pool == nil {
+		return 0
+	}
+	return q.pool.queued()
+}
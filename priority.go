@@ -0,0 +1,11 @@
+package delayqueue
+
+import "time"
+
+// PushPriority 用户推送带有优先级的任务
+// 执行时间相同的任务按优先级从高到低执行，优先级相同时按推送的先后顺序执行；普通推送的任务优先级为 0
+func (q *DelayQueue) PushPriority(priority int, timeInterval time.Duration, f func(), opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.fn = f
+	return q.submit(t.apply([]PushOption{WithPriority(priority)}).apply(opts))
+}
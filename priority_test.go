@@ -0,0 +1,31 @@
+package delayqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPushPriorityOrder(t *testing.T) {
+	q, clock := newTestQueue(t, WithConsumerMode())
+
+	ids := map[string]string{
+		q.PushPriority(0, time.Second, func() {}): "low-1",
+		q.PushPriority(5, time.Second, func() {}): "high-1",
+		q.PushPriority(0, time.Second, func() {}): "low-2",
+		q.PushPriority(5, time.Second, func() {}): "high-2",
+		q.PushPriority(1, time.Second, func() {}): "mid",
+	}
+	fireNext(clock, time.Second)
+
+	want := []string{"high-1", "high-2", "mid", "low-1", "low-2"}
+	for i, name := range want {
+		info, err := q.PopDue(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := ids[info.ID]; got != name {
+			t.Errorf("task %d = %s, want %s", i, got, name)
+		}
+	}
+}
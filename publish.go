@@ -0,0 +1,47 @@
+package delayqueue
+
+import (
+	"context"
+	"time"
+)
+
+// Publisher 将到期的任务作为消息发布到 Kafka、NATS 等消息系统，队列因此可以作为现有流式消费者之前的延时层
+// 本包不依赖具体的客户端，使用方将所用的客户端适配为该接口，例如：
+//
+//	// NATS
+//	delayqueue.PublisherFunc(func(ctx context.Context, topic string, payload []byte) error {
+//		return nc.Publish(topic, payload)
+//	})
+//	// Kafka（segmentio/kafka-go）
+//	delayqueue.PublisherFunc(func(ctx context.Context, topic string, payload []byte) error {
+//		return w.WriteMessages(ctx, kafka.Message{Topic: topic, Value: payload})
+//	})
+type Publisher interface {
+	// Publish 发布一条消息，返回错误时任务视为执行失败
+	Publish(ctx context.Context, topic string, payload []byte) error
+}
+
+// PublisherFunc 将函数适配为 Publisher
+type PublisherFunc func(ctx context.Context, topic string, payload []byte) error
+
+func (f PublisherFunc) Publish(ctx context.Context, topic string, payload []byte) error {
+	return f(ctx, topic, payload)
+}
+
+// PushPublish 用户推送到期时向 topic 发布消息 payload 的任务，消息由 WithPublisher 设置的发布者发出
+// 与具名处理函数任务一样不依赖闭包，设置了持久化存储时会被保存，也会出现在快照中；
+// 发布失败时任务视为执行失败，计入失败次数并交给 OnComplete 等回调
+func (q *DelayQueue) PushPublish(timeInterval time.Duration, topic string, payload []byte, opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.arg = payload
+	t.ensureExtra().publish = topic
+	return q.submit(t.apply(opts))
+}
+
+// publish 发布任务的消息，发布的 ctx 在队列停止时取消
+func (q *DelayQueue) publish(t *task) error {
+	if q.publisher == nil {
+		return ErrNoPublisher
+	}
+	return q.publisher.Publish(q.ctx, t.extra().publish, t.payload())
+}
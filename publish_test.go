@@ -0,0 +1,67 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type published struct {
+	topic   string
+	payload string
+	at      time.Time
+}
+
+func TestPushPublish(t *testing.T) {
+	msgs := make(chan published, 1)
+	var clock *ManualClock
+	q, clock := newTestQueue(t, WithPublisher(PublisherFunc(func(_ context.Context, topic string, payload []byte) error {
+		msgs <- published{topic: topic, payload: string(payload), at: clock.Now()}
+		return nil
+	})))
+
+	q.PushPublish(time.Minute, "orders.expired", []byte(`{"id":42}`))
+	settle(q)
+	select {
+	case m := <-msgs:
+		t.Fatalf("published %+v before the task was due", m)
+	default:
+	}
+
+	// 到期时发布者收到推送时的主题与消息
+	fireNext(clock, time.Minute)
+	m := receive(t, msgs)
+	want := published{topic: "orders.expired", payload: `{"id":42}`, at: testStart.Add(time.Minute)}
+	if m.topic != want.topic || m.payload != want.payload || !m.at.Equal(want.at) {
+		t.Errorf("published %+v, want %+v", m, want)
+	}
+}
+
+func TestPushPublishError(t *testing.T) {
+	errBroker := errors.New("broker unavailable")
+	errs := make(chan error, 2)
+	q, clock := newTestQueue(t,
+		WithPublisher(PublisherFunc(func(context.Context, string, []byte) error { return errBroker })),
+		OnComplete(func(_ string, _ time.Duration, err error) { errs <- err }),
+	)
+
+	// 发布失败时任务视为执行失败
+	q.PushPublish(time.Second, "orders.expired", nil)
+	fireNext(clock, time.Second)
+	if err := receive(t, errs); !errors.Is(err, errBroker) {
+		t.Errorf("error = %v, want %v", err, errBroker)
+	}
+	waitFor(t, "the failure to be counted", func() bool { return q.Metrics().Failed == 1 })
+}
+
+func TestPushPublishWithoutPublisher(t *testing.T) {
+	errs := make(chan error, 1)
+	q, clock := newTestQueue(t, OnComplete(func(_ string, _ time.Duration, err error) { errs <- err }))
+
+	q.PushPublish(time.Second, "orders.expired", nil)
+	fireNext(clock, time.Second)
+	if err := receive(t, errs); !errors.Is(err, ErrNoPublisher) {
+		t.Errorf("error = %v, want ErrNoPublisher", err)
+	}
+}
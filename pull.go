@@ -0,0 +1,85 @@
+package delayqueue
+
+import "context"
+
+// PopDue 阻塞等待下一个到期的任务，将其从队列中取出并返回任务信息，需要配合 WithConsumerMode 使用
+// 与 Next 不同，任务不会由队列执行，调用方根据返回的 Handler、Payload 等信息自行处理，可以自由控制并发；
+// 任务交出即视为结束：从存储中移除，一次性任务的句柄关闭 Done；设置了 WithVisibilityTimeout 时任务需要通过 Ack 确认。
+// 基于闭包的任务只能通过 Next 执行，不适合使用 PopDue。
+// ctx 结束时返回 ctx.Err()，队列停止时返回 ErrClosed
+func (q *DelayQueue) PopDue(ctx context.Context) (TaskInfo, error) {
+	select {
+	case t := <-q.ready:
+		return q.deliver(t), nil
+	case <-ctx.Done():
+		return TaskInfo{}, ctx.Err()
+	case <-q.quit:
+		return TaskInfo{}, ErrClosed
+	}
+}
+
+// Channel 返回传递到期任务的管道，需要配合 WithConsumerMode 使用，队列停止时管道被关闭
+// 与 PopDue 相同，任务被接收时才视为交出；多个协程可以同时从管道接收，每个任务只会被其中一个收到
+func (q *DelayQueue) Channel() <-chan TaskInfo {
+	q.pullOnce.Do(func() {
+		q.pullC = make(chan TaskInfo)
+		go q.forwardDue()
+	})
+	return q.pullC
+}
+
+// forwardDue 将到期的任务转发到 Channel 返回的管道中
+// 设置了可见性超时时任务在发送之前就记为等待确认，消费者收到后立即 Ack 也不会找不到任务；计时在发送之后才开始
+// 队列停止时已经领取、但还没有被接收的任务留在存储中，下次启动时重新加载
+func (q *DelayQueue) forwardDue() {
+	defer close(q.pullC)
+	for {
+		select {
+		case t := <-q.ready:
+			now := q.clock.Now()
+			var it *inflightTask
+			if q.visibilityTimeout > 0 {
+				it = q.register(t)
+			}
+			select {
+			case q.pullC <- q.taskInfo(t, now):
+				if it != nil {
+					q.logExecution(t, now, OutcomeDelivered, nil)
+					q.arm(it, q.visibilityTimeout)
+				} else {
+					q.deliver(t)
+				}
+			case <-q.quit:
+				if it != nil {
+					q.untrack(it)
+				}
+				return
+			}
+		case <-q.quit:
+			return
+		}
+	}
+}
+
+// deliver 任务交给了消费者，按执行完成处理，返回交出时的任务信息
+// 设置了可见性超时时任务等待消费者确认，超时未确认时重新交出
+func (q *DelayQueue) deliver(t *task) TaskInfo {
+	now := q.clock.Now()
+	q.logExecution(t, now, OutcomeDelivered, nil)
+	if q.visibilityTimeout > 0 {
+		info := q.taskInfo(t, now)
+		q.track(t, q.visibilityTimeout)
+		return info
+	}
+
+	if t.last && t.serializable() {
+		q.forget(t.id)
+	}
+	q.saveDeferred(t)
+	q.completed(t, 0, nil)
+	if t.last {
+		q.executions.add(t.id)
+		t.complete()
+	}
+	return q.taskInfo(t, now)
+}
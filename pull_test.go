@@ -0,0 +1,69 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPopDueHandlerTask(t *testing.T) {
+	storage := newTestStorage(t)
+	q, clock := newTestQueue(t, WithConsumerMode(), WithStorage(storage))
+	id := q.PushHandler(time.Second, "mail", []byte("hello"))
+
+	fireNext(clock, time.Second)
+	info, err := q.PopDue(context.Background())
+	if err != nil {
+		t.Fatalf("PopDue: %v", err)
+	}
+	if info.ID != id || info.Handler != "mail" || string(info.Payload) != "hello" {
+		t.Errorf("PopDue = %s %q %q, want %s mail hello", info.ID, info.Handler, info.Payload, id)
+	}
+
+	// 没有可见性超时，交出即视为结束
+	if n := q.Len(); n != 0 {
+		t.Errorf("Len after PopDue = %d, want 0", n)
+	}
+	if _, err := storage.Load(id); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("load delivered task error = %v, want ErrTaskNotFound", err)
+	}
+}
+
+func TestPopDueCanceledAndClosed(t *testing.T) {
+	q, _ := newTestQueue(t, WithConsumerMode())
+	q.Push(time.Hour, func() {})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := q.PopDue(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("PopDue before due = %v, want DeadlineExceeded", err)
+	}
+
+	stopQueue(t, q)
+	if _, err := q.PopDue(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("PopDue after Stop = %v, want ErrClosed", err)
+	}
+}
+
+func TestChannelDeliversInOrderAndCloses(t *testing.T) {
+	q, clock := newTestQueue(t, WithConsumerMode())
+	first := q.Push(time.Second, func() {})
+	second := q.Push(2*time.Second, func() {})
+
+	c := q.Channel()
+	clock.BlockUntil(1)
+	clock.Set(testStart.Add(2 * time.Second))
+	for _, want := range []string{first.ID(), second.ID()} {
+		if info := receive(t, c); info.ID != want {
+			t.Errorf("received %s, want %s", info.ID, want)
+		}
+	}
+	receive(t, first.Done())
+
+	// 队列停止后管道被关闭
+	stopQueue(t, q)
+	if _, ok := <-c; ok {
+		t.Error("Channel still open after Stop")
+	}
+}
@@ -0,0 +1,103 @@
+package delayqueue
+
+import "time"
+
+// PushOption 推送任务时的可选配置，同一次推送可以组合多个配置，例如
+//
+//	q.Push(time.Minute, f, delayqueue.WithPriority(1), delayqueue.WithTag("mail"), delayqueue.WithTimeout(time.Second))
+type PushOption func(t *task)
+
+// WithPriority 设置任务的优先级，同一时刻到期的任务优先级高的先执行，与 PushPriority 相同
+func WithPriority(priority int) PushOption {
+	return func(t *task) {
+		t.priority = priority
+	}
+}
+
+// WithKey 设置任务的执行 key，同一 key 的任务不会并发执行，与 PushKeyed 相同
+func WithKey(key string) PushOption {
+	return func(t *task) {
+		t.ensureExtra().key = key
+	}
+}
+
+// WithTag 设置任务的标签，可以按标签暂停、恢复与删除任务，与 PushTagged 相同
+func WithTag(tag string) PushOption {
+	return func(t *task) {
+		t.ensureExtra().tag = tag
+	}
+}
+
+// WithMetadata 设置任务的元数据，元数据会被复制一份，与 PushMeta 相同
+func WithMetadata(metadata map[string]string) PushOption {
+	return func(t *task) {
+		t.ensureExtra().metadata = copyMetadata(metadata)
+	}
+}
+
+// WithTaskJitter 设置任务执行时间的随机抖动范围，覆盖 WithJitter 的设置，与 PushJitter 相同
+func WithTaskJitter(jitter time.Duration) PushOption {
+	return func(t *task) {
+		t.jitter = jitter
+	}
+}
+
+// WithUniqueKey 设置任务的去重 key，同 key 的冲突按 WithUniquePolicy 处理
+// 与 PushUnique 不同，UniqueIgnore 策略下新任务在调度协程中被丢弃，返回的句柄随即结束
+func WithUniqueKey(key string) PushOption {
+	return func(t *task) {
+		t.ensureExtra().unique = key
+	}
+}
+
+// WithTimeout 设置任务的执行超时时间，与 PushTimeout 相同；timeout <= 0 表示不限制
+// 对 PushContext 的任务，执行函数收到的 ctx 在 timeout 之后到期；对不接收 ctx 的执行函数，超时后不再等待它返回
+func WithTimeout(timeout time.Duration) PushOption {
+	return func(t *task) {
+		if timeout > 0 {
+			t.ensureExtra().timeout = timeout
+		}
+	}
+}
+
+// WithRetry 设置任务执行失败（返回错误或 panic）后的重试策略，与 PushRetry 相同
+// 可以为具名处理函数、TypedQueue 等没有单独重试入口的任务设置重试
+func WithRetry(policy RetryPolicy) PushOption {
+	return func(t *task) {
+		t.ensureExtra().retry = &policy
+	}
+}
+
+// newPushTask 创建按延时推送的任务，由调用方设置执行函数后再应用推送配置
+func (q *DelayQueue) newPushTask(timeInterval time.Duration) *task {
+	return q.newPushTaskWithID(q.genTaskId(), timeInterval)
+}
+
+// newPushTaskWithID 使用指定id创建按延时推送的任务
+func (q *DelayQueue) newPushTaskWithID(id string, timeInterval time.Duration) *task {
+	now := q.clock.Now()
+	return &task{
+		id:          id,
+		execTime:    now.Add(timeInterval),
+		pushTime:    now,
+		fromEnqueue: q.delayFromEnqueue,
+	}
+}
+
+// newPushTaskAt 使用指定id创建在绝对时刻 execTime 执行的任务，不受 WithDelayFromEnqueue 与 WithJitter 的影响
+func (q *DelayQueue) newPushTaskAt(id string, execTime time.Time) *task {
+	return &task{
+		id:       id,
+		execTime: execTime,
+		jitter:   noJitter,
+		pushTime: q.clock.Now(),
+	}
+}
+
+// apply 依次应用推送配置
+func (t *task) apply(opts []PushOption) *task {
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
@@ -0,0 +1,97 @@
+package delayqueue
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPushOptionsCombined(t *testing.T) {
+	q, _ := newTestQueue(t)
+
+	meta := map[string]string{"order": "42"}
+	h := q.Push(time.Minute, func() {},
+		WithPriority(3),
+		WithKey("user-1"),
+		WithTag("mail"),
+		WithMetadata(meta),
+		WithTimeout(time.Second),
+	)
+	meta["order"] = "changed"
+
+	info, ok := q.Get(h.ID())
+	if !ok {
+		t.Fatalf("Get(%s) not found", h.ID())
+	}
+	if info.Priority != 3 || info.Key != "user-1" || info.Tag != "mail" {
+		t.Errorf("info = priority %d, key %q, tag %q, want 3, user-1, mail", info.Priority, info.Key, info.Tag)
+	}
+	if want := map[string]string{"order": "42"}; !reflect.DeepEqual(info.Metadata, want) {
+		t.Errorf("metadata = %v, want %v", info.Metadata, want)
+	}
+}
+
+func TestPushOptionsUniqueKey(t *testing.T) {
+	q, _ := newTestQueue(t, WithUniquePolicy(UniqueIgnore))
+
+	first := q.Push(time.Minute, func() {}, WithUniqueKey("report"), WithTag("daily"))
+	second := q.Push(time.Minute, func() {}, WithUniqueKey("report"))
+
+	// 后推送的同 key 任务被丢弃，句柄随即结束
+	receive(t, second.Done())
+	if _, ok := q.Get(second.ID()); ok {
+		t.Errorf("duplicate task %s still pending", second.ID())
+	}
+	if info, ok := q.Get(first.ID()); !ok || info.Tag != "daily" {
+		t.Errorf("Get(first) = %+v, %v, want tag daily", info, ok)
+	}
+}
+
+func TestPushOptionsOtherEntryPoints(t *testing.T) {
+	q, _ := newTestQueue(t)
+
+	ids := map[string]string{
+		"handler": q.PushHandler(time.Minute, "mail", []byte("x"), WithTag("mail"), WithPriority(2)),
+		"meta":    q.PushMeta(map[string]string{"tenant": "a"}, time.Minute, func() {}, WithTag("mail"), WithPriority(2)),
+		"publish": q.PushPublish(time.Minute, "orders", nil, WithTag("mail"), WithPriority(2)),
+		"topic":   q.Topic("reports").Push(time.Minute, func() {}, WithTag("mail"), WithPriority(2)),
+	}
+	id, err := q.PushJSON(time.Minute, "mail", map[string]int{"n": 1}, WithTag("mail"), WithPriority(2))
+	if err != nil {
+		t.Fatalf("PushJSON: %v", err)
+	}
+	ids["json"] = id
+
+	for name, id := range ids {
+		info, ok := q.Get(id)
+		if !ok {
+			t.Errorf("%s: Get(%q) not found", name, id)
+			continue
+		}
+		if info.Tag != "mail" || info.Priority != 2 {
+			t.Errorf("%s: tag %q, priority %d, want mail, 2", name, info.Tag, info.Priority)
+		}
+	}
+	// 固定参数设置的配置与 opts 同时生效
+	if info, _ := q.Get(ids["meta"]); info.Metadata["tenant"] != "a" {
+		t.Errorf("meta: metadata = %v, want tenant=a", info.Metadata)
+	}
+}
+
+func TestPushBatchUniqueKey(t *testing.T) {
+	q, _ := newTestQueue(t, WithUniquePolicy(UniqueIgnore))
+
+	first := q.PushUnique("report", time.Minute, func() {})
+	ids := q.PushBatch([]PushItem{{Delay: time.Minute, Func: func() {}}}, WithUniqueKey("report"))
+
+	// 批量中的同 key 任务同样按去重策略处理
+	if q.Len() != 1 {
+		t.Errorf("Len = %d, want 1", q.Len())
+	}
+	if _, ok := q.Get(ids[0]); ok {
+		t.Errorf("duplicate batch task %s still pending", ids[0])
+	}
+	if _, ok := q.Get(first); !ok {
+		t.Errorf("original task %s gone", first)
+	}
+}
@@ -0,0 +1,82 @@
+package delayqueue
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimitPolicy 超过限流时的处理策略
+type RateLimitPolicy int
+
+const (
+	RateLimitBlock  RateLimitPolicy = iota // 阻塞等待，直到获得令牌，默认策略
+	RateLimitReject                        // 立即拒绝，返回 ErrRateLimited
+)
+
+// tokenBucket 令牌桶
+type tokenBucket struct {
+	mu     sync.Mutex
+	clock  Clock
+	rate   float64   // 每秒生成的令牌数量
+	burst  float64   // 桶的容量
+	tokens float64   // 当前的令牌数量
+	last   time.Time // 上一次补充令牌的时间
+}
+
+// newTokenBucket 创建令牌桶，初始时桶是满的
+func newTokenBucket(clock Clock, rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		clock:  clock,
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   clock.Now(),
+	}
+}
+
+// reserve 尝试取走一个令牌；令牌不足时不取，返回还需要等待的时间
+func (b *tokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// allow 尝试取走一个令牌，令牌不足时返回 false
+func (b *tokenBucket) allow() bool {
+	return b.reserve() == 0
+}
+
+// wait 阻塞直到取走一个令牌，ctx 结束时返回 ctx.Err()
+func (b *tokenBucket) wait(ctx context.Context) error {
+	for {
+		d := b.reserve()
+		if d == 0 {
+			return nil
+		}
+		timer := b.clock.NewTimer(d)
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPushRateLimitReject(t *testing.T) {
+	q, clock := newTestQueue(t, WithPushRateLimit(3), WithPushRateLimitPolicy(RateLimitReject))
+
+	for i := 0; i < 3; i++ {
+		if _, err := q.TryPush(time.Hour, func() {}); err != nil {
+			t.Fatalf("push %d within limit: %v", i, err)
+		}
+	}
+	// 超出速率的推送被拒绝，不会进入队列
+	if _, err := q.TryPush(time.Hour, func() {}); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("push over limit error = %v, want ErrRateLimited", err)
+	}
+	if h := q.Push(time.Hour, func() {}); !errors.Is(h.Err(), ErrRateLimited) {
+		t.Errorf("Push over limit handle error = %v, want ErrRateLimited", h.Err())
+	}
+	if n := q.Len(); n != 3 {
+		t.Errorf("Len = %d, want 3", n)
+	}
+
+	// 一秒之后令牌补满
+	clock.Advance(time.Second)
+	if _, err := q.TryPush(time.Hour, func() {}); err != nil {
+		t.Errorf("push after refill: %v", err)
+	}
+}
+
+func TestPushRateLimitBlock(t *testing.T) {
+	q, clock := newTestQueue(t, WithPushRateLimit(2))
+
+	for i := 0; i < 2; i++ {
+		if _, err := q.PushCtx(context.Background(), time.Hour, func() {}); err != nil {
+			t.Fatalf("push %d within limit: %v", i, err)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := q.PushCtx(context.Background(), time.Hour, func() {})
+		done <- err
+	}()
+
+	// 调度协程为任务设置的计时器与限流等待令牌的计时器都就绪后，推送仍在等待
+	clock.BlockUntil(2)
+	select {
+	case err := <-done:
+		t.Fatalf("push over limit returned early: %v", err)
+	default:
+	}
+
+	// 半秒后补充一个令牌，推送完成
+	clock.Advance(500 * time.Millisecond)
+	if err := receive(t, done); err != nil {
+		t.Errorf("blocked push: %v", err)
+	}
+}
+
+func TestRateLimitSpacesExecutions(t *testing.T) {
+	q, clock := newTestQueue(t, WithRateLimit(2, 2))
+	ran := make(chan time.Time, 5)
+	for i := 0; i < 5; i++ {
+		q.Push(time.Second, func() { ran <- clock.Now() })
+	}
+
+	// 突发量内的两个任务立即执行，其余三个等待令牌
+	fireNext(clock, time.Second)
+	for i := 0; i < 2; i++ {
+		receive(t, ran)
+	}
+	for waiting := 3; waiting > 0; waiting-- {
+		clock.BlockUntil(waiting)
+		select {
+		case at := <-ran:
+			t.Fatalf("task ran at %v before a token was available", at)
+		default:
+		}
+
+		// 每半秒补充一个令牌，放行一个任务
+		clock.Advance(500 * time.Millisecond)
+		receive(t, ran)
+	}
+}
@@ -0,0 +1,100 @@
+package delayqueue
+
+import (
+	"sync"
+	"time"
+)
+
+// Recording 对队列 Push/Delete 操作的录制，用于在模拟时钟的队列上重放，复现线上的调度时序
+type Recording struct {
+	mu    sync.Mutex
+	start time.Time
+	now   func() time.Time // 录制队列的时钟
+	ops   []recordedOp
+}
+
+// recordedOp 录制下来的一次操作
+type recordedOp struct {
+	offset time.Duration // 相对于录制开始的时间
+	id     string        // 任务id
+	task   *task         // 推送的任务，为 nil 表示删除操作
+}
+
+// StartRecording 开始录制队列上的 Push/Delete 操作，重复调用会以新的录制替换之前的录制
+func (q *DelayQueue) StartRecording() *Recording {
+	r := &Recording{start: q.clock.Now(), now: q.clock.Now}
+	q.recording.Store(r)
+	return r
+}
+
+// StopRecording 停止录制
+func (q *DelayQueue) StopRecording() {
+	q.recording.Store(nil)
+}
+
+// recordPush 记录一次推送，t 是任务进入队列之前的副本
+func (r *Recording) recordPush(t *task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ops = append(r.ops, recordedOp{offset: t.pushTime.Sub(r.start), id: t.id, task: t})
+}
+
+// recordDelete 记录一次删除
+func (r *Recording) recordDelete(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.ops = append(r.ops, recordedOp{offset: r.now().Sub(r.start), id: id})
+}
+
+// Replay 将录制的操作按原有的相对时间依次应用到队列 q 上
+// q 使用 ManualClock 时，模拟时钟会被推进到每个操作对应的时刻，重放不需要真实等待；否则按真实时间等待
+// 推送的任务会生成新的 id，删除操作会自动映射到重放时对应的新 id；
+// 所有操作应用完毕后立即返回，此时仍未到期的任务需要调用方继续推进时钟
+func (r *Recording) Replay(q *DelayQueue) {
+	r.mu.Lock()
+	ops := make([]recordedOp, len(r.ops))
+	copy(ops, r.ops)
+	r.mu.Unlock()
+
+	manual, _ := q.clock.(*ManualClock)
+	start := q.clock.Now()
+	ids := make(map[string]string, len(ops))
+	for _, op := range ops {
+		// 等到操作对应的时刻
+		at := start.Add(op.offset)
+		if manual != nil {
+			manual.Set(at)
+		} else if d := at.Sub(q.clock.Now()); d > 0 {
+			<-q.clock.NewTimer(d).C()
+		}
+
+		if op.task == nil {
+			id, ok := ids[op.id]
+			if !ok {
+				id = op.id
+			}
+			q.Delete(id)
+			continue
+		}
+
+		// 以重放时刻为基准平移任务的各个时间
+		now := q.clock.Now()
+		shift := now.Sub(op.task.pushTime)
+		t := op.task.copy()
+		t.id = q.genTaskId()
+		t.execTime = t.execTime.Add(shift)
+		if !t.extra().expireTime.IsZero() {
+			t.ensureExtra().expireTime = t.extra().expireTime.Add(shift)
+		}
+		t.pushTime = now
+		// 录制的执行时间已经抖动过
+		t.jitter = noJitter
+		if t.extra().ctl != nil {
+			t.ensureExtra().ctl = newTaskControl(q, t.id)
+		}
+		ids[op.id] = t.id
+		q.submit(t)
+	}
+}
@@ -0,0 +1,52 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecordingReplayReproducesFireOrder(t *testing.T) {
+	fired := make(chan string, 4)
+	send := func(name string) func() {
+		return func() { fired <- name }
+	}
+	fireTimes := []time.Duration{3 * time.Second, 4 * time.Second, 5 * time.Second}
+
+	// 在原队列上录制一段操作：推送 a、b、c、d，删除 c
+	q, clock := newTestQueue(t)
+	r := q.StartRecording()
+	q.Push(5*time.Second, send("a"))
+	clock.Set(testStart.Add(time.Second))
+	q.Push(2*time.Second, send("b"))
+	clock.Set(testStart.Add(2 * time.Second))
+	c := q.Push(10*time.Second, send("c"))
+	q.Push(2*time.Second, send("d"))
+	if err := c.Cancel(); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	q.StopRecording()
+
+	var want []string
+	for _, at := range fireTimes {
+		clock.BlockUntil(1)
+		clock.Set(testStart.Add(at))
+		want = append(want, receive(t, fired))
+	}
+	if len(want) != 3 || want[0] != "b" || want[1] != "d" || want[2] != "a" {
+		t.Fatalf("original fire order = %v, want [b d a]", want)
+	}
+
+	// 在使用模拟时钟的新队列上重放，执行顺序与时刻都与原队列相同
+	replay, replayClock := newTestQueue(t)
+	r.Replay(replay)
+	for i, at := range fireTimes {
+		replayClock.BlockUntil(1)
+		replayClock.Set(testStart.Add(at))
+		if got := receive(t, fired); got != want[i] {
+			t.Errorf("replay fired %q at +%v, want %q", got, at, want[i])
+		}
+	}
+	if n := replay.Len(); n != 0 {
+		t.Errorf("replay queue has %d pending tasks, want 0 (c was deleted)", n)
+	}
+}
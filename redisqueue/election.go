@@ -0,0 +1,59 @@
+package redisqueue
+
+import (
+	"context"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+)
+
+// LockClient 选主依赖的 Redis 命令，使用方将所用的客户端适配为该接口
+type LockClient interface {
+	// SetNX 对应 SET key value NX PX ttl，返回是否设置成功
+	SetNX(ctx context.Context, key string, value string, ttl time.Duration) (bool, error)
+	// Eval 对应 EVAL script numkeys key... arg...，返回脚本的结果
+	Eval(ctx context.Context, script string, keys []string, args ...any) (any, error)
+}
+
+// 值仍是自己时才续期或删除，避免误操作其他实例已经持有的锁
+const (
+	renewScript  = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("PEXPIRE", KEYS[1], ARGV[2]) else return 0 end`
+	resignScript = `if redis.call("GET", KEYS[1]) == ARGV[1] then return redis.call("DEL", KEYS[1]) else return 0 end`
+)
+
+// Elector 基于 Redis SETNX 的选主，实现 delayqueue.Elector
+// 锁的值为实例id，带有过期时间 ttl；leader 每次竞选时续期，崩溃后锁过期，其他实例用 SETNX 抢到锁成为新的 leader
+type Elector struct {
+	client     LockClient
+	key        string
+	ttl        time.Duration
+	instanceID string
+}
+
+// NewElector 创建使用 key 作为锁的选主，ttl 为租约时长，需要明显长于 delayqueue.WithElector 设置的竞选间隔
+func NewElector(client LockClient, key string, ttl time.Duration) *Elector {
+	return &Elector{
+		client:     client,
+		key:        key,
+		ttl:        ttl,
+		instanceID: delayqueue.NewIDGenerator(delayqueue.IDFormatObjectID).NewID(),
+	}
+}
+
+// Campaign 已经持有锁时续期，否则尝试抢锁
+func (e *Elector) Campaign(ctx context.Context) (bool, error) {
+	res, err := e.client.Eval(ctx, renewScript, []string{e.key}, e.instanceID, e.ttl.Milliseconds())
+	if err != nil {
+		return false, err
+	}
+	if n, ok := res.(int64); ok && n == 1 {
+		return true, nil
+	}
+	return e.client.SetNX(ctx, e.key, e.instanceID, e.ttl)
+}
+
+// Resign 释放自己持有的锁
+func (e *Elector) Resign(ctx context.Context) error {
+	_, err := e.client.Eval(ctx, resignScript, []string{e.key}, e.instanceID)
+	return err
+}
@@ -0,0 +1,337 @@
+// Package redisqueue 提供基于 Redis 有序集合的分布式延时任务队列
+//
+// 多个服务实例使用同一个 key 共享一个队列：任务id以执行时间为分值写入有序集合，
+// 任务内容写入同名哈希表；各实例定时用 ZRANGEBYSCORE 查询到期的任务，并通过 ZREM 争抢，
+// 只有 ZREM 成功的实例会执行该任务，因此每个任务只会被执行一次。
+//
+// 执行函数无法跨进程共享，分布式模式只支持具名处理函数任务，PushHandler、Delete 的签名与返回约定与
+// delayqueue.DelayQueue 相同，可以直接替换；需要传入 ctx 的调用方使用 PushHandlerCtx、DeleteContext。实例在争抢成功之后、执行完成之前崩溃时，
+// 任务会丢失（至多一次）。
+//
+// Storage 以相同的结构实现了 delayqueue.Storage，可以直接查看与修改队列中的任务。
+//
+// 本包不依赖具体的 Redis 客户端，使用方需要将自己的客户端适配为 Client 接口。
+package redisqueue
+
+import (
+	"context"
+	"encoding/json"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+)
+
+// Client 分布式队列依赖的 Redis 命令，使用方将所用的客户端适配为该接口
+type Client interface {
+	// ZAdd 对应 ZADD key score member
+	ZAdd(ctx context.Context, key string, score float64, member string) error
+	// ZRem 对应 ZREM key member，返回实际移除的数量
+	ZRem(ctx context.Context, key string, member string) (int64, error)
+	// ZRangeByScore 对应 ZRANGEBYSCORE key -inf max LIMIT 0 count
+	ZRangeByScore(ctx context.Context, key string, max float64, count int64) ([]string, error)
+	// HSet 对应 HSET key field value
+	HSet(ctx context.Context, key string, field string, value string) error
+	// HGet 对应 HGET key field，字段不存在时返回 ok 为 false
+	HGet(ctx context.Context, key string, field string) (value string, ok bool, err error)
+	// HDel 对应 HDEL key field
+	HDel(ctx context.Context, key string, field string) error
+}
+
+// Option 分布式队列的可选配置
+type Option func(q *RedisDelayQueue)
+
+// WithPollInterval 设置查询到期任务的间隔，默认 100 毫秒；间隔越短，任务执行越及时，Redis 的压力也越大
+func WithPollInterval(d time.Duration) Option {
+	return func(q *RedisDelayQueue) {
+		q.pollInterval = d
+	}
+}
+
+// WithBatchSize 设置每次查询到期任务的最大数量，默认 100
+func WithBatchSize(n int64) Option {
+	return func(q *RedisDelayQueue) {
+		q.batchSize = n
+	}
+}
+
+// WithMissingHandlerDelay 设置到期时当前实例没有注册处理函数的任务推迟多久再查询，默认 1 分钟
+// 任务不会被取走，注册了处理函数的实例在此期间之后可以执行它；各实例注册的处理函数相同时不会用到
+func WithMissingHandlerDelay(d time.Duration) Option {
+	return func(q *RedisDelayQueue) {
+		q.missingHandlerDelay = d
+	}
+}
+
+// WithLogger 设置日志输出
+func WithLogger(logger delayqueue.Logger) Option {
+	return func(q *RedisDelayQueue) {
+		q.logger = logger
+	}
+}
+
+// WithClock 设置计算执行时间与轮询使用的时钟，默认使用系统时间
+// 多个实例共享队列时，各实例的时钟需要保持一致
+func WithClock(clock delayqueue.Clock) Option {
+	return func(q *RedisDelayQueue) {
+		q.clock = clock
+	}
+}
+
+// WithIDGenerator 设置任务id生成器，多个实例共享队列时需要保证生成的id全局唯一
+func WithIDGenerator(gen delayqueue.IDGenerator) Option {
+	return func(q *RedisDelayQueue) {
+		q.idGenerator = gen
+	}
+}
+
+// RedisDelayQueue 基于 Redis 的分布式延时任务队列
+type RedisDelayQueue struct {
+	client Client
+	key    string // 有序集合的 key，任务内容保存在 key + ":tasks" 哈希表中
+
+	pollInterval        time.Duration
+	batchSize           int64
+	missingHandlerDelay time.Duration // 没有注册处理函数的任务推迟查询的时间
+	logger              delayqueue.Logger
+	idGenerator         delayqueue.IDGenerator
+	clock               delayqueue.Clock
+
+	handlers   map[string]func(payload []byte) // 已注册的具名处理函数
+	handlersMu sync.RWMutex                    // 保护 handlers
+
+	quit     chan struct{}
+	stopOnce sync.Once
+	running  sync.WaitGroup // 轮询协程与正在执行的任务
+}
+
+// NewRedisDelayQueue 创建使用 key 存储任务的分布式延时任务队列，并开始轮询到期的任务
+func NewRedisDelayQueue(client Client, key string, opts ...Option) *RedisDelayQueue {
+	q := &RedisDelayQueue{
+		client:              client,
+		key:                 key,
+		pollInterval:        100 * time.Millisecond,
+		batchSize:           100,
+		missingHandlerDelay: time.Minute,
+		logger:              noopLogger{},
+		idGenerator:         delayqueue.NewIDGenerator(delayqueue.IDFormatObjectID),
+		clock:               delayqueue.SystemClock(),
+		handlers:            make(map[string]func(payload []byte)),
+		quit:                make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(q)
+	}
+
+	q.running.Add(1)
+	go q.poll()
+	return q
+}
+
+// RegisterHandler 注册具名处理函数，共享队列的每个实例都需要注册相同的处理函数
+func (q *RedisDelayQueue) RegisterHandler(name string, fn func(payload []byte)) {
+	q.handlersMu.Lock()
+	defer q.handlersMu.Unlock()
+	q.handlers[name] = fn
+}
+
+// PushHandler 推送由具名处理函数执行的任务，返回任务id，与 delayqueue.DelayQueue.PushHandler 相同
+// 写入 Redis 失败时记录日志并返回空字符串；需要限时或区分失败原因的调用方使用 PushHandlerCtx
+// opts 只为与内存队列的签名保持一致：推送配置作用于内存中的任务，无法随任务写入 Redis，传入时任务被拒绝
+func (q *RedisDelayQueue) PushHandler(timeInterval time.Duration, name string, payload []byte, opts ...delayqueue.PushOption) string {
+	if len(opts) > 0 {
+		q.logger.Printf("push task rejected: push options are not supported")
+		return ""
+	}
+	id, err := q.PushHandlerCtx(context.Background(), timeInterval, name, payload)
+	if err != nil {
+		q.logger.Printf("push task rejected: %v", err)
+		return ""
+	}
+	return id
+}
+
+// PushHandlerCtx 与 PushHandler 相同，Redis 命令使用 ctx，写入失败时返回错误
+func (q *RedisDelayQueue) PushHandlerCtx(ctx context.Context, timeInterval time.Duration, name string, payload []byte) (string, error) {
+	pt := delayqueue.PendingTask{
+		ID:       q.idGenerator.NewID(),
+		ExecTime: q.clock.Now().Add(timeInterval),
+		Handler:  name,
+		Payload:  payload,
+	}
+	data, err := json.Marshal(pt)
+	if err != nil {
+		return "", err
+	}
+
+	// 先写任务内容再写入有序集合，保证任务被查询到时内容已经存在
+	if err := q.client.HSet(ctx, q.tasksKey(), pt.ID, string(data)); err != nil {
+		return "", err
+	}
+	if err := q.client.ZAdd(ctx, q.key, score(pt.ExecTime), pt.ID); err != nil {
+		return "", err
+	}
+	return pt.ID, nil
+}
+
+// Delete 删除任务，与 delayqueue.DelayQueue.Delete 相同，任务在被执行之前删除时返回 true
+// 任务不存在或者已经被某个实例取走时返回 delayqueue.ErrTaskNotFound，Redis 中无法区分两者，不会返回 ErrTaskExecuted；
+// 访问 Redis 失败时返回该错误
+func (q *RedisDelayQueue) Delete(id string) (bool, error) {
+	return q.DeleteContext(context.Background(), id)
+}
+
+// DeleteContext 与 Delete 相同，Redis 命令使用 ctx
+func (q *RedisDelayQueue) DeleteContext(ctx context.Context, id string) (bool, error) {
+	n, err := q.client.ZRem(ctx, q.key, id)
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		// 任务不存在，或者已经被某个实例取走
+		return false, delayqueue.ErrTaskNotFound
+	}
+	return true, q.client.HDel(ctx, q.tasksKey(), id)
+}
+
+// Stop 停止轮询并等待正在执行的任务结束，ctx 结束时不再等待，返回 ctx.Err()
+func (q *RedisDelayQueue) Stop(ctx context.Context) error {
+	q.stopOnce.Do(func() {
+		close(q.quit)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		q.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// poll 定时查询并争抢到期的任务
+func (q *RedisDelayQueue) poll() {
+	defer q.running.Done()
+
+	for {
+		timer := q.clock.NewTimer(q.pollInterval)
+		select {
+		case <-timer.C():
+			q.fireDue()
+		case <-q.quit:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// fireDue 取出所有到期的任务并执行
+// 查询或争抢出错时放弃本轮，等下一次轮询再试，避免 Redis 故障期间反复查询同一批任务
+func (q *RedisDelayQueue) fireDue() {
+	ctx := context.Background()
+	for {
+		ids, err := q.client.ZRangeByScore(ctx, q.key, score(q.clock.Now()), q.batchSize)
+		if err != nil {
+			q.logger.Printf("query due tasks failed: %v", err)
+			return
+		}
+
+		for _, id := range ids {
+			if err := q.claim(ctx, id); err != nil {
+				q.logger.Printf("claim task %s failed: %v", id, err)
+				return
+			}
+		}
+		if int64(len(ids)) < q.batchSize {
+			return
+		}
+	}
+}
+
+// claim 争抢一个到期任务，争抢成功时异步执行；返回的错误表示 Redis 访问失败
+// 先读取任务内容确认处理函数已经注册再通过 ZREM 争抢，没有注册处理函数的任务留给其他实例，不会被取走丢弃
+func (q *RedisDelayQueue) claim(ctx context.Context, id string) error {
+	data, ok, err := q.client.HGet(ctx, q.tasksKey(), id)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		// 任务内容已经被删除：刚被其他实例取走或者被删除，有序集合中残留的成员一并清理
+		_, err := q.client.ZRem(ctx, q.key, id)
+		return err
+	}
+
+	var pt delayqueue.PendingTask
+	if err := json.Unmarshal([]byte(data), &pt); err != nil {
+		// 无法解码的任务永远无法执行，直接移除
+		q.logger.Printf("decode task %s failed, drop it: %v", id, err)
+		return q.remove(ctx, id)
+	}
+
+	q.handlersMu.RLock()
+	fn, ok := q.handlers[pt.Handler]
+	q.handlersMu.RUnlock()
+	if !ok {
+		// 推迟到 missingHandlerDelay 之后，留给注册了处理函数的实例，也不会占住每一轮查询的名额
+		q.logger.Printf("handler %q not registered, task %s left for other instances", pt.Handler, id)
+		return q.client.ZAdd(ctx, q.key, score(q.clock.Now().Add(q.missingHandlerDelay)), id)
+	}
+
+	n, err := q.client.ZRem(ctx, q.key, id)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		// 已经被其他实例取走或者被删除
+		return nil
+	}
+	if err := q.client.HDel(ctx, q.tasksKey(), id); err != nil {
+		q.logger.Printf("remove task %s failed: %v", id, err)
+	}
+
+	q.running.Add(1)
+	go func() {
+		defer q.running.Done()
+		q.run(id, fn, pt.Payload)
+	}()
+	return nil
+}
+
+// run 执行任务，处理函数 panic 时恢复并记录日志，不影响其他任务与轮询
+func (q *RedisDelayQueue) run(id string, fn func(payload []byte), payload []byte) {
+	defer func() {
+		if r := recover(); r != nil {
+			q.logger.Printf("task %s panic: %v\n%s", id, r, debug.Stack())
+		}
+	}()
+	fn(payload)
+}
+
+// remove 将任务从有序集合与哈希表中移除
+func (q *RedisDelayQueue) remove(ctx context.Context, id string) error {
+	if _, err := q.client.ZRem(ctx, q.key, id); err != nil {
+		return err
+	}
+	return q.client.HDel(ctx, q.tasksKey(), id)
+}
+
+// tasksKey 保存任务内容的哈希表的 key
+func (q *RedisDelayQueue) tasksKey() string {
+	return q.key + ":tasks"
+}
+
+// score 将执行时间转换为有序集合的分值，精确到毫秒
+func score(t time.Time) float64 {
+	return float64(t.UnixMilli())
+}
+
+// noopLogger 默认不输出日志
+type noopLogger struct{}
+
+func (noopLogger) Printf(string, ...any) {}
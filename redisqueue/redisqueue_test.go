@@ -0,0 +1,281 @@
+package redisqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+)
+
+// fakeClient 内存中的 Client 实现，zremErr 不为 nil 时 ZRem 返回该错误
+type fakeClient struct {
+	mu      sync.Mutex
+	zset    map[string]float64
+	hash    map[string]string
+	zremErr error
+	zrems   int
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{zset: make(map[string]float64), hash: make(map[string]string)}
+}
+
+func (c *fakeClient) ZAdd(_ context.Context, _ string, score float64, member string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.zset[member] = score
+	return nil
+}
+
+func (c *fakeClient) ZRem(_ context.Context, _ string, member string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.zrems++
+	if c.zremErr != nil {
+		return 0, c.zremErr
+	}
+	if _, ok := c.zset[member]; !ok {
+		return 0, nil
+	}
+	delete(c.zset, member)
+	return 1, nil
+}
+
+func (c *fakeClient) ZRangeByScore(_ context.Context, _ string, max float64, count int64) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var ids []string
+	for id, score := range c.zset {
+		if score <= max {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return c.zset[ids[i]] < c.zset[ids[j]]
+	})
+	if int64(len(ids)) > count {
+		ids = ids[:count]
+	}
+	return ids, nil
+}
+
+func (c *fakeClient) HSet(_ context.Context, _ string, field string, value string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.hash[field] = value
+	return nil
+}
+
+func (c *fakeClient) HGet(_ context.Context, _ string, field string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.hash[field]
+	return v, ok, nil
+}
+
+func (c *fakeClient) HDel(_ context.Context, _ string, field string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.hash, field)
+	return nil
+}
+
+func (c *fakeClient) HVals(context.Context, string) ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	values := make([]string, 0, len(c.hash))
+	for _, v := range c.hash {
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// logBuffer 收集日志的 Logger
+type logBuffer struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (l *logBuffer) Printf(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.lines = append(l.lines, fmt.Sprintf(format, args...))
+}
+
+func (l *logBuffer) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, line := range l.lines {
+		if strings.Contains(line, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// newTestQueue 创建不会自动轮询的队列，测试中直接调用 fireDue，测试结束时停止队列
+func newTestQueue(t *testing.T, client Client, clock delayqueue.Clock, opts ...Option) *RedisDelayQueue {
+	t.Helper()
+	q := NewRedisDelayQueue(client, "tasks", append([]Option{WithClock(clock), WithPollInterval(time.Hour)}, opts...)...)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := q.Stop(ctx); err != nil {
+			t.Errorf("stop queue: %v", err)
+		}
+	})
+	return q
+}
+
+func TestUnregisteredHandlerLeftForOtherInstances(t *testing.T) {
+	client := newFakeClient()
+	clock := delayqueue.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	a := newTestQueue(t, client, clock, WithMissingHandlerDelay(time.Minute))
+	b := newTestQueue(t, client, clock)
+	ran := make(chan string, 1)
+	b.RegisterHandler("order", func(payload []byte) {
+		ran <- string(payload)
+	})
+
+	id, err := a.PushHandlerCtx(context.Background(), 0, "order", []byte("42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 没有注册处理函数的实例不会取走任务，只是推迟之后再查询
+	a.fireDue()
+	if _, ok := client.hash[id]; !ok {
+		t.Fatal("task dropped by an instance without the handler")
+	}
+	b.fireDue()
+	select {
+	case <-ran:
+		t.Fatal("task ran before the missing handler delay")
+	default:
+	}
+
+	clock.Advance(time.Minute)
+	b.fireDue()
+	select {
+	case payload := <-ran:
+		if payload != "42" {
+			t.Errorf("payload = %q, want 42", payload)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("task never ran on the instance with the handler")
+	}
+}
+
+func TestClaimFailureBacksOff(t *testing.T) {
+	client := newFakeClient()
+	clock := delayqueue.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	q := newTestQueue(t, client, clock, WithBatchSize(2))
+	q.RegisterHandler("noop", func([]byte) {})
+	for i := 0; i < 4; i++ {
+		if _, err := q.PushHandlerCtx(context.Background(), 0, "noop", nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// ZREM 失败时放弃本轮，不会反复查询同一批任务
+	client.zremErr = errors.New("connection reset")
+	done := make(chan struct{})
+	go func() {
+		q.fireDue()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("fireDue kept retrying after ZRem failed")
+	}
+	if client.zrems != 1 {
+		t.Errorf("ZRem called %d times, want 1", client.zrems)
+	}
+	if len(client.zset) != 4 {
+		t.Errorf("%d tasks left, want all 4 kept for the next poll", len(client.zset))
+	}
+}
+
+func TestHandlerPanicRecovered(t *testing.T) {
+	client := newFakeClient()
+	clock := delayqueue.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	logs := &logBuffer{}
+	q := newTestQueue(t, client, clock, WithLogger(logs))
+	ran := make(chan struct{}, 1)
+	q.RegisterHandler("boom", func([]byte) { panic("boom") })
+	q.RegisterHandler("ok", func([]byte) { ran <- struct{}{} })
+
+	if _, err := q.PushHandlerCtx(context.Background(), 0, "boom", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.PushHandlerCtx(context.Background(), time.Millisecond, "ok", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// panic 的任务不影响进程与之后的任务
+	clock.Advance(time.Millisecond)
+	q.fireDue()
+	select {
+	case <-ran:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task after a panicking task never ran")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := q.Stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if !logs.contains("panic: boom") {
+		t.Errorf("panic not logged: %v", logs.lines)
+	}
+}
+
+// scheduler 分布式队列与内存队列共有的推送与删除方法，两者可以互相替换
+type scheduler interface {
+	PushHandler(timeInterval time.Duration, name string, payload []byte, opts ...delayqueue.PushOption) string
+	Delete(id string) (bool, error)
+}
+
+var (
+	_ scheduler = (*RedisDelayQueue)(nil)
+	_ scheduler = (*delayqueue.DelayQueue)(nil)
+)
+
+func TestPushHandlerAndDelete(t *testing.T) {
+	client := newFakeClient()
+	clock := delayqueue.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	q := newTestQueue(t, client, clock)
+
+	id := q.PushHandler(time.Minute, "order", []byte("42"))
+	if id == "" {
+		t.Fatal("PushHandler returned an empty id")
+	}
+	if ok, err := q.Delete(id); !ok || err != nil {
+		t.Fatalf("Delete = %v, %v, want true, nil", ok, err)
+	}
+	if len(client.zset) != 0 || len(client.hash) != 0 {
+		t.Errorf("task left in Redis after Delete: %v %v", client.zset, client.hash)
+	}
+	// 与内存队列相同，不存在的任务返回 ErrTaskNotFound
+	if ok, err := q.Delete(id); ok || !errors.Is(err, delayqueue.ErrTaskNotFound) {
+		t.Errorf("second Delete = %v, %v, want false, ErrTaskNotFound", ok, err)
+	}
+}
+
+func TestPushHandlerRejectsOptions(t *testing.T) {
+	client := newFakeClient()
+	clock := delayqueue.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	q := newTestQueue(t, client, clock)
+
+	// 推送配置无法随任务写入 Redis，任务被拒绝而不是悄悄丢掉配置
+	if id := q.PushHandler(time.Minute, "order", nil, delayqueue.WithTag("mail")); id != "" || len(client.zset) != 0 {
+		t.Errorf("PushHandler with options = %q, zset %v, want rejected", id, client.zset)
+	}
+}
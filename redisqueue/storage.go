@@ -0,0 +1,84 @@
+package redisqueue
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/gzltommy/delayqueue"
+)
+
+// StorageClient Storage 依赖的 Redis 命令，在 Client 的基础上增加列出全部任务需要的 HVALS
+type StorageClient interface {
+	Client
+	// HVals 对应 HVALS key
+	HVals(ctx context.Context, key string) ([]string, error)
+}
+
+// Storage 按 RedisDelayQueue 的结构读写任务的 delayqueue.Storage 实现：任务id以执行时间（毫秒）为分值写入有序集合 key，
+// 任务内容以 JSON 写入哈希表 key + ":tasks"。可以作为 delayqueue.WithStorage 的存储，也可以用来查看、修改分布式队列中的任务
+type Storage struct {
+	ctx    context.Context
+	client StorageClient
+	key    string
+}
+
+// NewStorage 创建读写 key 上任务的存储，所有 Redis 命令使用 ctx
+func NewStorage(ctx context.Context, client StorageClient, key string) *Storage {
+	return &Storage{ctx: ctx, client: client, key: key}
+}
+
+// Save 保存任务，与 RedisDelayQueue 推送任务相同，先写任务内容再写入有序集合
+func (s *Storage) Save(task delayqueue.PendingTask) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	if err := s.client.HSet(s.ctx, s.tasksKey(), task.ID, string(data)); err != nil {
+		return err
+	}
+	return s.client.ZAdd(s.ctx, s.key, score(task.ExecTime), task.ID)
+}
+
+// Load 读取指定 id 的任务，任务不存在时返回 delayqueue.ErrTaskNotFound
+func (s *Storage) Load(id string) (delayqueue.PendingTask, error) {
+	data, ok, err := s.client.HGet(s.ctx, s.tasksKey(), id)
+	if err != nil {
+		return delayqueue.PendingTask{}, err
+	}
+	if !ok {
+		return delayqueue.PendingTask{}, delayqueue.ErrTaskNotFound
+	}
+	var task delayqueue.PendingTask
+	err = json.Unmarshal([]byte(data), &task)
+	return task, err
+}
+
+// Remove 将任务从有序集合与哈希表中移除，任务不存在时不返回错误
+func (s *Storage) Remove(id string) error {
+	if _, err := s.client.ZRem(s.ctx, s.key, id); err != nil {
+		return err
+	}
+	return s.client.HDel(s.ctx, s.tasksKey(), id)
+}
+
+// List 返回哈希表中的所有任务，包括已经被实例取走、正在执行的任务
+func (s *Storage) List() ([]delayqueue.PendingTask, error) {
+	values, err := s.client.HVals(s.ctx, s.tasksKey())
+	if err != nil {
+		return nil, err
+	}
+	tasks := make([]delayqueue.PendingTask, 0, len(values))
+	for _, v := range values {
+		var task delayqueue.PendingTask
+		if err := json.Unmarshal([]byte(v), &task); err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// tasksKey 保存任务内容的哈希表的 key
+func (s *Storage) tasksKey() string {
+	return s.key + ":tasks"
+}
@@ -0,0 +1,79 @@
+package redisqueue
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gzltommy/delayqueue"
+)
+
+var _ delayqueue.Storage = (*Storage)(nil)
+
+func TestStorageSaveLoadListRemove(t *testing.T) {
+	client := newFakeClient()
+	s := NewStorage(context.Background(), client, "jobs")
+
+	task := delayqueue.PendingTask{
+		ID:       "t1",
+		ExecTime: time.Date(2024, 1, 1, 0, 1, 0, 0, time.UTC),
+		Handler:  "order",
+		Payload:  []byte("42"),
+		Metadata: map[string]string{"tenant": "a"},
+	}
+	if err := s.Save(task); err != nil {
+		t.Fatal(err)
+	}
+	// 与 RedisDelayQueue 的结构一致：有序集合的分值是执行时间的毫秒数
+	if got, want := client.zset["t1"], float64(task.ExecTime.UnixMilli()); got != want {
+		t.Errorf("score = %v, want %v", got, want)
+	}
+
+	got, err := s.Load("t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.ExecTime.Equal(task.ExecTime) || got.Handler != "order" || string(got.Payload) != "42" || !reflect.DeepEqual(got.Metadata, task.Metadata) {
+		t.Errorf("Load = %+v, want %+v", got, task)
+	}
+
+	list, err := s.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != "t1" {
+		t.Errorf("List = %+v, want t1", list)
+	}
+
+	if err := s.Remove("t1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Load("t1"); !errors.Is(err, delayqueue.ErrTaskNotFound) {
+		t.Errorf("Load after Remove error = %v, want ErrTaskNotFound", err)
+	}
+	if len(client.zset) != 0 {
+		t.Errorf("zset after Remove = %v, want empty", client.zset)
+	}
+	// 任务不存在时不返回错误
+	if err := s.Remove("t1"); err != nil {
+		t.Errorf("second Remove error = %v", err)
+	}
+}
+
+func TestStorageReadsQueueTasks(t *testing.T) {
+	client := newFakeClient()
+	clock := delayqueue.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	q := newTestQueue(t, client, clock)
+
+	// 分布式队列推送的任务可以通过存储读取
+	id := q.PushHandler(time.Minute, "order", []byte("42"))
+	pt, err := NewStorage(context.Background(), client, "tasks").Load(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt.Handler != "order" || !pt.ExecTime.Equal(clock.Now().Add(time.Minute)) {
+		t.Errorf("Load = %+v, want the pushed task", pt)
+	}
+}
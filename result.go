@@ -0,0 +1,151 @@
+package delayqueue
+
+import (
+	"sync"
+	"time"
+)
+
+// TaskResult 一次任务执行的结果
+type TaskResult struct {
+	ID       string            `json:"id"`                 // 任务id
+	Handler  string            `json:"handler,omitempty"`  // 具名处理函数的名称，基于闭包的任务为空
+	Metadata map[string]string `json:"metadata,omitempty"` // 任务的元数据
+	Attempt  int               `json:"attempt"`            // 第几次执行，从 1 开始
+	Start    time.Time         `json:"start"`              // 开始执行的时间
+	Duration time.Duration     `json:"duration"`           // 执行耗时
+	Outcome  string            `json:"outcome"`            // 执行结果
+	Error    string            `json:"error,omitempty"`    // 执行函数返回的错误
+	Output   []byte            `json:"output,omitempty"`   // PushResultFunc 任务返回的数据
+}
+
+// ResultStore 任务执行结果的存储，用于事后审计任务是否真正执行过
+// 方法会在执行任务的协程中并发调用，实现需要保证并发安全
+type ResultStore interface {
+	// Record 记录一次执行结果
+	Record(r TaskResult) error
+	// Results 返回指定任务的所有执行结果，按执行的先后顺序排列
+	Results(id string) ([]TaskResult, error)
+	// Recent 返回最近的 n 条执行结果，最近的在前
+	Recent(n int) ([]TaskResult, error)
+}
+
+// MemoryResultStore 基于环形缓冲区的内存结果存储，只保留最近的 capacity 条结果
+type MemoryResultStore struct {
+	mu      sync.Mutex
+	results []TaskResult
+	next    int  // 下一条结果写入的位置
+	full    bool // 缓冲区是否已经写满
+}
+
+// NewMemoryResultStore 创建最多保留 capacity 条结果的内存结果存储，capacity <= 0 时为 1000
+func NewMemoryResultStore(capacity int) *MemoryResultStore {
+	if capacity <= 0 {
+		capacity = 1000
+	}
+	return &MemoryResultStore{results: make([]TaskResult, capacity)}
+}
+
+// Record 记录一次执行结果，缓冲区写满后覆盖最早的一条
+func (s *MemoryResultStore) Record(r TaskResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results[s.next] = r
+	s.next++
+	if s.next == len(s.results) {
+		s.next, s.full = 0, true
+	}
+	return nil
+}
+
+// Results 返回缓冲区中指定任务的执行结果，按执行的先后顺序排列；已经被覆盖的结果不再返回
+func (s *MemoryResultStore) Results(id string) ([]TaskResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var results []TaskResult
+	s.each(func(r TaskResult) {
+		if r.ID == id {
+			results = append(results, r)
+		}
+	})
+	return results, nil
+}
+
+// Recent 返回最近的 n 条执行结果，最近的在前；n < 0 时返回缓冲区中的所有结果
+func (s *MemoryResultStore) Recent(n int) ([]TaskResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var results []TaskResult
+	s.each(func(r TaskResult) {
+		results = append(results, r)
+	})
+	// 按写入顺序收集后倒序，最近的在前
+	for i, j := 0, len(results)-1; i < j; i, j = i+1, j-1 {
+		results[i], results[j] = results[j], results[i]
+	}
+	if n >= 0 && len(results) > n {
+		results = results[:n]
+	}
+	return results, nil
+}
+
+// each 按写入的先后顺序遍历缓冲区中的结果
+func (s *MemoryResultStore) each(fn func(r TaskResult)) {
+	if s.full {
+		for _, r := range s.results[s.next:] {
+			fn(r)
+		}
+	}
+	for _, r := range s.results[:s.next] {
+		fn(r)
+	}
+}
+
+// PushResultFunc 用户推送返回数据的任务，返回的数据与错误会记录到 WithResultStore 设置的结果存储中
+func (q *DelayQueue) PushResultFunc(timeInterval time.Duration, f func() ([]byte, error), opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.ensureExtra().fr = f
+	return q.submit(t.apply(opts))
+}
+
+// Results 返回指定任务的所有执行结果，需要设置 WithResultStore，未设置时返回 nil
+func (q *DelayQueue) Results(id string) ([]TaskResult, error) {
+	if q.resultStore == nil {
+		return nil, nil
+	}
+	return q.resultStore.Results(id)
+}
+
+// RecentResults 返回最近的 n 条执行结果，最近的在前；需要设置 WithResultStore，未设置时返回 nil
+func (q *DelayQueue) RecentResults(n int) ([]TaskResult, error) {
+	if q.resultStore == nil {
+		return nil, nil
+	}
+	return q.resultStore.Recent(n)
+}
+
+// storeResult 将一次执行的结果写入结果存储
+func (q *DelayQueue) storeResult(t *task, start time.Time, d time.Duration, outcome string, err error) {
+	if q.resultStore == nil {
+		return
+	}
+
+	r := TaskResult{
+		ID:       t.id,
+		Handler:  t.handler,
+		Metadata: t.extra().metadata,
+		Attempt:  t.extra().attempt + 1,
+		Start:    start,
+		Duration: d,
+		Outcome:  outcome,
+		Output:   t.extra().output,
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	if err := q.resultStore.Record(r); err != nil {
+		q.logger.Printf("record result of task %s failed: %v", t.id, err)
+	}
+}
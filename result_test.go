@@ -0,0 +1,90 @@
+package delayqueue
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// resultIDs 返回结果的任务id与执行次数，例如 a#1
+func resultIDs(results []TaskResult) []string {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = fmt.Sprintf("%s#%d", r.ID, r.Attempt)
+	}
+	return ids
+}
+
+func TestMemoryResultStoreEviction(t *testing.T) {
+	s := NewMemoryResultStore(3)
+	for i, id := range []string{"a", "b", "a", "c", "a"} {
+		s.Record(TaskResult{ID: id, Attempt: i + 1})
+	}
+
+	// 写满后覆盖最早的结果，只保留最近的 3 条
+	recent, _ := s.Recent(-1)
+	if got, want := resultIDs(recent), []string{"a#5", "c#4", "a#3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Recent(-1) = %v, want %v", got, want)
+	}
+	recent, _ = s.Recent(2)
+	if got, want := resultIDs(recent), []string{"a#5", "c#4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Recent(2) = %v, want %v", got, want)
+	}
+
+	// 单个任务的结果按执行的先后顺序排列，被覆盖的结果不再返回
+	results, _ := s.Results("a")
+	if got, want := resultIDs(results), []string{"a#3", "a#5"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Results(a) = %v, want %v", got, want)
+	}
+	if results, _ := s.Results("b"); len(results) != 0 {
+		t.Errorf("Results(b) = %v, want none", resultIDs(results))
+	}
+}
+
+func TestQueueResults(t *testing.T) {
+	q, clock := newTestQueue(t, WithResultStore(NewMemoryResultStore(10)))
+	policy := RetryPolicy{MaxAttempts: 2, Backoff: ConstantBackoff(time.Second)}
+	failed := false
+	id := q.PushRetry(time.Second, func() error {
+		if !failed {
+			failed = true
+			return errors.New("temporary")
+		}
+		return nil
+	}, policy)
+	other := q.PushResultFunc(3*time.Second, func() ([]byte, error) { return []byte("done"), nil })
+
+	fireNext(clock, time.Second)
+	waitFor(t, "the retry to be scheduled", func() bool {
+		results, _ := q.Results(id)
+		_, pending := q.Get(id)
+		return len(results) == 1 && pending
+	})
+	fireNext(clock, time.Second)
+	waitFor(t, "the retry to finish", func() bool {
+		results, _ := q.Results(id)
+		return len(results) == 2
+	})
+	fireNext(clock, time.Second)
+	waitFor(t, "the other task to finish", func() bool {
+		recent, _ := q.RecentResults(-1)
+		return len(recent) == 3
+	})
+
+	// 同一个任务的多次执行按先后顺序返回
+	results, _ := q.Results(id)
+	if len(results) != 2 || results[0].Outcome != OutcomeError || results[0].Error != "temporary" || results[1].Outcome != OutcomeOK {
+		t.Errorf("Results = %+v, want an error and then ok", results)
+	}
+	if got, want := resultIDs(results), []string{id + "#1", id + "#2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Results = %v, want %v", got, want)
+	}
+
+	// 最近的结果在前
+	recent, _ := q.RecentResults(1)
+	if len(recent) != 1 || recent[0].ID != other || string(recent[0].Output) != "done" {
+		t.Errorf("RecentResults(1) = %+v, want the result of %s", recent, other)
+	}
+}
@@ -0,0 +1,110 @@
+package delayqueue
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff 计算第 retry 次重试（从 1 开始）之前需要等待的时间
+type Backoff func(retry int) time.Duration
+
+// ConstantBackoff 每次重试之前都等待固定的时间 d
+func ConstantBackoff(d time.Duration) Backoff {
+	return func(int) time.Duration {
+		return d
+	}
+}
+
+// ExponentialBackoff 第 n 次重试之前等待 base * 2^(n-1)，最长不超过 max
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(retry int) time.Duration {
+		d := base
+		for i := 1; i < retry; i++ {
+			d *= 2
+			if d >= max || d <= 0 {
+				return max
+			}
+		}
+		if d > max {
+			return max
+		}
+		return d
+	}
+}
+
+// RetryPolicy 任务执行失败（返回错误或 panic）后的重试策略
+type RetryPolicy struct {
+	MaxAttempts int     // 最多执行的次数，包括第一次执行；小于等于 1 表示不重试
+	Backoff     Backoff // 重试之前的等待时间，为 nil 时使用 ExponentialBackoff(time.Second, time.Minute)
+	Jitter      float64 // 等待时间的随机抖动比例，取值 [0, 1]，实际等待时间在 d*(1-Jitter) 到 d*(1+Jitter) 之间
+}
+
+// delay 计算第 retry 次重试之前的等待时间
+func (p *RetryPolicy) delay(retry int) time.Duration {
+	backoff := p.Backoff
+	if backoff == nil {
+		backoff = ExponentialBackoff(time.Second, time.Minute)
+	}
+
+	d := backoff(retry)
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		d = time.Duration(float64(d) * (1 + jitter*(2*rand.Float64()-1)))
+	}
+	return d
+}
+
+// PushRetry 用户推送失败后按 policy 重试的任务
+// 每次重试都使用同一个任务id，等待重试期间可以通过 Delete 删除；
+// 重试次数耗尽后任务进入死信列表（见 DeadLetters），并交给 OnGiveUp 设置的回调
+func (q *DelayQueue) PushRetry(timeInterval time.Duration, f func() error, policy RetryPolicy, opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.ensureExtra().fe = f
+	return q.submit(t.apply([]PushOption{WithRetry(policy)}).apply(opts))
+}
+
+// PushHandlerRetry 用户推送由具名处理函数执行、失败后按 policy 重试的任务
+// 具名处理函数没有返回值，处理函数 panic 视为执行失败
+func (q *DelayQueue) PushHandlerRetry(timeInterval time.Duration, name string, payload []byte, policy RetryPolicy, opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.handler = name
+	t.arg = payload
+	return q.submit(t.apply([]PushOption{WithRetry(policy)}).apply(opts))
+}
+
+// retryOrGiveUp 任务执行失败后，安排下一次重试，或者在重试次数耗尽时放弃；返回是否安排了重试
+func (q *DelayQueue) retryOrGiveUp(t *task, err error) bool {
+	attempt := t.extra().attempt + 1
+	if attempt >= t.extra().retry.MaxAttempts {
+		q.logEvent(LevelError, "task gave up", "id", t.id, "attempts", attempt, "error", err)
+		q.deadLetter(t, attempt, err)
+		if q.onGiveUp != nil {
+			q.onGiveUp(t.id, err)
+		} else {
+			q.logger.Printf("task %s failed after %d attempts, give up: %v", t.id, attempt, err)
+		}
+		return false
+	}
+
+	now := q.clock.Now()
+	next := t.copy()
+	next.execTime = now.Add(t.extra().retry.delay(attempt))
+	next.pushTime = now
+	next.fromEnqueue = false
+	next.ensureExtra().attempt = attempt
+	next.ensureExtra().rerun = true
+	// 具名处理函数任务更新保存的执行时间，进程重启后按重试时间恢复
+	if err := q.persist(next); err != nil {
+		q.logger.Printf("save task %s to storage failed: %v", t.id, err)
+	}
+	if err := q.enqueue(next); err != nil {
+		q.logger.Printf("retry task %s failed: %v", t.id, err)
+		return false
+	}
+	q.retries.Add(1)
+	q.logEvent(LevelInfo, "task retry scheduled", "id", t.id, "attempt", attempt, "exec_time", next.execTime, "error", err)
+	return true
+}
@@ -0,0 +1,62 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RetryPolicy
+		retry  int
+		want   time.Duration
+	}{
+		// 未设置 Backoff 时使用 ExponentialBackoff(time.Second, time.Minute)
+		{"default first", RetryPolicy{}, 1, time.Second},
+		{"default second", RetryPolicy{}, 2, 2 * time.Second},
+		{"default sixth", RetryPolicy{}, 6, 32 * time.Second},
+		{"default capped", RetryPolicy{}, 7, time.Minute},
+		{"default overflow", RetryPolicy{}, 100, time.Minute},
+		{"custom first", RetryPolicy{Backoff: ExponentialBackoff(100*time.Millisecond, time.Second)}, 1, 100 * time.Millisecond},
+		{"custom fourth", RetryPolicy{Backoff: ExponentialBackoff(100*time.Millisecond, time.Second)}, 4, 800 * time.Millisecond},
+		{"custom capped", RetryPolicy{Backoff: ExponentialBackoff(100*time.Millisecond, time.Second)}, 5, time.Second},
+		{"base above max", RetryPolicy{Backoff: ExponentialBackoff(time.Minute, time.Second)}, 1, time.Second},
+		{"constant", RetryPolicy{Backoff: ConstantBackoff(3 * time.Second)}, 9, 3 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.delay(tt.retry); got != tt.want {
+				t.Errorf("delay(%d) = %v, want %v", tt.retry, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDelayJitter(t *testing.T) {
+	tests := []struct {
+		name     string
+		jitter   float64
+		min, max time.Duration
+	}{
+		{"half", 0.5, 5 * time.Second, 15 * time.Second},
+		// 抖动比例超过 1 时按 1 计算
+		{"clamped", 3, 0, 20 * time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := RetryPolicy{Backoff: ConstantBackoff(10 * time.Second), Jitter: tt.jitter}
+			varied := false
+			for i := 0; i < 1000; i++ {
+				d := p.delay(1)
+				if d < tt.min || d > tt.max {
+					t.Fatalf("delay = %v, want within [%v, %v]", d, tt.min, tt.max)
+				}
+				varied = varied || d != 10*time.Second
+			}
+			if !varied {
+				t.Error("delay never varied with jitter")
+			}
+		})
+	}
+}
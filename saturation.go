@@ -0,0 +1,37 @@
+package delayqueue
+
+// pending 返回等待执行的任务数量，包括还在 add 管道中尚未被调度协程接收的任务
+// 读取无需经过调度协程，结果是近似值
+func (q *DelayQueue) pending() int {
+	return int(q.pendingCount.Load()) + len(q.add)
+}
+
+// Saturation 返回队列的饱和度，取值范围为 [0, 1]，越接近 1 表示队列越吃紧
+// 设置了 WithMaxPending 时，饱和度 = 等待执行的任务数量 / 任务数量上限；
+// 否则饱和度 = add 管道中积压的任务数量 / add 管道的容量，反映调度协程来不及接收新任务的程度。
+// 读取只涉及原子变量与管道长度，开销很小，可以高频调用
+func (q *DelayQueue) Saturation() float64 {
+	var ratio float64
+	if q.maxPending > 0 {
+		ratio = float64(q.pending()) / float64(q.maxPending)
+	} else if cap(q.add) > 0 {
+		ratio = float64(len(q.add)) / float64(cap(q.add))
+	}
+
+	if ratio > 1 {
+		return 1
+	}
+	return ratio
+}
+
+// PeakPending 返回自创建队列或上一次调用 ResetPeak 以来，等待执行的任务数量的峰值
+func (q *DelayQueue) PeakPending() int {
+	return int(q.peakPending.Load())
+}
+
+// ResetPeak 重置峰值，重置后的峰值从当前等待执行的任务数量开始重新统计
+func (q *DelayQueue) ResetPeak() {
+	q.do(func() {
+		q.peakPending.Store(int64(q.taskCount()))
+	})
+}
@@ -0,0 +1,77 @@
+package delayqueue
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSaturationRisesTowardMaxPending(t *testing.T) {
+	const max = 4
+	q, _ := newTestQueue(t, WithMaxPending(max))
+
+	if s := q.Saturation(); s != 0 {
+		t.Fatalf("Saturation of empty queue = %v, want 0", s)
+	}
+	for i := 1; i <= max; i++ {
+		if h := q.Push(time.Hour, func() {}); h.Err() != nil {
+			t.Fatalf("push %d: %v", i, h.Err())
+		}
+		settle(q)
+		if s, want := q.Saturation(), float64(i)/max; s != want {
+			t.Errorf("Saturation with %d pending = %v, want %v", i, s, want)
+		}
+	}
+
+	// 达到上限之后推送被拒绝，饱和度保持为 1
+	if h := q.Push(time.Hour, func() {}); !errors.Is(h.Err(), ErrQueueFull) {
+		t.Errorf("push over max pending error = %v, want ErrQueueFull", h.Err())
+	}
+	if s := q.Saturation(); s != 1 {
+		t.Errorf("Saturation at cap = %v, want 1", s)
+	}
+}
+
+func TestPeakPendingAcrossWaves(t *testing.T) {
+	q, clock := newTestQueue(t)
+
+	// 第一波推送三个任务并全部执行完，峰值保持为三
+	ran := make(chan struct{}, 3)
+	for i := 0; i < 3; i++ {
+		q.Push(time.Second, func() { ran <- struct{}{} })
+	}
+	settle(q)
+	if p := q.PeakPending(); p != 3 {
+		t.Fatalf("PeakPending after first wave = %d, want 3", p)
+	}
+	fireNext(clock, time.Second)
+	for i := 0; i < 3; i++ {
+		receive(t, ran)
+	}
+	settle(q)
+	if p := q.PeakPending(); p != 3 {
+		t.Errorf("PeakPending after drain = %d, want 3", p)
+	}
+
+	// 第二波较小，不改变峰值
+	for i := 0; i < 2; i++ {
+		q.Push(time.Hour, func() {})
+	}
+	settle(q)
+	if p := q.PeakPending(); p != 3 {
+		t.Errorf("PeakPending after smaller wave = %d, want 3", p)
+	}
+
+	// 重置后从当前数量重新统计
+	q.ResetPeak()
+	if p := q.PeakPending(); p != 2 {
+		t.Errorf("PeakPending after ResetPeak = %d, want 2", p)
+	}
+	for i := 0; i < 2; i++ {
+		q.Push(time.Hour, func() {})
+	}
+	settle(q)
+	if p := q.PeakPending(); p != 4 {
+		t.Errorf("PeakPending after third wave = %d, want 4", p)
+	}
+}
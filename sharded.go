@@ -0,0 +1,204 @@
+package delayqueue
+
+import (
+	"context"
+	"hash/fnv"
+	"sort"
+	"time"
+)
+
+// ShardedQueue 由多个内部队列组成的延时任务队列，每个内部队列有独立的调度协程
+// 任务按id的哈希值分配到内部队列中，推送、删除与到期触发分散在多个调度协程上并行处理，适合多核机器上的高吞吐场景；
+// 同一个id的所有操作总是落在同一个内部队列上，单个任务的语义与 DelayQueue 相同，不同内部队列之间的执行顺序不做保证
+type ShardedQueue struct {
+	shards      []*DelayQueue
+	idGenerator IDGenerator
+}
+
+// NewShardedQueue 创建由 n 个内部队列组成的延时任务队列，所有内部队列使用相同的配置
+// 设置了持久化存储时，存储中的任务只加载一次并按id分配到对应的内部队列；WithExpvar 对内部队列不生效
+// 设置了 WithElector 时所有内部队列共用一次竞选，同时成为 leader 或回到待命，成为 leader 时每个内部队列只重新加载路由到自己的任务
+func NewShardedQueue(n int, opts ...Option) *ShardedQueue {
+	if n <= 0 {
+		n = 1
+	}
+
+	var execLog *executionLog
+	shardOpts := make([]Option, 0, len(opts)+1)
+	shardOpts = append(shardOpts, opts...)
+	shardOpts = append(shardOpts, func(q *DelayQueue) {
+		// 存储中的任务由外层统一加载，多个内部队列不能重复发布同名的运行指标
+		q.skipLoadStore = true
+		q.expvarName = ""
+		q.electExternal = true
+		if execLog != nil {
+			// 所有内部队列共用第一个内部队列创建的执行记录输出
+			q.execLog = execLog.acquire()
+		}
+	})
+
+	s := &ShardedQueue{shards: make([]*DelayQueue, n)}
+	for i := range s.shards {
+		s.shards[i] = NewDelayQueue(shardOpts...)
+		execLog = s.shards[0].execLog
+	}
+	s.idGenerator = s.shards[0].idGenerator
+	for _, q := range s.shards {
+		q := q
+		q.owns = func(id string) bool { return s.shard(id) == q }
+	}
+
+	first := s.shards[0]
+	if first.deadLetterStorage != nil {
+		// 死信只用于查看与重新投递，集中加载到第一个内部队列
+		first.loadDeadLetters()
+	}
+	if first.storage != nil {
+		s.loadStorage(first.storage)
+	}
+	if first.elector != nil {
+		// 竞选协程挂在第一个内部队列上，第一个内部队列停止时放弃 leader 身份
+		first.electDone = make(chan struct{})
+		go first.campaign(s.setLeader)
+	}
+	return s
+}
+
+// setLeader 同时切换所有内部队列的 leader 身份
+func (s *ShardedQueue) setLeader(leader bool) {
+	for _, q := range s.shards {
+		q.setLeader(leader)
+	}
+}
+
+// loadStorage 加载存储中的任务，按id分配到对应的内部队列
+func (s *ShardedQueue) loadStorage(storage Storage) {
+	tasks, err := storage.List()
+	if err != nil {
+		s.shards[0].logger.Printf("load tasks from storage failed: %v", err)
+		return
+	}
+	for _, pt := range tasks {
+		s.shard(pt.ID).restore([]PendingTask{pt}, false)
+	}
+}
+
+// shard 返回任务id所属的内部队列
+func (s *ShardedQueue) shard(id string) *DelayQueue {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// Shards 返回所有内部队列，用于查看各内部队列的运行指标等合并接口没有覆盖的操作
+// 不要直接向内部队列推送任务：任务会留在该内部队列中，按id路由的删除、查询等操作找不到它
+func (s *ShardedQueue) Shards() []*DelayQueue {
+	return s.shards
+}
+
+// Push 用户推送任务，返回任务句柄，与 DelayQueue.Push 相同
+func (s *ShardedQueue) Push(timeInterval time.Duration, f func(), opts ...PushOption) *Task {
+	id := s.idGenerator.NewID()
+	q := s.shard(id)
+	t := q.newPushTaskWithID(id, timeInterval)
+	t.fn = f
+	return q.submitTask(t.apply(opts))
+}
+
+// PushAt 用户推送在指定时刻 execTime 执行的任务，返回的任务句柄与 Push 相同
+func (s *ShardedQueue) PushAt(execTime time.Time, f func(), opts ...PushOption) *Task {
+	id := s.idGenerator.NewID()
+	q := s.shard(id)
+	t := q.newPushTaskAt(id, execTime)
+	t.fn = f
+	return q.submitTask(t.apply(opts))
+}
+
+// PushHandler 用户推送由具名处理函数执行的任务
+func (s *ShardedQueue) PushHandler(timeInterval time.Duration, name string, payload []byte, opts ...PushOption) string {
+	id := s.idGenerator.NewID()
+	q := s.shard(id)
+	t := q.newPushTaskWithID(id, timeInterval)
+	t.handler = name
+	t.arg = payload
+	return q.submit(t.apply(opts))
+}
+
+// PushWithID 用户推送使用指定id的任务，与 DelayQueue.PushWithID 相同
+func (s *ShardedQueue) PushWithID(id string, timeInterval time.Duration, f func(), opts ...PushOption) error {
+	return s.shard(id).PushWithID(id, timeInterval, f, opts...)
+}
+
+// RegisterHandler 在所有内部队列上注册具名处理函数
+func (s *ShardedQueue) RegisterHandler(name string, fn func(payload []byte)) {
+	for _, q := range s.shards {
+		q.RegisterHandler(name, fn)
+	}
+}
+
+// RegisterHandlerContext 在所有内部队列上注册接收 ctx 并返回错误的具名处理函数
+func (s *ShardedQueue) RegisterHandlerContext(name string, fn func(ctx context.Context, payload []byte) error) {
+	for _, q := range s.shards {
+		q.RegisterHandlerContext(name, fn)
+	}
+}
+
+// Delete 删除任务，与 DelayQueue.Delete 相同
+func (s *ShardedQueue) Delete(id string) (bool, error) {
+	return s.shard(id).Delete(id)
+}
+
+// DeleteContext 删除任务，与 DelayQueue.DeleteContext 相同
+func (s *ShardedQueue) DeleteContext(ctx context.Context, id string) (bool, error) {
+	return s.shard(id).DeleteContext(ctx, id)
+}
+
+// Reschedule 将等待执行的任务调整为 newDelay 之后执行，与 DelayQueue.Reschedule 相同
+func (s *ShardedQueue) Reschedule(id string, newDelay time.Duration) error {
+	return s.shard(id).Reschedule(id, newDelay)
+}
+
+// Get 返回指定任务的信息，任务不在等待执行时返回 false
+func (s *ShardedQueue) Get(id string) (TaskInfo, bool) {
+	return s.shard(id).Get(id)
+}
+
+// Len 返回所有内部队列中等待执行的任务数量之和
+func (s *ShardedQueue) Len() int {
+	var n int
+	for _, q := range s.shards {
+		n += q.Len()
+	}
+	return n
+}
+
+// Tasks 返回所有等待执行的任务的信息，按执行时间排序
+// 各内部队列分别取快照后合并，结果不是同一时刻的一致视图
+func (s *ShardedQueue) Tasks() []TaskInfo {
+	var infos []TaskInfo
+	for _, q := range s.shards {
+		infos = append(infos, q.Tasks()...)
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		return infos[i].ExecTime.Before(infos[j].ExecTime)
+	})
+	return infos
+}
+
+// Stop 同时停止所有内部队列，并等待正在执行的任务结束，返回第一个遇到的错误
+func (s *ShardedQueue) Stop(ctx context.Context) error {
+	errs := make(chan error, len(s.shards))
+	for _, q := range s.shards {
+		go func(q *DelayQueue) {
+			errs <- q.Stop(ctx)
+		}(q)
+	}
+
+	var first error
+	for range s.shards {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
@@ -0,0 +1,133 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// newTestShardedQueue 创建使用模拟时钟、由 n 个内部队列组成的队列，测试结束时停止队列
+func newTestShardedQueue(t *testing.T, n int, opts ...Option) *ShardedQueue {
+	t.Helper()
+	s := NewShardedQueue(n, append([]Option{WithClock(NewManualClock(testStart))}, opts...)...)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.Stop(ctx); err != nil {
+			t.Fatalf("stop sharded queue: %v", err)
+		}
+	})
+	return s
+}
+
+// shardsHolding 返回等待执行的任务中有 id 的内部队列的下标
+func shardsHolding(s *ShardedQueue, id string) []int {
+	var idx []int
+	for i, q := range s.Shards() {
+		if _, ok := q.Get(id); ok {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+func TestShardedQueueRouting(t *testing.T) {
+	s := newTestShardedQueue(t, 4)
+
+	var ids []string
+	for i := 0; i < 40; i++ {
+		id := fmt.Sprintf("order-%d", i)
+		if err := s.PushWithID(id, time.Minute, func() {}); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, id)
+	}
+	ids = append(ids, s.Push(time.Minute, func() {}).ID(), s.PushHandler(time.Minute, "send", nil))
+
+	// 每个任务只在它的id路由到的内部队列中
+	used := map[int]bool{}
+	for _, id := range ids {
+		idx := shardsHolding(s, id)
+		if len(idx) != 1 || s.Shards()[idx[0]] != s.shard(id) {
+			t.Fatalf("task %s held by shards %v, want only its routed shard", id, idx)
+		}
+		used[idx[0]] = true
+	}
+	if len(used) < 2 {
+		t.Errorf("tasks spread over %d shards, want several", len(used))
+	}
+	if n := s.Len(); n != len(ids) {
+		t.Errorf("Len = %d, want %d", n, len(ids))
+	}
+
+	// 删除路由到同一个内部队列
+	for _, id := range ids[:10] {
+		shard := s.shard(id)
+		before := shard.Len()
+		if ok, err := s.Delete(id); !ok || err != nil {
+			t.Fatalf("Delete(%s) = %v, %v, want true, nil", id, ok, err)
+		}
+		if n := shard.Len(); n != before-1 {
+			t.Errorf("routed shard Len after Delete(%s) = %d, want %d", id, n, before-1)
+		}
+	}
+	if n := s.Len(); n != len(ids)-10 {
+		t.Errorf("Len after deletes = %d, want %d", n, len(ids)-10)
+	}
+}
+
+func TestShardedQueueStop(t *testing.T) {
+	s := NewShardedQueue(4, WithClock(NewManualClock(testStart)))
+
+	// 每个内部队列上都有一个正在执行的任务
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	covered := map[*DelayQueue]bool{}
+	for i := 0; len(covered) < len(s.Shards()); i++ {
+		id := fmt.Sprintf("job-%d", i)
+		if covered[s.shard(id)] {
+			continue
+		}
+		covered[s.shard(id)] = true
+		if err := s.PushWithID(id, 0, func() {
+			started <- struct{}{}
+			<-release
+		}); err != nil {
+			t.Fatal(err)
+		}
+		receive(t, started)
+	}
+	for i, q := range s.Shards() {
+		if q.ExecutingCount() != 1 {
+			t.Fatalf("shard %d executing %d tasks, want 1", i, q.ExecutingCount())
+		}
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		stopped <- s.Stop(ctx)
+	}()
+	// Stop 等待所有内部队列正在执行的任务结束
+	select {
+	case err := <-stopped:
+		t.Fatalf("Stop returned %v while tasks were running", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+	if err := receive(t, stopped); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+
+	for i, q := range s.Shards() {
+		if q.ExecutingCount() != 0 {
+			t.Errorf("shard %d still executing after Stop", i)
+		}
+	}
+	if err := s.PushWithID("after-stop", time.Second, func() {}); !errors.Is(err, ErrClosed) {
+		t.Errorf("PushWithID after Stop error = %v, want ErrClosed", err)
+	}
+}
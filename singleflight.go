@@ -0,0 +1,66 @@
+package delayqueue
+
+import (
+	"sync"
+	"time"
+)
+
+// SingleFlightPolicy 同一 key 的任务到期时，已有同 key 任务正在执行的处理策略
+type SingleFlightPolicy int
+
+const (
+	SingleFlightOff  SingleFlightPolicy = iota // 不做限制，同 key 的任务可以并发执行，默认策略
+	SingleFlightWait                           // 等待正在执行的任务结束后再执行
+	SingleFlightDrop                           // 直接丢弃后到期的任务
+)
+
+// keyLock 单个 key 的执行锁
+type keyLock struct {
+	mu   sync.Mutex
+	refs int // 正在持有或等待该锁的任务数量，为 0 时从 keyLocks 中移除
+}
+
+// PushKeyed 用户推送带有业务 key 的任务
+// 配合 WithSingleFlightKey 使用时，同一个 key 的任务不会并发执行
+func (q *DelayQueue) PushKeyed(key string, timeInterval time.Duration, f func(), opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.fn = f
+	return q.submit(t.apply([]PushOption{WithKey(key)}).apply(opts))
+}
+
+// acquireKey 获取 key 的执行锁，返回释放函数；SingleFlightDrop 策略下锁已被占用时返回 false
+func (q *DelayQueue) acquireKey(key string) (release func(), ok bool) {
+	q.keyLocksMu.Lock()
+	l, exists := q.keyLocks[key]
+	if !exists {
+		l = &keyLock{}
+		q.keyLocks[key] = l
+	}
+	l.refs++
+	q.keyLocksMu.Unlock()
+
+	if q.singleFlight == SingleFlightDrop {
+		if !l.mu.TryLock() {
+			q.releaseKeyRef(key, l)
+			return nil, false
+		}
+	} else {
+		l.mu.Lock()
+	}
+
+	return func() {
+		l.mu.Unlock()
+		q.releaseKeyRef(key, l)
+	}, true
+}
+
+// releaseKeyRef 释放对 key 锁的引用，没有任务再使用时移除该锁
+func (q *DelayQueue) releaseKeyRef(key string, l *keyLock) {
+	q.keyLocksMu.Lock()
+	defer q.keyLocksMu.Unlock()
+
+	l.refs--
+	if l.refs == 0 {
+		delete(q.keyLocks, key)
+	}
+}
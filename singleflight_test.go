@@ -0,0 +1,94 @@
+package delayqueue
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// keyWaiters 返回持有或等待 key 执行锁的任务数量
+func keyWaiters(q *DelayQueue, key string) int {
+	q.keyLocksMu.Lock()
+	defer q.keyLocksMu.Unlock()
+	if l := q.keyLocks[key]; l != nil {
+		return l.refs
+	}
+	return 0
+}
+
+func TestSingleFlightWaitRunsSerially(t *testing.T) {
+	q, clock := newTestQueue(t, WithSingleFlightKey(SingleFlightWait))
+
+	var (
+		mu     sync.Mutex
+		events []string
+	)
+	record := func(e string) {
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	run := func(name string) func() {
+		return func() {
+			record(name + " start")
+			started <- struct{}{}
+			<-release
+			record(name + " end")
+		}
+	}
+
+	// 两个同 key 的任务同时到期
+	q.PushKeyed("order-1", time.Second, run("a"))
+	q.PushKeyed("order-1", time.Second, run("b"))
+	fireNext(clock, time.Second)
+	receive(t, started)
+
+	// 后到期的任务在等待执行锁，而不是与先到期的任务并发执行
+	deadline := time.Now().Add(5 * time.Second)
+	for keyWaiters(q, "order-1") != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("second task never waited for the key lock")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	select {
+	case <-started:
+		t.Fatal("second task started while the first is still executing")
+	default:
+	}
+
+	close(release)
+	receive(t, started)
+	stopQueue(t, q)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 4 || events[1] != events[0][:1]+" end" {
+		t.Errorf("events = %v, want one task to finish before the other starts", events)
+	}
+}
+
+func TestSingleFlightDrop(t *testing.T) {
+	dropped := make(chan string, 1)
+	q, clock := newTestQueue(t, WithSingleFlightKey(SingleFlightDrop), OnDrop(func(info TaskInfo, reason string) {
+		dropped <- reason
+	}))
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		q.PushKeyed("order-1", time.Second, func() {
+			started <- struct{}{}
+			<-release
+		})
+	}
+	fireNext(clock, time.Second)
+	receive(t, started)
+
+	if reason := receive(t, dropped); reason != "single_flight" {
+		t.Errorf("drop reason = %q, want single_flight", reason)
+	}
+	close(release)
+}
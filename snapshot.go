@@ -0,0 +1,204 @@
+package delayqueue
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+)
+
+// PendingTask 等待执行的任务，可以被序列化保存
+type PendingTask struct {
+	ID       string            `json:"id"`                 // 任务id
+	ExecTime time.Time         `json:"exec_time"`          // 执行时间
+	Handler  string            `json:"handler"`            // 具名处理函数的名称
+	Payload  []byte            `json:"payload"`            // 传给处理函数的数据
+	Priority int               `json:"priority,omitempty"` // 任务的优先级
+	Metadata map[string]string `json:"metadata,omitempty"` // 任务的元数据
+	Publish  string            `json:"publish,omitempty"`  // 到期时发布消息的主题，设置时 Handler 为空
+	Topic    string            `json:"topic,omitempty"`    // 任务所属的子队列
+	Tag      string            `json:"tag,omitempty"`      // 任务的标签
+	Key      string            `json:"key,omitempty"`      // 任务的业务 key
+	Period   time.Duration     `json:"period,omitempty"`   // 周期任务的执行间隔，为 0 表示一次性任务
+	Retry    *PendingRetry     `json:"retry,omitempty"`    // 执行失败后的重试策略，为 nil 表示不重试
+	Attempt  int               `json:"attempt,omitempty"`  // 已经失败的次数
+}
+
+// PendingRetry 可序列化的重试策略
+// Backoff 是函数无法保存，保存时按 MaxAttempts 展开为每一次重试之前的等待时间，恢复后的退避与原策略一致
+type PendingRetry struct {
+	MaxAttempts int             `json:"max_attempts"`     // 最多执行的次数，包括第一次执行
+	Delays      []time.Duration `json:"delays,omitempty"` // 第 i+1 次重试之前的等待时间，不含随机抖动
+	Jitter      float64         `json:"jitter,omitempty"` // 等待时间的随机抖动比例
+}
+
+// pendingRetry 将重试策略转换为可序列化的形式
+func (p *RetryPolicy) pendingRetry() *PendingRetry {
+	if p == nil {
+		return nil
+	}
+
+	plain := RetryPolicy{Backoff: p.Backoff}
+	pr := &PendingRetry{MaxAttempts: p.MaxAttempts, Jitter: p.Jitter}
+	for retry := 1; retry < p.MaxAttempts; retry++ {
+		pr.Delays = append(pr.Delays, plain.delay(retry))
+	}
+	return pr
+}
+
+// policy 将保存的重试策略还原为 RetryPolicy，超出展开范围的重试沿用最后一次的等待时间
+func (pr *PendingRetry) policy() *RetryPolicy {
+	if pr == nil {
+		return nil
+	}
+
+	delays := pr.Delays
+	p := &RetryPolicy{MaxAttempts: pr.MaxAttempts, Jitter: pr.Jitter}
+	if len(delays) > 0 {
+		p.Backoff = func(retry int) time.Duration {
+			if retry > len(delays) {
+				retry = len(delays)
+			}
+			return delays[retry-1]
+		}
+	}
+	return p
+}
+
+// pendingTask 将任务转换为可序列化的形式
+func (t *task) pendingTask() PendingTask {
+	x := t.extra()
+	return PendingTask{
+		ID:       t.id,
+		ExecTime: t.execTime,
+		Handler:  t.handler,
+		Payload:  t.payload(),
+		Priority: t.priority,
+		Metadata: x.metadata,
+		Publish:  x.publish,
+		Topic:    x.topic,
+		Tag:      x.tag,
+		Key:      x.key,
+		Period:   x.period,
+		Retry:    x.retry.pendingRetry(),
+		Attempt:  x.attempt,
+	}
+}
+
+// serializable 判断任务能否序列化：具名处理函数任务与发布消息的任务不依赖闭包，可以保存与导出
+func (t *task) serializable() bool {
+	return t.handler != "" || t.extra().publish != ""
+}
+
+// Snapshot 导出当前所有等待执行的具名处理函数任务与发布消息的任务，按执行时间排序
+// 基于闭包的任务无法序列化，不会出现在快照中
+func (q *DelayQueue) Snapshot() []PendingTask {
+	var tasks []PendingTask
+	q.do(func() {
+		for _, t := range q.pendingTasks() {
+			if !t.serializable() {
+				continue
+			}
+			tasks = append(tasks, t.pendingTask())
+		}
+	})
+	return tasks
+}
+
+// Export 将 Snapshot 导出的任务以 JSON 数组的形式写入 w，可以用于备份或迁移到另一个进程的队列中
+func (q *DelayQueue) Export(w io.Writer) error {
+	tasks := q.Snapshot()
+	if tasks == nil {
+		tasks = []PendingTask{}
+	}
+	return json.NewEncoder(w).Encode(tasks)
+}
+
+// Import 从 r 读取 Export 导出的任务并通过 Restore 恢复，返回实际加入队列的任务数量
+// 按 WithMissedPolicy 跳过的过期任务与队列已经停止时无法加入的任务不计入；
+// 读取或解析失败时不恢复任何任务；导入之前需要注册好任务使用的具名处理函数
+func (q *DelayQueue) Import(r io.Reader) (int, error) {
+	var tasks []PendingTask
+	if err := json.NewDecoder(r).Decode(&tasks); err != nil {
+		return 0, err
+	}
+	return q.restore(tasks, true), nil
+}
+
+// Restore 从快照恢复任务，任务保持原有的 id 与执行时间
+// 处理函数名称在恢复时不做校验，到期时若仍未注册则交给 WithMissingHandler 设置的兜底处理
+// 快照中不包含原始的推送时间，恢复的任务以恢复时刻作为进入队列的时间；恢复不受推送限流的约束
+// 恢复时已经过期的任务按 WithMissedPolicy 设置的策略处理，被跳过的任务会交给 OnRestoreSkipped 设置的回调
+// 设置了持久化存储时，恢复的任务同样会被保存
+func (q *DelayQueue) Restore(tasks []PendingTask) {
+	q.restore(tasks, true)
+}
+
+// restore 恢复任务，persist 表示是否需要将恢复的任务保存到存储中，返回实际加入队列的任务数量
+func (q *DelayQueue) restore(tasks []PendingTask, persist bool) int {
+	now := q.clock.Now()
+	restored := 0
+	for _, pt := range tasks {
+		if q.skipMissed(pt, now) {
+			q.forget(pt.ID)
+			if q.onRestoreSkipped != nil {
+				q.onRestoreSkipped(pt)
+			}
+			continue
+		}
+
+		t := &task{
+			id:       pt.ID,
+			execTime: pt.ExecTime,
+			handler:  pt.Handler,
+			arg:      pt.Payload,
+			priority: pt.Priority,
+			pushTime: now,
+			ext: &taskExtra{
+				metadata: pt.Metadata,
+				publish:  pt.Publish,
+				topic:    pt.Topic,
+				tag:      pt.Tag,
+				key:      pt.Key,
+				period:   pt.Period,
+				retry:    pt.Retry.policy(),
+				attempt:  pt.Attempt,
+			},
+		}
+		if persist {
+			if err := q.persist(t); err != nil {
+				q.logger.Printf("save task %s to storage failed: %v", t.id, err)
+			}
+		}
+		if err := q.enqueue(t); err != nil {
+			q.logger.Printf("restore task %s failed: %v", t.id, err)
+			continue
+		}
+		restored++
+	}
+	return restored
+}
+
+// MissedPolicy 恢复快照时，对已经过期的任务的处理策略
+type MissedPolicy int
+
+const (
+	MissedFire            MissedPolicy = iota // 立即执行，默认策略
+	MissedSkip                                // 跳过
+	MissedFireWithinGrace                     // 过期时间在宽限期内的立即执行，超出宽限期的跳过
+)
+
+// skipMissed 判断恢复的任务是否因为过期而需要跳过
+func (q *DelayQueue) skipMissed(pt PendingTask, now time.Time) bool {
+	if !pt.ExecTime.Before(now) {
+		return false
+	}
+
+	switch q.missedPolicy {
+	case MissedSkip:
+		return true
+	case MissedFireWithinGrace:
+		return now.Sub(pt.ExecTime) > q.missedGrace
+	default:
+		return false
+	}
+}
@@ -0,0 +1,109 @@
+package delayqueue
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOnRestoreSkipped(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  MissedPolicy
+		grace   time.Duration
+		skipped []string
+	}{
+		{"skip", MissedSkip, 0, []string{"stale", "very-stale"}},
+		{"within grace", MissedFireWithinGrace, 10 * time.Second, []string{"very-stale"}},
+		{"fire", MissedFire, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var skipped []string
+			q, clock := newTestQueue(t, WithHandler("h", func([]byte) {}), WithMissedPolicy(tt.policy, tt.grace), OnRestoreSkipped(func(task PendingTask) {
+				skipped = append(skipped, task.ID)
+			}))
+
+			// 恢复时一个任务还没有到期，一个过期 5 秒，一个过期 1 分钟
+			clock.Set(testStart.Add(time.Hour))
+			now := clock.Now()
+			q.Restore([]PendingTask{
+				{ID: "fresh", ExecTime: now.Add(time.Second), Handler: "h"},
+				{ID: "stale", ExecTime: now.Add(-5 * time.Second), Handler: "h"},
+				{ID: "very-stale", ExecTime: now.Add(-time.Minute), Handler: "h"},
+			})
+
+			// 回调在 Restore 的调用方协程中同步执行
+			sort.Strings(skipped)
+			if len(skipped) != len(tt.skipped) {
+				t.Fatalf("skipped = %v, want %v", skipped, tt.skipped)
+			}
+			for i := range skipped {
+				if skipped[i] != tt.skipped[i] {
+					t.Errorf("skipped = %v, want %v", skipped, tt.skipped)
+				}
+			}
+		})
+	}
+}
+
+func TestExportImportRoundTrip(t *testing.T) {
+	src, _ := newTestQueue(t)
+	src.PushHandler(time.Minute, "mail", []byte("a"), WithTag("t"), WithPriority(2))
+	src.PushHandler(time.Hour, "mail", []byte("b"), WithMetadata(map[string]string{"tenant": "x"}))
+	src.Push(time.Minute, func() {}) // 闭包任务不会被导出
+
+	var buf bytes.Buffer
+	if err := src.Export(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, _ := newTestQueue(t, WithHandler("mail", func([]byte) {}))
+	n, err := dst.Import(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Import = %d, want 2", n)
+	}
+	if got, want := sortedSnapshot(dst), sortedSnapshot(src); !reflect.DeepEqual(got, want) {
+		t.Errorf("imported snapshot = %+v, want %+v", got, want)
+	}
+}
+
+func TestImportStoppedQueue(t *testing.T) {
+	q, _ := newTestQueue(t)
+	stopQueue(t, q)
+
+	n, err := q.Import(strings.NewReader(`[{"id":"a","exec_time":"2024-01-01T00:01:00Z","handler":"h"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 队列已经停止，任务没有加入队列
+	if n != 0 {
+		t.Errorf("Import on a stopped queue = %d, want 0", n)
+	}
+}
+
+func TestImportCountsSkippedTasks(t *testing.T) {
+	q, clock := newTestQueue(t, WithHandler("h", func([]byte) {}), WithMissedPolicy(MissedSkip, 0))
+	clock.Set(testStart.Add(time.Hour))
+
+	// 一个任务已经过期，按 MissedSkip 跳过，不计入返回的数量
+	n, err := q.Import(strings.NewReader(`[
+		{"id":"fresh","exec_time":"2024-01-01T02:00:00Z","handler":"h"},
+		{"id":"stale","exec_time":"2024-01-01T00:30:00Z","handler":"h"}
+	]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("Import = %d, want 1", n)
+	}
+	if q.Len() != 1 {
+		t.Errorf("Len = %d, want 1", q.Len())
+	}
+}
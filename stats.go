@@ -0,0 +1,48 @@
+package delayqueue
+
+import "time"
+
+// Stats 队列的统计快照，比 Metrics 多出删除数量、各管道的积压与执行协程的利用率
+type Stats struct {
+	Name              string        `json:"name,omitempty"`     // 队列名称，即 WithName 设置的名称
+	Pending           int           `json:"pending"`            // 等待执行的任务数量（近似值）
+	Executing         int           `json:"executing"`          // 正在执行的任务数量
+	Executed          uint64        `json:"executed"`           // 已经执行完成的任务数量，包括执行失败的任务
+	Deleted           uint64        `json:"deleted"`            // 被删除的任务数量
+	DriftAvg          time.Duration `json:"drift_avg"`          // 实际开始执行时间相对计划执行时间的平均延迟
+	DriftMax          time.Duration `json:"drift_max"`          // 实际开始执行时间相对计划执行时间的最大延迟
+	AddBacklog        int           `json:"add_backlog"`        // add 管道中尚未被调度协程接收的任务数量
+	RemoveBacklog     int           `json:"remove_backlog"`     // remove 管道中尚未被调度协程处理的删除信号数量
+	QueuedBacklog     int           `json:"queued_backlog"`     // 已经到期、排队等待执行协程的任务数量
+	Workers           int           `json:"workers"`            // 执行协程的数量，未设置 WithMaxConcurrency 时为 0
+	WorkerUtilization float64       `json:"worker_utilization"` // 执行协程的利用率，正在执行的任务数量 / Workers，未设置 WithMaxConcurrency 时为 0
+	Saturation        float64       `json:"saturation"`         // 饱和度，与 Saturation 相同
+	PeakPending       int           `json:"peak_pending"`       // 等待执行的任务数量的峰值，与 PeakPending 相同
+	ExecTimeouts      uint64        `json:"exec_timeouts"`      // 执行超时的任务数量，与 ExecTimeouts 相同
+}
+
+// Stats 返回队列当前的统计快照，在 Metrics 的基础上补充其余各项，同样只读取原子变量与管道长度，
+// 不经过调度协程，可以在健康检查接口中频繁调用；各项数值分别读取，不是同一时刻的一致视图
+func (q *DelayQueue) Stats() Stats {
+	m := q.Metrics()
+	s := Stats{
+		Name:          m.Name,
+		Pending:       m.Pending,
+		Executing:     m.Executing,
+		Executed:      m.Executed,
+		Deleted:       q.deleted.Load(),
+		DriftAvg:      m.DriftAvg,
+		DriftMax:      m.DriftMax,
+		AddBacklog:    m.AddQueueDepth,
+		RemoveBacklog: len(q.remove),
+		QueuedBacklog: q.QueuedCount(),
+		Workers:       q.maxConcurrency,
+		Saturation:    q.Saturation(),
+		PeakPending:   q.PeakPending(),
+		ExecTimeouts:  q.ExecTimeouts(),
+	}
+	if s.Workers > 0 {
+		s.WorkerUtilization = float64(s.Executing) / float64(s.Workers)
+	}
+	return s
+}
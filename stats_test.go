@@ -0,0 +1,27 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStatsMatchesMetrics(t *testing.T) {
+	q, clock := newTestQueue(t, WithName("billing"), WithMaxConcurrency(2))
+	q.Push(time.Second, func() {})
+	q.Push(time.Second, func() {})
+	q.Push(time.Minute, func() {})
+	q.Delete(q.Push(time.Minute, func() {}).ID())
+
+	fireNext(clock, time.Second)
+	waitFor(t, "the due tasks to finish", func() bool { return q.Metrics().Executed == 2 })
+
+	// 与 Metrics 共有的各项取自 Metrics
+	m, s := q.Metrics(), q.Stats()
+	if s.Name != m.Name || s.Pending != m.Pending || s.Executed != m.Executed ||
+		s.DriftAvg != m.DriftAvg || s.DriftMax != m.DriftMax || s.AddBacklog != m.AddQueueDepth {
+		t.Errorf("Stats = %+v, want the shared fields of Metrics %+v", s, m)
+	}
+	if s.Name != "billing" || s.Pending != 1 || s.Executed != 2 || s.Deleted != 1 || s.Workers != 2 {
+		t.Errorf("Stats = %+v, want billing with 1 pending, 2 executed, 1 deleted and 2 workers", s)
+	}
+}
@@ -0,0 +1,50 @@
+package delayqueue
+
+import "context"
+
+// Stop 停止队列：不再接受新任务，退出调度协程，并等待正在执行的任务结束
+// 尚未到期的任务会被丢弃，周期任务不再安排下一次执行，正在执行的任务的 context 会被取消；执行记录会被全部写出。
+// ctx 结束时不再等待调度协程退出、放弃 leader 身份与正在执行的任务结束，返回 ctx.Err()；重复调用是安全的，可以用新的 ctx 继续等待
+func (q *DelayQueue) Stop(ctx context.Context) error {
+	q.stopOnce.Do(func() {
+		q.stopped.Store(true)
+		close(q.quit)
+		// 通知正在执行的任务尽快结束
+		q.cancel()
+	})
+	if err := waitDone(ctx, q.loopDone); err != nil {
+		return err
+	}
+	if q.electDone != nil {
+		// 放弃 leader 身份之后再返回，其他实例可以立即接替
+		if err := waitDone(ctx, q.electDone); err != nil {
+			return err
+		}
+	}
+	if q.pool != nil {
+		// 排队中的任务执行完之后执行协程退出
+		q.pool.close()
+	}
+
+	// 调度协程退出之后不会再有新的任务开始执行
+	done := make(chan struct{})
+	go func() {
+		q.running.Wait()
+		close(done)
+	}()
+
+	if err := waitDone(ctx, done); err != nil {
+		return err
+	}
+	return q.CloseExecutionLog()
+}
+
+// waitDone 等待 done 关闭，ctx 先结束时返回 ctx.Err()
+func waitDone(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,41 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// stuckElector 放弃 leader 身份时一直阻塞，直到 release 被关闭
+type stuckElector struct {
+	release chan struct{}
+}
+
+func (e *stuckElector) Campaign(context.Context) (bool, error) { return true, nil }
+
+func (e *stuckElector) Resign(context.Context) error {
+	<-e.release
+	return nil
+}
+
+func TestStopHonorsContextWhileResigning(t *testing.T) {
+	elector := &stuckElector{release: make(chan struct{})}
+	q, _ := newTestQueue(t, WithElector(elector, time.Second))
+	for !q.IsLeader() {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 放弃 leader 身份阻塞时，Stop 在 ctx 结束时返回
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := q.Stop(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Stop while resign blocks = %v, want context.DeadlineExceeded", err)
+	}
+
+	// 再次调用可以用新的 ctx 继续等待
+	close(elector.release)
+	if err := q.Stop(context.Background()); err != nil {
+		t.Errorf("Stop after resign finished = %v", err)
+	}
+}
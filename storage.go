@@ -0,0 +1,156 @@
+package delayqueue
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage 等待执行的任务的持久化存储
+// 只有具名处理函数任务与发布消息的任务可以被持久化：推送时保存，执行或删除后移除；
+// 设置了存储的队列在创建时会自动加载其中的任务并重新安排执行，从而在进程重启后继续执行
+type Storage interface {
+	// Save 保存任务，相同 id 的任务已经存在时覆盖
+	Save(task PendingTask) error
+	// Load 读取指定 id 的任务，任务不存在时返回 ErrTaskNotFound
+	Load(id string) (PendingTask, error)
+	// Remove 移除指定 id 的任务，任务不存在时不返回错误
+	Remove(id string) error
+	// List 返回所有保存的任务，顺序不做保证
+	List() ([]PendingTask, error)
+}
+
+// loadStorage 加载存储中的任务并重新安排执行，在队列创建时调用
+func (q *DelayQueue) loadStorage() {
+	tasks, err := q.storage.List()
+	if err != nil {
+		q.logger.Printf("load tasks from storage failed: %v", err)
+		return
+	}
+	q.restore(tasks, false)
+}
+
+// persist 将具名处理函数任务保存到存储中
+func (q *DelayQueue) persist(t *task) error {
+	if q.storage == nil || !t.serializable() {
+		return nil
+	}
+
+	q.storageMu.Lock()
+	defer q.storageMu.Unlock()
+	if d, ok := q.deferred[t.id]; ok {
+		// 本次执行还没有结束，存储中需要保留本次执行，更新推迟保存的内容即可
+		d.pt = t.pendingTask()
+		q.deferred[t.id] = d
+		return nil
+	}
+	return q.storage.Save(t.pendingTask())
+}
+
+// forget 将任务从存储中移除
+func (q *DelayQueue) forget(id string) {
+	if q.storage == nil {
+		return
+	}
+
+	q.storageMu.Lock()
+	defer q.storageMu.Unlock()
+	// 任务被移除，推迟保存的下一次执行也不再需要
+	delete(q.deferred, id)
+	if err := q.storage.Remove(id); err != nil {
+		q.logger.Printf("remove task %s from storage failed: %v", id, err)
+	}
+}
+
+// FileStorage 基于本地目录的存储，每个任务保存为目录下的一个 JSON 文件
+// 写入先落到临时文件再重命名，进程在写入过程中崩溃也不会留下损坏的任务文件
+type FileStorage struct {
+	dir string
+}
+
+// NewFileStorage 创建基于目录 dir 的存储，目录不存在时自动创建
+func NewFileStorage(dir string) (*FileStorage, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStorage{dir: dir}, nil
+}
+
+const fileStorageExt = ".json"
+
+// path 返回任务文件的路径，任务id经过转义，避免出现路径分隔符
+func (s *FileStorage) path(id string) string {
+	return filepath.Join(s.dir, url.PathEscape(id)+fileStorageExt)
+}
+
+func (s *FileStorage) Save(task PendingTask) error {
+	data, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(task.ID))
+}
+
+func (s *FileStorage) Load(id string) (PendingTask, error) {
+	return s.read(s.path(id))
+}
+
+func (s *FileStorage) Remove(id string) error {
+	err := os.Remove(s.path(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func (s *FileStorage) List() ([]PendingTask, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []PendingTask
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, fileStorageExt) {
+			continue
+		}
+		task, err := s.read(filepath.Join(s.dir, name))
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	return tasks, nil
+}
+
+// read 读取并解析一个任务文件
+func (s *FileStorage) read(path string) (PendingTask, error) {
+	var task PendingTask
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return task, ErrTaskNotFound
+	}
+	if err != nil {
+		return task, err
+	}
+	err = json.Unmarshal(data, &task)
+	return task, err
+}
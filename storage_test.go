@@ -0,0 +1,128 @@
+package delayqueue
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newTestStorage 创建测试使用的临时目录存储
+func newTestStorage(t *testing.T) *FileStorage {
+	t.Helper()
+	storage, err := NewFileStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return storage
+}
+
+// sortedSnapshot 返回按id排序的快照，便于比较
+func sortedSnapshot(q *DelayQueue) []PendingTask {
+	tasks := q.Snapshot()
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks
+}
+
+func TestStorageKeepsTaskAttributes(t *testing.T) {
+	storage := newTestStorage(t)
+	a, _ := newTestQueue(t, WithStorage(storage))
+
+	a.Topic("emails").PushHandler(time.Minute, "send", []byte("a"))
+	a.PushHandlerRetry(2*time.Minute, "charge", []byte("b"), RetryPolicy{MaxAttempts: 3, Backoff: ConstantBackoff(5 * time.Second), Jitter: 0.1})
+	a.Restore([]PendingTask{{
+		ID:       "report",
+		ExecTime: testStart.Add(time.Hour),
+		Handler:  "report",
+		Tag:      "nightly",
+		Key:      "tenant-1",
+		Period:   24 * time.Hour,
+		Retry:    &PendingRetry{MaxAttempts: 2, Delays: []time.Duration{time.Minute}},
+		Attempt:  1,
+	}})
+	want := sortedSnapshot(a)
+	stopQueue(t, a)
+
+	// 另一个实例从存储中加载，任务的子队列、标签、key、周期与重试状态都保持不变
+	b, _ := newTestQueue(t, WithStorage(storage))
+	got := sortedSnapshot(b)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("tasks loaded from storage = %+v, want %+v", got, want)
+	}
+	for _, pt := range got {
+		if pt.Handler == "charge" && (pt.Retry == nil || !reflect.DeepEqual(pt.Retry.Delays, []time.Duration{5 * time.Second, 5 * time.Second})) {
+			t.Errorf("saved retry policy = %+v, want two 5s delays", pt.Retry)
+		}
+		if pt.Handler == "send" && pt.Topic != "emails" {
+			t.Errorf("saved topic = %q, want emails", pt.Topic)
+		}
+	}
+}
+
+func TestRestoredRetryUsesSavedBackoff(t *testing.T) {
+	storage := newTestStorage(t)
+	a, _ := newTestQueue(t, WithStorage(storage))
+	a.PushHandlerRetry(time.Second, "flaky", nil, RetryPolicy{MaxAttempts: 2, Backoff: ConstantBackoff(5 * time.Second)})
+	stopQueue(t, a)
+
+	ran := make(chan time.Time, 2)
+	var attempts atomic.Int32
+	b, clock := newTestQueue(t, WithStorage(storage))
+	b.RegisterHandler("flaky", func([]byte) {
+		ran <- clock.Now()
+		if attempts.Add(1) == 1 {
+			panic("first attempt fails")
+		}
+	})
+
+	// 恢复的任务按保存的退避时间重试，而不是默认的指数退避
+	fireNext(clock, time.Second)
+	receive(t, ran)
+	fireNext(clock, 5*time.Second)
+	if at := receive(t, ran); !at.Equal(testStart.Add(6 * time.Second)) {
+		t.Errorf("retry ran at %v, want %v", at, testStart.Add(6*time.Second))
+	}
+}
+
+func TestStoragePeriodicKeepsNextRun(t *testing.T) {
+	storage := newTestStorage(t)
+	ran := make(chan struct{}, 1)
+	q, clock := newTestQueue(t, WithStorage(storage), WithHandler("tick", func([]byte) {
+		ran <- struct{}{}
+	}))
+	q.Restore([]PendingTask{{ID: "tick", ExecTime: testStart.Add(time.Second), Handler: "tick", Period: 10 * time.Second}})
+
+	fireNext(clock, time.Second)
+	receive(t, ran)
+	stopQueue(t, q)
+
+	// 执行之后存储中保存的是下一次执行
+	pt, err := storage.Load("tick")
+	if err != nil {
+		t.Fatalf("load periodic task after run: %v", err)
+	}
+	if want := testStart.Add(11 * time.Second); !pt.ExecTime.Equal(want) {
+		t.Errorf("saved exec time = %v, want %v", pt.ExecTime, want)
+	}
+}
+
+func TestStorageForgetsExecutedTask(t *testing.T) {
+	storage := newTestStorage(t)
+	ran := make(chan struct{}, 1)
+	q, clock := newTestQueue(t, WithStorage(storage), WithHandler("once", func([]byte) {
+		ran <- struct{}{}
+	}))
+	id := q.PushHandler(time.Second, "once", nil)
+
+	fireNext(clock, time.Second)
+	receive(t, ran)
+	stopQueue(t, q)
+
+	if _, err := storage.Load(id); !errors.Is(err, ErrTaskNotFound) {
+		t.Errorf("load executed task error = %v, want ErrTaskNotFound", err)
+	}
+}
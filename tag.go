@@ -0,0 +1,73 @@
+package delayqueue
+
+import "time"
+
+// PushTagged 用户推送带有标签的任务，同一标签的任务可以通过 PauseTag/ResumeTag 统一暂停与恢复
+func (q *DelayQueue) PushTagged(tag string, timeInterval time.Duration, f func(), opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.fn = f
+	return q.submit(t.apply([]PushOption{WithTag(tag)}).apply(opts))
+}
+
+// PauseTag 暂停指定标签的任务，队列中其他任务照常执行
+// 暂停期间到期的该标签任务会被扣留，直到调用 ResumeTag 后按原有的执行时间顺序依次执行
+func (q *DelayQueue) PauseTag(tag string) {
+	q.do(func() {
+		q.pausedTags[tag] = struct{}{}
+	})
+}
+
+// ResumeTag 恢复指定标签的任务，暂停期间被扣留的任务会立即按顺序执行
+func (q *DelayQueue) ResumeTag(tag string) {
+	q.do(func() {
+		delete(q.pausedTags, tag)
+		q.releaseHeld()
+	})
+}
+
+// holdIfPaused 任务到期时，如果其标签或所属子队列处于暂停状态，则将任务扣留下来
+func (q *DelayQueue) holdIfPaused(t *task) bool {
+	if !q.isPaused(t) {
+		return false
+	}
+
+	q.heldTasks = append(q.heldTasks, t)
+	q.indexTask(t)
+	return true
+}
+
+// isPaused 判断任务的标签或所属子队列是否处于暂停状态
+func (q *DelayQueue) isPaused(t *task) bool {
+	if _, paused := q.pausedTags[t.extra().tag]; paused && t.extra().tag != "" {
+		return true
+	}
+	if _, paused := q.pausedTopics[t.extra().topic]; paused && t.extra().topic != "" {
+		return true
+	}
+	return false
+}
+
+// releaseHeld 将不再处于暂停状态的扣留任务重新加入任务列表
+func (q *DelayQueue) releaseHeld() {
+	remain := q.heldTasks[:0]
+	for _, t := range q.heldTasks {
+		if q.isPaused(t) {
+			remain = append(remain, t)
+			continue
+		}
+		// 扣留的任务保持原有的执行时间，重新加入任务列表后会按顺序立即到期
+		q.addTask(t)
+	}
+	q.heldTasks = remain
+}
+
+// removeHeldTask 从扣留的任务中移除指定任务，返回任务是否存在
+func (q *DelayQueue) removeHeldTask(id string) bool {
+	for i, t := range q.heldTasks {
+		if t.id == id {
+			q.heldTasks = append(q.heldTasks[:i], q.heldTasks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
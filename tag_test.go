@@ -0,0 +1,40 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPauseTagHoldsOnlyThatTag(t *testing.T) {
+	// 只有一个执行协程，任务按分发的顺序执行
+	q, clock := newTestQueue(t, WithMaxConcurrency(1))
+
+	ran := make(chan string, 3)
+	send := func(name string) func() {
+		return func() { ran <- name }
+	}
+	q.PushTagged("report", time.Second, send("report-1"))
+	q.PushTagged("report", 2*time.Second, send("report-2"))
+	q.PushTagged("email", 3*time.Second, send("email"))
+	q.PauseTag("report")
+
+	// 暂停的标签的任务到期后被扣留，其他标签照常执行
+	for _, at := range []time.Duration{time.Second, 2 * time.Second, 3 * time.Second} {
+		clock.BlockUntil(1)
+		clock.Set(testStart.Add(at))
+	}
+	if got := receive(t, ran); got != "email" {
+		t.Fatalf("executed %q while report is paused, want email", got)
+	}
+	if n := q.Len(); n != 2 {
+		t.Errorf("Len with held tasks = %d, want 2", n)
+	}
+
+	// 恢复后被扣留的任务按到期顺序执行
+	q.ResumeTag("report")
+	for _, want := range []string{"report-1", "report-2"} {
+		if got := receive(t, ran); got != want {
+			t.Errorf("executed %q after ResumeTag, want %q", got, want)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package delayqueue
+
+// PauseTask 暂停等待执行的任务，任务保留数据但不会到期执行，直到通过 ResumeTask 恢复
+// 暂停时记录任务剩余的等待时间，恢复后从剩余时间继续倒计时；已经到期、等待领取或被扣留的任务恢复后立即执行。
+// 暂停中的任务依然可以被 Delete 删除，也会出现在快照与任务列表中，但不能被 Reschedule 调整；
+// 任务不在等待执行时返回 ErrTaskNotFound，队列已经停止时返回 ErrClosed
+func (q *DelayQueue) PauseTask(id string) error {
+	err := ErrClosed
+	q.do(func() {
+		t := q.lookupTask(id)
+		if t == nil {
+			err = ErrTaskNotFound
+			return
+		}
+
+		t.ensureExtra().pauseLeft = t.execTime.Sub(q.clock.Now())
+		if t.extra().pauseLeft < 0 {
+			t.ensureExtra().pauseLeft = 0
+		}
+		q.removeTask(id)
+		t.ensureExtra().paused = true
+		q.pausedTasks = append(q.pausedTasks, t)
+		q.indexTask(t)
+		err = nil
+	})
+	return err
+}
+
+// ResumeTask 恢复被 PauseTask 暂停的任务，任务在剩余的等待时间之后执行
+// 任务没有被暂停时返回 ErrTaskNotFound，队列已经停止时返回 ErrClosed
+func (q *DelayQueue) ResumeTask(id string) error {
+	err := ErrClosed
+	q.do(func() {
+		t, ok := q.taskIndex[id]
+		if !ok || !t.extra().paused {
+			err = ErrTaskNotFound
+			return
+		}
+
+		q.removePausedTask(id)
+		t.execTime = q.clock.Now().Add(t.extra().pauseLeft)
+		t.ensureExtra().pauseLeft = 0
+		t.ensureExtra().paused = false
+		q.addTask(t)
+		if perr := q.persist(t); perr != nil {
+			q.logger.Printf("save task %s to storage failed: %v", id, perr)
+		}
+		err = nil
+	})
+	return err
+}
+
+// removePausedTask 从暂停的任务中移除指定任务，返回任务是否存在
+func (q *DelayQueue) removePausedTask(id string) bool {
+	for i, t := range q.pausedTasks {
+		if t.id == id {
+			q.pausedTasks = append(q.pausedTasks[:i], q.pausedTasks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
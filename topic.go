@@ -0,0 +1,148 @@
+package delayqueue
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Topic 队列中的具名子队列，同一进程中的多类任务（例如邮件、订单超时、缓存过期）可以共用一个队列，
+// 各自独立地限制并发、暂停、统计与清空，而不需要为每一类任务单独创建队列
+type Topic struct {
+	q    *DelayQueue
+	name string
+}
+
+// topicState 子队列的并发限制与执行统计，执行任务的协程会并发访问
+type topicState struct {
+	sem       chan struct{} // 并发限制，为 nil 表示不限制
+	executing atomic.Int64
+	executed  atomic.Uint64
+	failed    atomic.Uint64
+}
+
+// TopicStats 子队列的统计信息
+type TopicStats struct {
+	Pending   int    // 等待执行的任务数量
+	Executing int    // 正在执行的任务数量
+	Executed  uint64 // 已经执行完成的任务数量
+	Failed    uint64 // 执行失败（返回错误或 panic）的任务数量
+}
+
+// Topic 返回指定名称的子队列，子队列在第一次使用时自动创建
+func (q *DelayQueue) Topic(name string) *Topic {
+	return &Topic{q: q, name: name}
+}
+
+// Name 返回子队列名称
+func (t *Topic) Name() string {
+	return t.name
+}
+
+// Push 向子队列推送任务
+func (t *Topic) Push(timeInterval time.Duration, f func(), opts ...PushOption) string {
+	pt := t.q.newPushTask(timeInterval)
+	pt.fn = f
+	pt.ensureExtra().topic = t.name
+	return t.q.submit(pt.apply(opts))
+}
+
+// PushHandler 向子队列推送由具名处理函数执行的任务
+func (t *Topic) PushHandler(timeInterval time.Duration, name string, payload []byte, opts ...PushOption) string {
+	pt := t.q.newPushTask(timeInterval)
+	pt.handler = name
+	pt.arg = payload
+	pt.ensureExtra().topic = t.name
+	return t.q.submit(pt.apply(opts))
+}
+
+// SetMaxConcurrency 限制子队列同时执行的任务数量，超出的到期任务等待前面的任务执行完成；n <= 0 表示不限制
+// 只对之后开始执行的任务生效。开启了 WithMaxConcurrency 时，等待中的任务会占用队列的执行协程
+func (t *Topic) SetMaxConcurrency(n int) {
+	st := t.q.topicState(t.name)
+	t.q.topicsMu.Lock()
+	defer t.q.topicsMu.Unlock()
+	if n <= 0 {
+		st.sem = nil
+		return
+	}
+	st.sem = make(chan struct{}, n)
+}
+
+// Pause 暂停子队列，暂停期间到期的任务会被扣留，直到 Resume 后按原有的执行时间顺序依次执行
+func (t *Topic) Pause() {
+	t.q.do(func() {
+		t.q.pausedTopics[t.name] = struct{}{}
+	})
+}
+
+// Resume 恢复子队列，暂停期间被扣留的任务会立即按顺序执行
+func (t *Topic) Resume() {
+	t.q.do(func() {
+		delete(t.q.pausedTopics, t.name)
+		t.q.releaseHeld()
+	})
+}
+
+// Purge 删除子队列中所有等待执行的任务，返回删除的任务数量
+func (t *Topic) Purge() int {
+	return t.q.deleteWhere(func(task *task) bool {
+		return task.extra().topic == t.name
+	}, nil)
+}
+
+// Stats 返回子队列的统计信息
+func (t *Topic) Stats() TopicStats {
+	q := t.q
+	var pending int
+	q.do(func() {
+		for _, task := range q.pendingTasks() {
+			if task.extra().topic == t.name {
+				pending++
+			}
+		}
+	})
+
+	st := q.topicState(t.name)
+	return TopicStats{
+		Pending:   pending,
+		Executing: int(st.executing.Load()),
+		Executed:  st.executed.Load(),
+		Failed:    st.failed.Load(),
+	}
+}
+
+// topicState 返回子队列的状态，不存在时创建
+func (q *DelayQueue) topicState(name string) *topicState {
+	q.topicsMu.Lock()
+	defer q.topicsMu.Unlock()
+
+	st, ok := q.topics[name]
+	if !ok {
+		st = &topicState{}
+		q.topics[name] = st
+	}
+	return st
+}
+
+// enterTopic 任务开始执行前等待子队列的并发配额，返回执行结束后需要调用的函数
+func (q *DelayQueue) enterTopic(name string) func(err error) {
+	st := q.topicState(name)
+	q.topicsMu.Lock()
+	sem := st.sem
+	q.topicsMu.Unlock()
+
+	if sem != nil {
+		sem <- struct{}{}
+	}
+	st.executing.Add(1)
+	return func(err error) {
+		st.executing.Add(-1)
+		st.executed.Add(1)
+		if err != nil {
+			st.failed.Add(1)
+		}
+		if sem != nil {
+			<-sem
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package delayqueue
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestTopicMaxConcurrency(t *testing.T) {
+	q, clock := newTestQueue(t)
+	mail := q.Topic("mail")
+	mail.SetMaxConcurrency(1)
+
+	var running, peak atomic.Int64
+	release := make(chan struct{})
+	for i := 0; i < 3; i++ {
+		mail.Push(time.Second, func() {
+			n := running.Add(1)
+			if n > peak.Load() {
+				peak.Store(n)
+			}
+			<-release
+			running.Add(-1)
+		})
+	}
+
+	fireNext(clock, time.Second)
+	for i := 0; i < 3; i++ {
+		waitFor(t, "a task to start", func() bool { return running.Load() == 1 })
+		// 其余到期任务等待并发配额，不会同时执行
+		time.Sleep(10 * time.Millisecond)
+		if s := mail.Stats(); s.Executing != 1 {
+			t.Errorf("Executing = %d, want 1", s.Executing)
+		}
+		release <- struct{}{}
+	}
+	waitFor(t, "all tasks to finish", func() bool { return mail.Stats().Executed == 3 })
+	if peak.Load() != 1 {
+		t.Errorf("peak concurrency = %d, want 1", peak.Load())
+	}
+}
+
+func TestTopicPauseHoldsTasks(t *testing.T) {
+	q, clock := newTestQueue(t)
+	mail := q.Topic("mail")
+	ran := make(chan string, 2)
+	mail.Push(time.Second, func() { ran <- "mail" })
+	q.Topic("orders").Push(time.Second, func() { ran <- "orders" })
+
+	mail.Pause()
+	fireNext(clock, time.Second)
+
+	// 其他子队列照常执行，暂停的子队列扣留到期的任务
+	if got := receive(t, ran); got != "orders" {
+		t.Fatalf("ran %s, want orders", got)
+	}
+	settle(q)
+	select {
+	case got := <-ran:
+		t.Fatalf("%s ran while paused", got)
+	default:
+	}
+	if s := mail.Stats(); s.Pending != 1 {
+		t.Errorf("Pending while paused = %d, want 1", s.Pending)
+	}
+
+	mail.Resume()
+	if got := receive(t, ran); got != "mail" {
+		t.Errorf("ran %s after Resume, want mail", got)
+	}
+}
+
+func TestTopicPurgeAndStats(t *testing.T) {
+	q, clock := newTestQueue(t)
+	mail, orders := q.Topic("mail"), q.Topic("orders")
+	mail.Push(time.Minute, func() {})
+	mail.PushHandler(time.Minute, "send", nil)
+	orders.Push(time.Second, func() {})
+	orders.Push(time.Second, func() { panic("boom") })
+
+	// 只删除所属子队列的任务
+	if n := mail.Purge(); n != 2 {
+		t.Errorf("Purge = %d, want 2", n)
+	}
+	if s := mail.Stats(); s.Pending != 0 {
+		t.Errorf("mail Pending after Purge = %d, want 0", s.Pending)
+	}
+	if s := orders.Stats(); s.Pending != 2 {
+		t.Errorf("orders Pending = %d, want 2", s.Pending)
+	}
+
+	fireNext(clock, time.Second)
+	waitFor(t, "orders to finish", func() bool { return orders.Stats().Executed == 2 })
+	s := orders.Stats()
+	if s.Pending != 0 || s.Executing != 0 || s.Failed != 1 {
+		t.Errorf("orders Stats = %+v, want 0 pending, 0 executing, 1 failed", s)
+	}
+}
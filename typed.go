@@ -0,0 +1,64 @@
+package delayqueue
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// TypedQueue 类型化的延时任务队列，任务数据是 T 类型的值，由创建时指定的处理函数执行
+//
+// 任务以具名处理函数任务的形式保存在底层队列中，T 编码为 JSON 后作为任务数据，
+// 因此可以持久化、导出快照，也不需要为每个任务创建捕获可变状态的闭包。
+// 多个 TypedQueue 可以共享同一个底层队列，各自使用不同的处理函数名称
+type TypedQueue[T any] struct {
+	q    *DelayQueue
+	name string
+}
+
+// NewTypedQueue 在 q 上注册名为 name 的处理函数，返回推送 T 类型任务的队列
+// 任务数据无法解码为 T 时不调用 handler，任务以解码错误结束；推送时通过 WithRetry 设置了重试策略的任务，
+// 解码错误与 handler 返回的错误都会按该策略重试
+// 队列创建时从存储中加载的已过期任务会立即执行，它们的处理函数需要通过 WithHandler 提前注册
+func NewTypedQueue[T any](q *DelayQueue, name string, handler func(ctx context.Context, v T) error) *TypedQueue[T] {
+	q.RegisterHandlerContext(name, func(ctx context.Context, payload []byte) error {
+		var v T
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return fmt.Errorf("delayqueue: decode payload as %T: %w", v, err)
+		}
+		return handler(ctx, v)
+	})
+	return &TypedQueue[T]{q: q, name: name}
+}
+
+// Queue 返回底层队列
+func (tq *TypedQueue[T]) Queue() *DelayQueue {
+	return tq.q
+}
+
+// Push 推送 timeInterval 之后执行的任务，v 编码为 JSON 后作为任务数据；编码失败或任务被拒绝时返回错误
+func (tq *TypedQueue[T]) Push(timeInterval time.Duration, v T, opts ...PushOption) (string, error) {
+	return tq.q.PushJSON(timeInterval, tq.name, v, opts...)
+}
+
+// PushAt 推送在指定时刻 execTime 执行的任务，与 DelayQueue.PushAt 相同，execTime 不受 WithDelayFromEnqueue 的影响
+func (tq *TypedQueue[T]) PushAt(execTime time.Time, v T, opts ...PushOption) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+
+	t := tq.q.newPushTaskAt(tq.q.genTaskId(), execTime)
+	t.handler = tq.name
+	t.arg = data
+	if err := tq.q.push(t.apply(opts)); err != nil {
+		return "", err
+	}
+	return t.id, nil
+}
+
+// Delete 删除任务，与 DelayQueue.Delete 相同
+func (tq *TypedQueue[T]) Delete(id string) (bool, error) {
+	return tq.q.Delete(id)
+}
@@ -0,0 +1,88 @@
+package delayqueue
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type typedOrder struct {
+	ID    int      `json:"id"`
+	Email string   `json:"email"`
+	Items []string `json:"items"`
+}
+
+func TestTypedQueueRoundTrip(t *testing.T) {
+	q, clock := newTestQueue(t)
+	got := make(chan typedOrder, 1)
+	tq := NewTypedQueue(q, "order", func(_ context.Context, v typedOrder) error {
+		got <- v
+		return nil
+	})
+
+	want := typedOrder{ID: 42, Email: "a@example.com", Items: []string{"book", "pen"}}
+	if _, err := tq.Push(time.Second, want); err != nil {
+		t.Fatal(err)
+	}
+	fireNext(clock, time.Second)
+
+	v := receive(t, got)
+	if v.ID != want.ID || v.Email != want.Email || strings.Join(v.Items, ",") != "book,pen" {
+		t.Errorf("handler got %+v, want %+v", v, want)
+	}
+}
+
+func TestTypedQueueBadPayload(t *testing.T) {
+	errs := make(chan error, 1)
+	q, clock := newTestQueue(t, OnComplete(func(_ string, _ time.Duration, err error) { errs <- err }))
+	called := false
+	NewTypedQueue(q, "order", func(context.Context, typedOrder) error {
+		called = true
+		return nil
+	})
+
+	// 绕过 TypedQueue 直接推送无法解码的任务数据
+	q.PushHandler(time.Second, "order", []byte("not json"))
+	fireNext(clock, time.Second)
+
+	err := receive(t, errs)
+	if err == nil || !strings.Contains(err.Error(), "decode payload") {
+		t.Errorf("error = %v, want a decode error", err)
+	}
+	if called {
+		t.Error("handler called with an undecodable payload")
+	}
+}
+
+func TestTypedQueueWithRetry(t *testing.T) {
+	q, clock := newTestQueue(t)
+	attempts := make(chan int, 2)
+	n := 0
+	tq := NewTypedQueue(q, "order", func(context.Context, typedOrder) error {
+		n++
+		attempts <- n
+		if n == 1 {
+			return errors.New("temporary")
+		}
+		return nil
+	})
+
+	// 推送选项可以为类型化的任务设置重试策略
+	policy := RetryPolicy{MaxAttempts: 2, Backoff: ConstantBackoff(time.Second)}
+	id, err := tq.Push(time.Second, typedOrder{ID: 1}, WithRetry(policy))
+	if err != nil {
+		t.Fatal(err)
+	}
+	fireNext(clock, time.Second)
+	receive(t, attempts)
+	waitFor(t, "the retry to be scheduled", func() bool {
+		_, ok := q.Get(id)
+		return ok
+	})
+	fireNext(clock, time.Second)
+	if got := receive(t, attempts); got != 2 {
+		t.Errorf("attempt = %d, want 2", got)
+	}
+}
@@ -0,0 +1,78 @@
+package delayqueue
+
+import "time"
+
+// UniquePolicy 通过 PushUnique 推送的任务与已有的同 key 任务冲突时的处理策略
+type UniquePolicy int
+
+const (
+	UniqueReplace UniquePolicy = iota // 删除已有的任务，保留新推送的任务，默认策略
+	UniqueIgnore                      // 保留已有的任务，忽略新推送的任务
+	UniqueReject                      // 保留已有的任务，拒绝新推送的任务并返回 ErrDuplicateKey
+)
+
+// PushUnique 用户推送按 key 去重的任务，同一个 key 同时只会有一个等待执行的任务
+// 已有同 key 的任务在等待执行时，按 WithUniquePolicy 设置的策略处理：默认删除已有的任务，只保留最新的一次推送，
+// 适合「重新计算用户 X」这类只需要执行最后一次的任务；UniqueIgnore 策略下返回已有任务的id；
+// UniqueReject 策略下新任务被拒绝，记录 ErrDuplicateKey 并返回空字符串，需要拿到错误的调用方使用 Push 与 WithUniqueKey。
+// 已经开始执行的任务不参与去重，执行期间推送的同 key 任务会照常安排
+func (q *DelayQueue) PushUnique(key string, timeInterval time.Duration, f func(), opts ...PushOption) string {
+	if q.uniquePolicy == UniqueIgnore {
+		// 先在调度协程中查找已有的任务，避免无谓的推送；并发推送的竞争由调度协程接收任务时兜底
+		var existing string
+		q.do(func() {
+			if t := q.uniqueTask(key); t != nil {
+				existing = t.id
+			}
+		})
+		if existing != "" {
+			return existing
+		}
+	}
+
+	t := q.newPushTask(timeInterval)
+	t.fn = f
+	return q.submit(t.apply([]PushOption{WithUniqueKey(key)}).apply(opts))
+}
+
+// checkUniqueKey 推送前在调度协程中检查去重 key 是否已经有等待执行的任务，UniqueReject 策略下存在时返回 ErrDuplicateKey
+// 并发推送同一个 key 的竞争由调度协程接收任务时兜底
+func (q *DelayQueue) checkUniqueKey(key string) error {
+	if q.uniquePolicy != UniqueReject {
+		return nil
+	}
+	var exists bool
+	q.do(func() {
+		exists = q.uniqueTask(key) != nil
+	})
+	if exists {
+		return ErrDuplicateKey
+	}
+	return nil
+}
+
+// uniqueTask 返回等待执行的同 key 任务，不存在时返回 nil
+func (q *DelayQueue) uniqueTask(key string) *task {
+	return q.uniqueKeys[key]
+}
+
+// acceptUnique 调度协程接收去重任务时处理同 key 的冲突，返回新任务是否需要加入任务列表
+func (q *DelayQueue) acceptUnique(t *task) bool {
+	old := q.uniqueTask(t.extra().unique)
+	if old == nil {
+		return true
+	}
+
+	if q.uniquePolicy != UniqueReplace {
+		q.logEvent(LevelDebug, "task dropped", "id", t.id, "reason", "unique", "key", t.extra().unique)
+		q.fireDrop(t, "unique")
+		t.complete()
+		return false
+	}
+
+	q.takeTask(old.id)
+	old.complete()
+	q.forget(old.id)
+	q.logEvent(LevelDebug, "task replaced", "id", old.id, "by", t.id, "key", t.extra().unique)
+	return true
+}
@@ -0,0 +1,73 @@
+package delayqueue
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// pushTwice 以同一个 key 推送两个任务，执行时分别向 ran 发送 first、second
+func pushTwice(q *DelayQueue, ran chan string) (first, second string) {
+	first = q.PushUnique("recompute:42", time.Second, func() { ran <- "first" })
+	second = q.PushUnique("recompute:42", time.Second, func() { ran <- "second" })
+	return first, second
+}
+
+// expectOnly 到期后只有 want 执行
+func expectOnly(t *testing.T, q *DelayQueue, clock *ManualClock, ran chan string, want string) {
+	t.Helper()
+	fireNext(clock, time.Second)
+	if got := receive(t, ran); got != want {
+		t.Errorf("ran %s, want %s", got, want)
+	}
+	settle(q)
+	select {
+	case got := <-ran:
+		t.Errorf("%s also ran", got)
+	default:
+	}
+}
+
+func TestPushUniqueReplace(t *testing.T) {
+	q, clock := newTestQueue(t)
+	ran := make(chan string, 2)
+
+	first, second := pushTwice(q, ran)
+	if second == "" || second == first {
+		t.Fatalf("second id = %q, want a new id", second)
+	}
+	// 已有的任务被删除，只保留最新的一次推送
+	if _, ok := q.Get(first); ok {
+		t.Errorf("replaced task %s still pending", first)
+	}
+	expectOnly(t, q, clock, ran, "second")
+}
+
+func TestPushUniqueIgnore(t *testing.T) {
+	q, clock := newTestQueue(t, WithUniquePolicy(UniqueIgnore))
+	ran := make(chan string, 2)
+
+	// 新推送的任务被忽略，返回已有任务的id
+	first, second := pushTwice(q, ran)
+	if second != first {
+		t.Errorf("second id = %q, want the existing id %q", second, first)
+	}
+	expectOnly(t, q, clock, ran, "first")
+}
+
+func TestPushUniqueReject(t *testing.T) {
+	q, clock := newTestQueue(t, WithUniquePolicy(UniqueReject))
+	ran := make(chan string, 2)
+
+	// 新推送的任务被拒绝，返回空字符串
+	first, second := pushTwice(q, ran)
+	if first == "" || second != "" {
+		t.Errorf("ids = %q, %q, want the first id and an empty string", first, second)
+	}
+	// 通过 Push 推送时可以拿到具体的错误
+	h := q.Push(time.Second, func() { ran <- "third" }, WithUniqueKey("recompute:42"))
+	if err := h.Err(); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("Push with a duplicate key error = %v, want ErrDuplicateKey", err)
+	}
+	expectOnly(t, q, clock, ran, "first")
+}
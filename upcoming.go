@@ -0,0 +1,130 @@
+package delayqueue
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// UpcomingFireTimes 返回接下来最近的 n 个不同的执行时间，按时间先后排列，同一时刻到期的多个任务只算一个
+// 适合在一批任务执行之前预热下游连接；已经到期但被扣留或等待领取的任务不计入
+func (q *DelayQueue) UpcomingFireTimes(n int) []time.Time {
+	if n <= 0 {
+		return nil
+	}
+
+	var times []time.Time
+	q.do(func() {
+		times = q.earliestFireTimes(n)
+	})
+	return times
+}
+
+// earliestFireTimes 返回任务堆与时间轮中最早的 n 个不同的执行时间，按时间先后排列
+// 用大小为 n 的大顶堆做部分选择，复杂度为 O(m log n)，不需要对全部 m 个任务排序
+func (q *DelayQueue) earliestFireTimes(n int) []time.Time {
+	if total := q.scheduledCount(); n > total {
+		n = total
+	}
+	if n == 0 {
+		return nil
+	}
+	h := make(latestFirst, 0, n)
+	seen := make(map[int64]struct{}, n)
+	consider := func(t *task) {
+		key := t.execTime.UnixNano()
+		if _, ok := seen[key]; ok {
+			return
+		}
+		if len(h) < n {
+			heap.Push(&h, t.execTime)
+		} else if t.execTime.Before(h[0]) {
+			delete(seen, h[0].UnixNano())
+			h[0] = t.execTime
+			heap.Fix(&h, 0)
+		} else {
+			return
+		}
+		seen[key] = struct{}{}
+	}
+
+	for _, t := range q.tasks {
+		consider(t)
+	}
+	if q.wheel != nil {
+		q.wheel.each(consider)
+	}
+
+	times := []time.Time(h)
+	sort.Slice(times, func(i, j int) bool {
+		return times[i].Before(times[j])
+	})
+	return times
+}
+
+// scheduledCount 返回任务堆与时间轮中的任务数量
+func (q *DelayQueue) scheduledCount() int {
+	n := len(q.tasks)
+	if q.wheel != nil {
+		n += q.wheel.n
+	}
+	return n
+}
+
+// latestFirst 执行时间的大顶堆，堆顶是最晚的时间
+type latestFirst []time.Time
+
+func (h latestFirst) Len() int           { return len(h) }
+func (h latestFirst) Less(i, j int) bool { return h[i].After(h[j]) }
+func (h latestFirst) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *latestFirst) Push(x any) {
+	*h = append(*h, x.(time.Time))
+}
+
+func (h *latestFirst) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return t
+}
+
+// Peek 返回下一个将要到期的任务的信息，没有等待到期的任务时返回 false
+// 与 UpcomingFireTimes 相同，已经到期但被扣留或等待领取的任务不计入；可以与调度协程并发调用
+func (q *DelayQueue) Peek() (TaskInfo, bool) {
+	var (
+		info TaskInfo
+		ok   bool
+	)
+	q.do(func() {
+		if t := q.nextScheduled(); t != nil {
+			info, ok = q.taskInfo(t, q.clock.Now()), true
+		}
+	})
+	return info, ok
+}
+
+// NextFireTime 返回下一个将要到期的任务的执行时间，没有等待到期的任务时返回 false
+// 可以据此展示下一次执行的剩余时间，或者在长时间没有任务时决定是否停止服务
+func (q *DelayQueue) NextFireTime() (time.Time, bool) {
+	info, ok := q.Peek()
+	return info.ExecTime, ok
+}
+
+// nextScheduled 返回任务堆与时间轮中最先到期的任务
+// 堆顶是任务堆中最早的任务，时间轮中的任务无序，只需线性查找其中最早的一个，不需要排序
+func (q *DelayQueue) nextScheduled() *task {
+	var next *task
+	if len(q.tasks) > 0 {
+		next = q.tasks[0]
+	}
+	if q.wheel == nil || q.wheel.n == 0 {
+		return next
+	}
+	q.wheel.each(func(t *task) {
+		if next == nil || t.before(next) {
+			next = t
+		}
+	})
+	return next
+}
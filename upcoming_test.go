@@ -0,0 +1,76 @@
+package delayqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestUpcomingFireTimes(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts []Option
+	}{
+		{"heap", nil},
+		{"wheel", []Option{WithTimingWheel(time.Second, 4)}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			q, _ := newTestQueue(t, tt.opts...)
+
+			// 乱序推送，部分任务同时到期，时间轮中的任务分布在多层
+			for _, d := range []time.Duration{30, 5, 12, 5, 90, 12, 3, 60} {
+				q.Push(d*time.Second, func() {})
+			}
+
+			got := q.UpcomingFireTimes(4)
+			want := []time.Duration{3, 5, 12, 30}
+			if len(got) != len(want) {
+				t.Fatalf("UpcomingFireTimes(4) = %v, want %d times", got, len(want))
+			}
+			for i, d := range want {
+				if at := testStart.Add(d * time.Second); !got[i].Equal(at) {
+					t.Errorf("UpcomingFireTimes(4)[%d] = %v, want %v", i, got[i], at)
+				}
+			}
+			if got := q.UpcomingFireTimes(100); len(got) != 6 {
+				t.Errorf("UpcomingFireTimes(100) returned %d times, want all 6 distinct", len(got))
+			}
+
+			info, ok := q.Peek()
+			if !ok || !info.ExecTime.Equal(testStart.Add(3*time.Second)) {
+				t.Errorf("Peek = %v %v, want task at +3s", info.ExecTime, ok)
+			}
+		})
+	}
+}
+
+func TestUpcomingFireTimesEmpty(t *testing.T) {
+	q, _ := newTestQueue(t)
+
+	if got := q.UpcomingFireTimes(3); got != nil {
+		t.Errorf("UpcomingFireTimes on empty queue = %v, want nil", got)
+	}
+	if _, ok := q.Peek(); ok {
+		t.Error("Peek on empty queue returned a task")
+	}
+	if _, ok := q.NextFireTime(); ok {
+		t.Error("NextFireTime on empty queue returned a time")
+	}
+}
+
+func BenchmarkUpcomingFireTimes(b *testing.B) {
+	q := NewDelayQueue(WithClock(NewManualClock(testStart)))
+	defer func() {
+		_ = q.Stop(context.Background())
+	}()
+	for i := 0; i < 100_000; i++ {
+		q.Push(time.Duration(i%50_000+1)*time.Second, func() {})
+	}
+	q.Len()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		q.UpcomingFireTimes(10)
+	}
+}
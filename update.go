@@ -0,0 +1,75 @@
+package delayqueue
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Reschedule 将等待执行的任务调整为 newDelay 之后执行，可以推迟也可以提前
+// 调整在调度协程中完成，与任务到期不会产生竞争：返回 nil 时任务一定会按新的时间执行；
+// 任务不在等待执行时返回 ErrTaskNotFound，队列已经停止时返回 ErrClosed
+func (q *DelayQueue) Reschedule(id string, newDelay time.Duration) error {
+	return q.updateTask(id, nil, func(t *task) {
+		t.execTime = q.clock.Now().Add(newDelay)
+	})
+}
+
+// UpdatePayload 替换等待执行的具名处理函数任务或发布消息的任务的数据，执行时间保持不变
+// 任务不在等待执行时返回 ErrTaskNotFound，基于闭包的任务没有数据，返回 ErrNotSerializable；队列已经停止时返回 ErrClosed
+func (q *DelayQueue) UpdatePayload(id string, payload []byte) error {
+	return q.updateTask(id, func(t *task) error {
+		if !t.serializable() {
+			return ErrNotSerializable
+		}
+		return nil
+	}, func(t *task) {
+		t.arg = payload
+	})
+}
+
+// updateTask 在调度协程中修改等待执行的任务，修改后重新调整任务在任务列表中的位置
+// check 不为 nil 时先检查任务，返回错误时不修改任务并返回该错误
+func (q *DelayQueue) updateTask(id string, check func(t *task) error, fn func(t *task)) error {
+	err := ErrClosed
+	q.do(func() {
+		t := q.lookupTask(id)
+		if t == nil {
+			err = ErrTaskNotFound
+			return
+		}
+		if check != nil {
+			if err = check(t); err != nil {
+				return
+			}
+		}
+
+		if t.index >= 0 {
+			fn(t)
+			heap.Fix(&q.tasks, t.index)
+		} else {
+			// 时间轮中的任务按新的执行时间重新放置，已经到期的任务（被扣留或等待领取）重新回到任务列表中
+			if t.bucket != nil {
+				q.wheel.remove(t)
+			} else {
+				q.removeHeldTask(id)
+				q.removeReadyTask(id)
+			}
+			fn(t)
+			q.addTask(t)
+		}
+
+		if perr := q.persist(t); perr != nil {
+			q.logger.Printf("save task %s to storage failed: %v", id, perr)
+		}
+		err = nil
+	})
+	return err
+}
+
+// lookupTask 查找等待执行的任务，包括被扣留与等待领取的任务，不包括暂停的任务
+func (q *DelayQueue) lookupTask(id string) *task {
+	if t := q.findTask(id); t != nil && !t.extra().paused {
+		return t
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+package delayqueue
+
+// ExecTimeouts 返回执行超时的任务数量
+func (q *DelayQueue) ExecTimeouts() uint64 {
+	return q.execTimeouts.Load()
+}
+
+// watchExec 为正在执行的任务开启看门狗，任务执行超过 maxExecDuration 时记录日志、计数并回调 onExecTimeout
+// Go 无法强制结束协程，超时的任务会继续执行直到自行返回；返回的函数用于在任务结束时关闭看门狗
+func (q *DelayQueue) watchExec(id string) (stop func()) {
+	timer := q.clock.NewTimer(q.maxExecDuration)
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-timer.C():
+			q.execTimeouts.Add(1)
+			q.logger.Printf("task %s has been executing for more than %s", id, q.maxExecDuration)
+			if q.onExecTimeout != nil {
+				q.onExecTimeout(id)
+			}
+		case <-done:
+			timer.Stop()
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
@@ -0,0 +1,38 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaxExecDurationReportsSlowTask(t *testing.T) {
+	timedOut := make(chan string, 1)
+	q, clock := newTestQueue(t, WithMaxExecDuration(2*time.Second), OnExecTimeout(func(id string) {
+		timedOut <- id
+	}))
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	slow := q.Push(time.Second, func() {
+		close(started)
+		<-release
+	})
+	fireNext(clock, time.Second)
+	receive(t, started)
+
+	// 任务开始执行时看门狗已经开始计时，超过 2 秒仍未返回时回调
+	fireNext(clock, 2*time.Second)
+	if id := receive(t, timedOut); id != slow.ID() {
+		t.Errorf("OnExecTimeout id = %q, want %q", id, slow.ID())
+	}
+	close(release)
+	receive(t, slow.Done())
+
+	// 按时返回的任务不会触发回调
+	fast := q.Push(time.Second, func() {})
+	fireNext(clock, time.Second)
+	receive(t, fast.Done())
+	if n := q.ExecTimeouts(); n != 1 {
+		t.Errorf("ExecTimeouts = %d, want 1", n)
+	}
+}
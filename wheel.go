@@ -0,0 +1,249 @@
+package delayqueue
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// timingWheel 分层时间轮，与任务堆配合使用
+//
+// 执行时间落在当前刻度之后的任务放入时间轮，插入与删除都是 O(1)；时间轮转到任务所在的刻度时，
+// 任务被移入任务堆，由任务堆在刻度内精确排序并按时触发。这样任务堆中只有即将到期的少量任务，
+// 大量短延时任务的推送不再需要 O(log n) 的堆调整。
+//
+// 第一层覆盖 tick*size 的时长，超出的任务放入上一层，上一层的刻度是下一层的总时长，依此按需逐层创建；
+// 上层的刻度到期时，其中的任务重新插入下层，逐级降落到任务堆中。
+type timingWheel struct {
+	root *wheelLevel
+	n    int // 时间轮中的任务数量
+}
+
+// wheelLevel 时间轮的一层
+type wheelLevel struct {
+	tick     time.Duration        // 刻度
+	size     int64                // 槽位数量
+	interval time.Duration        // 一圈的时长，tick*size
+	current  time.Time            // 当前刻度的起始时间
+	buckets  []map[*task]struct{} // 各个槽位中的任务
+	overflow *wheelLevel          // 上一层，按需创建
+}
+
+// newTimingWheel 创建起始时间为 now 的时间轮
+func newTimingWheel(tick time.Duration, size int, now time.Time) *timingWheel {
+	return &timingWheel{root: newWheelLevel(tick, int64(size), now)}
+}
+
+// newWheelLevel 创建时间轮的一层
+func newWheelLevel(tick time.Duration, size int64, now time.Time) *wheelLevel {
+	w := &wheelLevel{
+		tick:     tick,
+		size:     size,
+		interval: tick * time.Duration(size),
+		buckets:  make([]map[*task]struct{}, size),
+	}
+	for i := range w.buckets {
+		w.buckets[i] = make(map[*task]struct{})
+	}
+	w.current = w.floor(now)
+	return w
+}
+
+// add 将任务放入时间轮，任务在当前刻度内到期、应当直接放入任务堆时返回 false
+func (tw *timingWheel) add(t *task) bool {
+	if !tw.root.add(t) {
+		return false
+	}
+	tw.n++
+	return true
+}
+
+// remove 将任务移出时间轮
+func (tw *timingWheel) remove(t *task) {
+	delete(t.bucket, t)
+	t.bucket = nil
+	tw.n--
+}
+
+// advance 将时间轮转到 now，转过的刻度中的任务交给 expired 重新放置
+func (tw *timingWheel) advance(now time.Time, expired func(t *task)) {
+	tw.root.advance(now, func(t *task) {
+		tw.n--
+		expired(t)
+	})
+}
+
+// next 返回时间轮下一次需要转动的时间，时间轮为空时返回零值
+func (tw *timingWheel) next() time.Time {
+	if tw.n == 0 {
+		return time.Time{}
+	}
+	return tw.root.next()
+}
+
+// drain 取出时间轮中的所有任务，按执行顺序排列
+func (tw *timingWheel) drain() []*task {
+	tasks := tw.tasks()
+	for _, t := range tasks {
+		tw.remove(t)
+	}
+	return tasks
+}
+
+// tasks 返回时间轮中的所有任务，按执行顺序排列
+func (tw *timingWheel) tasks() []*task {
+	tasks := make([]*task, 0, tw.n)
+	tw.each(func(t *task) {
+		tasks = append(tasks, t)
+	})
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].before(tasks[j])
+	})
+	return tasks
+}
+
+// each 遍历时间轮中的所有任务，不保证顺序
+func (tw *timingWheel) each(fn func(t *task)) {
+	for w := tw.root; w != nil; w = w.overflow {
+		for _, b := range w.buckets {
+			for t := range b {
+				fn(t)
+			}
+		}
+	}
+}
+
+// floor 返回 t 所在刻度的起始时间
+func (w *wheelLevel) floor(t time.Time) time.Time {
+	n := t.UnixNano()
+	d := int64(w.tick)
+	r := n % d
+	if r < 0 {
+		r += d
+	}
+	return time.Unix(0, n-r)
+}
+
+// slot 返回 t 所在刻度对应的槽位
+func (w *wheelLevel) slot(t time.Time) int64 {
+	s := (t.UnixNano() / int64(w.tick)) % w.size
+	if s < 0 {
+		s += w.size
+	}
+	return s
+}
+
+func (w *wheelLevel) add(t *task) bool {
+	if t.execTime.Before(w.current.Add(w.tick)) {
+		return false
+	}
+	if t.execTime.Before(w.current.Add(w.interval)) {
+		b := w.buckets[w.slot(t.execTime)]
+		b[t] = struct{}{}
+		t.bucket = b
+		return true
+	}
+
+	// 超出本层的范围，放入上一层
+	if w.overflow == nil {
+		w.overflow = newWheelLevel(w.interval, w.size, w.current)
+	}
+	return w.overflow.add(t)
+}
+
+func (w *wheelLevel) advance(now time.Time, expired func(t *task)) {
+	target := w.floor(now)
+	if !target.After(w.current) {
+		return
+	}
+
+	// 先转到新的刻度再处理到期的任务，重新放置的任务不会落回正在清空的槽位
+	from := w.current
+	w.current = target
+
+	// 转过的刻度超过一圈时，所有槽位都已经到期
+	steps := int64(target.Sub(from) / w.tick)
+	if steps > w.size {
+		steps = w.size
+	}
+	for i := int64(1); i <= steps; i++ {
+		b := w.buckets[w.slot(from.Add(time.Duration(i)*w.tick))]
+		for t := range b {
+			delete(b, t)
+			t.bucket = nil
+			expired(t)
+		}
+	}
+
+	if w.overflow != nil {
+		w.overflow.advance(now, expired)
+	}
+}
+
+// next 返回本层与上面各层中最早需要转动的刻度，没有任务时返回零值
+// 上层的刻度比本层粗，上层槽位中的任务可能早于本层第一个非空的槽位到期，因此需要与上层的结果比较
+func (w *wheelLevel) next() time.Time {
+	var at time.Time
+	for i := int64(1); i <= w.size; i++ {
+		boundary := w.current.Add(time.Duration(i) * w.tick)
+		if len(w.buckets[w.slot(boundary)]) > 0 {
+			at = boundary
+			break
+		}
+	}
+	if w.overflow != nil {
+		if up := w.overflow.next(); !up.IsZero() && (at.IsZero() || up.Before(at)) {
+			return up
+		}
+	}
+	return at
+}
+
+// placeTask 将任务放入时间轮或任务堆
+func (q *DelayQueue) placeTask(t *task) {
+	if q.wheel != nil && q.wheel.add(t) {
+		t.index = -1
+		return
+	}
+	heap.Push(&q.tasks, t)
+}
+
+// advanceWheel 将时间轮转到 now，进入当前刻度的任务移入任务堆
+func (q *DelayQueue) advanceWheel(now time.Time) {
+	if q.wheel == nil {
+		return
+	}
+	q.wheel.advance(now, q.placeTask)
+}
+
+// nextWake 返回调度协程下一次需要醒来的时间，以及届时到期的任务
+// 时间轮需要先于堆顶的任务转动时，返回的任务为 nil；没有任何任务时返回零值
+func (q *DelayQueue) nextWake() (time.Time, *task) {
+	var (
+		wake time.Time
+		top  *task
+	)
+	if len(q.tasks) > 0 {
+		top = q.tasks[0]
+		wake = top.execTime
+	}
+	if q.wheel != nil {
+		if next := q.wheel.next(); !next.IsZero() && (wake.IsZero() || next.Before(wake)) {
+			return next, nil
+		}
+	}
+	return wake, top
+}
+
+// scheduledTasks 返回任务堆与时间轮中的所有任务，按执行顺序排列
+func (q *DelayQueue) scheduledTasks() []*task {
+	tasks := q.tasks.sorted()
+	if q.wheel == nil || q.wheel.n == 0 {
+		return tasks
+	}
+	tasks = append(tasks, q.wheel.tasks()...)
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].before(tasks[j])
+	})
+	return tasks
+}
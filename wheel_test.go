@@ -0,0 +1,29 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWheelOverflowFiresBeforeLaterRootTask(t *testing.T) {
+	q, clock := newTestQueue(t, WithTimingWheel(time.Second, 10))
+
+	fired := make(chan time.Time, 2)
+	q.Push(12*time.Second, func() { fired <- clock.Now() })
+
+	// 时间轮转到第 5 秒之后推送的任务落在第一层，先推送的任务仍在上一层的槽位中
+	// Len 让调度协程完成一轮循环，循环开始时时间轮转到当前时刻；推送之后再调用一次，确保任务已经放入时间轮
+	clock.BlockUntil(1)
+	clock.Set(testStart.Add(5 * time.Second))
+	q.Len()
+	q.Push(9*time.Second, func() { fired <- clock.Now() })
+	q.Len()
+
+	for _, want := range []time.Duration{12 * time.Second, 14 * time.Second} {
+		clock.BlockUntil(1)
+		clock.Set(testStart.Add(want))
+		if at := receive(t, fired); !at.Equal(testStart.Add(want)) {
+			t.Errorf("task fired at +%v, want +%v", at.Sub(testStart), want)
+		}
+	}
+}
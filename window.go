@@ -0,0 +1,88 @@
+package delayqueue
+
+import "time"
+
+// TimeWindow 每天重复的允许执行时间段，按 Location 所在时区的墙上时间计算，夏令时切换日同样适用
+type TimeWindow struct {
+	Start    time.Duration  // 时间段的开始，距当天零点的墙上时间，例如 9*time.Hour 表示 09:00
+	End      time.Duration  // 时间段的结束（不含），小于 Start 表示跨越零点到次日结束，等于 Start 表示全天
+	Location *time.Location // 时区，为 nil 时使用 time.Local
+}
+
+// PushWindowed 用户推送只在允许时间段内执行的任务
+// 按 timeInterval 计算出的执行时间如果落在任意一个时间段内则保持不变，否则顺延到下一个时间段的开始；
+// windows 为空时与 Push 相同；设置了 WithJitter 时，抖动后超出允许时间段的任务放弃抖动，保持在时间段内执行
+func (q *DelayQueue) PushWindowed(timeInterval time.Duration, windows []TimeWindow, f func(), opts ...PushOption) string {
+	t := q.newPushTask(timeInterval)
+	t.fn = f
+	// 允许时间段按墙上时间计算，执行时间在推送时确定，不随接收时刻平移
+	t.fromEnqueue = false
+	execTime := nextAllowedTime(t.execTime, windows)
+	t.execTime = execTime
+	t.apply(opts)
+	q.applyJitter(t)
+	if !nextAllowedTime(t.execTime, windows).Equal(t.execTime) {
+		t.execTime = execTime
+	}
+	t.jitter = noJitter
+
+	return q.submit(t)
+}
+
+// nextAllowedTime 计算 t 之后（含 t）最早落在允许时间段内的时刻
+func nextAllowedTime(t time.Time, windows []TimeWindow) time.Time {
+	if len(windows) == 0 {
+		return t
+	}
+
+	var next time.Time
+	for _, w := range windows {
+		candidate := w.next(t)
+		if next.IsZero() || candidate.Before(next) {
+			next = candidate
+		}
+	}
+	return next
+}
+
+// next 计算 t 之后（含 t）最早落在该时间段内的时刻
+func (w TimeWindow) next(t time.Time) time.Time {
+	loc := w.Location
+	if loc == nil {
+		loc = time.Local
+	}
+
+	local := t.In(loc)
+	year, month, day := local.Date()
+	// 跨零点的时间段可能从前一天开始，因此从前一天开始逐天检查；时间段每天都会出现，最多检查到次日
+	for offset := -1; offset <= 1; offset++ {
+		start := wallTime(year, month, day+offset, w.Start, loc)
+		end := wallTime(year, month, day+offset, w.End, loc)
+		if w.End <= w.Start {
+			end = wallTime(year, month, day+offset+1, w.End, loc)
+		}
+
+		if t.Before(start) {
+			return start
+		}
+		if t.Before(end) {
+			return t
+		}
+	}
+
+	// 理论上不会到达这里，兜底返回两天后的开始时间
+	return wallTime(year, month, day+2, w.Start, loc)
+}
+
+// wallTime 返回指定日期在 loc 时区中距零点 offset 的墙上时间
+// 使用 time.Date 按时分秒构造，夏令时切换当天得到的仍然是对应的墙上时间，而不是零点加上固定时长；
+// 因夏令时跳变而不存在的墙上时间按 time.Date 的规则归一化
+func wallTime(year int, month time.Month, day int, offset time.Duration, loc *time.Location) time.Time {
+	hour := int(offset / time.Hour)
+	offset -= time.Duration(hour) * time.Hour
+	min := int(offset / time.Minute)
+	offset -= time.Duration(min) * time.Minute
+	sec := int(offset / time.Second)
+	offset -= time.Duration(sec) * time.Second
+	return time.Date(year, month, day, hour, min, sec, int(offset), loc)
+}
@@ -0,0 +1,63 @@
+package delayqueue
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextAllowedTime(t *testing.T) {
+	business := TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour, Location: time.UTC}
+	night := TimeWindow{Start: 22 * time.Hour, End: 2 * time.Hour, Location: time.UTC}
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2024, 1, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name    string
+		t       time.Time
+		windows []TimeWindow
+		want    time.Time
+	}{
+		{"inside window unchanged", at(1, 10, 30), []TimeWindow{business}, at(1, 10, 30)},
+		{"before window deferred to start", at(1, 6, 0), []TimeWindow{business}, at(1, 9, 0)},
+		{"after window deferred to next day", at(1, 18, 0), []TimeWindow{business}, at(2, 9, 0)},
+		{"end is exclusive", at(1, 17, 0), []TimeWindow{business}, at(2, 9, 0)},
+		{"window across midnight", at(2, 1, 0), []TimeWindow{night}, at(2, 1, 0)},
+		{"earliest of several windows", at(1, 18, 0), []TimeWindow{business, night}, at(1, 22, 0)},
+		{"no windows", at(1, 3, 0), nil, at(1, 3, 0)},
+	}
+	for _, tt := range tests {
+		if got := nextAllowedTime(tt.t, tt.windows); !got.Equal(tt.want) {
+			t.Errorf("%s: nextAllowedTime(%v) = %v, want %v", tt.name, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestNextAllowedTimeDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data not available: %v", err)
+	}
+
+	// 2024-03-10 凌晨 2 点切换到夏令时，当天的 09:00 距零点只有 8 个小时
+	w := TimeWindow{Start: 9 * time.Hour, End: 17 * time.Hour, Location: loc}
+	got := nextAllowedTime(time.Date(2024, 3, 10, 1, 0, 0, 0, loc), []TimeWindow{w})
+	if want := time.Date(2024, 3, 10, 9, 0, 0, 0, loc); !got.Equal(want) {
+		t.Errorf("nextAllowedTime on DST day = %v, want %v", got, want)
+	}
+}
+
+func TestPushWindowedDefersOutsideWindow(t *testing.T) {
+	q, clock := newTestQueue(t)
+	windows := []TimeWindow{{Start: 9 * time.Hour, End: 17 * time.Hour, Location: time.UTC}}
+
+	// testStart 是零点，一小时后不在允许的时间段内，顺延到 09:00
+	ran := make(chan time.Time, 1)
+	q.PushWindowed(time.Hour, windows, func() { ran <- clock.Now() })
+
+	fireNext(clock, time.Hour)
+	fireNext(clock, 8*time.Hour)
+	if at, want := receive(t, ran), testStart.Add(9*time.Hour); !at.Equal(want) {
+		t.Errorf("windowed task fired at %v, want %v", at, want)
+	}
+}